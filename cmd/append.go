@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxEntryTitleLength is the maximum length of the question in the header of a notebook entry
+const maxEntryTitleLength = 80
+
+// entryHeader returns the header of an entry of the --append notebook: the time of the answer and the
+// first line of its question
+func entryHeader(now time.Time, question string) string {
+	title := entryTitle(question)
+	if title == "" {
+		return fmt.Sprintf("## %s\n\n", now.Format("2006-01-02 15:04:05"))
+	}
+	return fmt.Sprintf("## %s · %s\n\n", now.Format("2006-01-02 15:04:05"), title)
+}
+
+// transcriptEntry renders a question and its answer as a section of a transcript: the question as a header
+// (quoted in full when it spans several lines), then the answer
+func transcriptEntry(now time.Time, question, answer string) string {
+	var entry strings.Builder
+	entry.WriteString(entryHeader(now, question))
+	if strings.Contains(strings.TrimSpace(question), "\n") {
+		entry.WriteString("> " + strings.ReplaceAll(strings.TrimSpace(question), "\n", "\n> ") + "\n\n")
+	}
+	entry.WriteString(strings.TrimSpace(answer) + "\n\n")
+	return entry.String()
+}
+
+// entryTitle returns the first line of a question, shortened to maxEntryTitleLength
+func entryTitle(question string) string {
+	title, _, multiline := strings.Cut(strings.TrimSpace(question), "\n")
+	title = strings.TrimSpace(title)
+	if runes := []rune(title); len(runes) > maxEntryTitleLength {
+		return string(runes[:maxEntryTitleLength]) + "…"
+	} else if multiline {
+		return title + " …"
+	}
+	return title
+}
+
+// openNotebook opens the --append notebook for appending, creating it (and its directory) when missing
+func openNotebook(path string) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// appendResult appends an answer to the --append notebook as a new entry and returns its path
+func appendResult(opts askOptions, content string) (string, error) {
+	file, err := openNotebook(opts.appendFile)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	entry := entryHeader(time.Now(), opts.question) + strings.TrimSpace(content) + sourcesSection("###", opts.sources) + "\n\n"
+	if _, err := file.WriteString(entry); err != nil {
+		return "", err
+	}
+	return opts.appendFile, nil
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/codeblocks"
+	"github.com/budgies-nest/budgie-cli/pkg/history"
+	"github.com/budgies-nest/budgie-cli/pkg/textdiff"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// RunApply handles the apply command execution: it writes the code blocks of an answer annotated with a file
+// path (```go title=pkg/foo.go) into the workspace, after showing their diff and a confirmation per file
+func RunApply(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	historyID, _ := cmd.Flags().GetInt("history")
+	yes, _ := cmd.Flags().GetBool("yes")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	answer, origin, err := applySource(configFile, args, historyID)
+	if err != nil {
+		return err
+	}
+
+	var blocks []codeblocks.Block
+	for _, block := range codeblocks.Parse(answer) {
+		if block.Path != "" {
+			blocks = append(blocks, block)
+		}
+	}
+	if len(blocks) == 0 {
+		fmt.Printf("No code block annotated with a file path in %s (e.g. ```go title=pkg/foo.go)\n", origin)
+		return nil
+	}
+	if !yes && !dryRun && !isTerminal(os.Stdin) {
+		return fmt.Errorf("cannot confirm the changes: stdin is not a terminal (use --yes or --dry-run)")
+	}
+
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	fmt.Println(dimStyle.Render(fmt.Sprintf("📄 %d file(s) in %s", len(blocks), origin)))
+
+	written := 0
+	for _, block := range blocks {
+		ok, err := applyBlock(block, yes, dryRun)
+		if err != nil {
+			return err
+		}
+		if ok {
+			written++
+		}
+	}
+
+	if dryRun {
+		return nil
+	}
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ %d file(s) written", written)))
+	return nil
+}
+
+// applySource returns the answer whose code blocks are applied and a description of where it comes from:
+// the Markdown file given as argument (e.g. a result file), or an answer of the history (the last one by default)
+func applySource(configFile string, args []string, historyID int) (string, string, error) {
+	if len(args) > 0 {
+		if historyID > 0 {
+			return "", "", fmt.Errorf("--history cannot be used with a file")
+		}
+		content, err := os.ReadFile(args[0])
+		if err != nil {
+			return "", "", fmt.Errorf("error reading %s: %w", args[0], err)
+		}
+		return string(content), args[0], nil
+	}
+
+	path := history.Path(configFile)
+	if historyID > 0 {
+		entry, err := history.Get(path, historyID)
+		if err != nil {
+			return "", "", err
+		}
+		return entry.Answer, fmt.Sprintf("history answer #%d", entry.ID), nil
+	}
+	entries, err := history.Load(path)
+	if err != nil {
+		return "", "", fmt.Errorf("error reading history: %w", err)
+	}
+	if len(entries) == 0 {
+		return "", "", errors.New("no answer in the history: give a result file, or enable the history with budgie config set history true")
+	}
+	last := entries[len(entries)-1]
+	return last.Answer, fmt.Sprintf("history answer #%d", last.ID), nil
+}
+
+// applyBlock shows the diff of a code block against its file and writes it once confirmed.
+// It reports whether the file was written.
+func applyBlock(block codeblocks.Block, yes, dryRun bool) (bool, error) {
+	path := filepath.FromSlash(block.Path)
+	// The files are only written inside the workspace
+	if err := checkWorkspacePath(block.Path); err != nil {
+		fmt.Printf("⚠️  Skipping: %v\n", err)
+		return false, nil
+	}
+
+	var current []byte
+	oldName := "a/" + block.Path
+	info, err := os.Stat(path)
+	switch {
+	case err == nil && info.IsDir():
+		fmt.Printf("⚠️  Skipping %s: it is a directory\n", block.Path)
+		return false, nil
+	case err == nil:
+		if current, err = readWorkspaceFile(path); err != nil {
+			return false, fmt.Errorf("error reading %s: %w", block.Path, err)
+		}
+	case errors.Is(err, os.ErrNotExist):
+		oldName = "/dev/null"
+	default:
+		return false, fmt.Errorf("error reading %s: %w", block.Path, err)
+	}
+
+	diff := textdiff.Unified(oldName, "b/"+block.Path, string(current), block.Content)
+	if diff == "" {
+		fmt.Printf("✅ %s is up to date\n", block.Path)
+		return false, nil
+	}
+	fmt.Println()
+	printDiff(diff)
+	fmt.Println()
+	if dryRun {
+		return false, nil
+	}
+
+	if !yes {
+		confirmed := false
+		title := fmt.Sprintf("Write %s?", block.Path)
+		if current == nil {
+			title = fmt.Sprintf("Create %s?", block.Path)
+		}
+		err := huh.NewConfirm().
+			Title(title).
+			Affirmative("Write").
+			Negative("Skip").
+			Value(&confirmed).
+			Run()
+		if err != nil {
+			return false, fmt.Errorf("error getting confirmation: %w", err)
+		}
+		if !confirmed {
+			fmt.Printf("⏭️  %s skipped\n", block.Path)
+			return false, nil
+		}
+	}
+
+	mode := os.FileMode(0644)
+	if info != nil {
+		mode = info.Mode().Perm()
+	}
+	if err := writeWorkspaceFile(path, []byte(block.Content), mode); err != nil {
+		return false, fmt.Errorf("error writing %s: %w", block.Path, err)
+	}
+	fmt.Printf("📝 %s written\n", block.Path)
+	return true, nil
+}
+
+// writeWorkspaceFile writes a file of the workspace, creating its folders, through an os.Root like
+// readWorkspaceFile
+func writeWorkspaceFile(path string, content []byte, mode os.FileMode) error {
+	root, err := os.OpenRoot(".")
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	path = filepath.FromSlash(path)
+	dir := ""
+	for _, name := range strings.Split(filepath.Dir(path), string(filepath.Separator)) {
+		if name == "." {
+			continue
+		}
+		dir = filepath.Join(dir, name)
+		if err := root.Mkdir(dir, 0755); err != nil && !errors.Is(err, os.ErrExist) {
+			return err
+		}
+	}
+	file, err := root.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(content)
+	return errors.Join(err, file.Close())
+}
+
+// printDiff prints a unified diff, the added lines in green and the removed ones in red
+func printDiff(diff string) {
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	redStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+	boldStyle := lipgloss.NewStyle().Bold(true)
+
+	for _, line := range strings.Split(strings.TrimSuffix(diff, "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+			fmt.Println(boldStyle.Render(line))
+		case strings.HasPrefix(line, "@@"):
+			fmt.Println(blueStyle.Render(line))
+		case strings.HasPrefix(line, "+"):
+			fmt.Println(greenStyle.Render(line))
+		case strings.HasPrefix(line, "-"):
+			fmt.Println(redStyle.Render(line))
+		default:
+			fmt.Println(line)
+		}
+	}
+}
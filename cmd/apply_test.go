@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/codeblocks"
+)
+
+// The blocks are written in the workspace only, even with --yes
+func TestApplyBlock(t *testing.T) {
+	base := t.TempDir()
+	workspace := filepath.Join(base, "workspace")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{workspace, outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		filepath.Join(workspace, "shared"):   outside,
+		filepath.Join(workspace, "dangling"): filepath.Join(outside, "created.txt"),
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symbolic links not supported: %v", err)
+		}
+	}
+	t.Chdir(workspace)
+
+	tests := []struct {
+		path    string
+		written bool
+	}{
+		{"pkg/new/file.go", true},
+		{"README.md", true},
+		{"../outside/escape.txt", false},
+		{"shared/escape.txt", false},
+		{"dangling", false},
+	}
+	for _, test := range tests {
+		written, err := applyBlock(codeblocks.Block{Path: test.path, Content: "package new\n"}, true, false)
+		if written != test.written {
+			t.Errorf("applyBlock(%s) = %v, %v, want written: %v", test.path, written, err, test.written)
+		}
+		if test.written {
+			if content, err := os.ReadFile(filepath.FromSlash(test.path)); err != nil || string(content) != "package new\n" {
+				t.Errorf("%s = %q, %v", test.path, content, err)
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > 0 {
+		t.Errorf("%d file(s) written outside the workspace", len(entries))
+	}
+}
@@ -2,475 +2,1577 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/debuglog"
+	"github.com/budgies-nest/budgie-cli/pkg/glossary"
+	"github.com/budgies-nest/budgie-cli/pkg/prompts"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
 	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/results"
+	"github.com/budgies-nest/budgie-cli/pkg/session"
+	"github.com/budgies-nest/budgie-cli/pkg/structured"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
 	"github.com/budgies-nest/budgie-cli/pkg/utils"
+	"github.com/budgies-nest/budgie-cli/pkg/wsstream"
 	"github.com/budgies-nest/budgie/agents"
+	"github.com/budgies-nest/budgie/helpers"
 	"github.com/charmbracelet/huh"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/openai/openai-go"
 	"github.com/spf13/cobra"
 )
 
-// processQuestion handles a single question processing workflow
-func processQuestion(question, systemFile, configFile, outputPath, useFile, embeddingsFile string, generate, ragEnabled bool) error {
-	config, err := config.LoadConfig(configFile)
-	if err != nil {
-		return fmt.Errorf("error loading config file: %w", err)
+// ragContextHeader prefixes the message carrying the retrieved documentation chunks
+const ragContextHeader = "Relevant context from documentation:\n\n"
+
+// askOptions groups the ask command flags shared by the single question and interactive modes
+type askOptions struct {
+	systemFiles    []string
+	configFile     string
+	overrides      config.Overrides
+	outputPath     string
+	outputName     string
+	quiet          bool
+	raw            bool
+	showRedactions bool
+	noPost         bool
+	noCache        bool
+	copy           string
+	appendFile     string
+	noFrontMatter  bool
+	useFile        string
+	useContent     string
+	contextDir     string
+	contextContent string
+	urls           []string
+	urlContents    []string
+	diff           string
+	diffContent    string
+	embeddingsFile string
+	// store is the named store the retrieval is scoped to (its embeddings are embeddingsFile)
+	store         string
+	generate      bool
+	ragEnabled    bool
+	session       string
+	calibrate     bool
+	topK          int
+	keywordWeight float64
+	rerank        bool
+	clarify       bool
+	piped         string
+	format        string
+	out           io.Writer
+	// log receives the progress and the diagnostics (stdout unless the answer must be alone on stdout)
+	log io.Writer
+	// jsonSchema constrains the answer to JSON matching a schema, invalid answers are asked again schemaRetries times
+	jsonSchema    *structured.Schema
+	schemaRetries int
+	offline       bool
+	heartbeat     time.Duration
+	sameLanguage  bool
+	filters       []rag.MetadataCondition
+	checkPrevious bool
+	pager         string
+	modelCheck    *modelCheck
+	tools         *toolSession
+	ws            *wsstream.Server
+	// question and sources describe the answer being generated in its result file
+	question string
+	sources  []rag.Similarity
+}
+
+// writer returns where the answer is printed (stdout unless --format moved the diagnostics to stderr)
+func (opts askOptions) writer() io.Writer {
+	if opts.out == nil {
+		return os.Stdout
 	}
+	return opts.out
+}
 
-	systemInstructions, err := os.ReadFile(systemFile)
-	if err != nil {
-		return fmt.Errorf("error reading system instructions file: %w", err)
+// logWriter returns where the progress and the diagnostics are printed
+func (opts askOptions) logWriter() io.Writer {
+	if opts.log == nil {
+		return os.Stdout
+	}
+	return opts.log
+}
+
+// ragRequested checks if RAG search is requested (either via --rag flag or #rag prefix)
+func ragRequested(opts askOptions, question string) bool {
+	_, _, prefixed := ragPrefix(question)
+	return opts.ragEnabled || prefixed
+}
+
+// ragStorePaths returns the vector stores searched by RAG questions:
+// the docs embeddings file and the code collection maintained by `budgie index`
+func ragStorePaths(configFile, embeddingsFile string) []string {
+	return []string{
+		embeddingsFile,
+		filepath.Join(filepath.Dir(configFile), codeStoreName),
 	}
+}
+
+// searchedStores returns the vector stores searched by the RAG questions of ask: only the named store
+// with --store or #rag:<store>
+func searchedStores(opts askOptions) []string {
+	if opts.store != "" {
+		return []string{opts.embeddingsFile}
+	}
+	return ragStorePaths(opts.configFile, opts.embeddingsFile)
+}
 
-	var similarities []string
-	var actualQuestion = question
+// searchContext performs the RAG similarity search when requested (either via --rag flag or #rag prefix).
+// It returns the question without the #rag prefix and the found similarities, best scores first.
+// It fails when the user aborts because the embedding model changed, or when #rag:<store> names an unknown store.
+func searchContext(config *config.Config, opts askOptions, question string) (string, []rag.Similarity, error) {
+	if !ragRequested(opts, question) {
+		return question, nil, nil
+	}
 
-	// Check if RAG search is requested (either via --rag flag or #rag prefix)
-	ragRequested := ragEnabled || strings.HasPrefix(question, "#rag ")
-	
-	if ragRequested {
-		// Remove #rag prefix if present (when using --rag flag, #rag prefix is not needed)
-		if strings.HasPrefix(question, "#rag ") {
-			actualQuestion = strings.TrimPrefix(question, "#rag ")
+	// Remove #rag prefix if present (when using --rag flag, #rag prefix is not needed)
+	store, actualQuestion, _ := ragPrefix(question)
+	if store != "" {
+		var err error
+		if opts, err = withStore(config, opts, store); err != nil {
+			return actualQuestion, nil, err
 		}
+	}
 
-		// Create search agent and perform similarity search
-		fmt.Print("🔍 Searching... ")
-		searchAgent, err := rag.CreateSearchAgent(config, embeddingsFile)
+	useRAG, err := checkEmbeddingModel(config, opts)
+	if err != nil || !useRAG {
+		return actualQuestion, nil, err
+	}
+
+	// Create search agent and perform similarity search
+	if !opts.quiet {
+		fmt.Fprint(opts.logWriter(), "🔍 Searching... ")
+	}
+	opts.ws.Status("searching", "")
+	done := debuglog.Phase("rag search")
+	similarities, searched, errs := ragSearch(config, opts, actualQuestion)
+	done()
+	for _, err := range errs {
+		fmt.Fprintf(opts.logWriter(), "\nWarning: %v\n", err)
+	}
+	for _, similarity := range similarities {
+		debuglog.Printf("retrieved %s (score %.4f)", similarity.ID, similarity.Score)
+	}
+	opts.ws.Status("search-done", fmt.Sprintf("%d", len(similarities)))
+
+	// Display similarities in green
+	if !opts.quiet {
+		if searched {
+			fmt.Fprintln(opts.logWriter(), "✓")
+		}
+		rag.DisplaySimilarities(opts.logWriter(), similarities)
+	}
+
+	return actualQuestion, similarities, nil
+}
+
+// ragSearch searches every RAG store for the question (expanded with the glossary definitions), best scores first
+// (or most relevant first according to the chat model with rag.rerank), without printing anything. It reports whether a store was searched and the errors of the failed stores.
+func ragSearch(config *config.Config, opts askOptions, question string) ([]rag.Similarity, bool, []error) {
+	// Expand project-specific terms with their glossary definitions
+	entries, _ := glossary.Load(glossaryPath(opts.configFile))
+	searchQuery, err := redactText(config, glossary.Expand(question, entries))
+	if err != nil {
+		return nil, false, []error{err}
+	}
+
+	// The rerank stage scores more candidates than the chunks it keeps
+	searchConfig := config
+	if config.RAG.Rerank {
+		candidatesConfig := *config
+		candidatesConfig.TopK = rerankCandidates(config)
+		searchConfig = &candidatesConfig
+	}
+
+	var similarities []rag.Similarity
+	var errs []error
+	searched := false
+	for _, storePath := range searchedStores(opts) {
+		// The embedding model of the docs store was checked by checkEmbeddingModel
+		searchAgent, err := rag.CreateSearchAgent(config, storePath, storePath == opts.embeddingsFile)
 		if err != nil {
-			fmt.Printf("\nWarning: Error creating search agent: %v\n", err)
+			errs = append(errs, fmt.Errorf("error creating search agent: %w", err))
 		} else if searchAgent != nil {
-			similarities, err = rag.SearchSimilarities(actualQuestion, searchAgent, config)
+			var filter rag.Filter
+			if config.SameLanguage {
+				filter = rag.LanguageFilter(storePath, question)
+			}
+			metadataFilter, err := rag.MetadataFilter(storePath, opts.filters)
 			if err != nil {
-				fmt.Printf("\nWarning: Error searching similarities: %v\n", err)
-			} else {
-				fmt.Println("✓")
+				rag.CloseStore(searchAgent)
+				errs = append(errs, fmt.Errorf("error reading the chunk metadata: %w", err))
+				continue
+			}
+			found, err := rag.SearchSimilarities(searchQuery, searchAgent, searchConfig, rag.AllFilters(filter, metadataFilter))
+			rag.CloseStore(searchAgent)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("error searching similarities: %w", err))
+				continue
 			}
+			setSources(storePath, found)
+			similarities = append(similarities, found...)
+			searched = true
+		}
+	}
+	similarities = rag.TopK(similarities, searchConfig.TopK)
+	if config.RAG.Rerank {
+		var err error
+		if similarities, err = rerankChunks(config, question, similarities); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return fitContext(config, similarities), searched, errs
+}
+
+// fitContext truncates or drops the lowest-scoring chunks so the context fits in rag.max-context-tokens,
+// reporting them in the debug log
+func fitContext(config *config.Config, similarities []rag.Similarity) []rag.Similarity {
+	kept, dropped := rag.FitContext(similarities, config.RAG.MaxContextTokens)
+	if len(kept) > 0 && kept[len(kept)-1].Truncated {
+		last := kept[len(kept)-1]
+		debuglog.Printf("context budget (%d tokens): truncated %s (score %.4f) to ~%d tokens", config.RAG.MaxContextTokens, last.ID, last.Score, tokens.Estimate(last.Content))
+	}
+	for _, similarity := range dropped {
+		debuglog.Printf("context budget (%d tokens): dropped %s (score %.4f, ~%d tokens)", config.RAG.MaxContextTokens, similarity.ID, similarity.Score, tokens.Estimate(similarity.Content))
+	}
+	return kept
+}
+
+// newChatAgent creates a chat agent for the configured provider and model with the given conversation
+func newChatAgent(config *config.Config, messages []openai.ChatCompletionMessageParamUnion) (*agents.Agent, error) {
+	clientOption, err := provider.ClientOption(config)
+	if err != nil {
+		return nil, err
+	}
+	// Nothing leaves the machine before the secrets are masked
+	messages, err = redactMessages(config, messages)
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := agents.NewAgent("budgie",
+		clientOption,
+		agents.WithParams(completionParams(config, messages)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating agent: %w", err)
+	}
+	return agent, nil
+}
+
+// completionParams returns the parameters of the completion of a conversation with the configured model,
+// temperature and seed (a new parameter shaping the answer belongs to the answer cache key too, see
+// answerCacheKey)
+func completionParams(config *config.Config, messages []openai.ChatCompletionMessageParamUnion) openai.ChatCompletionNewParams {
+	params := openai.ChatCompletionNewParams{
+		Model:       config.Model,
+		Temperature: openai.Opt(config.Temperature),
+		Messages:    messages,
+	}
+	if config.Seed != nil {
+		params.Seed = openai.Opt(*config.Seed)
+	}
+	return params
+}
+
+// errStreamStopped is returned when the user stops the streaming of an answer (ESC, Ctrl+C or SIGINT)
+var errStreamStopped = errors.New("streaming stopped")
+
+// streamCompletion creates an agent with the given conversation and streams its response to the terminal
+// (and to the websocket clients when --ws is set). With --generate, the response is also written to its
+// result file as it streams: it returns the path of the result file ("" when nothing was written).
+func streamCompletion(config *config.Config, messages []openai.ChatCompletionMessageParamUnion, opts askOptions) (string, string, error) {
+	ws := opts.ws
+	out, log := opts.writer(), opts.logWriter()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// The post-processed answers are shown once complete
+	post := postProcessors(config, opts)
+	var pager *pagerWriter
+	var markdown *markdownWriter
+	interactive := opts.format == formatMarkdown || opts.format == ""
+	if interactive {
+		// Long answers are shown in the pager once complete
+		if pager = newPagerWriter(opts.pager, out); pager != nil {
+			out = pager
 		}
+		// The Markdown renderer is created first: it queries the background color of the terminal
+		if markdown = newMarkdownWriter(opts.raw || opts.writer() != os.Stdout, out); markdown != nil {
+			out = markdown
+		}
+		if len(post) > 0 && !opts.quiet {
+			fmt.Fprintf(log, "🔧 The answer is shown once post-processed (%s)\n", strings.Join(post, " | "))
+		}
+	}
+	// SIGINT/SIGTERM stop the stream whatever the format, so the partial answer is still saved. The keyboard
+	// is only taken over for the interactive (markdown) output on a terminal: CI, pipes and the json/plain
+	// formats leave it alone.
+	listener := utils.ListenForCancel(cancel, interactive && isTerminal(os.Stdin) && isTerminal(os.Stdout), os.Stderr)
+	defer listener.Stop()
+	if listener.Keyboard() && !opts.quiet {
+		fmt.Fprintln(log, "💡 Press ESC or Ctrl+C to stop streaming")
+	}
 
-		// Display similarities in green
-		rag.DisplaySimilarities(similarities)
+	// Report the progress of long completions
+	beat := startHeartbeat(opts.heartbeat)
+	defer beat.Stop()
+
+	// Write the response to the result file as it streams, so an interruption or a crash does not lose it
+	// (the post-processed answers are saved once processed)
+	var tee *resultTee
+	if opts.generate && len(post) == 0 {
+		var err error
+		if tee, err = startResultTee(config, opts); err != nil {
+			fmt.Fprintf(log, "Warning: error creating the result file: %v\n", err)
+		}
+	}
+
+	ws.Status("streaming", "")
+	done := debuglog.Phase("completion")
+	firstToken := true
+	response, err := streamWithRetry(ctx, config, messages, func(content string) {
+		if firstToken {
+			debuglog.Printf("completion: first token")
+			firstToken = false
+		}
+		if len(post) == 0 {
+			fmt.Fprint(out, content)
+		}
+		ws.Token(content)
+		beat.Add(content)
+		tee.Write(content)
+	}, func(retry int, delay time.Duration, err error) {
+		ws.Status("retrying", err.Error())
+		yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+		fmt.Fprintln(log)
+		fmt.Fprintln(log, yellowStyle.Render(fmt.Sprintf("⚠️  %v", err)))
+		fmt.Fprintln(log, yellowStyle.Render(fmt.Sprintf("🔁 Retrying in %s (attempt %d/%d)...", delay, retry+1, config.RetryAttempts)))
+	})
+	listener.Stop()
+	done()
+	if len(post) > 0 {
+		// An interrupted answer is shown as received
+		if err == nil {
+			response = applyPostProcessors(post, response, log)
+		}
+		fmt.Fprint(out, response)
+	}
+	if flushErr := markdown.Flush(); flushErr != nil {
+		fmt.Fprintf(log, "Warning: error rendering the answer: %v\n", flushErr)
+	}
+	if err != nil {
+		ws.Status("error", err.Error())
+		if resultFile := tee.Interrupt(); resultFile != "" && !opts.quiet {
+			fmt.Fprintln(log)
+			yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+			fmt.Fprintln(log, yellowStyle.Render(fmt.Sprintf("💾 Partial result saved to: %s", resultFile)))
+		}
+		if errors.Is(err, context.Canceled) {
+			return response, "", errStreamStopped
+		}
+		return response, "", fmt.Errorf("error during streaming: %w", err)
+	}
+	ws.Status("done", "")
+
+	fmt.Fprintln(out)
+
+	if pager.Paged() {
+		if err := pager.Page(); err != nil {
+			fmt.Fprintf(log, "Warning: %v\n", err)
+		}
+	}
+
+	resultFile, err := tee.Finalize(response)
+	if err != nil {
+		return response, "", fmt.Errorf("error saving result to file: %w", err)
+	}
+	return response, resultFile, nil
+}
+
+// saveResult writes the response to a timestamped result file in the output directory,
+// organized according to the results-layout config option (or appends it to the --append notebook)
+func saveResult(config *config.Config, opts askOptions, content string) (string, error) {
+	if opts.appendFile != "" {
+		path, err := appendResult(opts, content)
+		if err == nil {
+			printResultSaved(opts, path)
+		}
+		return path, err
+	}
+	filepath, err := writeResult(config, opts, content)
+	if err != nil {
+		return "", err
+	}
+	printResultSaved(opts, filepath)
+	return filepath, nil
+}
+
+// printResultSaved tells where the result file was saved (unless --quiet)
+func printResultSaved(opts askOptions, path string) {
+	if opts.quiet {
+		return
+	}
+	blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+	fmt.Fprintln(opts.logWriter(), blueStyle.Render(fmt.Sprintf("💾 Result saved to: %s", path)))
+}
+
+// writeResult writes the result file without printing anything and returns its path.
+// The file starts with a front-matter recording the conditions which produced the answer.
+func writeResult(config *config.Config, opts askOptions, content string) (string, error) {
+	now := time.Now()
+	filepath, err := resultPath(config, opts, now)
+	if err != nil {
+		return "", err
+	}
+
+	content += sourcesSection("##", opts.sources)
+	if !opts.noFrontMatter {
+		content = resultMetadata(config, opts, now).FrontMatter() + content
+	}
+	if err := os.WriteFile(filepath, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return filepath, nil
+}
+
+// resultPath returns a free path for the result file of an answer completed at a time, named after the
+// output-name template (--output-name, or the output-name config option), and creates its directory
+func resultPath(config *config.Config, opts askOptions, now time.Time) (string, error) {
+	dir, err := results.Dir(opts.outputPath, config.ResultsLayout, opts.session, now)
+	if err != nil {
+		return "", err
+	}
+	template := opts.outputName
+	if template == "" {
+		template = config.OutputName
+	}
+	if err := results.CheckNameTemplate(template); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	name := results.FileName(template, now, opts.question, map[string]string{
+		"model":   config.Model,
+		"profile": config.ActiveProfile,
+		"session": opts.session,
+	})
+	path := results.UniquePath(filepath.Join(dir, name))
+	recordResult(opts, path)
+	return path, nil
+}
+
+// baseMessages builds the messages every conversation starts with: the system instructions,
+// the project glossary (if any), the additional file specified via --use (read by loadUseFile)
+// and the git diff of --diff (read by loadDiff)
+func baseMessages(opts askOptions) ([]openai.ChatCompletionMessageParamUnion, error) {
+	systemInstructions, err := readSystemInstructions(opts.systemFiles)
+	if err != nil {
+		return nil, fmt.Errorf("error reading system instructions file: %w", err)
 	}
 
-	// Build messages array starting with system message
 	messages := []openai.ChatCompletionMessageParamUnion{
-		openai.SystemMessage(string(systemInstructions)),
+		openai.SystemMessage(systemInstructions),
+	}
+
+	// Add the project glossary as compact context
+	entries, err := glossary.Load(glossaryPath(opts.configFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading glossary file: %w", err)
+	}
+	if len(entries) > 0 {
+		messages = append(messages, openai.SystemMessage(glossary.Compact(entries)))
+	}
+
+	// Add the context pack of the --context directory
+	if opts.contextContent != "" {
+		messages = append(messages, openai.SystemMessage(opts.contextContent))
+	}
+
+	// Add the web pages of --url
+	for _, content := range opts.urlContents {
+		messages = append(messages, openai.SystemMessage(content))
 	}
 
 	// Add additional file content as system message if specified
-	if useFile != "" {
-		useFileContent, err := os.ReadFile(useFile)
+	if opts.useContent != "" {
+		messages = append(messages, openai.SystemMessage(opts.useContent))
+	}
+
+	// Add the git diff to review
+	if opts.diffContent != "" {
+		messages = append(messages, openai.SystemMessage(opts.diffContent))
+	}
+
+	return messages, nil
+}
+
+// loadUseFile reads the additional file specified via --use, limited to the attachment token limit
+func loadUseFile(config *config.Config, opts askOptions, confirm bool) (string, error) {
+	if opts.useFile == "" {
+		return "", nil
+	}
+	useFileContent, err := os.ReadFile(opts.useFile)
+	if err != nil {
+		return "", fmt.Errorf("error reading use file %s: %w", opts.useFile, err)
+	}
+	return limitAttachment(config, opts.useFile, string(useFileContent), confirm, opts.logWriter())
+}
+
+// glossaryPath returns the path of the project glossary, stored next to the config file
+func glossaryPath(configFile string) string {
+	return filepath.Join(filepath.Dir(configFile), "glossary.md")
+}
+
+// loadAskConfig loads the config file, completed by the ask flags overriding its RAG options
+func loadAskConfig(opts askOptions) (*config.Config, error) {
+	loaded, err := config.LoadConfig(opts.configFile, opts.overrides)
+	if err != nil {
+		return nil, fmt.Errorf("error loading config file: %w", err)
+	}
+	if opts.topK > 0 {
+		loaded.TopK = opts.topK
+	}
+	if opts.keywordWeight > 0 {
+		loaded.KeywordWeight = opts.keywordWeight
+	}
+	if opts.rerank {
+		loaded.RAG.Rerank = true
+	}
+	if opts.sameLanguage {
+		loaded.SameLanguage = true
+	}
+	if opts.checkPrevious {
+		loaded.CheckPrevious = true
+	}
+	if opts.showRedactions {
+		loaded.Redact.Show = true
+	}
+	return loaded, nil
+}
+
+// processQuestion handles a single question processing workflow
+func processQuestion(question string, opts askOptions) error {
+	config, err := loadAskConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	// Guard against huge attachments (--use, --from or piped files)
+	opts.useContent, err = loadUseFile(config, opts, true)
+	if err != nil {
+		return err
+	}
+	if opts.contextContent, err = loadContextPack(config, opts); err != nil {
+		return err
+	}
+	if opts.urlContents, err = loadURLPages(config, opts, true); err != nil {
+		return err
+	}
+	if opts.diff != "" {
+		if opts.diffContent, err = loadDiff(config, opts.diff, true, opts.logWriter()); err != nil {
+			return err
+		}
+	}
+	if opts.piped != "" {
+		opts.piped, err = limitAttachment(config, "Piped content", opts.piped, true, opts.logWriter())
 		if err != nil {
-			return fmt.Errorf("error reading use file %s: %w", useFile, err)
+			return err
+		}
+	}
+	question, err = limitAttachment(config, "Question", question, true, opts.logWriter())
+	if err != nil {
+		return err
+	}
+
+	// Ask one clarifying question first when the question is ambiguous
+	if opts.clarify && !opts.offline {
+		clarified, err := clarifyQuestion(config, nil, question, opts.logWriter())
+		if err != nil {
+			fmt.Fprintf(opts.logWriter(), "Warning: Error checking question clarity: %v\n", err)
+		} else {
+			question = clarified
+		}
+	}
+
+	// Offer the answer of a similar question asked before
+	if config.CheckPrevious && !opts.offline && opts.format == formatMarkdown && opts.piped == "" {
+		if _, shown := offerPreviousAnswer(config, opts, question); shown {
+			return nil
 		}
-		messages = append(messages, openai.SystemMessage(string(useFileContent)))
+	}
+
+	// Build messages array starting with system message
+	messages, err := baseMessages(opts)
+	if err != nil {
+		return err
+	}
+
+	actualQuestion, similarities, err := searchContext(config, opts, question)
+	if err != nil {
+		return err
 	}
 
 	// Add similarity results if found
 	if len(similarities) > 0 {
-		contextMessage := "Relevant context from documentation:\n\n" + strings.Join(similarities, "\n\n")
+		contextMessage := ragContextHeader + strings.Join(rag.Contents(similarities), "\n\n")
 		messages = append(messages, openai.UserMessage(contextMessage))
 	}
 
-	// Add user question (without #rag prefix if it was used)
-	messages = append(messages, openai.UserMessage(actualQuestion))
+	// Add the piped content as context for the question
+	if opts.piped != "" {
+		messages = append(messages, openai.UserMessage(pipedContextMessage(opts.piped)))
+	}
 
-	agent, err := agents.NewAgent("budgie",
-		agents.WithDMR(config.BaseURL),
-		agents.WithParams(openai.ChatCompletionNewParams{
-			Model:       config.Model,
-			Temperature: openai.Opt(config.Temperature),
-			Messages:    messages,
-		}),
-	)
-	if err != nil {
-		return fmt.Errorf("error creating agent: %w", err)
+	// Ask the model to rate its confidence and refuse when retrieval is weak
+	if opts.calibrate && ragRequested(opts, question) {
+		messages = append(messages, openai.SystemMessage(calibrationMessage(config, similarities, opts.logWriter())))
 	}
 
-	fmt.Println("💡 Press ESC to stop streaming")
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	utils.SetupEscListener(ctx, cancel)
+	// Describe the expected JSON to the providers without constrained decoding
+	if opts.jsonSchema != nil {
+		messages = append(messages, openai.SystemMessage(opts.jsonSchema.Instructions()))
+	}
 
-	var responseBuilder strings.Builder
-	_, err = agent.ChatCompletionStream(ctx, func(self *agents.Agent, content string, err error) error {
+	// Add user question (without #rag prefix if it was used)
+	messages = append(messages, openai.UserMessage(actualQuestion))
+
+	opts.question, opts.sources = actualQuestion, similarities
+
+	// Let the model call the project tools before it answers
+	if opts.tools != nil && !opts.offline {
+		done := debuglog.Phase("tool calls")
+		messages, err = opts.tools.callTools(config, messages)
+		done()
 		if err != nil {
 			return err
 		}
-		fmt.Print(content)
-		responseBuilder.WriteString(content)
-		return nil
-	})
-
-	if err != nil {
-		return fmt.Errorf("error during streaming: %w", err)
 	}
 
-	fmt.Println()
+	// A prompt answered before is answered from the cache
+	cacheKey := answerCacheKey(config, opts, messages)
+	cached, fromCache := cachedAnswer(config, opts, cacheKey)
+
+	start := time.Now()
+	var response, resultFile string
+	var usage *tokenUsage
+	if fromCache {
+		response = cached.Answer
+		if opts.format != formatJSON {
+			printCachedAnswer(opts, cached)
+		}
+	} else if opts.offline {
+		err = errOffline
+	} else if opts.jsonSchema != nil {
+		beat := startHeartbeat(opts.heartbeat)
+		done := debuglog.Phase("completion")
+		response, usage, err = completeStructured(config, messages, opts.jsonSchema, opts.schemaRetries)
+		done()
+		beat.Stop()
+		if err == nil && opts.format != formatJSON {
+			fmt.Fprintln(opts.writer(), response)
+		}
+	} else if opts.format == formatJSON {
+		beat := startHeartbeat(opts.heartbeat)
+		done := debuglog.Phase("completion")
+		response, usage, err = completeWithUsage(config, messages)
+		done()
+		beat.Stop()
+		if post := postProcessors(config, opts); err == nil && len(post) > 0 {
+			response = applyPostProcessors(post, response, opts.logWriter())
+		}
+	} else {
+		response, resultFile, err = streamCompletion(config, messages, opts)
+	}
 
-	if generate {
-		timestamp := time.Now().Format("2006-01-02-15-04-05")
-		filename := fmt.Sprintf("result-%s.md", timestamp)
-		filepath := filepath.Join(outputPath, filename)
+	// Fall back to an extractive answer when the chat backend is unreachable (it is not JSON)
+	offline := false
+	if (opts.offline || backendUnreachable(err)) && opts.jsonSchema == nil {
+		if answer, found, ok := offlineAnswer(config, opts, question, similarities); ok {
+			response, similarities, offline, err = answer, found, true, nil
+			opts.sources = similarities
+			if opts.format != formatJSON {
+				fmt.Fprintln(opts.writer(), response)
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	latency := time.Since(start)
+	if !offline && !fromCache {
+		recordUsage(config, opts, "ask", usage, latency, true)
+		cacheAnswer(config, opts, cacheKey, actualQuestion, response)
+	}
+	if !offline {
+		recordQuestion(config, opts, actualQuestion, response, false)
+		recordHistory(config, opts, "ask", actualQuestion, response, ragRequested(opts, question))
+		if opts.format == formatMarkdown || opts.format == "" {
+			printSources(opts, similarities)
+		}
+	}
 
-		err = os.WriteFile(filepath, []byte(responseBuilder.String()), 0644)
+	if resultFile != "" {
+		printResultSaved(opts, resultFile)
+	} else if opts.generate {
+		resultFile, err = saveResult(config, opts, response)
 		if err != nil {
 			return fmt.Errorf("error saving result to file: %w", err)
 		}
+	}
 
-		blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
-		fmt.Println(blueStyle.Render(fmt.Sprintf("💾 Result saved to: %s", filepath)))
+	if opts.copy != "" {
+		printCopy(opts, response, opts.copy)
+	}
+
+	if opts.format == formatJSON {
+		return writeEnvelope(opts.out, answerEnvelope{
+			Question:   actualQuestion,
+			Provider:   config.Provider,
+			Model:      config.Model,
+			Chunks:     similarities,
+			Response:   response,
+			Usage:      usage,
+			LatencyMs:  latency.Milliseconds(),
+			ResultFile: resultFile,
+			Offline:    offline,
+			Cached:     fromCache,
+		})
 	}
 
 	return nil
 }
 
-// RunAsk handles the ask command execution
-func RunAsk(cmd *cobra.Command, args []string) error {
-	systemFile, _ := cmd.Flags().GetString("system")
-	configFile, _ := cmd.Flags().GetString("config")
-	outputPath, _ := cmd.Flags().GetString("output")
-	generate, _ := cmd.Flags().GetBool("generate")
-	question, _ := cmd.Flags().GetString("question")
-	prompt, _ := cmd.Flags().GetBool("prompt")
-	useFile, _ := cmd.Flags().GetString("use")
-	fromFile, _ := cmd.Flags().GetString("from")
-	ragEnabled, _ := cmd.Flags().GetBool("rag")
-	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+// runInteractive handles the interactive TUI prompt mode
+func runInteractive(opts askOptions, initialQuestion string) error {
+	fmt.Println("Interactive mode - type '/bye' to exit")
+	fmt.Println()
 
-	if prompt {
-		fmt.Println("Interactive mode - type '/bye' to exit")
+	// Load config and system instructions once for the session
+	config, err := loadAskConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	opts.useContent, err = loadUseFile(config, opts, true)
+	if err != nil {
+		return err
+	}
+	if opts.contextContent, err = loadContextPack(config, opts); err != nil {
+		return err
+	}
+	if opts.urlContents, err = loadURLPages(config, opts, true); err != nil {
+		return err
+	}
+	if opts.diff != "" {
+		if opts.diffContent, err = loadDiff(config, opts.diff, true, opts.logWriter()); err != nil {
+			return err
+		}
+	}
+
+	// Initialize conversation history with system message
+	messages, err := baseMessages(opts)
+	if err != nil {
+		return err
+	}
+
+	// Resume the named session if it was saved before
+	sessionsDir := filepath.Join(filepath.Dir(opts.configFile), "sessions")
+	currentSession := opts.session
+	if currentSession != "" && session.Exists(sessionsDir, currentSession) {
+		saved, err := session.Load(sessionsDir, currentSession)
+		if err != nil {
+			return fmt.Errorf("error loading session: %w", err)
+		}
+		messages = saved.Messages
+		fmt.Printf("📂 Resumed session %s (%d messages)\n", currentSession, len(messages))
 		fmt.Println()
+	}
+
+	// The plugins of the user and of the trusted .budgie/plugins/ add their slash commands (the untrusted
+	// plugins of the default project were reported at startup)
+	pluginLog := io.Writer(os.Stdout)
+	if opts.configFile == defaultConfigFile {
+		pluginLog = io.Discard
+	}
+	available := loadPlugins(opts.configFile, pluginLog)
+
+	// lastQuestion and lastAnswer are the last exchange, rated with /feedback
+	var lastQuestion, lastAnswer string
+
+	// askQuestion runs one conversation turn and, when record is true, keeps it in the history
+	askQuestion := func(userInput string, record bool) error {
+		// An unknown store does not end the session
+		if store, _, _ := ragPrefix(userInput); store != "" {
+			if _, err := withStore(config, opts, store); err != nil {
+				fmt.Printf("❌ %v\n", err)
+				fmt.Println()
+				return nil
+			}
+		}
+
+		// Summarize the older exchanges when the history exceeds the token budget
+		if !opts.offline {
+			compacted, count, err := compactHistory(config, messages)
+			if err != nil {
+				fmt.Printf("Warning: Error compacting the conversation history: %v\n", err)
+			} else if count > 0 {
+				messages = compacted
+				fmt.Printf("🗜️  Summarized %d earlier message(s) to keep the history within %d tokens\n", count, config.HistoryTokenBudget)
+			}
+		}
+
+		// Ask one clarifying question first when the question is ambiguous
+		if opts.clarify {
+			clarified, err := clarifyQuestion(config, messages, userInput, os.Stdout)
+			if err != nil {
+				fmt.Printf("Warning: Error checking question clarity: %v\n", err)
+			} else {
+				userInput = clarified
+			}
+		}
 
-		// Load config and system instructions once for the session
-		config, err := config.LoadConfig(configFile)
+		// Offer the answer of a similar question asked before
+		if config.CheckPrevious && !opts.offline {
+			if answer, shown := offerPreviousAnswer(config, opts, userInput); shown {
+				lastQuestion, lastAnswer = userInput, answer
+				if record {
+					messages = append(messages, openai.UserMessage(userInput), openai.AssistantMessage(answer))
+				}
+				fmt.Println()
+				return nil
+			}
+		}
+
+		actualUserInput, similarities, err := searchContext(config, opts, userInput)
 		if err != nil {
-			return fmt.Errorf("error loading config file: %w", err)
+			return err
+		}
+
+		turn := slices.Clone(messages)
+
+		// Add similarity results if found
+		if len(similarities) > 0 {
+			contextMessage := ragContextHeader + strings.Join(rag.Contents(similarities), "\n\n")
+			turn = append(turn, openai.SystemMessage(contextMessage))
+		}
+
+		// Ask the model to rate its confidence and refuse when retrieval is weak
+		if opts.calibrate && ragRequested(opts, userInput) {
+			turn = append(turn, openai.SystemMessage(calibrationMessage(config, similarities, opts.logWriter())))
 		}
 
-		systemInstructions, err := os.ReadFile(systemFile)
+		// Add user message to conversation history (without #rag prefix if it was used)
+		turn = append(turn, openai.UserMessage(actualUserInput))
+		opts.question, opts.sources = actualUserInput, similarities
+
+		var assistantResponse, resultFile string
+		start := time.Now()
+		if opts.offline {
+			err = errOffline
+		} else {
+			if opts.tools != nil {
+				if turn, err = opts.tools.callTools(config, turn); err != nil {
+					return err
+				}
+			}
+			assistantResponse, resultFile, err = streamCompletion(config, turn, opts)
+			if err == nil {
+				recordUsage(config, opts, "ask", nil, time.Since(start), true)
+				recordQuestion(config, opts, actualUserInput, assistantResponse, false)
+				recordHistory(config, opts, "ask", actualUserInput, assistantResponse, ragRequested(opts, userInput))
+			}
+		}
+
+		// Fall back to an extractive answer when the chat backend is unreachable
+		if opts.offline || backendUnreachable(err) {
+			if answer, _, ok := offlineAnswer(config, opts, userInput, similarities); ok {
+				assistantResponse, err = answer, nil
+				fmt.Println(assistantResponse)
+			}
+		}
 		if err != nil {
-			return fmt.Errorf("error reading system instructions file: %w", err)
+			return err
+		}
+
+		lastQuestion, lastAnswer = actualUserInput, assistantResponse
+
+		// Add assistant response to conversation history
+		if record {
+			messages = append(turn, openai.AssistantMessage(assistantResponse))
+		}
+
+		printSources(opts, opts.sources)
+		if resultFile != "" {
+			printResultSaved(opts, resultFile)
+		} else if opts.generate {
+			if _, err := saveResult(config, opts, assistantResponse); err != nil {
+				fmt.Printf("Error saving result to file: %v\n", err)
+			}
+		}
+
+		fmt.Println()
+		return nil
+	}
+
+	// Handle --from (or --template) flag in interactive mode - trigger completion immediately
+	if initialQuestion != "" {
+		if err := askQuestion(initialQuestion, true); err != nil {
+			return err
+		}
+	}
+
+	for {
+		var userInput string
+		err := runPromptInput(huh.NewInput().
+			Title(promptTitle(opts)).
+			Description("Enter your question for the AI agent ('/bye' to exit, '/clear' to reset, '/use [file]' to load files, '/from [file]' to ask from a file (without a path, a file picker opens), '/save <name>' and '/load <name>' for sessions, '/oneshot <question>' to ask off the record, '/forget <N>' to drop exchanges, '/feedback good|bad' to rate the last answer, '/run <command>' to add a command output, '/diff [range]' to add the git diff, '/fetch [--embed] <url>' to add a web page, '/voice [file]' to dictate a question, '/copy [code]' to copy the last answer or its first code block, '/system <file>' to replace the system instructions, '/model <name>' and '/temp <value>' to switch the model and temperature, '/rag on|off' to toggle the RAG search, '#rag' prefix for RAG search when --rag flag not used ('#rag:<store>' to search a named store), tab to complete commands and file paths)").
+			Suggestions(interactiveSuggestions(sessionsDir, pluginNames(available))).
+			Value(&userInput))
+		if err != nil {
+			return fmt.Errorf("error getting user input: %w", err)
+		}
+
+		if userInput == "/bye" {
+			// Keep the named session up to date on exit
+			if currentSession != "" {
+				if path, err := session.Save(sessionsDir, currentSession, messages); err != nil {
+					fmt.Printf("❌ Error saving session: %v\n", err)
+				} else {
+					fmt.Printf("💾 Session saved to: %s\n", path)
+				}
+			}
+			fmt.Println("Goodbye!")
+			break
+		}
+
+		if userInput == "/save" || strings.HasPrefix(userInput, "/save ") {
+			name := strings.TrimSpace(strings.TrimPrefix(userInput, "/save"))
+			if name == "" {
+				name = currentSession
+			}
+			if name == "" {
+				fmt.Println("❌ Please specify a session name: /save <name>")
+				fmt.Println()
+				continue
+			}
+
+			path, err := session.Save(sessionsDir, name, messages)
+			if err != nil {
+				fmt.Printf("❌ Error saving session: %v\n", err)
+				fmt.Println()
+				continue
+			}
+			currentSession = name
+			fmt.Printf("✅ Session saved to: %s\n", path)
+			fmt.Println()
+			continue
+		}
+
+		if strings.HasPrefix(userInput, "/load ") {
+			name := strings.TrimSpace(strings.TrimPrefix(userInput, "/load "))
+			if name == "" {
+				fmt.Println("❌ Please specify a session name: /load <name>")
+				fmt.Println()
+				continue
+			}
+
+			saved, err := session.Load(sessionsDir, name)
+			if err != nil {
+				fmt.Printf("❌ Error loading session %s: %v\n", name, err)
+				fmt.Println()
+				continue
+			}
+			messages = saved.Messages
+			currentSession = name
+			fmt.Printf("✅ Session %s loaded (%d messages)\n", name, len(messages))
+			fmt.Println()
+			continue
+		}
+
+		if userInput == "/clear" {
+			// Reset conversation history with reloaded system instructions (and the initial use file)
+			reloaded, err := baseMessages(opts)
+			if err != nil {
+				fmt.Printf("Error reloading system instructions: %v\n", err)
+				continue
+			}
+			messages = reloaded
+
+			fmt.Println("✅ Conversation cleared and system instructions reloaded")
+			fmt.Println()
+			continue
+		}
+
+		if userInput == "/rag" || strings.HasPrefix(userInput, "/rag ") {
+			switch strings.TrimSpace(strings.TrimPrefix(userInput, "/rag")) {
+			case "on":
+				opts.ragEnabled = true
+				fmt.Println("✅ RAG search enabled for every question")
+			case "off":
+				opts.ragEnabled = false
+				fmt.Println("✅ RAG search disabled (prefix a question with #rag to search once)")
+			default:
+				fmt.Println("❌ Please specify the RAG mode: /rag on|off")
+			}
+			fmt.Println()
+			continue
+		}
+
+		if userInput == "/model" || strings.HasPrefix(userInput, "/model ") {
+			name := strings.TrimSpace(strings.TrimPrefix(userInput, "/model"))
+			if name == "" {
+				fmt.Printf("🤖 Model: %s (temperature %g)\n", config.Model, config.Temperature)
+				fmt.Println()
+				continue
+			}
+
+			// The next turns create their agent with the new model, the conversation is kept
+			model, err := switchModel(config, name)
+			if err != nil {
+				fmt.Printf("❌ %v\n", err)
+				fmt.Println()
+				continue
+			}
+			fmt.Printf("✅ Switched to model %s\n", model)
+			fmt.Println()
+			continue
 		}
 
-		// Initialize conversation history with system message
-		messages := []openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(string(systemInstructions)),
-		}
+		if userInput == "/temp" || strings.HasPrefix(userInput, "/temp ") {
+			value := strings.TrimSpace(strings.TrimPrefix(userInput, "/temp"))
+			if value == "" {
+				fmt.Printf("🌡️  Temperature: %g\n", config.Temperature)
+				fmt.Println()
+				continue
+			}
 
-		// Add additional file content as system message if specified via flag
-		if useFile != "" {
-			useFileContent, err := os.ReadFile(useFile)
+			temperature, err := parseTemperature(value)
 			if err != nil {
-				return fmt.Errorf("error reading use file %s: %w", useFile, err)
+				fmt.Printf("❌ %v\n", err)
+				fmt.Println()
+				continue
 			}
-			messages = append(messages, openai.SystemMessage(string(useFileContent)))
+			config.Temperature = temperature
+			fmt.Printf("✅ Temperature set to %g\n", temperature)
+			fmt.Println()
+			continue
 		}
 
-		// Handle --from flag in interactive mode - trigger completion immediately
-		if fromFile != "" {
-			fileContent, err := os.ReadFile(fromFile)
+		if userInput == "/system" || strings.HasPrefix(userInput, "/system ") {
+			path := strings.TrimSpace(strings.TrimPrefix(userInput, "/system"))
+			if path == "" {
+				fmt.Printf("📜 System instructions: %s\n", strings.Join(opts.systemFiles, ", "))
+				fmt.Println()
+				continue
+			}
+
+			instructions, err := readSystemInstructions([]string{path})
 			if err != nil {
-				return fmt.Errorf("error reading from file %s: %w", fromFile, err)
+				fmt.Printf("❌ Error reading system instructions %s: %v\n", path, err)
+				fmt.Println()
+				continue
 			}
-			userInput := string(fileContent)
+			// The new instructions replace the active ones, and are the ones /clear reloads
+			messages = replaceSystemPrompt(messages, instructions)
+			opts.systemFiles = []string{path}
+			fmt.Printf("✅ System instructions replaced by %s\n", path)
+			fmt.Println()
+			continue
+		}
 
-			var similarities []string
-			var actualUserInput = userInput
+		if userInput == "/use" || strings.HasPrefix(userInput, "/use ") {
+			filePaths := []string{strings.TrimSpace(strings.TrimPrefix(userInput, "/use"))}
 
-			// Check if RAG search is requested (either via --rag flag or #rag prefix)
-			ragRequested := ragEnabled || strings.HasPrefix(userInput, "#rag ")
-			
-			if ragRequested {
-				// Remove #rag prefix if present (when using --rag flag, #rag prefix is not needed)
-				if strings.HasPrefix(userInput, "#rag ") {
-					actualUserInput = strings.TrimPrefix(userInput, "#rag ")
+			// Without a path, the files are chosen with the file picker
+			if filePaths[0] == "" {
+				picked, err := pickFiles("Files to load (/use)", true)
+				if err != nil {
+					fmt.Printf("❌ %v\n", err)
+					fmt.Println()
+					continue
 				}
+				filePaths = picked
+			}
 
-				// Create search agent and perform similarity search
-				fmt.Print("🔍 Searching... ")
-				searchAgent, err := rag.CreateSearchAgent(config, embeddingsFile)
+			for _, filePath := range filePaths {
+				fileContent, err := os.ReadFile(filePath)
 				if err != nil {
-					fmt.Printf("\nWarning: Error creating search agent: %v\n", err)
-				} else if searchAgent != nil {
-					similarities, err = rag.SearchSimilarities(actualUserInput, searchAgent, config)
-					if err != nil {
-						fmt.Printf("\nWarning: Error searching similarities: %v\n", err)
-					} else {
-						fmt.Println("✓")
-					}
+					fmt.Printf("❌ Error reading file %s: %v\n", filePath, err)
+					continue
 				}
 
-				// Display similarities in green
-				rag.DisplaySimilarities(similarities)
-
-				// Add similarity results if found
-				if len(similarities) > 0 {
-					contextMessage := "Relevant context from documentation:\n\n" + strings.Join(similarities, "\n\n")
-					messages = append(messages, openai.SystemMessage(contextMessage))
+				content, err := limitAttachment(config, filePath, string(fileContent), true, opts.logWriter())
+				if err != nil {
+					fmt.Printf("❌ File %s not loaded: %v\n", filePath, err)
+					continue
 				}
+
+				messages = append(messages, openai.SystemMessage(content))
+				fmt.Printf("✅ File %s loaded as system message\n", filePath)
 			}
+			fmt.Println()
+			continue
+		}
 
-			// Add user message to conversation history (without #rag prefix if it was used)
-			messages = append(messages, openai.UserMessage(actualUserInput))
+		if userInput == "/fetch" || strings.HasPrefix(userInput, "/fetch ") {
+			address := strings.TrimSpace(strings.TrimPrefix(userInput, "/fetch"))
+			address, embed := strings.CutPrefix(address, "--embed ")
+			address = strings.TrimSpace(address)
+			if address == "" || address == "--embed" {
+				fmt.Println("❌ Please specify a URL: /fetch [--embed] <url>")
+				fmt.Println()
+				continue
+			}
 
-			// Create agent with current conversation history
-			agent, err := agents.NewAgent("budgie",
-				agents.WithDMR(config.BaseURL),
-				agents.WithParams(openai.ChatCompletionNewParams{
-					Model:       config.Model,
-					Temperature: openai.Opt(config.Temperature),
-					Messages:    messages,
-				}),
-			)
+			page, err := fetchPage(address)
 			if err != nil {
-				return fmt.Errorf("error creating agent: %v", err)
+				fmt.Printf("❌ %v\n", err)
+				fmt.Println()
+				continue
 			}
 
-			fmt.Println("💡 Press ESC to stop streaming")
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
-			utils.SetupEscListener(ctx, cancel)
-
-			var responseBuilder strings.Builder
-			_, err = agent.ChatCompletionStream(ctx, func(self *agents.Agent, content string, err error) error {
+			if embed {
+				path, err := embedPage(opts.configFile, opts.overrides, page)
 				if err != nil {
-					return err
+					fmt.Printf("❌ Page %s not embedded: %v\n", page.URL, err)
+				} else {
+					fmt.Printf("✅ Page %s saved to %s and embedded\n", page.Title, path)
 				}
-				fmt.Print(content)
-				responseBuilder.WriteString(content)
-				return nil
-			})
+				fmt.Println()
+				continue
+			}
 
+			content, err := limitAttachment(config, page.URL, pageContextMessage(page), true, opts.logWriter())
 			if err != nil {
-				return fmt.Errorf("error during streaming: %v", err)
+				fmt.Printf("❌ Page %s not loaded: %v\n", page.URL, err)
+				fmt.Println()
+				continue
 			}
-
+			messages = append(messages, openai.SystemMessage(content))
+			fmt.Printf("✅ Page %s (%s) added to the conversation (about %d tokens)\n", page.Title, page.URL, tokens.Estimate(content))
 			fmt.Println()
+			continue
+		}
 
-			// Add assistant response to conversation history
-			assistantResponse := responseBuilder.String()
-			messages = append(messages, openai.AssistantMessage(assistantResponse))
-
-			if generate {
-				timestamp := time.Now().Format("2006-01-02-15-04-05")
-				filename := fmt.Sprintf("result-%s.md", timestamp)
-				filepath := filepath.Join(outputPath, filename)
-
-				err = os.WriteFile(filepath, []byte(assistantResponse), 0644)
-				if err != nil {
-					fmt.Printf("Error saving result to file: %v\n", err)
-				} else {
-					blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
-					fmt.Println(blueStyle.Render(fmt.Sprintf("💾 Result saved to: %s", filepath)))
+		if strings.HasPrefix(userInput, "/forget") {
+			count := 1
+			if arg := strings.TrimSpace(strings.TrimPrefix(userInput, "/forget")); arg != "" {
+				n, err := strconv.Atoi(arg)
+				if err != nil || n < 1 {
+					fmt.Println("❌ Please specify a positive number of exchanges: /forget <N>")
+					fmt.Println()
+					continue
 				}
+				count = n
+			}
+
+			starts := exchangeStarts(messages)
+			if count > len(starts) {
+				count = len(starts)
+			}
+			if count == 0 {
+				fmt.Println("Nothing to forget")
+				fmt.Println()
+				continue
 			}
 
+			messages = messages[:starts[len(starts)-count]]
+			fmt.Printf("✅ Forgot the last %d exchange(s)\n", count)
 			fmt.Println()
+			continue
 		}
 
-		for {
-			var userInput string
-			err := huh.NewInput().
-				Title("What's your question?").
-				Description("Enter your question for the AI agent ('/bye' to exit, '/clear' to reset, '/use <file>' to load file, '/from <file>' to ask from file, '#rag' prefix for RAG search when --rag flag not used)").
-				Value(&userInput).
-				Run()
-			if err != nil {
-				return fmt.Errorf("error getting user input: %w", err)
+		if userInput == "/feedback" || strings.HasPrefix(userInput, "/feedback ") {
+			rating, comment, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(userInput, "/feedback")), " ")
+			if rating != "good" && rating != "bad" {
+				fmt.Println("❌ Please rate the last answer: /feedback good|bad [comment]")
+				fmt.Println()
+				continue
 			}
-
-			if userInput == "/bye" {
-				fmt.Println("Goodbye!")
-				break
+			if lastAnswer == "" {
+				fmt.Println("No answer to rate yet")
+				fmt.Println()
+				continue
 			}
-
-			if userInput == "/clear" {
-				// Reload system instructions
-				systemInstructions, err := os.ReadFile(systemFile)
-				if err != nil {
-					fmt.Printf("Error reloading system instructions: %v\n", err)
-					continue
-				}
-
-				// Reset conversation history with new system message
-				messages = []openai.ChatCompletionMessageParamUnion{
-					openai.SystemMessage(string(systemInstructions)),
-				}
-
-				// Re-add initial use file if it was specified via flag
-				if useFile != "" {
-					useFileContent, err := os.ReadFile(useFile)
-					if err != nil {
-						fmt.Printf("Error re-reading use file: %v\n", err)
-					} else {
-						messages = append(messages, openai.SystemMessage(string(useFileContent)))
-					}
-				}
-
-				fmt.Println("✅ Conversation cleared and system instructions reloaded")
+			if err := recordFeedback(config, opts, rating, strings.TrimSpace(comment), lastQuestion, lastAnswer); err != nil {
+				fmt.Printf("❌ Error recording feedback: %v\n", err)
 				fmt.Println()
 				continue
 			}
+			fmt.Println("✅ Thanks for your feedback")
+			fmt.Println()
+			continue
+		}
 
-			if strings.HasPrefix(userInput, "/use ") {
-				filePath := strings.TrimPrefix(userInput, "/use ")
-				filePath = strings.TrimSpace(filePath)
+		if userInput == "/copy" || strings.HasPrefix(userInput, "/copy ") {
+			what := strings.TrimSpace(strings.TrimPrefix(userInput, "/copy"))
+			if what != "" && what != copyCode {
+				fmt.Println("❌ Please specify what to copy: /copy or /copy code")
+				fmt.Println()
+				continue
+			}
+			if lastAnswer == "" {
+				fmt.Println("No answer to copy yet")
+				fmt.Println()
+				continue
+			}
+			printCopy(opts, lastAnswer, what)
+			fmt.Println()
+			continue
+		}
 
-				if filePath == "" {
-					fmt.Println("❌ Please specify a file path: /use <file-path>")
-					fmt.Println()
-					continue
-				}
+		if userInput == "/diff" || strings.HasPrefix(userInput, "/diff ") {
+			spec := strings.TrimSpace(strings.TrimPrefix(userInput, "/diff"))
+			content, err := loadDiff(config, spec, true, opts.logWriter())
+			if err != nil {
+				fmt.Printf("❌ Git diff not loaded: %v\n", err)
+				fmt.Println()
+				continue
+			}
 
-				fileContent, err := os.ReadFile(filePath)
-				if err != nil {
-					fmt.Printf("❌ Error reading file %s: %v\n", filePath, err)
-					fmt.Println()
-					continue
-				}
+			messages = append(messages, openai.SystemMessage(content))
+			fmt.Println("✅ Git diff loaded as system message")
+			fmt.Println()
+			continue
+		}
 
-				messages = append(messages, openai.SystemMessage(string(fileContent)))
-				fmt.Printf("✅ File %s loaded as system message\n", filePath)
+		if userInput == "/run" || strings.HasPrefix(userInput, "/run ") {
+			commandLine := strings.TrimSpace(strings.TrimPrefix(userInput, "/run"))
+			if commandLine == "" {
+				fmt.Println("❌ Please specify a command: /run <command>")
 				fmt.Println()
 				continue
 			}
 
-			if strings.HasPrefix(userInput, "/from ") {
-				filePath := strings.TrimPrefix(userInput, "/from ")
-				filePath = strings.TrimSpace(filePath)
-
-				if filePath == "" {
-					fmt.Println("❌ Please specify a file path: /from <file-path>")
-					fmt.Println()
-					continue
-				}
+			content, err := runCommand(config, commandLine)
+			if err != nil {
+				fmt.Printf("❌ Command output not added: %v\n", err)
+				fmt.Println()
+				continue
+			}
 
-				fileContent, err := os.ReadFile(filePath)
-				if err != nil {
-					fmt.Printf("❌ Error reading file %s: %v\n", filePath, err)
-					fmt.Println()
-					continue
-				}
+			messages = append(messages, openai.SystemMessage(content))
+			fmt.Println("✅ Command output added to the conversation")
+			fmt.Println()
+			continue
+		}
 
-				// Process the file content as a user question
-				userInput = string(fileContent)
-				fmt.Printf("📁 Loaded question from file: %s\n", filePath)
-				// Don't continue here - let it fall through to process the question
+		if userInput == "/voice" || strings.HasPrefix(userInput, "/voice ") {
+			source := strings.TrimSpace(strings.TrimPrefix(userInput, "/voice"))
+			if source == "" {
+				source = voiceMicrophone
+			}
+			transcript, err := voiceTranscript(config, source, false, os.Stdout)
+			if err != nil {
+				fmt.Printf("❌ No question from /voice: %v\n", err)
+				fmt.Println()
+				continue
 			}
+			// The transcript is asked like a typed question
+			userInput = transcript
+		}
 
-			if userInput == "" {
-				fmt.Println("Please enter a question, '/clear' to reset, '/use <file>' to load file, '/from <file>' to ask question from file, '/bye' to exit, or prefix with '#rag' for RAG search (when --rag flag not used)")
+		if plugin, input, found := slashPlugin(available, userInput); found {
+			var question string
+			messages, question, err = runSlashPlugin(config, opts, plugin, input, messages, lastAnswer)
+			if err != nil {
+				fmt.Printf("❌ Error running /%s: %v\n", plugin.Name, err)
+			}
+			if err != nil || question == "" {
+				fmt.Println()
 				continue
 			}
+			// The question of the plugin is asked like a typed one
+			userInput = question
+		}
 
-			var similarities []string
-			var actualUserInput = userInput
+		// Ask without recording the exchange into the history
+		record := true
+		if strings.HasPrefix(userInput, "/oneshot ") {
+			userInput = strings.TrimSpace(strings.TrimPrefix(userInput, "/oneshot "))
+			record = false
+		}
 
-			// Check if RAG search is requested (either via --rag flag or #rag prefix)
-			ragRequested := ragEnabled || strings.HasPrefix(userInput, "#rag ")
-			
-			if ragRequested {
-				// Remove #rag prefix if present (when using --rag flag, #rag prefix is not needed)
-				if strings.HasPrefix(userInput, "#rag ") {
-					actualUserInput = strings.TrimPrefix(userInput, "#rag ")
-				}
+		if userInput == "/from" || strings.HasPrefix(userInput, "/from ") {
+			filePath := strings.TrimSpace(strings.TrimPrefix(userInput, "/from"))
 
-				// Create search agent and perform similarity search
-				fmt.Print("🔍 Searching... ")
-				searchAgent, err := rag.CreateSearchAgent(config, embeddingsFile)
+			// Without a path, the file is chosen with the file picker
+			if filePath == "" {
+				picked, err := pickFiles("File of the question (/from)", false)
 				if err != nil {
-					fmt.Printf("\nWarning: Error creating search agent: %v\n", err)
-				} else if searchAgent != nil {
-					similarities, err = rag.SearchSimilarities(actualUserInput, searchAgent, config)
-					if err != nil {
-						fmt.Printf("\nWarning: Error searching similarities: %v\n", err)
-					} else {
-						fmt.Println("✓")
-					}
-				}
-
-				// Display similarities in green
-				rag.DisplaySimilarities(similarities)
-
-				// Add similarity results if found
-				if len(similarities) > 0 {
-					contextMessage := "Relevant context from documentation:\n\n" + strings.Join(similarities, "\n\n")
-					messages = append(messages, openai.SystemMessage(contextMessage))
+					fmt.Printf("❌ %v\n", err)
+					fmt.Println()
+					continue
 				}
+				filePath = picked[0]
 			}
 
-			// Add user message to conversation history (without #rag prefix if it was used)
-			messages = append(messages, openai.UserMessage(actualUserInput))
-
-			// Create agent with current conversation history
-			agent, err := agents.NewAgent("budgie",
-				agents.WithDMR(config.BaseURL),
-				agents.WithParams(openai.ChatCompletionNewParams{
-					Model:       config.Model,
-					Temperature: openai.Opt(config.Temperature),
-					Messages:    messages,
-				}),
-			)
+			fileContent, err := os.ReadFile(filePath)
 			if err != nil {
-				fmt.Printf("Error creating agent: %v\n", err)
+				fmt.Printf("❌ Error reading file %s: %v\n", filePath, err)
+				fmt.Println()
 				continue
 			}
 
-			fmt.Println("💡 Press ESC to stop streaming")
-			ctx, cancel := context.WithCancel(context.Background())
-			defer cancel()
-			utils.SetupEscListener(ctx, cancel)
+			// Process the file content as a user question
+			userInput = string(fileContent)
+			fmt.Printf("📁 Loaded question from file: %s\n", filePath)
+			// Don't continue here - let it fall through to process the question
+		}
 
-			var responseBuilder strings.Builder
-			_, err = agent.ChatCompletionStream(ctx, func(self *agents.Agent, content string, err error) error {
-				if err != nil {
-					return err
-				}
-				fmt.Print(content)
-				responseBuilder.WriteString(content)
-				return nil
-			})
+		if userInput == "" {
+			fmt.Println("Please enter a question, '/clear' to reset, '/use <file>' to load file, '/from <file>' to ask question from file, '/bye' to exit, or prefix with '#rag' for RAG search (when --rag flag not used)")
+			continue
+		}
 
-			if err != nil {
-				fmt.Printf("Error during streaming: %v\n", err)
-				continue
+		if err := askQuestion(userInput, record); err != nil {
+			// The stopped streams were already reported
+			if !errors.Is(err, errStreamStopped) {
+				fmt.Printf("Error: %v\n", err)
 			}
+			continue
+		}
+	}
+	return nil
+}
 
-			fmt.Println()
+// promptTitle returns the title of the interactive prompt, telling whether the RAG search is active
+func promptTitle(opts askOptions) string {
+	if opts.ragEnabled {
+		return "What's your question? [🔍 RAG on]"
+	}
+	return "What's your question? [RAG off]"
+}
+
+// RunAsk handles the ask command execution
+func RunAsk(cmd *cobra.Command, args []string) error {
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	configFile, _ := cmd.Flags().GetString("config")
+	outputPath, _ := cmd.Flags().GetString("output")
+	generate, _ := cmd.Flags().GetBool("generate")
+	question, _ := cmd.Flags().GetString("question")
+	prompt, _ := cmd.Flags().GetBool("prompt")
+	useFile, _ := cmd.Flags().GetString("use")
+	contextDir, _ := cmd.Flags().GetString("context")
+	urls, _ := cmd.Flags().GetStringArray("url")
+	fromFile, _ := cmd.Flags().GetString("from")
+	voiceSource, _ := cmd.Flags().GetString("voice")
+	ragEnabled, _ := cmd.Flags().GetBool("rag")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	store, _ := cmd.Flags().GetString("store")
+	wsAddr, _ := cmd.Flags().GetString("ws")
+	wsOrigins, _ := cmd.Flags().GetStringArray("ws-origin")
+	wsAnyOrigin, _ := cmd.Flags().GetBool("ws-any-origin")
+	sessionName, _ := cmd.Flags().GetString("session")
+	calibrate, _ := cmd.Flags().GetBool("calibrate")
+	topK, _ := cmd.Flags().GetInt("top-k")
+	keywordWeight, _ := cmd.Flags().GetFloat64("keyword-weight")
+	rerank, _ := cmd.Flags().GetBool("rerank")
+	clarify, _ := cmd.Flags().GetBool("clarify")
+	format, _ := cmd.Flags().GetString("format")
+	offline, _ := cmd.Flags().GetBool("offline")
+	heartbeat, _ := cmd.Flags().GetDuration("heartbeat")
+	sameLanguage, _ := cmd.Flags().GetBool("same-language")
+	filters, _ := cmd.Flags().GetStringArray("filter")
+	pager, _ := cmd.Flags().GetString("pager")
+	checkPrevious, _ := cmd.Flags().GetBool("check-previous")
+	allowTools, _ := cmd.Flags().GetBool("allow-tools")
+	diff, _ := cmd.Flags().GetString("diff")
+	templateName, _ := cmd.Flags().GetString("template")
+	vars, _ := cmd.Flags().GetStringArray("var")
+	outputName, _ := cmd.Flags().GetString("output-name")
+	frontMatter, _ := cmd.Flags().GetBool("front-matter")
+	appendFile, _ := cmd.Flags().GetString("append")
+	quiet, _ := cmd.Flags().GetBool("quiet")
+	raw, _ := cmd.Flags().GetBool("raw")
+	showRedactions, _ := cmd.Flags().GetBool("show-redactions")
+	noPost, _ := cmd.Flags().GetBool("no-post")
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	copyTarget, _ := cmd.Flags().GetString("copy")
+	scriptFile, _ := cmd.Flags().GetString("script")
+	jsonSchema, _ := cmd.Flags().GetString("json-schema")
+	schemaRetries, _ := cmd.Flags().GetInt("schema-retries")
+
+	opts := askOptions{
+		systemFiles:    systemFiles,
+		configFile:     configFile,
+		overrides:      configOverrides(cmd),
+		outputPath:     outputPath,
+		outputName:     outputName,
+		appendFile:     appendFile,
+		quiet:          quiet,
+		raw:            raw,
+		showRedactions: showRedactions,
+		noPost:         noPost,
+		noCache:        noCache,
+		copy:           copyTarget,
+		noFrontMatter:  !frontMatter,
+		useFile:        useFile,
+		contextDir:     contextDir,
+		urls:           urls,
+		embeddingsFile: embeddingsFile,
+		generate:       generate,
+		ragEnabled:     ragEnabled,
+		session:        sessionName,
+		calibrate:      calibrate,
+		topK:           topK,
+		keywordWeight:  keywordWeight,
+		rerank:         rerank,
+		clarify:        clarify,
+		format:         format,
+		offline:        offline,
+		heartbeat:      heartbeat,
+		sameLanguage:   sameLanguage,
+		pager:          pager,
+		checkPrevious:  checkPrevious,
+		diff:           diff,
+		modelCheck:     &modelCheck{},
+	}
 
-			// Add assistant response to conversation history
-			assistantResponse := responseBuilder.String()
-			messages = append(messages, openai.AssistantMessage(assistantResponse))
+	if err := validateFormat(format); err != nil {
+		return err
+	}
+	// Machine-readable formats (and --json-schema, which prints the JSON alone) keep stdout for the answer:
+	// progress and diagnostics go to stderr
+	if format != formatMarkdown || jsonSchema != "" {
+		opts.out, opts.log = os.Stdout, os.Stderr
+	}
+	if keywordWeight < 0 || keywordWeight > 1 {
+		return fmt.Errorf("--keyword-weight must be between 0 and 1 (got %g)", keywordWeight)
+	}
+	conditions, err := rag.ParseMetadataFilters(filters)
+	if err != nil {
+		return err
+	}
+	opts.filters = conditions
+	if store != "" {
+		if cmd.Flags().Changed("embeddings") {
+			return fmt.Errorf("--store and --embeddings cannot be used together")
+		}
+		loaded, err := config.LoadConfig(configFile, opts.overrides)
+		if err != nil {
+			return fmt.Errorf("error loading config file: %w", err)
+		}
+		if opts, err = withStore(loaded, opts, store); err != nil {
+			return err
+		}
+		// Naming a store asks for the retrieval
+		opts.ragEnabled = true
+	}
+	values, err := prompts.ParseVars(vars)
+	if err != nil {
+		return err
+	}
+	if len(values) > 0 && templateName == "" {
+		return fmt.Errorf("--var requires --template")
+	}
+	if err := results.CheckNameTemplate(outputName); err != nil {
+		return err
+	}
+	if quiet {
+		// The heartbeat is progress output too
+		opts.heartbeat = 0
+	}
+	if appendFile != "" {
+		if cmd.Flags().Changed("output-name") {
+			return fmt.Errorf("--append and --output-name cannot be used together")
+		}
+		// The answers accumulate in the notebook instead of the result files
+		opts.generate = true
+	}
+	if allowTools {
+		loaded, err := config.LoadConfig(configFile, opts.overrides)
+		if err != nil {
+			return fmt.Errorf("error loading config file: %w", err)
+		}
+		if opts.tools, err = loadToolSession(configFile, loaded, cmd.Root().Version, opts.logWriter()); err != nil {
+			return err
+		}
+		defer opts.tools.Close()
+	}
+	if err := validatePager(pager); err != nil {
+		return err
+	}
+	if jsonSchema != "" {
+		if prompt || scriptFile != "" {
+			return fmt.Errorf("--json-schema is only supported in single question mode (not with --prompt or --script)")
+		}
+		if schemaRetries < 0 {
+			return fmt.Errorf("--schema-retries must be at least 0 (got %d)", schemaRetries)
+		}
+		if opts.jsonSchema, err = structured.Load(jsonSchema); err != nil {
+			return err
+		}
+		opts.schemaRetries = schemaRetries
+		// Only the validated JSON is printed on stdout
+		if format == formatMarkdown {
+			format, opts.format = formatPlain, formatPlain
+		}
+	}
+	if format != formatMarkdown && prompt {
+		return fmt.Errorf("--format %s is only supported in single question mode (not with --prompt)", format)
+	}
+	if scriptFile != "" {
+		if prompt || question != "" || fromFile != "" || templateName != "" || voiceSource != "" {
+			return fmt.Errorf("--script cannot be used with --prompt, --question, --from, --template or --voice")
+		}
+		if format != formatMarkdown || copyTarget != "" {
+			return fmt.Errorf("--script only supports the markdown format (without --copy)")
+		}
+	}
+	if copyTarget != "" {
+		if prompt {
+			return fmt.Errorf("--copy is only supported in single question mode (use /copy in interactive mode)")
+		}
+		if copyTarget != copyAnswer && copyTarget != copyCode {
+			return fmt.Errorf("invalid --copy value %q (expected %s or %s)", copyTarget, copyAnswer, copyCode)
+		}
+	}
 
-			if generate {
-				timestamp := time.Now().Format("2006-01-02-15-04-05")
-				filename := fmt.Sprintf("result-%s.md", timestamp)
-				filepath := filepath.Join(outputPath, filename)
+	// The clarifying question is answered on the terminal
+	if clarify && !prompt {
+		if scriptFile != "" {
+			return fmt.Errorf("--clarify cannot be used with --script")
+		}
+		if !isTerminal(os.Stdin) {
+			return fmt.Errorf("--clarify asks a clarifying question: it requires a terminal (stdin is not one)")
+		}
+	}
 
-				err = os.WriteFile(filepath, []byte(assistantResponse), 0644)
-				if err != nil {
-					fmt.Printf("Error saving result to file: %v\n", err)
-				} else {
-					blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
-					fmt.Println(blueStyle.Render(fmt.Sprintf("💾 Result saved to: %s", filepath)))
-				}
-			}
+	if sessionName != "" && !prompt {
+		return fmt.Errorf("--session flag requires --prompt (interactive mode)")
+	}
 
-			fmt.Println()
+	// Mirror streamed tokens and status events over a websocket if requested
+	if wsAddr != "" {
+		ws, err := wsstream.Start(wsAddr, wsstream.Options{AllowedOrigins: wsOrigins, AnyOrigin: wsAnyOrigin})
+		if err != nil {
+			return fmt.Errorf("error starting websocket server on %s: %w", wsAddr, err)
+		}
+		defer ws.Close()
+		opts.ws = ws
+		if !quiet {
+			fmt.Fprintf(opts.logWriter(), "📡 Streaming to websocket clients on ws://%s\n", ws.Addr())
 		}
-		return nil
 	}
 
-	// Handle --from flag for single question mode
+	// Handle --from flag
 	if fromFile != "" {
 		fileContent, err := os.ReadFile(fromFile)
 		if err != nil {
@@ -479,9 +1581,107 @@ func RunAsk(cmd *cobra.Command, args []string) error {
 		question = string(fileContent)
 	}
 
+	// Handle --voice flag: the transcript is the question, or completes the -q or -f one
+	if voiceSource != "" {
+		loaded, err := config.LoadConfig(configFile, opts.overrides)
+		if err != nil {
+			return fmt.Errorf("error loading config file: %w", err)
+		}
+		transcript, err := voiceTranscript(loaded, voiceSource, quiet, opts.logWriter())
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(question) == "" {
+			question = transcript
+		} else {
+			question += "\n\n" + transcript
+		}
+	}
+
+	if scriptFile != "" {
+		return runScript(opts, scriptFile)
+	}
+
+	if prompt {
+		if templateName != "" {
+			filled, _, err := templateQuestion(configFile, templateName, values, "")
+			if err != nil {
+				return err
+			}
+			question = joinTemplateQuestion(filled, question)
+		} else if fromFile == "" && voiceSource == "" {
+			question = ""
+		}
+		return runInteractive(opts, question)
+	}
+
+	// Handle piped stdin: it becomes the question when none is given, otherwise context for the question
+	piped, err := readPipedInput()
+	if err != nil {
+		return err
+	}
+
+	// Handle --template flag: the filled template is the question, completed by -q or -f
+	if templateName != "" {
+		filled, usedPiped, err := templateQuestion(configFile, templateName, values, piped)
+		if err != nil {
+			return err
+		}
+		question = joinTemplateQuestion(filled, question)
+		if usedPiped {
+			piped = ""
+		}
+	}
+
+	if piped != "" {
+		if question == "" {
+			question = piped
+		} else {
+			opts.piped = piped
+		}
+	}
+
 	if question == "" {
-		return fmt.Errorf("question is required (either via -q flag or -f flag)")
+		return fmt.Errorf("question is required (either via -q flag, -f flag, --template flag, --voice flag or piped stdin)")
+	}
+
+	return processQuestion(question, opts)
+}
+
+// joinTemplateQuestion completes the question of a prompt template with the -q or -f question
+func joinTemplateQuestion(filled, question string) string {
+	if question = strings.TrimSpace(question); question != "" {
+		return filled + "\n\n" + question
+	}
+	return filled
+}
+
+// exchangeStarts returns the index in the history where each question/answer exchange starts.
+// An exchange begins at a user message, or at the per-turn system messages right before it
+// (the RAG context and the --calibrate instructions).
+func exchangeStarts(messages []openai.ChatCompletionMessageParamUnion) []int {
+	var starts []int
+	for i, message := range messages {
+		fields, err := helpers.MessageToMap(message)
+		if err != nil || fields["role"] != "user" {
+			continue
+		}
+
+		start := i
+		for start > 0 && turnSystemMessage(messages[start-1]) {
+			start--
+		}
+		starts = append(starts, start)
 	}
+	return starts
+}
 
-	return processQuestion(question, systemFile, configFile, outputPath, useFile, embeddingsFile, generate, ragEnabled)
-}
\ No newline at end of file
+// turnSystemMessage reports whether a message is a system message added to a single question
+// (RAG context or calibration), which belongs to the exchange of the question
+func turnSystemMessage(message openai.ChatCompletionMessageParamUnion) bool {
+	fields, err := helpers.MessageToMap(message)
+	if err != nil || fields["role"] != "system" {
+		return false
+	}
+	return strings.HasPrefix(fields["content"], ragContextHeader) || strings.HasPrefix(fields["content"], calibrationHeader)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/openai/openai-go"
+)
+
+// streamingServer streams a first token, sends SIGINT to the process as a user or a CI runner would,
+// then holds the stream open until the client goes away
+func streamingServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"1","object":"chat.completion.chunk","created":0,"model":"test","choices":[{"index":0,"delta":{"role":"assistant","content":"Hello"}}]}`+"\n\n")
+		w.(http.Flusher).Flush()
+
+		time.Sleep(100 * time.Millisecond)
+		if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+			t.Error(err)
+		}
+		select {
+		case <-r.Context().Done():
+		case <-time.After(10 * time.Second):
+			t.Error("the stream was not stopped")
+		}
+	}))
+}
+
+// Piped executions (json and plain formats, no terminal) are stopped by SIGINT: the partial answer is
+// returned instead of the process being killed
+func TestStreamCompletionPipedInterrupt(t *testing.T) {
+	for _, format := range []string{formatJSON, formatPlain, formatMarkdown} {
+		t.Run(format, func(t *testing.T) {
+			server := streamingServer(t)
+			defer server.Close()
+
+			var out bytes.Buffer
+			cfg := &config.Config{BaseURL: server.URL, Model: "test", RetryAttempts: 1}
+			opts := askOptions{format: format, raw: true, quiet: true, out: &out}
+			response, _, err := streamCompletion(cfg, []openai.ChatCompletionMessageParamUnion{openai.UserMessage("Hi")}, opts)
+
+			if !errors.Is(err, errStreamStopped) {
+				t.Fatalf("err = %v, want %v", err, errStreamStopped)
+			}
+			if response != "Hello" {
+				t.Errorf("response = %q, want the partial answer %q", response, "Hello")
+			}
+		})
+	}
+}
+
+// The per-turn system messages (RAG context, --calibrate instructions) belong to the exchange of their question:
+// /forget and the history compaction drop them with it
+func TestExchangeStarts(t *testing.T) {
+	cfg := &config.Config{ConfidenceMinScore: 0.5, ConfidenceMinChunks: 1}
+	similarities := []rag.Similarity{{ID: "doc.md#chunk-0", Content: "content", Score: 0.8}}
+	ragContext := openai.SystemMessage(ragContextHeader + "content")
+	calibration := openai.SystemMessage(calibrationMessage(cfg, similarities, io.Discard))
+
+	tests := []struct {
+		name     string
+		messages []openai.ChatCompletionMessageParamUnion
+		want     []int
+	}{
+		{
+			name: "plain exchanges",
+			messages: []openai.ChatCompletionMessageParamUnion{
+				openai.SystemMessage("You are an expert"),
+				openai.UserMessage("Q1"), openai.AssistantMessage("A1"),
+				openai.UserMessage("Q2"), openai.AssistantMessage("A2"),
+			},
+			want: []int{1, 3},
+		},
+		{
+			name: "rag context",
+			messages: []openai.ChatCompletionMessageParamUnion{
+				openai.SystemMessage("You are an expert"),
+				ragContext, openai.UserMessage("Q1"), openai.AssistantMessage("A1"),
+				ragContext, openai.UserMessage("Q2"), openai.AssistantMessage("A2"),
+			},
+			want: []int{1, 4},
+		},
+		{
+			name: "rag context and calibration",
+			messages: []openai.ChatCompletionMessageParamUnion{
+				openai.SystemMessage("You are an expert"),
+				ragContext, calibration, openai.UserMessage("Q1"), openai.AssistantMessage("A1"),
+				ragContext, calibration, openai.UserMessage("Q2"), openai.AssistantMessage("A2"),
+			},
+			want: []int{1, 5},
+		},
+		{
+			name: "calibration without chunks",
+			messages: []openai.ChatCompletionMessageParamUnion{
+				openai.SystemMessage("You are an expert"),
+				calibration, openai.UserMessage("Q1"), openai.AssistantMessage("A1"),
+			},
+			want: []int{1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			starts := exchangeStarts(test.messages)
+			if fmt.Sprint(starts) != fmt.Sprint(test.want) {
+				t.Fatalf("starts = %v, want %v", starts, test.want)
+			}
+
+			// /forget 1 keeps the history up to the start of the last exchange
+			kept := test.messages[:starts[len(starts)-1]]
+			if len(kept) > 0 && turnSystemMessage(kept[len(kept)-1]) {
+				t.Errorf("/forget left a system message of the forgotten exchange")
+			}
+		})
+	}
+}
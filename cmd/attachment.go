@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+	"github.com/charmbracelet/huh"
+)
+
+// errAttachmentCancelled is returned when the user refuses to send an oversized attachment
+var errAttachmentCancelled = errors.New("attachment cancelled")
+
+// Choices offered when an attachment exceeds the token limit
+const (
+	attachmentTruncate = "truncate"
+	attachmentWhole    = "whole"
+	attachmentCancel   = "cancel"
+)
+
+// limitAttachment guards against accidentally sending huge contents (--use, /use, piped stdin):
+// above the attachment token limit, it asks the user whether to truncate, send the whole content
+// or cancel when confirm is true and stdin is a terminal, otherwise it truncates with a notice.
+// The question and the notice are shown on out.
+func limitAttachment(config *config.Config, name, content string, confirm bool, out io.Writer) (string, error) {
+	limit := config.AttachmentTokenLimit
+	size := tokens.Estimate(content)
+	if limit < 0 || size <= limit {
+		return content, nil
+	}
+
+	choice := attachmentTruncate
+	if confirm && isTerminal(os.Stdin) {
+		err := runField(huh.NewSelect[string]().
+			Title(fmt.Sprintf("⚠️  %s is about %d tokens (attachment-token-limit: %d)", name, size, limit)).
+			Options(
+				huh.NewOption(fmt.Sprintf("Truncate to %d tokens", limit), attachmentTruncate),
+				huh.NewOption("Send the whole content", attachmentWhole),
+				huh.NewOption("Cancel", attachmentCancel),
+			).
+			Value(&choice), out)
+		if err != nil {
+			return "", fmt.Errorf("error getting confirmation: %w", err)
+		}
+	}
+
+	switch choice {
+	case attachmentWhole:
+		return content, nil
+	case attachmentCancel:
+		return "", fmt.Errorf("%s: %w", name, errAttachmentCancelled)
+	}
+
+	fmt.Fprintf(out, "✂️  %s truncated to about %d of %d tokens (attachment-token-limit)\n", name, limit, size)
+	return truncateContent(content, limit), nil
+}
+
+// truncateContent keeps the beginning of the content up to about limit tokens, cut at a line end when possible
+func truncateContent(content string, limit int) string {
+	truncated := tokens.Truncate(content, limit)
+	if newline := strings.LastIndex(truncated, "\n"); newline > len(truncated)/2 {
+		truncated = truncated[:newline]
+	}
+	return truncated + fmt.Sprintf("\n\n[... truncated to about %d tokens]", limit)
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// RunAuthLogin handles the auth login command execution: it stores the API key of a provider in the OS keychain.
+// The key is asked without echo on a terminal, or read from stdin (e.g. `echo $KEY | budgie auth login openai`).
+func RunAuthLogin(cmd *cobra.Command, args []string) error {
+	authConfig, err := loadAuthConfig(cmd, args)
+	if err != nil {
+		return err
+	}
+	envVar, err := provider.APIKeyEnv(authConfig)
+	if err != nil {
+		return err
+	}
+	if envVar == "" {
+		return fmt.Errorf("the %s provider does not use an API key", authConfig.Provider)
+	}
+
+	apiKey, err := readAPIKey(authConfig.Provider)
+	if err != nil {
+		return err
+	}
+	if err := provider.StoreAPIKey(authConfig.Provider, apiKey); err != nil {
+		return fmt.Errorf("%w (set the %s environment variable instead)", err, envVar)
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ API key of %s stored in the OS keychain", authConfig.Provider)))
+	if os.Getenv(envVar) != "" {
+		yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+		fmt.Println(yellowStyle.Render(fmt.Sprintf("⚠️  %s is set: it is used instead of the stored key", envVar)))
+	}
+	return nil
+}
+
+// RunAuthLogout handles the auth logout command execution: it removes the API key of a provider from the OS keychain
+func RunAuthLogout(cmd *cobra.Command, args []string) error {
+	authConfig, err := loadAuthConfig(cmd, args)
+	if err != nil {
+		return err
+	}
+	err = provider.DeleteAPIKey(authConfig.Provider)
+	if errors.Is(err, provider.ErrNoStoredKey) {
+		fmt.Printf("No API key of %s stored in the OS keychain\n", authConfig.Provider)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ API key of %s removed from the OS keychain", authConfig.Provider)))
+	return nil
+}
+
+// RunAuthStatus handles the auth status command execution: it shows where the API key of each provider is read
+// from (environment variable or OS keychain), the provider of the config first
+func RunAuthStatus(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	current, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		// Without a config file, the keys of every provider are still listed
+		current = nil
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	redStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+
+	var configs []*config.Config
+	if current != nil {
+		configs = append(configs, current)
+	}
+	for _, name := range provider.Names() {
+		if current == nil || name != current.Provider {
+			configs = append(configs, &config.Config{Provider: name})
+		}
+	}
+	for _, providerConfig := range configs {
+		envVar, err := provider.APIKeyEnv(providerConfig)
+		if err != nil {
+			return err
+		}
+		label := providerConfig.Provider
+		if providerConfig == current {
+			label += " (current)"
+		}
+		if envVar == "" {
+			// The local providers are only listed when they are the current one
+			if providerConfig == current {
+				fmt.Println(dimStyle.Render(fmt.Sprintf("   %s: no API key required", label)))
+			}
+			continue
+		}
+
+		_, origin, err := provider.LookupAPIKey(providerConfig)
+		switch {
+		case err != nil:
+			fmt.Println(redStyle.Render(fmt.Sprintf("❌ %s: not set (budgie auth login %s, or %s)", label, providerConfig.Provider, envVar)))
+		case origin == provider.OriginKeychain:
+			fmt.Println(greenStyle.Render(fmt.Sprintf("✅ %s: OS keychain", label)))
+		default:
+			line := fmt.Sprintf("✅ %s: %s environment variable", label, origin)
+			if _, err := provider.StoredAPIKey(providerConfig.Provider); err == nil {
+				line += " (overrides the OS keychain)"
+			}
+			fmt.Println(greenStyle.Render(line))
+		}
+	}
+	return nil
+}
+
+// loadAuthConfig returns the config whose provider the auth commands manage: the provider given as argument,
+// or the one of the config file (and its api-key-env)
+func loadAuthConfig(cmd *cobra.Command, args []string) (*config.Config, error) {
+	configFile, _ := cmd.Flags().GetString("config")
+	loaded, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil && len(args) == 0 {
+		return nil, fmt.Errorf("error loading config file: %w (give the provider, e.g. budgie auth login openai)", err)
+	}
+	if len(args) > 0 && (loaded == nil || loaded.Provider != args[0]) {
+		loaded = &config.Config{Provider: args[0]}
+	}
+	if _, err := provider.Get(loaded.Provider); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// readAPIKey reads the API key of a provider: asked without echo on a terminal, read from stdin otherwise
+func readAPIKey(name string) (string, error) {
+	var apiKey string
+	if isTerminal(os.Stdin) {
+		err := huh.NewInput().
+			Title(fmt.Sprintf("API key of %s:", name)).
+			Description("Stored in the OS keychain").
+			EchoMode(huh.EchoModePassword).
+			Value(&apiKey).
+			Run()
+		if err != nil {
+			return "", fmt.Errorf("error reading the API key: %w", err)
+		}
+	} else {
+		piped, err := readPipedInput()
+		if err != nil {
+			return "", err
+		}
+		apiKey = piped
+	}
+	apiKey = strings.TrimSpace(apiKey)
+	if apiKey == "" {
+		return "", fmt.Errorf("no API key given")
+	}
+	return apiKey, nil
+}
@@ -0,0 +1,347 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/results"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// batchReportName is the report written in the output directory of a batch
+const batchReportName = "batch-report.json"
+
+// Status of a batch question
+const (
+	batchAnswered = "answered"
+	batchFailed   = "failed"
+	batchSkipped  = "skipped"
+)
+
+// batchQuestion is a question of the batch input: a line of the input file, or a file of the input directory
+type batchQuestion struct {
+	index    int
+	question string
+	source   string
+	file     string
+}
+
+// batchResult is the outcome of a question, recorded in the batch report
+type batchResult struct {
+	Index     int         `json:"index"`
+	Question  string      `json:"question"`
+	Source    string      `json:"source,omitempty"`
+	File      string      `json:"file,omitempty"`
+	Status    string      `json:"status"`
+	Attempts  int         `json:"attempts,omitempty"`
+	LatencyMs int64       `json:"latency_ms,omitempty"`
+	Usage     *tokenUsage `json:"usage,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// batchReport is the summary of a batch run written to batch-report.json
+type batchReport struct {
+	Input      string        `json:"input"`
+	OutputDir  string        `json:"output_dir"`
+	Model      string        `json:"model"`
+	RAG        bool          `json:"rag"`
+	Started    time.Time     `json:"started"`
+	DurationMs int64         `json:"duration_ms"`
+	Answered   int           `json:"answered"`
+	Failed     int           `json:"failed"`
+	Skipped    int           `json:"skipped"`
+	Questions  []batchResult `json:"questions"`
+}
+
+// RunBatch handles the batch command execution: it answers the questions of a file (one per line) or of a
+// directory (one per file) concurrently, writes each answer to a file of the output directory and a report
+func RunBatch(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	ragEnabled, _ := cmd.Flags().GetBool("rag")
+	topK, _ := cmd.Flags().GetInt("top-k")
+	input, _ := cmd.Flags().GetString("input")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	retryAttempts, _ := cmd.Flags().GetInt("retry-attempts")
+	skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1 (got %d)", concurrency)
+	}
+
+	opts := askOptions{
+		systemFiles:    systemFiles,
+		configFile:     configFile,
+		overrides:      configOverrides(cmd),
+		embeddingsFile: embeddingsFile,
+		ragEnabled:     ragEnabled,
+		topK:           topK,
+		quiet:          true,
+		modelCheck:     &modelCheck{},
+	}
+	config, err := loadAskConfig(opts)
+	if err != nil {
+		return err
+	}
+	if retryAttempts > 0 {
+		config.RetryAttempts = retryAttempts
+	}
+
+	questions, err := readBatchQuestions(input)
+	if err != nil {
+		return err
+	}
+	if len(questions) == 0 {
+		return fmt.Errorf("no question in %s", input)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+
+	messages, err := baseMessages(opts)
+	if err != nil {
+		return err
+	}
+	// Ask about a changed embedding model once, before the workers start
+	if ragEnabled {
+		if opts.ragEnabled, err = checkEmbeddingModel(config, opts); err != nil {
+			return err
+		}
+	}
+	metadata := resultMetadata(config, opts, time.Now())
+
+	fmt.Printf("📚 Answering %d question(s) from %s with %s (%d at a time)\n", len(questions), input, config.Model, concurrency)
+
+	report := batchReport{
+		Input:     input,
+		OutputDir: outputDir,
+		Model:     config.Model,
+		RAG:       opts.ragEnabled,
+		Started:   time.Now(),
+		Questions: make([]batchResult, len(questions)),
+	}
+
+	// mutex serializes the output, the store searches and the ledger and history writes of the workers
+	var mutex sync.Mutex
+	var workers sync.WaitGroup
+	pending := make(chan batchQuestion)
+	completed := 0
+	for range concurrency {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for question := range pending {
+				result := answerBatchQuestion(config, opts, metadata, messages, outputDir, question, skipExisting, &mutex)
+
+				mutex.Lock()
+				completed++
+				report.Questions[question.index] = result
+				printBatchResult(result, completed, len(questions))
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, question := range questions {
+		pending <- question
+	}
+	close(pending)
+	workers.Wait()
+
+	report.DurationMs = time.Since(report.Started).Milliseconds()
+	var total int64
+	for _, result := range report.Questions {
+		switch result.Status {
+		case batchAnswered:
+			report.Answered++
+		case batchFailed:
+			report.Failed++
+		case batchSkipped:
+			report.Skipped++
+		}
+		if result.Usage != nil {
+			total += result.Usage.TotalTokens
+		}
+	}
+
+	reportPath := filepath.Join(outputDir, batchReportName)
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding the batch report: %w", err)
+	}
+	if err := os.WriteFile(reportPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing the batch report: %w", err)
+	}
+
+	fmt.Println()
+	printSummaryTable([][]string{
+		{"Questions", fmt.Sprint(len(questions))},
+		{"Answered", fmt.Sprint(report.Answered)},
+		{"Failed", fmt.Sprint(report.Failed)},
+		{"Skipped (already answered)", fmt.Sprint(report.Skipped)},
+		{"Tokens", fmt.Sprint(total)},
+		{"Duration", time.Duration(report.DurationMs * int64(time.Millisecond)).Round(time.Second).String()},
+	})
+	blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+	fmt.Println(blueStyle.Render(fmt.Sprintf("📊 Report saved to: %s", reportPath)))
+
+	if report.Failed > 0 {
+		return fmt.Errorf("%d question(s) failed (run again with --skip-existing to retry them)", report.Failed)
+	}
+	return nil
+}
+
+// readBatchQuestions reads the questions of the batch input: the non-empty lines of a file, or the
+// .md and .txt files of a directory (in name order). Each question is given the name of its answer file.
+func readBatchQuestions(input string) ([]batchQuestion, error) {
+	if input == "" {
+		return nil, errors.New("--input is required (a file with one question per line, or a directory of question files)")
+	}
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input: %w", err)
+	}
+
+	var questions []batchQuestion
+	add := func(question, source, name string) {
+		index := len(questions)
+		questions = append(questions, batchQuestion{
+			index:    index,
+			question: question,
+			source:   source,
+			file:     fmt.Sprintf("%03d-%s.md", index+1, results.Slug(name)),
+		})
+	}
+
+	if !info.IsDir() {
+		content, err := os.ReadFile(input)
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %w", err)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if question := strings.TrimSpace(line); question != "" {
+				add(question, "", question)
+			}
+		}
+		return questions, nil
+	}
+
+	entries, err := os.ReadDir(input)
+	if err != nil {
+		return nil, fmt.Errorf("error reading input: %w", err)
+	}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if !entry.Type().IsRegular() || (ext != ".md" && ext != ".txt") {
+			continue
+		}
+		path := filepath.Join(input, entry.Name())
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %w", err)
+		}
+		if question := strings.TrimSpace(string(content)); question != "" {
+			add(question, path, strings.TrimSuffix(entry.Name(), ext))
+		}
+	}
+	return questions, nil
+}
+
+// answerBatchQuestion answers a question of the batch and writes its answer file (with the front-matter and
+// sources of the result files). With skipExisting, the questions whose answer file exists are skipped.
+func answerBatchQuestion(config *config.Config, opts askOptions, metadata results.Metadata, base []openai.ChatCompletionMessageParamUnion, outputDir string, question batchQuestion, skipExisting bool, mutex *sync.Mutex) batchResult {
+	path := filepath.Join(outputDir, question.file)
+	result := batchResult{Index: question.index + 1, Question: question.question, Source: question.source, File: path}
+	fail := func(err error) batchResult {
+		result.Status, result.Error = batchFailed, err.Error()
+		return result
+	}
+
+	if skipExisting {
+		if _, err := os.Stat(path); err == nil {
+			result.Status = batchSkipped
+			return result
+		}
+	}
+
+	messages := slices.Clone(base)
+	var similarities []rag.Similarity
+	if opts.ragEnabled {
+		mutex.Lock()
+		found, _, errs := ragSearch(config, opts, question.question)
+		mutex.Unlock()
+		if len(errs) > 0 {
+			return fail(errors.Join(errs...))
+		}
+		similarities = found
+		if len(similarities) > 0 {
+			messages = append(messages, openai.UserMessage(ragContextHeader+strings.Join(rag.Contents(similarities), "\n\n")))
+		}
+	}
+	messages = append(messages, openai.UserMessage(question.question))
+
+	start := time.Now()
+	answer, usage, attempts, err := completeWithRetry(config, messages)
+	result.Attempts, result.LatencyMs, result.Usage = attempts, time.Since(start).Milliseconds(), usage
+	if err != nil {
+		return fail(err)
+	}
+
+	metadata.Date, metadata.Question, metadata.Sources = time.Now(), question.question, rag.IDs(similarities)
+	content := metadata.FrontMatter() + answer + sourcesSection("##", similarities)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fail(fmt.Errorf("error writing %s: %w", path, err))
+	}
+
+	mutex.Lock()
+	recordUsage(config, opts, "batch", usage, time.Since(start), true)
+	recordHistory(config, opts, "batch", question.question, answer, opts.ragEnabled)
+	mutex.Unlock()
+
+	result.Status = batchAnswered
+	return result
+}
+
+// completeWithRetry completes the conversation without streaming, retrying the retryable failures up to
+// retry-attempts times with an exponential backoff. It returns the answer, its usage and the number of attempts.
+func completeWithRetry(config *config.Config, messages []openai.ChatCompletionMessageParamUnion) (string, *tokenUsage, int, error) {
+	for attempt := 1; ; attempt++ {
+		answer, usage, err := completeWithUsage(config, messages)
+		if err == nil || !retryableError(err) || attempt >= config.RetryAttempts {
+			return answer, usage, attempt, err
+		}
+		time.Sleep(retryDelay(config, attempt))
+	}
+}
+
+// printBatchResult prints the outcome of a question as it completes
+func printBatchResult(result batchResult, completed, total int) {
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	redStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	progress := fmt.Sprintf("[%d/%d]", completed, total)
+
+	switch result.Status {
+	case batchAnswered:
+		details := fmt.Sprintf("→ %s (%s", result.File, time.Duration(result.LatencyMs)*time.Millisecond)
+		if result.Attempts > 1 {
+			details += fmt.Sprintf(", %d attempts", result.Attempts)
+		}
+		fmt.Printf("✅ %s %s %s\n", progress, entryTitle(result.Question), dimStyle.Render(details+")"))
+	case batchSkipped:
+		fmt.Printf("⏭️  %s %s %s\n", progress, entryTitle(result.Question), dimStyle.Render("(already answered)"))
+	default:
+		fmt.Printf("❌ %s %s %s\n", progress, entryTitle(result.Question), redStyle.Render(result.Error))
+	}
+}
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/answercache"
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/ledger"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+)
+
+// answerCacheKey returns the cache address of the answer of a prompt: the messages (system prompt, retrieved
+// chunks, question...) along with the parameters shaping the request (see completionParams: the model, its
+// temperature and seed, the JSON schema of the answer) and the post-process commands. It is "" when the
+// cache is disabled.
+func answerCacheKey(config *config.Config, opts askOptions, messages []openai.ChatCompletionMessageParamUnion) string {
+	if !config.Cache.Enabled {
+		return ""
+	}
+	var schema map[string]any
+	if opts.jsonSchema != nil {
+		schema = opts.jsonSchema.Document
+	}
+	key, err := answercache.Key(messages, map[string]any{
+		"provider":     config.Provider,
+		"baseURL":      config.BaseURL,
+		"model":        config.Model,
+		"temperature":  config.Temperature,
+		"seed":         config.Seed,
+		"json-schema":  schema,
+		"post-process": postProcessors(config, opts),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error hashing the prompt for the answer cache: %v\n", err)
+		return ""
+	}
+	return key
+}
+
+// cachedAnswer returns the cached answer of a prompt (by key), unless --no-cache asks the model again
+func cachedAnswer(config *config.Config, opts askOptions, key string) (*answercache.Entry, bool) {
+	if key == "" || opts.noCache {
+		return nil, false
+	}
+	var entry *answercache.Entry
+	found := false
+	err := ledger.With(ledger.Path(opts.configFile), func(db *ledger.DB) error {
+		var err error
+		entry, found, err = answercache.Get(db, key, config.Cache.Expiry())
+		return err
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error reading the answer cache: %v\n", err)
+		return nil, false
+	}
+	return entry, found
+}
+
+// cacheAnswer keeps the answer of a prompt (by key) in the cache
+func cacheAnswer(config *config.Config, opts askOptions, key, question, answer string) {
+	if key == "" || answer == "" {
+		return
+	}
+	entry := answercache.Entry{Created: time.Now(), Model: config.Model, Question: question, Answer: answer}
+	err := ledger.With(ledger.Path(opts.configFile), func(db *ledger.DB) error {
+		return answercache.Put(db, key, entry)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// printCachedAnswer shows an answer read from the cache
+func printCachedAnswer(opts askOptions, entry *answercache.Entry) {
+	if !opts.quiet {
+		dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+		fmt.Fprintln(opts.logWriter(), dimStyle.Render(fmt.Sprintf("⚡ Cached answer of %s (%s), --no-cache asks the model again", entry.Model, entry.Created.Local().Format("2006-01-02 15:04"))))
+	}
+	out := opts.writer()
+	markdown := newMarkdownWriter(opts.raw || opts.format == formatPlain || out != os.Stdout, out)
+	if markdown != nil {
+		out = markdown
+	}
+	fmt.Fprintln(out, entry.Answer)
+	if err := markdown.Flush(); err != nil {
+		fmt.Fprintf(opts.logWriter(), "Warning: error rendering the answer: %v\n", err)
+	}
+}
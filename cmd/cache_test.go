@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/structured"
+	"github.com/openai/openai-go"
+)
+
+// Every parameter shaping the request changes the cache address of the answer
+func TestAnswerCacheKey(t *testing.T) {
+	seed := func(value int64) *int64 { return &value }
+	schema, err := structured.Load(`{"type": "object", "properties": {"name": {"type": "string"}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := func() (*config.Config, askOptions) {
+		return &config.Config{Model: "ai/qwen2.5", Temperature: 0.5, Cache: config.CacheConfig{Enabled: true}}, askOptions{}
+	}
+	messages := []openai.ChatCompletionMessageParamUnion{openai.UserMessage("How do I install budgie?")}
+	baseKey := func() string {
+		config, opts := base()
+		return answerCacheKey(config, opts, messages)
+	}
+	reference := baseKey()
+
+	tests := []struct {
+		name   string
+		change func(config *config.Config, opts *askOptions)
+	}{
+		{"model", func(config *config.Config, opts *askOptions) { config.Model = "ai/llama3.2" }},
+		{"temperature", func(config *config.Config, opts *askOptions) { config.Temperature = 0.8 }},
+		{"seed", func(config *config.Config, opts *askOptions) { config.Seed = seed(42) }},
+		{"other seed", func(config *config.Config, opts *askOptions) { config.Seed = seed(7) }},
+		{"provider", func(config *config.Config, opts *askOptions) { config.Provider = "openai" }},
+		{"json schema", func(config *config.Config, opts *askOptions) { opts.jsonSchema = schema }},
+		{"post-process", func(config *config.Config, opts *askOptions) { config.PostProcess = []string{"prettier"} }},
+	}
+	keys := map[string]string{reference: "reference"}
+	for _, test := range tests {
+		config, opts := base()
+		test.change(config, &opts)
+		key := answerCacheKey(config, opts, messages)
+		if previous, found := keys[key]; found {
+			t.Errorf("the %s change has the key of the %s one", test.name, previous)
+		}
+		keys[key] = test.name
+	}
+
+	// The same prompt and parameters have the same address
+	if key := baseKey(); key != reference {
+		t.Error("the same prompt has 2 different keys")
+	}
+	config, opts := base()
+	config.Cache.Enabled = false
+	if key := answerCacheKey(config, opts, messages); key != "" {
+		t.Errorf("key = %q with the cache disabled", key)
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// calibrationHeader prefixes the calibration system message added to each question with --calibrate
+const calibrationHeader = "Answer only from the provided documentation context.\n"
+
+// notCoveredAnswer is the sentence the model must use when the documentation does not answer the question
+const notCoveredAnswer = "not covered by the provided documentation"
+
+// calibrationMessage builds the system message asking the model to rate its confidence.
+// The retrieval strength is computed from the similarity scores of the found chunks:
+// retrieval is weak when fewer than confidence-min-chunks chunks reach confidence-min-score (reported on out).
+func calibrationMessage(config *config.Config, similarities []rag.Similarity, out io.Writer) string {
+	strongChunks := 0
+	bestScore := 0.0
+	for _, similarity := range similarities {
+		if similarity.Score >= config.ConfidenceMinScore {
+			strongChunks++
+		}
+		bestScore = max(bestScore, similarity.Score)
+	}
+	weak := strongChunks < config.ConfidenceMinChunks
+
+	yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	if weak {
+		fmt.Fprintln(out, yellowStyle.Render(fmt.Sprintf("📉 Weak retrieval: %d chunk(s) above %.2f (best score: %.2f)", strongChunks, config.ConfidenceMinScore, bestScore)))
+	} else {
+		fmt.Fprintln(out, yellowStyle.Render(fmt.Sprintf("📈 Strong retrieval: %d chunk(s) above %.2f (best score: %.2f)", strongChunks, config.ConfidenceMinScore, bestScore)))
+	}
+
+	var message strings.Builder
+	message.WriteString(calibrationHeader)
+	fmt.Fprintf(&message, "Retrieval diagnostics: %d documentation chunks were found, %d of them with a similarity score of at least %.2f, the best score is %.2f.\n",
+		len(similarities), strongChunks, config.ConfidenceMinScore, bestScore)
+	if weak {
+		fmt.Fprintf(&message, "The retrieval is WEAK: the documentation probably does not cover this question. If the context does not contain the answer, reply exactly \"%s\" and do not guess.\n", notCoveredAnswer)
+	} else {
+		fmt.Fprintf(&message, "If the context does not contain the answer, reply exactly \"%s\" and do not guess.\n", notCoveredAnswer)
+	}
+	message.WriteString("End your answer with a line \"Confidence: high\", \"Confidence: medium\" or \"Confidence: low\" rating how well the documentation supports your answer.")
+
+	return message.String()
+}
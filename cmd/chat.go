@@ -0,0 +1,493 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// chatInputHeight is the number of lines of the input box
+const chatInputHeight = 3
+
+// chatHelp lists the chat keybindings
+const chatHelp = "enter send • alt+enter newline • esc stop • ctrl+r regenerate • ctrl+y copy answer • pgup/pgdown scroll • /copy code • /clear • ctrl+c quit"
+
+var (
+	chatUserStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("4")).Bold(true)
+	chatBudgieStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	chatInfoStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	chatErrorStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	chatStatusStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	chatDividerStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// chatEntry is a line of the displayed transcript
+type chatEntry struct {
+	role    string // "user", "assistant", "info" or "error"
+	content string
+}
+
+// Messages sent by the streaming goroutine to the TUI
+type (
+	chatCompactMsg struct{ count int }
+	chatRetryMsg   string
+	chatSearchMsg  struct{ count int }
+	chatTokenMsg   string
+	chatDoneMsg    struct {
+		turn     []openai.ChatCompletionMessageParamUnion
+		question string
+		sources  []rag.Similarity
+		response string
+		err      error
+	}
+)
+
+// chatModel is the Bubble Tea model of the chat command
+type chatModel struct {
+	config *config.Config
+	opts   askOptions
+
+	// messages is the conversation history sent to the model,
+	// previous the history before the last exchange (used to regenerate it)
+	messages     []openai.ChatCompletionMessageParamUnion
+	previous     []openai.ChatCompletionMessageParamUnion
+	lastQuestion string
+
+	entries  []chatEntry
+	viewport viewport.Model
+	input    textarea.Model
+	spinner  spinner.Model
+	ready    bool
+
+	streaming bool
+	events    chan tea.Msg
+	cancel    context.CancelFunc
+	status    string
+}
+
+// RunChat handles the chat command execution: a full-screen chat with a scrollable history
+func RunChat(cmd *cobra.Command, args []string) error {
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	configFile, _ := cmd.Flags().GetString("config")
+	outputPath, _ := cmd.Flags().GetString("output")
+	generate, _ := cmd.Flags().GetBool("generate")
+	useFile, _ := cmd.Flags().GetString("use")
+	ragEnabled, _ := cmd.Flags().GetBool("rag")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	topK, _ := cmd.Flags().GetInt("top-k")
+	rerank, _ := cmd.Flags().GetBool("rerank")
+
+	opts := askOptions{
+		systemFiles:    systemFiles,
+		configFile:     configFile,
+		overrides:      configOverrides(cmd),
+		outputPath:     outputPath,
+		useFile:        useFile,
+		embeddingsFile: embeddingsFile,
+		generate:       generate,
+		ragEnabled:     ragEnabled,
+		topK:           topK,
+		modelCheck:     &modelCheck{},
+	}
+
+	config, err := config.LoadConfig(configFile, opts.overrides)
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	if topK > 0 {
+		config.TopK = topK
+	}
+	if rerank {
+		config.RAG.Rerank = true
+	}
+
+	opts.useContent, err = loadUseFile(config, opts, true)
+	if err != nil {
+		return err
+	}
+
+	// The full-screen chat cannot ask what to do when the embedding model changed: ask before it starts
+	if _, err := checkEmbeddingModel(config, opts); err != nil {
+		return err
+	}
+
+	messages, err := baseMessages(opts)
+	if err != nil {
+		return err
+	}
+
+	input := textarea.New()
+	input.Placeholder = "Ask budgie... ('#rag' prefix for RAG search when --rag flag not used)"
+	input.ShowLineNumbers = false
+	input.CharLimit = 0
+	input.SetHeight(chatInputHeight)
+	input.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+	input.Focus()
+
+	model := &chatModel{
+		config:   config,
+		opts:     opts,
+		messages: messages,
+		input:    input,
+		spinner:  spinner.New(spinner.WithSpinner(spinner.Dot), spinner.WithStyle(chatStatusStyle)),
+		entries: []chatEntry{
+			{role: "info", content: fmt.Sprintf("🐦 budgie chat - model: %s", config.Model)},
+		},
+	}
+
+	program := tea.NewProgram(model, tea.WithAltScreen(), tea.WithMouseCellMotion())
+	_, err = program.Run()
+	if model.cancel != nil {
+		model.cancel()
+	}
+	return err
+}
+
+// Init implements tea.Model
+func (m *chatModel) Init() tea.Cmd {
+	return textarea.Blink
+}
+
+// Update implements tea.Model
+func (m *chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.resize(msg.Width, msg.Height)
+		return m, nil
+
+	case tea.MouseMsg:
+		var cmd tea.Cmd
+		m.viewport, cmd = m.viewport.Update(msg)
+		return m, cmd
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c":
+			if m.cancel != nil {
+				m.cancel()
+			}
+			return m, tea.Quit
+		case "esc":
+			if m.streaming && m.cancel != nil {
+				m.cancel()
+			}
+			return m, nil
+		case "enter":
+			if m.streaming {
+				return m, nil
+			}
+			question := strings.TrimSpace(m.input.Value())
+			m.input.Reset()
+			return m, m.submit(question)
+		case "ctrl+r":
+			return m, m.regenerate()
+		case "ctrl+y":
+			m.copyLastAnswer(copyAnswer)
+			return m, nil
+		case "pgup", "pgdown":
+			var cmd tea.Cmd
+			m.viewport, cmd = m.viewport.Update(msg)
+			return m, cmd
+		}
+
+	case spinner.TickMsg:
+		if !m.streaming {
+			return m, nil
+		}
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case chatCompactMsg:
+		m.entries = slices.Insert(m.entries, len(m.entries)-2, chatEntry{
+			role:    "info",
+			content: fmt.Sprintf("🗜️  %d earlier message(s) summarized to keep the history within %d tokens", msg.count, m.config.HistoryTokenBudget),
+		})
+		m.refresh()
+		return m, m.waitForEvent()
+
+	case chatRetryMsg:
+		m.status = string(msg)
+		return m, m.waitForEvent()
+
+	case chatSearchMsg:
+		m.status = "Generating..."
+		m.entries = slices.Insert(m.entries, len(m.entries)-1, chatEntry{
+			role:    "info",
+			content: fmt.Sprintf("📚 %d relevant documentation chunk(s) found", msg.count),
+		})
+		m.refresh()
+		return m, m.waitForEvent()
+
+	case chatTokenMsg:
+		m.entries[len(m.entries)-1].content += string(msg)
+		m.refresh()
+		return m, m.waitForEvent()
+
+	case chatDoneMsg:
+		m.finishTurn(msg)
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// View implements tea.Model
+func (m *chatModel) View() string {
+	if !m.ready {
+		return "Loading..."
+	}
+
+	status := chatInfoStyle.Render(chatHelp)
+	if m.streaming {
+		status = m.spinner.View() + " " + chatStatusStyle.Render(m.status) + chatInfoStyle.Render(" (esc to stop)")
+	} else if m.status != "" {
+		status = chatStatusStyle.Render(m.status) + "  " + chatInfoStyle.Render(chatHelp)
+	}
+
+	divider := chatDividerStyle.Render(strings.Repeat("─", m.viewport.Width))
+	return lipgloss.JoinVertical(lipgloss.Left, m.viewport.View(), divider, status, m.input.View())
+}
+
+// resize lays out the history viewport above the status line and the input box
+func (m *chatModel) resize(width, height int) {
+	viewportHeight := max(height-chatInputHeight-2, 1)
+	if !m.ready {
+		m.viewport = viewport.New(width, viewportHeight)
+		m.viewport.KeyMap = viewport.KeyMap{
+			PageDown: key.NewBinding(key.WithKeys("pgdown")),
+			PageUp:   key.NewBinding(key.WithKeys("pgup")),
+		}
+		m.ready = true
+	} else {
+		m.viewport.Width = width
+		m.viewport.Height = viewportHeight
+	}
+	m.input.SetWidth(width)
+	m.refresh()
+}
+
+// refresh renders the transcript into the viewport, following the output when it was at the bottom
+func (m *chatModel) refresh() {
+	if !m.ready {
+		return
+	}
+	atBottom := m.viewport.AtBottom()
+	m.viewport.SetContent(m.renderTranscript())
+	if atBottom || m.streaming {
+		m.viewport.GotoBottom()
+	}
+}
+
+// renderTranscript renders the conversation entries wrapped to the viewport width
+func (m *chatModel) renderTranscript() string {
+	wrap := lipgloss.NewStyle().Width(max(m.viewport.Width-1, 10))
+
+	var transcript strings.Builder
+	for _, entry := range m.entries {
+		switch entry.role {
+		case "user":
+			transcript.WriteString(chatUserStyle.Render("You") + "\n" + wrap.Render(entry.content) + "\n\n")
+		case "assistant":
+			transcript.WriteString(chatBudgieStyle.Render("Budgie") + "\n" + wrap.Render(entry.content) + "\n\n")
+		case "error":
+			transcript.WriteString(chatErrorStyle.Render(wrap.Render("❌ "+entry.content)) + "\n\n")
+		default:
+			transcript.WriteString(chatInfoStyle.Render(wrap.Render(entry.content)) + "\n\n")
+		}
+	}
+	return transcript.String()
+}
+
+// submit handles a line typed by the user: a chat command or a question
+func (m *chatModel) submit(question string) tea.Cmd {
+	switch {
+	case question == "":
+		return nil
+	case question == "/bye":
+		return tea.Quit
+	case question == "/copy" || question == "/copy code":
+		m.copyLastAnswer(strings.TrimSpace(strings.TrimPrefix(question, "/copy")))
+		return nil
+	case question == "/clear":
+		messages, err := baseMessages(m.opts)
+		if err != nil {
+			m.status = err.Error()
+			return nil
+		}
+		m.messages, m.previous, m.lastQuestion = messages, nil, ""
+		m.entries = m.entries[:1]
+		m.status = "🧹 Conversation cleared"
+		m.refresh()
+		return nil
+	}
+
+	m.previous = slices.Clone(m.messages)
+	m.lastQuestion = question
+	return m.startTurn(question)
+}
+
+// regenerate drops the last exchange and asks its question again
+func (m *chatModel) regenerate() tea.Cmd {
+	if m.streaming || m.lastQuestion == "" {
+		return nil
+	}
+
+	m.messages = slices.Clone(m.previous)
+	// Remove the displayed exchange: the question, its RAG info line and the answer
+	for i := len(m.entries) - 1; i > 0; i-- {
+		if m.entries[i].role == "user" {
+			m.entries = m.entries[:i]
+			break
+		}
+	}
+	return m.startTurn(m.lastQuestion)
+}
+
+// copyLastAnswer copies the last answer (what is copyAnswer) or its first code block (copyCode) to the clipboard
+func (m *chatModel) copyLastAnswer(what string) {
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		if m.entries[i].role != "assistant" {
+			continue
+		}
+		if message, err := copyToClipboard(m.entries[i].content, what); err != nil {
+			m.status = err.Error()
+		} else {
+			m.status = "📋 " + message
+		}
+		return
+	}
+	m.status = "No answer to copy yet"
+}
+
+// startTurn runs the RAG search and streams the completion in a goroutine,
+// sending its progress to the TUI through the events channel
+func (m *chatModel) startTurn(question string) tea.Cmd {
+	m.entries = append(m.entries,
+		chatEntry{role: "user", content: question},
+		chatEntry{role: "assistant"},
+	)
+	m.streaming = true
+	m.status = "Thinking..."
+	m.refresh()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	m.events = make(chan tea.Msg)
+
+	config, opts, history, events := m.config, m.opts, slices.Clone(m.messages), m.events
+	go func() {
+		defer close(events)
+
+		// Summarize the older exchanges when the history exceeds the token budget
+		if compacted, count, err := compactHistory(config, history); err == nil && count > 0 {
+			history = compacted
+			events <- chatCompactMsg{count: count}
+		}
+
+		actualQuestion := question
+		turn := history
+		var sources []rag.Similarity
+		if ragRequested(opts, question) {
+			var store string
+			store, actualQuestion, _ = ragPrefix(question)
+			if store != "" {
+				scoped, err := withStore(config, opts, store)
+				if err != nil {
+					events <- chatDoneMsg{turn: turn, question: actualQuestion, err: err}
+					return
+				}
+				opts = scoped
+			}
+		}
+		if useRAG, _ := checkEmbeddingModel(config, opts); useRAG && ragRequested(opts, question) {
+			similarities, _, _ := ragSearch(config, opts, actualQuestion)
+			events <- chatSearchMsg{count: len(similarities)}
+			sources = similarities
+			if len(similarities) > 0 {
+				contextMessage := ragContextHeader + strings.Join(rag.Contents(similarities), "\n\n")
+				turn = append(turn, openai.SystemMessage(contextMessage))
+			}
+		}
+		turn = append(turn, openai.UserMessage(actualQuestion))
+
+		start := time.Now()
+		response, err := streamWithRetry(ctx, config, turn, func(content string) {
+			events <- chatTokenMsg(content)
+		}, func(retry int, delay time.Duration, err error) {
+			events <- chatRetryMsg(fmt.Sprintf("🔁 %v - retrying in %s (attempt %d/%d)", err, delay, retry+1, config.RetryAttempts))
+		})
+		if err == nil {
+			recordUsage(config, opts, "chat", nil, time.Since(start), false)
+			recordHistory(config, opts, "chat", actualQuestion, response, ragRequested(opts, question))
+		}
+		events <- chatDoneMsg{turn: turn, question: actualQuestion, sources: sources, response: response, err: err}
+	}()
+
+	return tea.Batch(m.spinner.Tick, m.waitForEvent())
+}
+
+// waitForEvent waits for the next message of the streaming goroutine
+func (m *chatModel) waitForEvent() tea.Cmd {
+	events := m.events
+	return func() tea.Msg {
+		msg, ok := <-events
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// finishTurn records the completed exchange in the history
+func (m *chatModel) finishTurn(msg chatDoneMsg) {
+	m.streaming = false
+	m.cancel = nil
+	m.status = ""
+
+	answer := &m.entries[len(m.entries)-1]
+	answer.content = msg.response
+	switch {
+	case errors.Is(msg.err, context.Canceled):
+		m.status = "⏹ Streaming stopped"
+	case msg.err != nil:
+		m.entries = append(m.entries, chatEntry{role: "error", content: fmt.Sprintf("error during streaming: %v", msg.err)})
+	}
+
+	if msg.response != "" {
+		m.messages = append(msg.turn, openai.AssistantMessage(msg.response))
+		if len(msg.sources) > 0 {
+			var sources []string
+			for _, cited := range citations(msg.sources) {
+				sources = append(sources, cited.source)
+			}
+			m.entries = append(m.entries, chatEntry{role: "info", content: "📎 Sources: " + strings.Join(sources, ", ")})
+		}
+		if m.opts.generate {
+			opts := m.opts
+			opts.question, opts.sources = msg.question, msg.sources
+			if path, err := writeResult(m.config, opts, msg.response); err != nil {
+				m.status = fmt.Sprintf("Error saving result to file: %v", err)
+			} else {
+				m.status = "💾 Result saved to: " + path
+			}
+		}
+	}
+	m.refresh()
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/chunking"
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/document"
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// RunChunks handles the chunks command execution: it prints the chunks a file would be split into by
+// generate-embeddings, so the chunking rules can be tuned before regenerating the store. The file uses the
+// rule of its extension from the config (or its default rule) unless the flags select another one.
+func RunChunks(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	strategy, _ := cmd.Flags().GetString("strategy")
+	delimiter, _ := cmd.Flags().GetString("delimiter")
+	chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+	overlap, _ := cmd.Flags().GetInt("overlap")
+	statsOnly, _ := cmd.Flags().GetBool("stats")
+	filePath := args[0]
+
+	// The config is optional: without a project, the default rules apply
+	var configRules map[string]config.ChunkingRule
+	if _, err := os.Stat(configFile); err == nil {
+		loaded, err := config.LoadConfig(configFile, config.Overrides{})
+		if err != nil {
+			return fmt.Errorf("error loading config: %w", err)
+		}
+		configRules = loaded.Chunking
+	}
+
+	extension := strings.ToLower(filepath.Ext(filePath))
+	rule := chunking.DefaultRule(extension)
+	for ext, configRule := range configRules {
+		if strings.EqualFold(normalizeExtension(ext), extension) {
+			rule = configRule
+		}
+	}
+	// The strategy parameters come from the chunking flags, like the extension strategies of generate-embeddings
+	switch {
+	case strategy != "":
+		rule = config.ChunkingRule{Strategy: strings.TrimPrefix(strategy, "markdown-"), Delimiter: delimiter, Size: chunkSize, Overlap: overlap}
+	case chunkSize > 0:
+		rule = config.ChunkingRule{Strategy: chunking.Size, Size: chunkSize, Overlap: overlap}
+	case delimiter != "":
+		rule = config.ChunkingRule{Strategy: chunking.Delimiter, Delimiter: delimiter}
+	case overlap > 0:
+		return fmt.Errorf("--overlap flag requires --chunk-size or --strategy to be specified")
+	}
+	if err := chunking.Validate(rule); err != nil {
+		return fmt.Errorf("invalid chunking rule: %w", err)
+	}
+
+	var content string
+	var err error
+	if document.Supported(filePath) {
+		content, err = document.ReadText(filePath)
+	} else {
+		content, err = sniff.ReadTextFile(filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", filePath, err)
+	}
+	chunks := chunking.Chunk(content, rule)
+
+	boundaryStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Bold(true)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	fmt.Printf("📄 %s: %d chunks (%s)\n", filePath, len(chunks), chunking.Describe(rule))
+
+	totalTokens, smallest, largest := 0, 0, 0
+	for i, chunk := range chunks {
+		chunkTokens := tokens.Estimate(chunk)
+		totalTokens += chunkTokens
+		if i == 0 || len(chunk) < smallest {
+			smallest = len(chunk)
+		}
+		largest = max(largest, len(chunk))
+		if statsOnly {
+			fmt.Printf("  %4d  %6d chars  ~%5d tokens\n", i+1, len(chunk), chunkTokens)
+			continue
+		}
+
+		fmt.Println()
+		fmt.Println(boundaryStyle.Render(fmt.Sprintf("── chunk %d/%d · %d chars · ~%d tokens ──", i+1, len(chunks), len(chunk), chunkTokens)))
+		// The text repeated from the previous chunk (overlap) is dimmed
+		shared := 0
+		if i > 0 && rule.Overlap > 0 {
+			shared = sharedPrefix(chunks[i-1], chunk)
+		}
+		if shared > 0 {
+			fmt.Print(dimStyle.Render(chunk[:shared]))
+		}
+		fmt.Println(chunk[shared:])
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	printSummaryTable([][]string{
+		{"Chunks", fmt.Sprint(len(chunks))},
+		{"Size (chars)", fmt.Sprintf("min %d · avg %d · max %d", smallest, len(strings.Join(chunks, ""))/len(chunks), largest)},
+		{"Estimated tokens", fmt.Sprintf("~%d", totalTokens)},
+	})
+	return nil
+}
+
+// sharedPrefix returns the length of the longest end of previous that starts next (the overlap of two
+// consecutive fixed-size chunks)
+func sharedPrefix(previous, next string) int {
+	for length := min(len(previous), len(next)); length > 0; length-- {
+		if strings.HasSuffix(previous, next[:length]) {
+			return length
+		}
+	}
+	return 0
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/tracing"
+	"github.com/budgies-nest/budgie/helpers"
+	"github.com/charmbracelet/huh"
+	"github.com/openai/openai-go"
+)
+
+// clarityCheckInstructions asks the (cheap) model whether a question needs clarification
+const clarityCheckInstructions = `You decide whether the user's latest question is clear enough to be answered well.
+Take the previous conversation into account: follow-up questions are usually clear.
+If the question is clear, reply exactly: CLEAR
+Otherwise reply with ONE short clarifying question for the user, and nothing else.`
+
+// clarityCheckHistory is the number of previous conversation messages given to the clarity check
+const clarityCheckHistory = 4
+
+// clarifyTemperature is the operation whose temperature is used for the clarity check (temperatures config map)
+const clarifyTemperature = config.TemperatureClarify
+
+// clarifyQuestion checks the question with the clarify model (a cheap model, defaults to the chat model)
+// and, when it is ambiguous, asks the user one clarifying question (rendered on out).
+// It returns the question completed with the clarification.
+func clarifyQuestion(config *config.Config, history []openai.ChatCompletionMessageParamUnion, question string, out io.Writer) (string, error) {
+	checkMessages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(clarityCheckInstructions),
+	}
+
+	// Give the recent exchanges (without system messages) as context
+	var recent []openai.ChatCompletionMessageParamUnion
+	for _, message := range history {
+		fields, err := helpers.MessageToMap(message)
+		if err != nil || fields["role"] == "system" {
+			continue
+		}
+		recent = append(recent, message)
+	}
+	if len(recent) > clarityCheckHistory {
+		recent = recent[len(recent)-clarityCheckHistory:]
+	}
+	checkMessages = append(checkMessages, recent...)
+	_, text, _ := ragPrefix(question)
+	checkMessages = append(checkMessages, openai.UserMessage(text))
+
+	clarifyConfig := *config
+	if config.ClarifyModel != "" {
+		clarifyConfig.Model = config.ClarifyModel
+	}
+	clarifyConfig.Temperature = config.TemperatureFor(clarifyTemperature)
+
+	agent, err := newChatAgent(&clarifyConfig, checkMessages)
+	if err != nil {
+		return question, err
+	}
+
+	ctx, span := tracing.Start(context.Background(), "clarify", completionAttributes(&clarifyConfig)...)
+	verdict, err := agent.ChatCompletion(ctx)
+	tracing.End(span, err)
+	if err != nil {
+		return question, err
+	}
+
+	verdict = strings.TrimSpace(verdict)
+	if verdict == "" || strings.HasPrefix(strings.ToUpper(verdict), "CLEAR") {
+		return question, nil
+	}
+
+	var answer string
+	err = runField(huh.NewInput().
+		Title("🤔 "+verdict).
+		Description("Answer to clarify your question (leave empty to ask it as is)").
+		Value(&answer), out)
+	if err != nil {
+		return question, fmt.Errorf("error getting clarification: %w", err)
+	}
+
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return question, nil
+	}
+
+	return fmt.Sprintf("%s\n\nClarification - %s\n%s", question, verdict, answer), nil
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/atotto/clipboard"
+	"github.com/budgies-nest/budgie-cli/pkg/codeblocks"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Values of --copy and of the /copy argument
+const (
+	copyAnswer = "answer"
+	copyCode   = "code"
+)
+
+// errNoCodeBlock is returned when the first code block of an answer is copied but the answer has none
+var errNoCodeBlock = errors.New("the answer has no code block")
+
+// copyToClipboard copies an answer (what is copyAnswer) or its first fenced code block (copyCode) to the
+// system clipboard and returns a description of what was copied
+func copyToClipboard(answer, what string) (string, error) {
+	text, description := answer, "Answer"
+	switch what {
+	case copyAnswer, "":
+	case copyCode:
+		block, ok := codeblocks.First(answer)
+		if !ok {
+			return "", errNoCodeBlock
+		}
+		text, description = block.Content, "Code block"
+		if block.Language != "" {
+			description = fmt.Sprintf("Code block (%s)", block.Language)
+		}
+	default:
+		return "", fmt.Errorf("invalid copy target %q (expected %s or %s)", what, copyAnswer, copyCode)
+	}
+
+	if err := clipboard.WriteAll(text); err != nil {
+		return "", fmt.Errorf("error copying to the clipboard: %w", err)
+	}
+	return description + " copied to clipboard", nil
+}
+
+// printCopy copies the answer (or its first code block) to the clipboard and reports the outcome
+func printCopy(opts askOptions, answer, what string) {
+	message, err := copyToClipboard(answer, what)
+	if err != nil {
+		fmt.Fprintf(opts.logWriter(), "❌ %v\n", err)
+		return
+	}
+	if opts.quiet {
+		return
+	}
+	blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+	fmt.Fprintln(opts.logWriter(), blueStyle.Render("📋 "+message))
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// commitTemperature is the operation whose temperature is used for the commit messages (temperatures config map)
+const commitTemperature = config.TemperatureCommit
+
+// defaultCommitTemplate is the system prompt of the commit messages when the project has no template
+const defaultCommitTemplate = `You write the git commit message of the staged changes given as a diff.
+Start with a summary line in the imperative mood ("Add", "Fix", "Remove"...), of at most 72 characters, without a trailing period.
+When the changes need explanations, add a blank line then a short body wrapped at 72 characters, explaining what changed and why.
+Do not describe the diff line by line, do not mention the file names unless it helps.
+Reply with the commit message only, without quotes nor code fences.`
+
+// conventionalCommitInstructions are added to the template with the conventional-commits config option
+const conventionalCommitInstructions = `Follow the Conventional Commits specification: the summary line is "<type>(<optional scope>): <description>",
+with type one of feat, fix, docs, style, refactor, perf, test, build, ci, chore or revert, and a lowercase description.
+Add "!" after the type (or scope) and a "BREAKING CHANGE:" footer when the changes break compatibility.`
+
+// RunCommit handles the commit command execution: it writes a commit message for the staged changes with
+// the model, then prints it or runs git commit with it after confirmation
+func RunCommit(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	templateFile, _ := cmd.Flags().GetString("template")
+	hint, _ := cmd.Flags().GetString("hint")
+	printOnly, _ := cmd.Flags().GetBool("print")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+
+	if !printOnly && !yes && !isTerminal(os.Stdin) {
+		return fmt.Errorf("cannot confirm the commit: stdin is not a terminal (use --yes to commit or --print to only print the message)")
+	}
+
+	diff, err := runGitDiff("--staged")
+	if err != nil {
+		return err
+	}
+	if diff == "" {
+		return fmt.Errorf("%w staged: stage the changes to commit with git add", errNoChanges)
+	}
+
+	instructions, err := commitInstructions(templateFile, cmd.Flags().Changed("template"), config.ConventionalCommits)
+	if err != nil {
+		return err
+	}
+
+	// The message is printed on stdout with --print, the diagnostics go to stderr
+	var log io.Writer = os.Stdout
+	if printOnly {
+		log = os.Stderr
+	}
+
+	diff, err = limitAttachment(config, "The staged diff", strings.TrimRight(diff, "\n"), isTerminal(os.Stdin), log)
+	if err != nil {
+		return err
+	}
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(instructions),
+		openai.UserMessage(diffContextMessage("staged changes", diff)),
+	}
+	if hint != "" {
+		messages = append(messages, openai.UserMessage("Context of the changes: "+hint))
+	}
+
+	fmt.Fprintf(log, "✍️  Writing the commit message with %s...\n", config.Model)
+	commitConfig := *config
+	commitConfig.Temperature = config.TemperatureFor(commitTemperature)
+	start := time.Now()
+	message, usage, err := completeWithUsage(&commitConfig, messages)
+	if err != nil {
+		return err
+	}
+	recordUsage(config, askOptions{configFile: configFile}, "commit", usage, time.Since(start), false)
+
+	message = cleanCommitMessage(message)
+	if message == "" {
+		return fmt.Errorf("the model returned an empty commit message")
+	}
+
+	if printOnly {
+		fmt.Println(message)
+		return nil
+	}
+
+	fmt.Println()
+	fmt.Println(message)
+	fmt.Println()
+
+	if !yes {
+		confirmed := false
+		err := huh.NewConfirm().
+			Title("Commit the staged changes with this message?").
+			Affirmative("Commit").
+			Negative("Cancel").
+			Value(&confirmed).
+			Run()
+		if err != nil {
+			return fmt.Errorf("error getting confirmation: %w", err)
+		}
+		if !confirmed {
+			fmt.Println("❌ Commit cancelled")
+			return nil
+		}
+	}
+
+	if err := gitCommit(message); err != nil {
+		return err
+	}
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	fmt.Println(greenStyle.Render("✅ Changes committed"))
+	return nil
+}
+
+// commitInstructions returns the system prompt of the commit messages: the template file (the built-in
+// template when the default file does not exist), completed by the Conventional Commits rules when enabled
+func commitInstructions(templateFile string, explicit, conventional bool) (string, error) {
+	instructions := defaultCommitTemplate
+	content, err := os.ReadFile(templateFile)
+	switch {
+	case err == nil:
+		instructions = string(content)
+	case explicit || !os.IsNotExist(err):
+		return "", fmt.Errorf("error reading commit template file: %w", err)
+	}
+	if conventional {
+		instructions = strings.TrimRight(instructions, "\n") + "\n" + conventionalCommitInstructions
+	}
+	return instructions, nil
+}
+
+// cleanCommitMessage removes the code fences and quotes the models sometimes wrap the message with
+func cleanCommitMessage(message string) string {
+	message = strings.TrimSpace(message)
+	if strings.HasPrefix(message, "```") {
+		message = strings.TrimPrefix(message, "```")
+		if newline := strings.Index(message, "\n"); newline >= 0 {
+			message = message[newline+1:]
+		}
+		message = strings.TrimSuffix(strings.TrimSpace(message), "```")
+	}
+	return strings.Trim(strings.TrimSpace(message), `"`)
+}
+
+// gitCommit runs git commit with the message, git prints its summary
+func gitCommit(message string) error {
+	var stderr bytes.Buffer
+	command := exec.Command("git", "commit", "-F", "-")
+	command.Stdin = strings.NewReader(message + "\n")
+	command.Stdout = os.Stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			return fmt.Errorf("error running git commit: %s", output)
+		}
+		return fmt.Errorf("error running git commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+	"github.com/budgies-nest/budgie-cli/pkg/tracing"
+	"github.com/budgies-nest/budgie/helpers"
+	"github.com/openai/openai-go"
+)
+
+// historySummaryHeader starts the system message replacing the summarized exchanges
+const historySummaryHeader = "Summary of the earlier conversation:\n"
+
+// compactKeepExchanges is the number of most recent exchanges never summarized
+const compactKeepExchanges = 2
+
+// summarizeTemperature is the operation whose temperature is used for the summaries (temperatures config map)
+const summarizeTemperature = config.TemperatureSummarize
+
+// summarizeInstructions asks the model to summarize the earlier conversation
+const summarizeInstructions = `You summarize a conversation between a user and an AI assistant so it can continue without the full history.
+Keep the facts, decisions, names, code identifiers and open questions. Drop greetings and repetitions.
+Reply with the summary only, as a short list of bullet points.`
+
+// compactHistory summarizes the older exchanges of the history with the model when the history exceeds
+// the configured token budget. The summary replaces them as a system message, the system instructions
+// and the most recent exchanges are kept as is. It returns the number of summarized messages.
+func compactHistory(config *config.Config, messages []openai.ChatCompletionMessageParamUnion) ([]openai.ChatCompletionMessageParamUnion, int, error) {
+	if config.HistoryTokenBudget < 0 || tokens.EstimateMessages(messages) <= config.HistoryTokenBudget {
+		return messages, 0, nil
+	}
+
+	starts := exchangeStarts(messages)
+	if len(starts) <= compactKeepExchanges {
+		return messages, 0, nil
+	}
+	end := starts[len(starts)-compactKeepExchanges]
+
+	// The leading system messages are kept, except a previous summary which is summarized again
+	var head []openai.ChatCompletionMessageParamUnion
+	var transcript strings.Builder
+	for _, message := range messages[:starts[0]] {
+		fields, err := helpers.MessageToMap(message)
+		if err == nil && strings.HasPrefix(fields["content"], historySummaryHeader) {
+			transcript.WriteString(fields["content"] + "\n\n")
+			continue
+		}
+		head = append(head, message)
+	}
+	for _, message := range messages[starts[0]:end] {
+		fields, err := helpers.MessageToMap(message)
+		if err != nil {
+			continue
+		}
+		transcript.WriteString(fields["role"] + ": " + fields["content"] + "\n\n")
+	}
+
+	summaryConfig := *config
+	summaryConfig.Temperature = config.TemperatureFor(summarizeTemperature)
+	agent, err := newChatAgent(&summaryConfig, []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(summarizeInstructions),
+		openai.UserMessage(transcript.String()),
+	})
+	if err != nil {
+		return messages, 0, err
+	}
+	ctx, span := tracing.Start(context.Background(), "summarize", completionAttributes(&summaryConfig)...)
+	summary, err := agent.ChatCompletion(ctx)
+	tracing.End(span, err)
+	if err != nil {
+		return messages, 0, fmt.Errorf("error summarizing the conversation: %w", err)
+	}
+
+	compacted := append(head, openai.SystemMessage(historySummaryHeader+strings.TrimSpace(summary)))
+	compacted = append(compacted, messages[end:]...)
+	return compacted, end - len(head), nil
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/textdiff"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// minCompareColumn is the narrowest column of the side by side answers, narrower terminals stack them
+const minCompareColumn = 30
+
+// compareRun is the answer of a profile or model to the compared question
+type compareRun struct {
+	label   string
+	config  *config.Config
+	answer  string
+	usage   *tokenUsage
+	latency time.Duration
+	err     error
+}
+
+// RunCompare handles the compare command execution: it asks the same question, with the same RAG context,
+// to several profiles or models at the same time, then prints their answers side by side (or as diffs
+// against the first one) with the latency and tokens of each run
+func RunCompare(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	ragEnabled, _ := cmd.Flags().GetBool("rag")
+	topK, _ := cmd.Flags().GetInt("top-k")
+	question, _ := cmd.Flags().GetString("question")
+	profiles, _ := cmd.Flags().GetStringArray("profile")
+	models, _ := cmd.Flags().GetStringArray("model")
+	diff, _ := cmd.Flags().GetBool("diff")
+
+	// The question can also be given as arguments
+	if question == "" {
+		question = strings.Join(args, " ")
+	}
+	if question == "" {
+		return fmt.Errorf("question is required (either via -q flag or as arguments)")
+	}
+	if len(profiles)+len(models) < 2 {
+		return fmt.Errorf("at least two runs are required (--profile and --model are repeatable, e.g. --profile fast --profile quality)")
+	}
+
+	// --profile and --model are the runs of this command, the other overrides apply to all of them
+	overrides := configOverrides(cmd)
+	overrides.Profile, overrides.Model = "", ""
+	opts := askOptions{
+		systemFiles:    systemFiles,
+		configFile:     configFile,
+		overrides:      overrides,
+		embeddingsFile: embeddingsFile,
+		ragEnabled:     ragEnabled,
+		topK:           topK,
+		quiet:          true,
+		modelCheck:     &modelCheck{},
+	}
+	baseConfig, err := loadAskConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	var runs []*compareRun
+	for _, profile := range profiles {
+		runOpts := opts
+		runOpts.overrides.Profile = profile
+		runConfig, err := loadAskConfig(runOpts)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, &compareRun{label: profile, config: runConfig})
+	}
+	for _, model := range models {
+		runOpts := opts
+		runOpts.overrides.Model = model
+		runConfig, err := loadAskConfig(runOpts)
+		if err != nil {
+			return err
+		}
+		runs = append(runs, &compareRun{label: model, config: runConfig})
+	}
+
+	messages, err := baseMessages(opts)
+	if err != nil {
+		return err
+	}
+	// The context is retrieved once, with the embedding model of the config: every run gets the same chunks
+	var similarities []rag.Similarity
+	if ragEnabled {
+		if opts.ragEnabled, err = checkEmbeddingModel(baseConfig, opts); err != nil {
+			return err
+		}
+	}
+	if opts.ragEnabled {
+		found, _, errs := ragSearch(baseConfig, opts, question)
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		similarities = found
+		if len(similarities) > 0 {
+			messages = append(messages, openai.UserMessage(ragContextHeader+strings.Join(rag.Contents(similarities), "\n\n")))
+		}
+	}
+	messages = append(messages, openai.UserMessage(question))
+
+	labels := make([]string, len(runs))
+	for i, run := range runs {
+		labels[i] = run.label
+	}
+	fmt.Printf("⚖️  Comparing %s", strings.Join(labels, ", "))
+	if opts.ragEnabled {
+		fmt.Printf(" with %d chunk(s) of context", len(similarities))
+	}
+	fmt.Println()
+
+	var workers sync.WaitGroup
+	for _, run := range runs {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			start := time.Now()
+			run.answer, run.usage, _, run.err = completeWithRetry(run.config, slices.Clone(messages))
+			run.latency = time.Since(start)
+		}()
+	}
+	workers.Wait()
+
+	failed := 0
+	for _, run := range runs {
+		if run.err != nil {
+			failed++
+			continue
+		}
+		recordUsage(run.config, opts, "compare", run.usage, run.latency, true)
+	}
+
+	fmt.Println()
+	if diff {
+		printCompareDiffs(runs)
+	} else {
+		printSideBySide(runs)
+	}
+	fmt.Println()
+
+	var rows [][]string
+	for _, run := range runs {
+		stats := "failed"
+		if run.err == nil {
+			stats = fmt.Sprintf("%s · %d prompt + %d completion tokens", run.latency.Round(10*time.Millisecond), run.usage.PromptTokens, run.usage.CompletionTokens)
+		}
+		rows = append(rows, []string{compareTitle(run), stats})
+	}
+	printSummaryTable(rows)
+
+	if failed > 0 {
+		return fmt.Errorf("%d run(s) failed", failed)
+	}
+	return nil
+}
+
+// compareTitle returns the heading of the answer of a run
+func compareTitle(run *compareRun) string {
+	if run.label == run.config.Model {
+		return run.label
+	}
+	return fmt.Sprintf("%s · %s", run.label, run.config.Model)
+}
+
+// compareText returns the answer of a run, or its error
+func compareText(run *compareRun) string {
+	if run.err != nil {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color("1")).Render(run.err.Error())
+	}
+	return strings.TrimSpace(run.answer)
+}
+
+// printSideBySide prints the answers in columns, one per run. They are stacked when stdout is not a
+// terminal or the terminal is too narrow.
+func printSideBySide(runs []*compareRun) {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3"))
+	width := 0
+	if isTerminal(os.Stdout) {
+		width, _, _ = term.GetSize(os.Stdout.Fd())
+	}
+	// Each column has a border and a padding of 1 on both sides
+	column := width/len(runs) - 4
+	if column < minCompareColumn {
+		for i, run := range runs {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Println(titleStyle.Render("## " + compareTitle(run)))
+			fmt.Println(compareText(run))
+		}
+		return
+	}
+
+	boxStyle := lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("8")).Padding(0, 1).Width(column + 2)
+	columns := make([]string, len(runs))
+	for i, run := range runs {
+		columns[i] = boxStyle.Render(titleStyle.Render(compareTitle(run)) + "\n\n" + compareText(run))
+	}
+	fmt.Println(lipgloss.JoinHorizontal(lipgloss.Top, columns...))
+}
+
+// printCompareDiffs prints the answer of the first run, then the diff of each other answer against it
+func printCompareDiffs(runs []*compareRun) {
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3"))
+	first := runs[0]
+	fmt.Println(titleStyle.Render("## " + compareTitle(first)))
+	fmt.Println(compareText(first))
+	for _, run := range runs[1:] {
+		fmt.Println()
+		fmt.Println(titleStyle.Render(fmt.Sprintf("## %s (diff against %s)", compareTitle(run), first.label)))
+		if first.err != nil || run.err != nil {
+			fmt.Println(compareText(run))
+			continue
+		}
+		diff := textdiff.Unified(first.label, run.label, strings.TrimSpace(first.answer)+"\n", strings.TrimSpace(run.answer)+"\n")
+		if diff == "" {
+			fmt.Println("(same answer)")
+			continue
+		}
+		printDiff(diff)
+	}
+}
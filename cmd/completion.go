@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/history"
+	"github.com/budgies-nest/budgie-cli/pkg/prompts"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/huh"
+	"github.com/spf13/cobra"
+)
+
+// maxPathSuggestions bounds the project files suggested after the file commands of interactive mode
+const maxPathSuggestions = 2000
+
+// slashCommands are the commands of interactive mode suggested when typing "/"
+var slashCommands = []string{
+	"/bye", "/clear", "/copy", "/copy code", "/diff", "/feedback bad", "/feedback good", "/fetch ", "/fetch --embed ", "/forget", "/from ",
+	"/load ", "/model", "/oneshot ", "/rag off", "/rag on", "/run ", "/save", "/system", "/temp", "/use ", "/voice",
+}
+
+// fileSlashCommands are the commands of interactive mode taking a file path
+var fileSlashCommands = []string{"/use ", "/from ", "/system ", "/voice "}
+
+// IsCompletionCommand reports whether the command generates a completion script or completes a command
+// line for the shell: these commands must not log nor trace anything
+func IsCompletionCommand(c *cobra.Command) bool {
+	switch c.Name() {
+	case cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return true
+	}
+	return c.HasParent() && c.Parent().Name() == "completion"
+}
+
+// completionConfigFile returns the config file of the command being completed
+func completionConfigFile(cmd *cobra.Command) string {
+	configFile, err := cmd.Flags().GetString("config")
+	if err != nil || configFile == "" {
+		return defaultConfigFile
+	}
+	return configFile
+}
+
+// CompleteTemplates completes the names of the prompt templates (--template)
+func CompleteTemplates(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, _ := prompts.List(prompts.Dir(completionConfigFile(cmd)))
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteProviders completes the provider argument of the auth commands
+func CompleteProviders(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return provider.Names(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteProfiles completes the names of the profiles of the config file (--profile, config use)
+func CompleteProfiles(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 && cmd.Name() == "use" {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	loaded, err := config.LoadConfig(completionConfigFile(cmd), config.Overrides{})
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return loaded.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteSessions completes the names of the saved interactive sessions (--session)
+func CompleteSessions(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	return sessionNames(filepath.Join(filepath.Dir(completionConfigFile(cmd)), "sessions")), cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteStores completes the names of the named stores of the project and of the shared ones (--store)
+func CompleteStores(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	configFile := completionConfigFile(cmd)
+	names := storeNames(configFile)
+	if loaded, err := config.LoadConfig(configFile, config.Overrides{}); err == nil {
+		for _, name := range sharedStoreNames(loaded) {
+			if !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names, cobra.ShellCompDirectiveNoFileComp
+}
+
+// CompleteHistoryIDs completes the ids of the history entries, described by their question (history show, rerun)
+func CompleteHistoryIDs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	entries, err := history.Load(history.Path(completionConfigFile(cmd)))
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	// Most recent first, as the shells keep the order of the completions
+	completions := make([]string, 0, len(entries))
+	for _, entry := range slices.Backward(entries) {
+		completions = append(completions, fmt.Sprintf("%d\t%s", entry.ID, entryTitle(entry.Question)))
+	}
+	return completions, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// sessionNames returns the names of the sessions saved in the sessions directory
+func sessionNames(dir string) []string {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, strings.TrimSuffix(filepath.Base(file), ".json"))
+	}
+	return names
+}
+
+// interactiveSuggestions returns the suggestions of the interactive prompt: the slash commands (followed by
+// the extra ones of the plugins), the saved sessions after /load and the files of the project after the
+// commands taking a path
+func interactiveSuggestions(sessionsDir string, extra []string) []string {
+	suggestions := append(slices.Clone(slashCommands), extra...)
+	for _, name := range sessionNames(sessionsDir) {
+		suggestions = append(suggestions, "/load "+name)
+	}
+	paths := projectPaths(".")
+	for _, command := range fileSlashCommands {
+		for _, path := range paths {
+			suggestions = append(suggestions, command+path)
+		}
+	}
+	return suggestions
+}
+
+// projectPaths returns the files and directories (with a trailing slash) under root, skipping the
+// hidden, dependency and build directories like the code index, at most maxPathSuggestions of them
+func projectPaths(root string) []string {
+	var paths []string
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || path == root {
+			return nil
+		}
+		if len(paths) >= maxPathSuggestions {
+			return filepath.SkipAll
+		}
+		if d.IsDir() {
+			if skipIndexDir(path) {
+				return filepath.SkipDir
+			}
+			paths = append(paths, filepath.ToSlash(path)+"/")
+			return nil
+		}
+		paths = append(paths, filepath.ToSlash(path))
+		return nil
+	})
+	return paths
+}
+
+// runPromptInput runs the question input of interactive mode: tab completes the suggestion shown after
+// the typed text (up and down cycle through the other matches), enter submits the question
+func runPromptInput(input *huh.Input) error {
+	keymap := huh.NewDefaultKeyMap()
+	keymap.Input.AcceptSuggestion = key.NewBinding(key.WithKeys("tab", "ctrl+e"), key.WithHelp("tab", "complete"))
+	keymap.Input.Next = key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "next"))
+	return huh.NewForm(huh.NewGroup(input)).WithShowHelp(false).WithKeyMap(keymap).Run()
+}
+
+// runField runs a single field prompt like its Run method, rendering it on out
+// (stderr when the answer is alone on stdout)
+func runField(field huh.Field, out io.Writer) error {
+	return huh.NewForm(huh.NewGroup(field)).WithShowHelp(false).WithOutput(out).Run()
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// RunConfigUse handles the config use command execution: it sets the default profile of the config file
+func RunConfigUse(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	profile := args[0]
+
+	// Loading the config with the profile checks that it exists
+	loaded, err := config.LoadConfig(configFile, config.Overrides{Profile: profile})
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+
+	if err := config.SetField(configFile, "profile", profile); err != nil {
+		return fmt.Errorf("error updating config file: %w", err)
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ Default profile set to %s (model: %s)", profile, loaded.Model)))
+	return nil
+}
+
+// RunConfigProfiles handles the config profiles command execution: it lists the profiles of the config file
+func RunConfigProfiles(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+
+	loaded, err := config.LoadConfig(configFile, config.Overrides{})
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+
+	if len(loaded.Profiles) == 0 {
+		fmt.Println("No profiles defined in " + configFile)
+		return nil
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	for _, name := range loaded.ProfileNames() {
+		profile := loaded.Profiles[name]
+		line := fmt.Sprintf("  %s", name)
+		if profile.Model != "" {
+			line += fmt.Sprintf(" (model: %s)", profile.Model)
+		}
+		if name == loaded.Profile {
+			fmt.Println(greenStyle.Render("* " + line[2:] + " [default]"))
+		} else {
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
+
+// RunConfigGet handles the config get command execution: it prints the value of a config key used by the
+// commands (the value of the config file, or its default)
+func RunConfigGet(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	key := args[0]
+	if err := config.CheckKey(key); err != nil {
+		return err
+	}
+
+	loaded, err := config.LoadConfig(configFile, config.Overrides{})
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	values, err := loaded.Values()
+	if err != nil {
+		return err
+	}
+	value, found := config.Lookup(values, key)
+	if !found {
+		return fmt.Errorf("%s is not set", key)
+	}
+	fmt.Println(formatConfigValue(value, true))
+	return nil
+}
+
+// RunConfigSet handles the config set command execution: it sets a config key, validated against the
+// type of its field, then checks the resulting config (the file is restored when it is invalid)
+func RunConfigSet(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	key := args[0]
+
+	value, err := config.ParseValue(key, args[1])
+	if err != nil {
+		return err
+	}
+	if err := updateConfigValue(configFile, key, value); err != nil {
+		return err
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ %s set to %s", key, formatConfigValue(value, false))))
+	return nil
+}
+
+// RunConfigUnset handles the config unset command execution: it removes a config key, the commands use its default
+func RunConfigUnset(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	key := args[0]
+
+	if err := updateConfigValue(configFile, key, nil); err != nil {
+		return err
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ %s removed from %s", key, configFile)))
+	return nil
+}
+
+// RunConfigList handles the config list command execution: it prints every config key with the value used
+// by the commands, the keys not set in the config file are dimmed
+func RunConfigList(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+
+	loaded, err := config.LoadConfig(configFile, config.Overrides{})
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	values, err := loaded.Values()
+	if err != nil {
+		return err
+	}
+	fileKeys, err := config.FileKeys(configFile)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+
+	fmt.Printf("⚙️  %s\n", configFile)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	for _, key := range config.Keys() {
+		value, found := values[key]
+		line := fmt.Sprintf("  %s = ", key)
+		if found {
+			line += formatConfigValue(value, false)
+		} else {
+			line += "(not set)"
+		}
+		if slices.Contains(fileKeys, key) {
+			fmt.Println(line)
+		} else {
+			fmt.Println(dimStyle.Render(line))
+		}
+	}
+	return nil
+}
+
+// updateConfigValue sets (or removes when value is nil) a key of the config file, and restores the file
+// when the resulting config does not load
+func updateConfigValue(configFile, key string, value any) error {
+	original, err := os.ReadFile(configFile)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	if err := config.SetValue(configFile, key, value); err != nil {
+		return fmt.Errorf("error updating config file: %w", err)
+	}
+	if _, err := config.LoadConfig(configFile, config.Overrides{}); err != nil {
+		if restoreErr := os.WriteFile(configFile, original, 0644); restoreErr != nil {
+			return fmt.Errorf("error restoring config file: %w", restoreErr)
+		}
+		return fmt.Errorf("invalid config, %s not changed: %w", configFile, err)
+	}
+	return nil
+}
+
+// formatConfigValue formats a config value: strings as is, the other values as JSON (indented with indent)
+func formatConfigValue(value any, indent bool) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	var data []byte
+	if indent {
+		data, _ = json.MarshalIndent(value, "", "  ")
+	} else {
+		data, _ = json.Marshal(value)
+	}
+	return string(data)
+}
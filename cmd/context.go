@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/contextpack"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+)
+
+// loadContextPack builds the context of --context: the file tree of the directory and the contents of its
+// files, within the attachment token limit
+func loadContextPack(config *config.Config, opts askOptions) (string, error) {
+	if opts.contextDir == "" {
+		return "", nil
+	}
+	pack, err := contextpack.Build(opts.contextDir, contextpack.Options{MaxTokens: config.AttachmentTokenLimit})
+	if err != nil {
+		return "", fmt.Errorf("error building the context of %s: %w", opts.contextDir, err)
+	}
+	if len(pack.Files) == 0 {
+		return "", fmt.Errorf("no text file in %s", opts.contextDir)
+	}
+
+	message := pack.Message()
+	if !opts.quiet {
+		fmt.Fprintf(opts.logWriter(), "📦 Context of %s: %d of %d files included (about %d tokens)\n", opts.contextDir, pack.Included(), len(pack.Files), tokens.Estimate(message))
+	}
+	return message, nil
+}
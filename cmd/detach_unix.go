@@ -0,0 +1,14 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detach runs the command in its own process group, so it keeps running
+// when the terminal sends Ctrl+C to budgie
+func detach(command *exec.Cmd) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
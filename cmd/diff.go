@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+)
+
+// diffStaged is the --diff value (and the /diff default) for the staged changes
+const diffStaged = "staged"
+
+// errNoChanges is returned when the git diff is empty
+var errNoChanges = errors.New("no changes")
+
+// gitDiff runs git diff in the current directory and returns the diff and its description.
+// spec is "staged" for the staged changes (the working tree changes when nothing is staged),
+// otherwise the git diff arguments: a commit, a range (e.g. main..HEAD, HEAD~3) and optionally paths.
+func gitDiff(spec string) (string, string, error) {
+	if spec == "" || spec == diffStaged {
+		diff, err := runGitDiff("--staged")
+		if err != nil || diff != "" {
+			return diff, "staged changes", err
+		}
+		diff, err = runGitDiff()
+		if err == nil && diff == "" {
+			err = fmt.Errorf("%w: nothing staged nor modified", errNoChanges)
+		}
+		return diff, "working tree changes", err
+	}
+
+	diff, err := runGitDiff(strings.Fields(spec)...)
+	if err == nil && diff == "" {
+		err = fmt.Errorf("%w in %s", errNoChanges, spec)
+	}
+	return diff, spec, err
+}
+
+// runGitDiff runs git diff with the arguments
+func runGitDiff(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	command := exec.Command("git", append([]string{"diff", "--no-color", "--no-ext-diff"}, args...)...)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("error running git diff: %s", firstLine(message))
+		}
+		return "", fmt.Errorf("error running git diff: %w", err)
+	}
+	return stdout.String(), nil
+}
+
+// loadDiff runs the git diff of --diff (or /diff) and returns it as a context message, limited to the
+// attachment token limit (its notices are shown on out)
+func loadDiff(config *config.Config, spec string, confirm bool, out io.Writer) (string, error) {
+	diff, description, err := gitDiff(spec)
+	if err != nil {
+		return "", err
+	}
+	diff, err = limitAttachment(config, "The git diff", strings.TrimRight(diff, "\n"), confirm, out)
+	if err != nil {
+		return "", err
+	}
+	return diffContextMessage(description, diff), nil
+}
+
+// diffContextMessage formats a git diff as context for the question
+func diffContextMessage(description, diff string) string {
+	return fmt.Sprintf("Git diff (%s):\n\n```diff\n%s\n```", description, diff)
+}
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// doctorTimeout is the timeout of each request to the provider
+const doctorTimeout = 15 * time.Second
+
+// doctor prints the results of the checks and counts the failures
+type doctor struct {
+	failures int
+	warnings int
+}
+
+// ok reports a successful check
+func (d *doctor) ok(message string) {
+	fmt.Println("✅ " + message)
+}
+
+// warn reports a check which does not prevent budgie from working, with its fix
+func (d *doctor) warn(message, fix string) {
+	d.warnings++
+	yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	fmt.Println(yellowStyle.Render("⚠️  " + message))
+	d.fix(fix)
+}
+
+// fail reports a failed check, with its fix
+func (d *doctor) fail(message, fix string) {
+	d.failures++
+	redStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	fmt.Println(redStyle.Render("❌ " + message))
+	d.fix(fix)
+}
+
+// fix prints the fix of a check
+func (d *doctor) fix(fix string) {
+	if fix != "" {
+		dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+		fmt.Println(dimStyle.Render("   💡 " + fix))
+	}
+}
+
+// RunDoctor handles the doctor command execution: it checks the config, the provider, the models and
+// the embeddings store of the project, and reports how to fix the problems found
+func RunDoctor(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+
+	fmt.Println("🩺 Checking the budgie project...")
+	fmt.Println()
+	d := &doctor{}
+
+	config := d.checkConfig(configFile, configOverrides(cmd))
+	if config != nil {
+		for _, systemFile := range systemFiles {
+			if _, err := systemFilePaths([]string{systemFile}); err != nil {
+				d.fail(fmt.Sprintf("System instructions %s not found: %v", systemFile, err), "Create it, or run budgie init --upgrade")
+			} else {
+				d.ok(fmt.Sprintf("System instructions %s", systemFile))
+			}
+		}
+
+		dimension := d.checkProvider(config)
+		d.checkEmbeddings(config, embeddingsFile, dimension)
+	}
+
+	fmt.Println()
+	switch {
+	case d.failures > 0:
+		return fmt.Errorf("%d check(s) failed, %d warning(s)", d.failures, d.warnings)
+	case d.warnings > 0:
+		fmt.Printf("🩺 No problem preventing budgie from working, %d warning(s)\n", d.warnings)
+	default:
+		greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+		fmt.Println(greenStyle.Render("🩺 Everything looks good"))
+	}
+	return nil
+}
+
+// checkConfig validates the config file: its JSON, its keys and its values. It returns the loaded config,
+// nil when it does not load.
+func (d *doctor) checkConfig(configFile string, overrides config.Overrides) *config.Config {
+	if _, err := os.Stat(configFile); err != nil {
+		d.fail(fmt.Sprintf("Config file %s not found", configFile), "Run budgie init to create the project")
+		return nil
+	}
+	loaded, err := config.LoadConfig(configFile, overrides)
+	if err != nil {
+		d.fail(fmt.Sprintf("Invalid config file %s: %v", configFile, err), "Fix the value with budgie config set <key> <value>")
+		return nil
+	}
+
+	keys, _ := config.FileKeys(configFile)
+	var unknown []string
+	for _, key := range keys {
+		if !slices.Contains(config.Keys(), key) {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		d.warn(fmt.Sprintf("Unknown key(s) in %s, ignored: %s", configFile, strings.Join(unknown, ", ")), "Check the spelling (budgie config list shows the keys), or remove them from the file")
+	} else {
+		d.ok(fmt.Sprintf("Config file %s", configFile))
+	}
+
+	if loaded.Model == "" {
+		d.fail("No chat model configured", "Set it with budgie config set model <model>")
+	}
+	if loaded.EmbeddingModel == "" {
+		d.warn("No embedding model configured: RAG is unavailable", "Set it with budgie config set embedding-model <model>")
+	}
+	return loaded
+}
+
+// checkProvider checks that the provider is reachable and serves the configured models. It returns the
+// dimension of the embeddings of the embedding model (0 when unknown).
+func (d *doctor) checkProvider(config *config.Config) int {
+	baseURL, err := provider.BaseURL(config)
+	if err != nil {
+		d.fail(err.Error(), "Set the provider with budgie config set provider <name>, and its URL with budgie config set baseURL <url>")
+		return 0
+	}
+	if _, err := provider.APIKey(config); err != nil {
+		d.fail(err.Error(), "")
+		return 0
+	}
+	client, err := provider.NewClient(config)
+	if err != nil {
+		d.fail(err.Error(), "")
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), doctorTimeout)
+	defer cancel()
+	page, err := client.Models.List(ctx)
+	if err != nil {
+		if backendUnreachable(err) {
+			d.fail(fmt.Sprintf("Provider %s unreachable at %s: %v", providerName(config), baseURL, err), "Start the model runner (e.g. Docker Model Runner, ollama serve), or fix baseURL with budgie config set baseURL <url>")
+			return 0
+		}
+		d.warn(fmt.Sprintf("Provider %s at %s does not list its models: %v", providerName(config), baseURL, err), "The models are checked with a request instead")
+	} else {
+		d.ok(fmt.Sprintf("Provider %s reachable at %s", providerName(config), baseURL))
+		var available []string
+		for _, model := range page.Data {
+			available = append(available, model.ID)
+		}
+		for _, model := range []string{config.Model, config.EmbeddingModel} {
+			if model == "" {
+				continue
+			}
+			if hasModel(available, model) {
+				d.ok(fmt.Sprintf("Model %s available", model))
+			} else {
+				d.fail(fmt.Sprintf("Model %s not found on the provider", model), pullHint(config, model)+", or choose one of: "+strings.Join(available, ", "))
+			}
+		}
+	}
+
+	if config.EmbeddingModel == "" {
+		return 0
+	}
+	embedding, err := client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: openai.EmbeddingModel(config.EmbeddingModel),
+		Input: openai.EmbeddingNewParamsInputUnion{OfString: openai.String("budgie doctor")},
+	})
+	if err != nil || len(embedding.Data) == 0 {
+		d.fail(fmt.Sprintf("The embedding model %s does not create embeddings: %v", config.EmbeddingModel, err), "Check that embedding-model is an embedding model")
+		return 0
+	}
+	dimension := len(embedding.Data[0].Embedding)
+	d.ok(fmt.Sprintf("Embedding model %s creates embeddings of dimension %d", config.EmbeddingModel, dimension))
+	return dimension
+}
+
+// checkEmbeddings checks that the embeddings store was generated with the configured embedding model
+func (d *doctor) checkEmbeddings(config *config.Config, embeddingsFile string, dimension int) {
+	if !rag.StoreExists(config, embeddingsFile) {
+		d.warn(fmt.Sprintf("No embeddings store at %s: RAG is unavailable", rag.StoreFile(config, embeddingsFile)), "Generate it with budgie generate-embeddings")
+		return
+	}
+	store, err := rag.OpenStore(config, embeddingsFile, true)
+	if err != nil {
+		d.fail(fmt.Sprintf("Invalid embeddings store %s: %v", rag.StoreFile(config, embeddingsFile), err), "Regenerate it with budgie generate-embeddings")
+		return
+	}
+	defer store.Close()
+
+	storeDimension, err := rag.StoreDimension(store)
+	if err != nil {
+		d.fail(fmt.Sprintf("Error reading the embeddings store: %v", err), "Regenerate it with budgie generate-embeddings")
+		return
+	}
+	if storeDimension == 0 {
+		d.warn(fmt.Sprintf("The embeddings store %s is empty", rag.StoreFile(config, embeddingsFile)), "Add documentation to the docs directory, then run budgie generate-embeddings")
+		return
+	}
+
+	storeModel, _ := rag.StoreEmbeddingModel(embeddingsFile)
+	switch {
+	case dimension > 0 && storeDimension != dimension:
+		d.fail(fmt.Sprintf("The embeddings store has embeddings of dimension %d, %s creates embeddings of dimension %d", storeDimension, config.EmbeddingModel, dimension), "Regenerate the embeddings with budgie generate-embeddings")
+	case storeModel != "" && storeModel != config.EmbeddingModel:
+		d.fail(fmt.Sprintf("The embeddings store was generated with %s, the configured embedding model is %s", storeModel, config.EmbeddingModel), "Regenerate the embeddings with budgie generate-embeddings")
+	case storeModel == "":
+		d.warn(fmt.Sprintf("The embeddings store %s does not record its embedding model", rag.StoreFile(config, embeddingsFile)), "Regenerate it with budgie generate-embeddings to record it")
+	default:
+		d.ok(fmt.Sprintf("Embeddings store %s generated with %s (dimension %d)", rag.StoreFile(config, embeddingsFile), storeModel, storeDimension))
+	}
+}
+
+// hasModel reports whether a model is in the models listed by the provider ("name" matches "name:latest")
+func hasModel(available []string, model string) bool {
+	for _, id := range available {
+		if id == model || id == model+":latest" || strings.TrimSuffix(id, ":latest") == model {
+			return true
+		}
+	}
+	return false
+}
+
+// pullHint tells how to get a missing model on the provider
+func pullHint(config *config.Config, model string) string {
+	switch providerName(config) {
+	case provider.DMR:
+		return "Pull it with docker model pull " + model
+	case provider.Ollama:
+		return "Pull it with ollama pull " + model
+	default:
+		return "Check the model name"
+	}
+}
+
+// providerName returns the name of the configured provider
+func providerName(config *config.Config) string {
+	if config.Provider == "" {
+		return provider.DMR
+	}
+	return config.Provider
+}
@@ -0,0 +1,459 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/pathfilter"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	budgierag "github.com/budgies-nest/budgie/rag"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// embeddingsStats describes the content of a vector store
+type embeddingsStats struct {
+	Store              string      `json:"store"`
+	EmbeddingModel     string      `json:"embedding_model,omitempty"`
+	Chunks             int         `json:"chunks"`
+	Files              int         `json:"files"`
+	AverageChunkLength int         `json:"average_chunk_length"`
+	Dimensions         []int       `json:"dimensions"`
+	SizeBytes          int64       `json:"size_bytes"`
+	PerFile            []fileStats `json:"per_file"`
+	OrphanedChunks     int         `json:"orphaned_chunks"`
+	OrphanedFiles      []string    `json:"orphaned_files,omitempty"`
+}
+
+// fileStats is the number of chunks of a source file
+type fileStats struct {
+	File   string `json:"file"`
+	Chunks int    `json:"chunks"`
+}
+
+// chunkSources maps the chunks of a store to their source file: the files of the embeddings manifest,
+// or the path prefix of the chunk id in the code collection ("cmd/ask.go#chunk-1")
+func chunkSources(manifest *rag.Manifest) func(id string) string {
+	sources := make(map[string]string)
+	for file, entry := range manifest.Files {
+		for _, id := range entry.Chunks {
+			sources[id] = file
+		}
+	}
+	return func(id string) string {
+		if source, ok := sources[id]; ok {
+			return source
+		}
+		if source, _, found := strings.Cut(id, "#chunk-"); found {
+			return source
+		}
+		return ""
+	}
+}
+
+// RunEmbeddingsStats handles the embeddings stats command execution: it inspects the vector store
+func RunEmbeddingsStats(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	if !rag.StoreExists(config, embeddingsFile) {
+		return fmt.Errorf("no embeddings found at %s (run budgie generate-embeddings first)", rag.StoreFile(config, embeddingsFile))
+	}
+
+	store, err := rag.OpenStore(config, embeddingsFile, true)
+	if err != nil {
+		return fmt.Errorf("error loading vector store: %w", err)
+	}
+	defer store.Close()
+	manifest, err := rag.LoadManifest(rag.ManifestPath(embeddingsFile))
+	if err != nil {
+		return fmt.Errorf("error loading embeddings manifest: %w", err)
+	}
+	sourceOf := chunkSources(manifest)
+
+	stats := embeddingsStats{Store: rag.StoreFile(config, embeddingsFile), EmbeddingModel: manifest.EmbeddingModel}
+	perFile := make(map[string]int)
+	dimensions := make(map[int]bool)
+	totalLength := 0
+	err = store.ForEach(func(record budgierag.VectorRecord) error {
+		stats.Chunks++
+		totalLength += len([]rune(record.Prompt))
+		dimensions[len(record.Embedding)] = true
+		perFile[sourceOf(record.Id)]++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error reading vector store: %w", err)
+	}
+	if stats.Chunks > 0 {
+		stats.AverageChunkLength = totalLength / stats.Chunks
+	}
+	for dimension := range dimensions {
+		stats.Dimensions = append(stats.Dimensions, dimension)
+	}
+	sort.Ints(stats.Dimensions)
+
+	// The chunks whose source file is unknown or no longer exists are orphaned
+	for file, chunks := range perFile {
+		stats.PerFile = append(stats.PerFile, fileStats{File: file, Chunks: chunks})
+		if _, err := os.Stat(file); file == "" || err != nil {
+			stats.OrphanedChunks += chunks
+			stats.OrphanedFiles = append(stats.OrphanedFiles, file)
+		}
+	}
+	sort.Slice(stats.PerFile, func(i, j int) bool {
+		if stats.PerFile[i].Chunks != stats.PerFile[j].Chunks {
+			return stats.PerFile[i].Chunks > stats.PerFile[j].Chunks
+		}
+		return stats.PerFile[i].File < stats.PerFile[j].File
+	})
+	sort.Strings(stats.OrphanedFiles)
+	stats.Files = len(perFile)
+
+	// The store file along with its manifest and keyword index
+	for _, path := range []string{stats.Store, rag.ManifestPath(embeddingsFile), rag.KeywordIndexPath(embeddingsFile)} {
+		if info, err := os.Stat(path); err == nil {
+			stats.SizeBytes += info.Size()
+		}
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	}
+	printEmbeddingsStats(stats)
+	return nil
+}
+
+// embeddingsStatsTopFiles is the number of files listed in the per-file distribution
+const embeddingsStatsTopFiles = 10
+
+// printEmbeddingsStats prints the statistics of a vector store
+func printEmbeddingsStats(stats embeddingsStats) {
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+
+	dimensions := make([]string, 0, len(stats.Dimensions))
+	for _, dimension := range stats.Dimensions {
+		dimensions = append(dimensions, fmt.Sprintf("%d", dimension))
+	}
+
+	fmt.Println(headerStyle.Render("📦 " + stats.Store))
+	rows := [][]string{
+		{"Chunks", fmt.Sprintf("%d", stats.Chunks)},
+		{"Files", fmt.Sprintf("%d", stats.Files)},
+		{"Average chunk length", fmt.Sprintf("%d chars", stats.AverageChunkLength)},
+		{"Embedding dimension", strings.Join(dimensions, ", ")},
+		{"Size on disk", formatBytes(stats.SizeBytes)},
+		{"Orphaned chunks", fmt.Sprintf("%d", stats.OrphanedChunks)},
+	}
+	if stats.EmbeddingModel != "" {
+		rows = append([][]string{{"Embedding model", stats.EmbeddingModel}}, rows...)
+	}
+	printSummaryTable(rows)
+	if len(stats.Dimensions) > 1 {
+		fmt.Println(yellowStyle.Render("⚠️  The chunks have different embedding dimensions: regenerate the embeddings with budgie generate-embeddings"))
+	}
+
+	if len(stats.PerFile) > 0 {
+		fmt.Println()
+		fmt.Println(headerStyle.Render("Chunks per file"))
+		for i, file := range stats.PerFile {
+			if i == embeddingsStatsTopFiles {
+				fmt.Printf("  ... and %d more files\n", len(stats.PerFile)-embeddingsStatsTopFiles)
+				break
+			}
+			fmt.Printf("  %5d  %s\n", file.Chunks, displaySource(file.File))
+		}
+	}
+
+	if len(stats.OrphanedFiles) > 0 {
+		fmt.Println()
+		fmt.Println(yellowStyle.Render(fmt.Sprintf("⚠️  %d orphaned chunks from missing source files:", stats.OrphanedChunks)))
+		for _, file := range stats.OrphanedFiles {
+			fmt.Printf("  - %s\n", displaySource(file))
+		}
+	}
+}
+
+// displaySource names the source file of chunks
+func displaySource(file string) string {
+	if file == "" {
+		return "(unknown source)"
+	}
+	return file
+}
+
+// formatBytes formats a size in bytes with a binary unit
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// sourceChunks groups the chunk ids of a store by source file
+func sourceChunks(store rag.Store, manifest *rag.Manifest) (map[string][]string, error) {
+	sourceOf := chunkSources(manifest)
+	chunks := make(map[string][]string)
+	err := store.ForEach(func(record budgierag.VectorRecord) error {
+		source := sourceOf(record.Id)
+		chunks[source] = append(chunks[source], record.Id)
+		return nil
+	})
+	return chunks, err
+}
+
+// removeSources deletes the chunks of the source files from the vector store and the manifest,
+// then persists them (the keyword index is rebuilt). With dryRun, the files are only listed.
+func removeSources(config *config.Config, embeddingsFile string, selected func(source string) bool, dryRun bool) error {
+	store, err := rag.OpenStore(config, embeddingsFile, dryRun)
+	if err != nil {
+		return fmt.Errorf("error loading vector store: %w", err)
+	}
+	defer store.Close()
+	manifestPath := rag.ManifestPath(embeddingsFile)
+	manifest, err := rag.LoadManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("error loading embeddings manifest: %w", err)
+	}
+	chunks, err := sourceChunks(store, manifest)
+	if err != nil {
+		return fmt.Errorf("error reading vector store: %w", err)
+	}
+
+	var sources []string
+	var ids []string
+	for source, sourceIDs := range chunks {
+		if source != "" && selected(source) {
+			sources = append(sources, source)
+			ids = append(ids, sourceIDs...)
+		}
+	}
+	sort.Strings(sources)
+	if len(sources) == 0 {
+		fmt.Println("No matching embeddings")
+		return nil
+	}
+
+	for _, source := range sources {
+		fmt.Printf("  - %s (%d chunks)\n", source, len(chunks[source]))
+	}
+	if dryRun {
+		fmt.Printf("Would delete %d chunks of %d files (dry run)\n", len(ids), len(sources))
+		return nil
+	}
+
+	if err := store.Delete(ids...); err != nil {
+		return fmt.Errorf("error deleting chunks: %w", err)
+	}
+	if err := store.Persist(); err != nil {
+		return fmt.Errorf("error saving vector store: %w", err)
+	}
+	for _, source := range sources {
+		delete(manifest.Files, source)
+	}
+	if _, err := os.Stat(manifestPath); err == nil {
+		if err := manifest.Save(manifestPath); err != nil {
+			return fmt.Errorf("error saving embeddings manifest: %w", err)
+		}
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ Deleted %d chunks of %d files", len(ids), len(sources))))
+	return nil
+}
+
+// RunEmbeddingsDelete handles the embeddings delete command execution: it deletes the chunks of the source
+// files matching the given paths or glob patterns, without regenerating the store
+func RunEmbeddingsDelete(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	filter := pathfilter.Filter{Include: args}
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	if !rag.StoreExists(config, embeddingsFile) {
+		return fmt.Errorf("no embeddings found at %s", rag.StoreFile(config, embeddingsFile))
+	}
+	manifest, err := rag.LoadManifest(rag.ManifestPath(embeddingsFile))
+	if err != nil {
+		return fmt.Errorf("error loading embeddings manifest: %w", err)
+	}
+
+	// A source matches with its path as recorded or relative to the docs directory
+	return removeSources(config, embeddingsFile, func(source string) bool {
+		if filter.Allowed(source) {
+			return true
+		}
+		if manifest.Docs != "" {
+			if relPath, err := filepath.Rel(manifest.Docs, source); err == nil && !strings.HasPrefix(relPath, "..") {
+				return filter.Allowed(relPath)
+			}
+		}
+		return false
+	}, dryRun)
+}
+
+// RunEmbeddingsPrune handles the embeddings prune command execution: it deletes the chunks of the
+// source files which no longer exist, without regenerating the store
+func RunEmbeddingsPrune(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	if !rag.StoreExists(config, embeddingsFile) {
+		return fmt.Errorf("no embeddings found at %s", rag.StoreFile(config, embeddingsFile))
+	}
+
+	return removeSources(config, embeddingsFile, func(source string) bool {
+		_, err := os.Stat(source)
+		return os.IsNotExist(err)
+	}, dryRun)
+}
+
+// RunEmbeddingsExport handles the embeddings export command execution: it writes the vector store, its
+// manifest and the metadata of its generation (embedding model, chunking) to an archive
+func RunEmbeddingsExport(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	if !rag.StoreExists(config, embeddingsFile) {
+		return fmt.Errorf("no embeddings found at %s (run budgie generate-embeddings first)", rag.StoreFile(config, embeddingsFile))
+	}
+	store, err := rag.OpenStore(config, embeddingsFile, true)
+	if err != nil {
+		return fmt.Errorf("error loading vector store: %w", err)
+	}
+	defer store.Close()
+	manifest, err := rag.LoadManifest(rag.ManifestPath(embeddingsFile))
+	if err != nil {
+		return fmt.Errorf("error loading embeddings manifest: %w", err)
+	}
+	if manifest.EmbeddingModel == "" {
+		manifest.EmbeddingModel = config.EmbeddingModel
+	}
+
+	archive, err := rag.NewArchive(store, manifest, cmd.Root().Version)
+	if err != nil {
+		return err
+	}
+	if err := archive.Write(args[0]); err != nil {
+		return err
+	}
+
+	size := ""
+	if info, err := os.Stat(args[0]); err == nil {
+		size = fmt.Sprintf(" (%s)", formatBytes(info.Size()))
+	}
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ Exported %d chunks of %d files to %s%s", archive.Info.Chunks, archive.Info.Files, args[0], size)))
+	return nil
+}
+
+// RunEmbeddingsImport handles the embeddings import command execution: it replaces the vector store with
+// the one of an archive written by embeddings export, in the configured backend
+func RunEmbeddingsImport(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	force, _ := cmd.Flags().GetBool("force")
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	if rag.StoreExists(config, embeddingsFile) && !force {
+		return fmt.Errorf("%s already exists (--force replaces it)", rag.StoreFile(config, embeddingsFile))
+	}
+	archive, err := rag.ReadArchive(args[0])
+	if err != nil {
+		return err
+	}
+	printArchiveInfo(args[0], archive.Info)
+
+	if err := os.MkdirAll(filepath.Dir(embeddingsFile), 0755); err != nil {
+		return fmt.Errorf("error creating the store directory: %w", err)
+	}
+	store, err := rag.OpenStore(config, embeddingsFile, false)
+	if err != nil {
+		return fmt.Errorf("error loading vector store: %w", err)
+	}
+	defer store.Close()
+	if err := store.Reset(); err != nil {
+		return fmt.Errorf("error resetting vector store: %w", err)
+	}
+	for _, record := range archive.Records {
+		if _, err := store.Save(record); err != nil {
+			return fmt.Errorf("error saving chunk %s: %w", record.Id, err)
+		}
+	}
+	// Persisting rebuilds the keyword index
+	if err := store.Persist(); err != nil {
+		return fmt.Errorf("error saving vector store: %w", err)
+	}
+	archive.Manifest.VectorStore = rag.StoreBackend(config)
+	if err := archive.Manifest.Save(rag.ManifestPath(embeddingsFile)); err != nil {
+		return fmt.Errorf("error saving embeddings manifest: %w", err)
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ Imported %d chunks into %s", len(archive.Records), rag.StoreFile(config, embeddingsFile))))
+	if archive.Info.EmbeddingModel != "" && archive.Info.EmbeddingModel != config.EmbeddingModel {
+		yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+		fmt.Println(yellowStyle.Render(fmt.Sprintf("⚠️  The store was generated with %s but the configured embedding model is %s: set embedding-model to %s to search it", archive.Info.EmbeddingModel, config.EmbeddingModel, archive.Info.EmbeddingModel)))
+	}
+	return nil
+}
+
+// printArchiveInfo prints how the store of an archive was generated
+func printArchiveInfo(path string, info rag.ArchiveInfo) {
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	fmt.Println(headerStyle.Render("📦 " + path))
+	rows := [][]string{
+		{"Embedding model", info.EmbeddingModel},
+		{"Embedding dimension", fmt.Sprintf("%d", info.Dimension)},
+		{"Chunks", fmt.Sprintf("%d", info.Chunks)},
+		{"Files", fmt.Sprintf("%d", info.Files)},
+	}
+	if len(info.Chunking) > 0 {
+		rows = append(rows, []string{"Chunking", strings.Join(info.Chunking, "; ")})
+	}
+	created := info.Created.Local().Format("2006-01-02 15:04")
+	if info.BudgieVersion != "" {
+		created += " (budgie " + info.BudgieVersion + ")"
+	}
+	rows = append(rows, []string{"Exported", created})
+	printSummaryTable(rows)
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/document"
+	"github.com/budgies-nest/budgie-cli/pkg/pathfilter"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+	budgierag "github.com/budgies-nest/budgie/rag"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// dryRunLatency is the assumed duration of an embedding request, used to estimate the duration of a run
+const dryRunLatency = 200 * time.Millisecond
+
+// embeddingPrices are the prices of the hosted embedding models, in USD per million tokens
+// (the embedding-price config key overrides them)
+var embeddingPrices = map[string]float64{
+	"text-embedding-3-small": 0.02,
+	"text-embedding-3-large": 0.13,
+	"text-embedding-ada-002": 0.10,
+}
+
+// estimate is the dry run of generate-embeddings: it finds and chunks the files like a run would, and prints
+// the chunks and estimated tokens of each file, then the estimated embedding requests, cost and duration.
+// No embedding is created and the store is only read.
+func (run *embeddingsRun) estimate(config *config.Config, embeddingsPath string, rules map[string]config.ChunkingRule, filter pathfilter.Filter) error {
+	manifest, err := rag.LoadManifest(rag.ManifestPath(embeddingsPath))
+	if err != nil {
+		return fmt.Errorf("error loading embeddings manifest: %w", err)
+	}
+	incremental := run.incremental
+	if incremental && manifest.EmbeddingModel != "" && manifest.EmbeddingModel != config.EmbeddingModel {
+		fmt.Printf("Embedding model changed (%s -> %s), all the files would be embedded\n", manifest.EmbeddingModel, config.EmbeddingModel)
+		incremental = false
+	}
+
+	// The chunks identical to the stored ones would reuse their embeddings
+	stored := make(map[[sha256.Size]byte]bool)
+	if manifest.EmbeddingModel == config.EmbeddingModel && rag.StoreExists(config, embeddingsPath) {
+		store, err := rag.OpenStore(config, embeddingsPath, true)
+		if err != nil {
+			return fmt.Errorf("error loading vector store: %w", err)
+		}
+		err = store.ForEach(func(record budgierag.VectorRecord) error {
+			stored[sha256.Sum256([]byte(record.Prompt))] = true
+			return nil
+		})
+		store.Close()
+		if err != nil {
+			return fmt.Errorf("error reading vector store: %w", err)
+		}
+	}
+
+	extensions := make([]string, 0, len(rules))
+	for ext := range rules {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	foundFiles, _, err := findDocsFiles(run.docsPath, extensions, rules, filter)
+	if err != nil {
+		return err
+	}
+
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	fmt.Println("🔎 Dry run: nothing is embedded and the store is not modified")
+	files, chunks, requests, reused, unchanged, skipped, totalTokens, requestTokens := 0, 0, 0, 0, 0, 0, 0, 0
+	seen := make(map[[sha256.Size]byte]bool)
+	ctx := context.Background()
+	for file := range chunkFiles(ctx, readFiles(ctx, extensions, rules, foundFiles), manifest.Files, incremental) {
+		switch {
+		case errors.Is(file.err, sniff.ErrBinary) || errors.Is(file.err, document.ErrNoText):
+			fmt.Println(dimStyle.Render(fmt.Sprintf("  %s skipped: %v", file.path, file.err)))
+			skipped++
+			continue
+		case file.err != nil:
+			fmt.Printf("Error reading file %s: %v\n", file.path, file.err)
+			continue
+		case file.unchanged:
+			unchanged++
+			continue
+		}
+
+		fileTokens := 0
+		for _, chunk := range file.chunks {
+			chunkTokens := tokens.Estimate(chunk)
+			fileTokens += chunkTokens
+			key := sha256.Sum256([]byte(chunk))
+			if stored[key] || seen[key] {
+				reused++
+				continue
+			}
+			seen[key] = true
+			requests++
+			requestTokens += chunkTokens
+		}
+		files++
+		chunks += len(file.chunks)
+		totalTokens += fileTokens
+		fmt.Printf("  %5d chunks  ~%6d tokens  %s\n", len(file.chunks), fileTokens, file.path)
+	}
+
+	summary := [][]string{
+		{"Files to embed", fmt.Sprint(files)},
+		{"Chunks", fmt.Sprintf("%d (~%d tokens)", chunks, totalTokens)},
+	}
+	if reused > 0 {
+		summary = append(summary, []string{"Duplicate chunks reused", fmt.Sprint(reused)})
+	}
+	summary = append(summary, []string{"Embedding requests", fmt.Sprintf("%d (~%d tokens)", requests, requestTokens)})
+	if incremental {
+		summary = append(summary, []string{"Unchanged files", fmt.Sprint(unchanged)})
+	}
+	if skipped > 0 {
+		summary = append(summary, []string{"Binary files skipped", fmt.Sprint(skipped)})
+	}
+	summary = append(summary,
+		[]string{"Estimated cost", estimatedCost(config, requestTokens)},
+		[]string{"Estimated time", estimatedTime(requests, run.concurrency, run.rateLimit)},
+	)
+	printSummaryTable(summary)
+	return nil
+}
+
+// estimatedCost describes the cost of embedding a number of tokens with the configured embedding model
+func estimatedCost(config *config.Config, count int) string {
+	if envVar, err := provider.APIKeyEnv(config); err == nil && envVar == "" {
+		return "free (local model)"
+	}
+	price := config.EmbeddingPrice
+	if price == 0 {
+		// The models of the OpenAI-compatible gateways may be prefixed by their provider (openai/text-embedding-3-small)
+		model := config.EmbeddingModel[strings.LastIndex(config.EmbeddingModel, "/")+1:]
+		var known bool
+		if price, known = embeddingPrices[model]; !known {
+			return "unknown (set embedding-price)"
+		}
+	}
+	cost := float64(count) * price / 1e6
+	if cost > 0 && cost < 0.0001 {
+		return fmt.Sprintf("<$0.0001 (at $%g per million tokens)", price)
+	}
+	return fmt.Sprintf("~$%.4f (at $%g per million tokens)", cost, price)
+}
+
+// estimatedTime describes the duration of a number of embedding requests, sent by concurrency workers
+// with at most rateLimit requests per second
+func estimatedTime(requests, concurrency int, rateLimit float64) string {
+	duration := time.Duration(requests) * dryRunLatency / time.Duration(concurrency)
+	if limited := time.Duration(float64(requests) / rateLimit * float64(time.Second)); rateLimit > 0 && limited > duration {
+		return fmt.Sprintf("~%s (at %g requests per second)", limited.Round(time.Second), rateLimit)
+	}
+	return fmt.Sprintf("~%s (at ~%s per request)", duration.Round(time.Second), dryRunLatency)
+}
@@ -0,0 +1,292 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/chunking"
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/document"
+	"github.com/budgies-nest/budgie-cli/pkg/language"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+	"github.com/budgies-nest/budgie/agents"
+	budgierag "github.com/budgies-nest/budgie/rag"
+	"github.com/openai/openai-go"
+)
+
+// The embeddings generation is a pipeline: the reader, chunker and embedder stages run in their own
+// goroutine and are connected with channels, so the disk reads, the chunking and the embedding requests
+// overlap. The writer stage (RunGenerateEmbeddings) saves the embeddings and updates the manifest.
+
+// pipelineBuffer is the number of files buffered between two stages of the pipeline
+const pipelineBuffer = 16
+
+// sourceFile is a file read by the reader stage
+type sourceFile struct {
+	path    string
+	rule    config.ChunkingRule
+	content string
+	err     error
+}
+
+// chunkedFile is a file split by the chunker stage (unchanged files are not split)
+type chunkedFile struct {
+	sourceFile
+	hash      string
+	unchanged bool
+	chunks    []string
+	languages []string
+	metadata  rag.DocMetadata
+}
+
+// embeddedChunk is the embedding of a chunk, or the error returned by the embedding model
+type embeddedChunk struct {
+	embedding openai.Embedding
+	err       error
+}
+
+// embeddedFile is a file whose chunks were embedded by the embedder stage. When the generation is
+// interrupted, partial is set and embeddings only holds the chunks embedded before the interruption
+type embeddedFile struct {
+	chunkedFile
+	embeddings []embeddedChunk
+	partial    bool
+}
+
+// send passes a value to the next stage, it returns false when the pipeline is interrupted
+func send[T any](ctx context.Context, out chan<- T, value T) bool {
+	select {
+	case out <- value:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// readFiles is the reader stage: it reads the files of each extension, in order (binary files are rejected,
+// the text of the PDF and DOCX documents is extracted)
+func readFiles(ctx context.Context, extensions []string, rules map[string]config.ChunkingRule, foundFiles map[string][]string) <-chan sourceFile {
+	out := make(chan sourceFile, pipelineBuffer)
+	go func() {
+		defer close(out)
+		for _, fileExtension := range extensions {
+			for _, filePath := range foundFiles[fileExtension] {
+				var content string
+				var err error
+				if document.Supported(filePath) {
+					content, err = document.ReadText(filePath)
+				} else {
+					content, err = sniff.ReadTextFile(filePath)
+				}
+				if !send(ctx, out, sourceFile{path: filePath, rule: rules[fileExtension], content: content, err: err}) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// chunkFiles is the chunker stage: it skips the files whose content and chunking rule did not change
+// since the last run (incremental mode), splits the others and detects the language of their chunks.
+// The METADATA block of every file is parsed, unchanged ones included.
+func chunkFiles(ctx context.Context, in <-chan sourceFile, previousFiles map[string]rag.FileEntry, incremental bool) <-chan chunkedFile {
+	out := make(chan chunkedFile, pipelineBuffer)
+	go func() {
+		defer close(out)
+		for file := range in {
+			chunked := chunkedFile{sourceFile: file}
+			if file.err == nil {
+				chunked.hash = rag.HashContent(file.content)
+				chunked.metadata = rag.ParseDocMetadata(file.content)
+				previous, known := previousFiles[file.path]
+				chunked.unchanged = incremental && known && previous.Hash == chunked.hash && previous.Chunking == chunking.Describe(file.rule)
+				if !chunked.unchanged {
+					chunked.chunks = chunking.Chunk(file.content, file.rule)
+					for _, chunk := range chunked.chunks {
+						chunked.languages = append(chunked.languages, language.Detect(chunk))
+					}
+				}
+			}
+			if !send(ctx, out, chunked) {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// embedJob is a chunk to embed by a worker of the embedder stage
+type embedJob struct {
+	file  *pendingFile
+	index int
+}
+
+// pendingFile is a file whose chunks are being embedded by the workers. Its wait group counts
+// the chunks not embedded yet, the workers fill embeddings (by chunk index)
+type pendingFile struct {
+	file       chunkedFile
+	embeddings []embeddedChunk
+	dispatched int
+	done       sync.WaitGroup
+}
+
+// embedChunks is the embedder stage: a pool of workers (one per embedder agent) creates the embeddings
+// of the chunks, at most rateLimit requests per second (unlimited when 0). The files are sent to the
+// writer in order, once all their chunks are embedded. When the pipeline is interrupted, the in-flight
+// chunks are finished and the file being embedded is sent as partial.
+// The duplicate chunks get the embedding of the vector cache instead of an embedding request.
+// onEmbedded is called by the workers after each chunk.
+func embedChunks(ctx context.Context, embedders []*agents.Agent, rateLimit float64, vectors *vectorCache, in <-chan chunkedFile, onEmbedded func(err error)) <-chan embeddedFile {
+	out := make(chan embeddedFile, pipelineBuffer)
+	pending := make(chan *pendingFile, pipelineBuffer)
+	jobs := make(chan embedJob)
+
+	// Rate limiting: every request waits for a tick
+	var ticks <-chan time.Time
+	if rateLimit > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / rateLimit))
+		ticks = ticker.C
+		go func() {
+			<-ctx.Done()
+			ticker.Stop()
+		}()
+	}
+
+	// Workers
+	for _, embedder := range embedders {
+		go func() {
+			for job := range jobs {
+				embedding, err := vectors.embed(job.file.file.chunks[job.index], func(text string) (openai.Embedding, error) {
+					if ticks != nil {
+						// The stopped ticker does not tick anymore: the interruption fails the waiting chunks
+						select {
+						case <-ticks:
+						case <-ctx.Done():
+							return openai.Embedding{}, fmt.Errorf("interrupted while waiting for the rate limit: %w", ctx.Err())
+						}
+					}
+					// The in-flight requests are not cancelled by the interruption
+					return rag.Embed(context.Background(), embedder, text)
+				})
+				job.file.embeddings[job.index] = embeddedChunk{embedding: embedding, err: err}
+				onEmbedded(err)
+				job.file.done.Done()
+			}
+		}()
+	}
+
+	// Dispatcher: queues the files in order and hands their chunks to the workers
+	go func() {
+		defer close(pending)
+		defer close(jobs)
+		for file := range in {
+			if ctx.Err() != nil {
+				return
+			}
+			pf := &pendingFile{file: file, embeddings: make([]embeddedChunk, len(file.chunks))}
+			pf.done.Add(len(file.chunks))
+			pending <- pf
+			for index := range file.chunks {
+				if ctx.Err() != nil {
+					// The chunks which will not be embedded are done
+					pf.dispatched = index
+					pf.done.Add(index - len(file.chunks))
+					break
+				}
+				pf.dispatched = index + 1
+				jobs <- embedJob{file: pf, index: index}
+			}
+		}
+	}()
+
+	// Collector: sends the files to the writer in order once embedded
+	go func() {
+		defer close(out)
+		for pf := range pending {
+			pf.done.Wait()
+			// The writer gets the last file even when the pipeline is interrupted
+			out <- embeddedFile{
+				chunkedFile: pf.file,
+				embeddings:  pf.embeddings[:pf.dispatched],
+				partial:     pf.dispatched < len(pf.file.chunks),
+			}
+		}
+	}()
+
+	return out
+}
+
+// vectorCache reuses the embeddings of identical chunks (repeated headers, license blocks, boilerplate...):
+// the chunks are keyed on the hash of their content, each content is only embedded once per run, and the
+// chunks of the store embedded by the previous runs are reused. It is safe for concurrent use.
+type vectorCache struct {
+	mu      sync.Mutex
+	entries map[[sha256.Size]byte]*cachedVector
+	reused  int
+	saved   int
+}
+
+// cachedVector is the embedding of a chunk content, ready once embedded
+type cachedVector struct {
+	ready     chan struct{}
+	embedding openai.Embedding
+	err       error
+}
+
+// newVectorCache creates an empty vector cache
+func newVectorCache() *vectorCache {
+	return &vectorCache{entries: make(map[[sha256.Size]byte]*cachedVector)}
+}
+
+// seed adds the embeddings of the chunks of a store
+func (c *vectorCache) seed(store rag.Store) error {
+	return store.ForEach(func(record budgierag.VectorRecord) error {
+		ready := make(chan struct{})
+		close(ready)
+		c.entries[sha256.Sum256([]byte(record.Prompt))] = &cachedVector{ready: ready, embedding: openai.Embedding{Embedding: record.Embedding}}
+		return nil
+	})
+}
+
+// embed returns the embedding of a chunk: the cached one, or the one created by create. The duplicates of
+// a chunk being embedded wait for its embedding; a failed embedding is not cached.
+func (c *vectorCache) embed(text string, create func(text string) (openai.Embedding, error)) (openai.Embedding, error) {
+	key := sha256.Sum256([]byte(text))
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		<-entry.ready
+		if entry.err == nil {
+			c.mu.Lock()
+			c.reused++
+			c.saved += tokens.Estimate(text)
+			c.mu.Unlock()
+		}
+		return entry.embedding, entry.err
+	}
+	entry := &cachedVector{ready: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.embedding, entry.err = create(text)
+	if entry.err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+	}
+	close(entry.ready)
+	return entry.embedding, entry.err
+}
+
+// savings returns the number of chunks whose embedding was reused and the estimated tokens they saved
+func (c *vectorCache) savings() (int, int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reused, c.saved
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+	budgierag "github.com/budgies-nest/budgie/rag"
+	"github.com/openai/openai-go"
+)
+
+// countingEmbedder returns an embedding function counting its calls, the embedding of a text being its length
+func countingEmbedder(calls *atomic.Int32) func(text string) (openai.Embedding, error) {
+	return func(text string) (openai.Embedding, error) {
+		calls.Add(1)
+		return openai.Embedding{Embedding: []float64{float64(len(text))}}, nil
+	}
+}
+
+// Each content is embedded once, its duplicates reuse the embedding
+func TestVectorCache(t *testing.T) {
+	cache := newVectorCache()
+	var calls atomic.Int32
+	license := "Licensed under the Apache License, Version 2.0"
+	texts := []string{license, "# Install", license, license, "# Usage"}
+	for _, text := range texts {
+		embedding, err := cache.embed(text, countingEmbedder(&calls))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if embedding.Embedding[0] != float64(len(text)) {
+			t.Errorf("embedding of %q = %v, want the one of the text", text, embedding.Embedding)
+		}
+	}
+	if calls.Load() != 3 {
+		t.Errorf("%d embedding requests, want 3", calls.Load())
+	}
+	if reused, saved := cache.savings(); reused != 2 || saved != 2*tokens.Estimate(license) {
+		t.Errorf("savings = %d chunks, %d tokens, want 2 chunks, %d tokens", reused, saved, 2*tokens.Estimate(license))
+	}
+}
+
+// The duplicates embedded concurrently wait for the first request
+func TestVectorCacheConcurrent(t *testing.T) {
+	cache := newVectorCache()
+	var calls atomic.Int32
+	release := make(chan struct{})
+	create := func(text string) (openai.Embedding, error) {
+		calls.Add(1)
+		<-release
+		return openai.Embedding{Embedding: []float64{1}}, nil
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := cache.embed("same chunk", create); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	close(release)
+	wg.Wait()
+	if calls.Load() != 1 {
+		t.Errorf("%d embedding requests, want 1", calls.Load())
+	}
+	if reused, _ := cache.savings(); reused != 7 {
+		t.Errorf("%d chunks reused, want 7", reused)
+	}
+}
+
+// A failed embedding is not cached, the next duplicate is embedded again
+func TestVectorCacheError(t *testing.T) {
+	cache := newVectorCache()
+	failure := errors.New("rate limited")
+	if _, err := cache.embed("chunk", func(string) (openai.Embedding, error) { return openai.Embedding{}, failure }); !errors.Is(err, failure) {
+		t.Fatalf("embed = %v, want the error of the request", err)
+	}
+
+	var calls atomic.Int32
+	embedding, err := cache.embed("chunk", countingEmbedder(&calls))
+	if err != nil || calls.Load() != 1 || len(embedding.Embedding) != 1 {
+		t.Errorf("embed after a failure = %v, %v with %d requests, want a new request", embedding, err, calls.Load())
+	}
+	if reused, saved := cache.savings(); reused != 0 || saved != 0 {
+		t.Errorf("savings = %d, %d, want nothing reused", reused, saved)
+	}
+}
+
+// The chunks embedded by the previous runs are reused
+func TestVectorCacheSeed(t *testing.T) {
+	store, err := rag.OpenStore(&config.Config{}, filepath.Join(t.TempDir(), "embeddings.json"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if _, err := store.Save(budgierag.VectorRecord{Id: "guide.md#chunk-1", Prompt: "# Install", Embedding: []float64{0.5, 0.25}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := newVectorCache()
+	if err := cache.seed(store); err != nil {
+		t.Fatal(err)
+	}
+	var calls atomic.Int32
+	embedding, err := cache.embed("# Install", countingEmbedder(&calls))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 0 || len(embedding.Embedding) != 2 || embedding.Embedding[0] != 0.5 {
+		t.Errorf("embed of a stored chunk = %v with %d requests, want the stored embedding", embedding.Embedding, calls.Load())
+	}
+	if _, err := cache.embed("# Usage", countingEmbedder(&calls)); err != nil || calls.Load() != 1 {
+		t.Errorf("embed of a new chunk = %v with %d requests, want 1 request", err, calls.Load())
+	}
+}
@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/eval"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// evalResult is the outcome of a question of an evaluation set
+type evalResult struct {
+	Question  string   `json:"question"`
+	Retrieved []string `json:"retrieved"`
+	// Recall is the share of the expected sources among the retrieved chunks (nil without expected sources)
+	Recall *float64 `json:"recall,omitempty"`
+	// Rank is the rank of the first chunk of an expected source (0 when none was retrieved)
+	Rank        int    `json:"rank,omitempty"`
+	Keywords    int    `json:"keywords,omitempty"`
+	KeywordHits int    `json:"keyword_hits,omitempty"`
+	Answer      string `json:"answer,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// evalReport is the report of an evaluation run
+type evalReport struct {
+	File           string       `json:"file"`
+	EmbeddingModel string       `json:"embedding_model"`
+	Model          string       `json:"model,omitempty"`
+	TopK           int          `json:"top_k"`
+	CosineLimit    float64      `json:"cosine_limit"`
+	Generated      bool         `json:"generated"`
+	RecallAtK      *float64     `json:"recall_at_k,omitempty"`
+	MRR            *float64     `json:"mrr,omitempty"`
+	KeywordHitRate *float64     `json:"keyword_hit_rate,omitempty"`
+	Questions      []evalResult `json:"questions"`
+}
+
+// RunEval handles the eval command execution: it runs the retrieval of ask --rag for the questions of an
+// evaluation set (and their answers with --generate), and reports the recall@k and MRR of the expected
+// sources and the hit rate of the expected keywords, to measure the changes of the chunking or of the search
+// settings.
+func RunEval(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	topK, _ := cmd.Flags().GetInt("top-k")
+	keywordWeight, _ := cmd.Flags().GetFloat64("keyword-weight")
+	rerank, _ := cmd.Flags().GetBool("rerank")
+	generate, _ := cmd.Flags().GetBool("generate")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	if keywordWeight < 0 || keywordWeight > 1 {
+		return fmt.Errorf("--keyword-weight must be between 0 and 1 (got %g)", keywordWeight)
+	}
+	set, err := eval.Load(args[0])
+	if err != nil {
+		return fmt.Errorf("error reading evaluation set: %w", err)
+	}
+
+	opts := askOptions{
+		systemFiles:    systemFiles,
+		configFile:     configFile,
+		overrides:      configOverrides(cmd),
+		embeddingsFile: embeddingsFile,
+		ragEnabled:     true,
+		topK:           topK,
+		keywordWeight:  keywordWeight,
+		rerank:         rerank,
+		quiet:          true,
+		modelCheck:     &modelCheck{},
+	}
+	config, err := loadAskConfig(opts)
+	if err != nil {
+		return err
+	}
+	// Similarity scores are meaningless when the embedding model changed
+	useRAG, err := checkEmbeddingModel(config, opts)
+	if err != nil {
+		return err
+	}
+	if !useRAG {
+		return nil
+	}
+	var base []openai.ChatCompletionMessageParamUnion
+	if generate {
+		if base, err = baseMessages(opts); err != nil {
+			return err
+		}
+	}
+
+	report := evalReport{
+		File:           args[0],
+		EmbeddingModel: config.EmbeddingModel,
+		TopK:           config.TopK,
+		CosineLimit:    config.CosineLimit,
+		Generated:      generate,
+		Questions:      make([]evalResult, 0, len(set.Cases)),
+	}
+	if generate {
+		report.Model = config.Model
+	}
+	// Without top-k, all the chunks above the cosine limit are retrieved
+	k := "all"
+	if config.TopK > 0 {
+		k = fmt.Sprint(config.TopK)
+	}
+	if !jsonOutput {
+		fmt.Printf("🧪 Evaluating %d question(s) from %s (top-k %s, cosine-limit %g)\n", len(set.Cases), args[0], k, config.CosineLimit)
+	}
+
+	var recalls, ranks []float64
+	keywords, hits, failed := 0, 0, 0
+	for i, c := range set.Cases {
+		similarities, searched, errs := ragSearch(config, opts, c.Question)
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		if !searched {
+			return fmt.Errorf("no embeddings store found (run budgie generate-embeddings first)")
+		}
+
+		result := evalResult{Question: c.Question, Retrieved: make([]string, 0, len(similarities))}
+		for _, similarity := range similarities {
+			result.Retrieved = append(result.Retrieved, similarity.Source)
+		}
+		if len(c.Sources) > 0 {
+			recall := eval.Recall(c.Sources, result.Retrieved)
+			result.Recall = &recall
+			result.Rank = eval.Rank(c.Sources, result.Retrieved)
+			recalls = append(recalls, recall)
+			if result.Rank > 0 {
+				ranks = append(ranks, 1/float64(result.Rank))
+			} else {
+				ranks = append(ranks, 0)
+			}
+		}
+
+		// Without --generate, the keywords are looked for in the retrieved chunks
+		text := strings.Join(rag.Contents(similarities), "\n\n")
+		if generate {
+			messages := slices.Clone(base)
+			if len(similarities) > 0 {
+				messages = append(messages, openai.UserMessage(ragContextHeader+text))
+			}
+			messages = append(messages, openai.UserMessage(c.Question))
+			start := time.Now()
+			answer, usage, _, err := completeWithRetry(config, messages)
+			if err != nil {
+				result.Error = err.Error()
+				failed++
+			} else {
+				recordUsage(config, opts, "eval", usage, time.Since(start), true)
+			}
+			result.Answer, text = answer, answer
+		}
+		if len(c.Keywords) > 0 && result.Error == "" {
+			result.Keywords, result.KeywordHits = len(c.Keywords), eval.KeywordHits(c.Keywords, text)
+			keywords += result.Keywords
+			hits += result.KeywordHits
+		}
+
+		report.Questions = append(report.Questions, result)
+		if !jsonOutput {
+			printEvalResult(result, i+1, len(set.Cases))
+		}
+	}
+
+	report.RecallAtK, report.MRR = average(recalls), average(ranks)
+	if keywords > 0 {
+		rate := float64(hits) / float64(keywords)
+		report.KeywordHitRate = &rate
+	}
+
+	if jsonOutput {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding the evaluation report: %w", err)
+		}
+		fmt.Println(string(data))
+	} else {
+		keywordLabel := "Keyword hit rate (retrieved chunks)"
+		if generate {
+			keywordLabel = "Keyword hit rate (answers)"
+		}
+		fmt.Println()
+		printSummaryTable([][]string{
+			{"Questions", fmt.Sprint(len(set.Cases))},
+			{"Recall@" + k, formatRate(report.RecallAtK)},
+			{"MRR", formatRate(report.MRR)},
+			{keywordLabel, formatRate(report.KeywordHitRate)},
+		})
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d answer(s) failed", failed)
+	}
+	return nil
+}
+
+// printEvalResult prints the outcome of a question of an evaluation set
+func printEvalResult(result evalResult, index, total int) {
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	redStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	progress := fmt.Sprintf("[%d/%d]", index, total)
+
+	var details []string
+	if result.Recall != nil {
+		rank := "not retrieved"
+		if result.Rank > 0 {
+			rank = fmt.Sprintf("rank %d", result.Rank)
+		}
+		details = append(details, fmt.Sprintf("recall %.2f", *result.Recall), rank)
+	}
+	if result.Keywords > 0 {
+		details = append(details, fmt.Sprintf("keywords %d/%d", result.KeywordHits, result.Keywords))
+	}
+
+	switch {
+	case result.Error != "":
+		fmt.Printf("❌ %s %s %s\n", progress, entryTitle(result.Question), redStyle.Render(result.Error))
+	case (result.Recall == nil || *result.Recall == 1) && result.KeywordHits == result.Keywords:
+		fmt.Printf("✅ %s %s %s\n", progress, entryTitle(result.Question), dimStyle.Render(strings.Join(details, " · ")))
+	default:
+		fmt.Printf("⚠️  %s %s %s\n", progress, entryTitle(result.Question), dimStyle.Render(strings.Join(details, " · ")))
+	}
+}
+
+// average returns the mean of values, nil without values
+func average(values []float64) *float64 {
+	if len(values) == 0 {
+		return nil
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+	return &mean
+}
+
+// formatRate formats a metric between 0 and 1, "-" when it was not measured
+func formatRate(rate *float64) string {
+	if rate == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%.2f", *rate)
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/results"
+	"github.com/budgies-nest/budgie-cli/pkg/webpage"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// webDocsDir is the directory of the docs where the pages embedded with /fetch --embed are saved
+const webDocsDir = "web"
+
+// fetchPage fetches a web page (--url, /fetch) as readable Markdown
+func fetchPage(address string) (*webpage.Page, error) {
+	return webpage.Fetch(address, fetchTimeout, fetchMaxBytes)
+}
+
+// pageContextMessage formats a fetched page as context for the questions
+func pageContextMessage(page *webpage.Page) string {
+	return fmt.Sprintf("Web page %q (%s):\n\n%s", page.Title, page.URL, page.Markdown)
+}
+
+// loadURLPages fetches the pages of --url as context messages, each limited to the attachment token limit
+func loadURLPages(config *config.Config, opts askOptions, confirm bool) ([]string, error) {
+	var contents []string
+	for _, address := range opts.urls {
+		page, err := fetchPage(address)
+		if err != nil {
+			return nil, err
+		}
+		content, err := limitAttachment(config, page.URL, pageContextMessage(page), confirm, opts.logWriter())
+		if err != nil {
+			return nil, err
+		}
+		if !opts.quiet {
+			fmt.Fprintf(opts.logWriter(), "🌐 Fetched %s (%s)\n", page.Title, page.URL)
+		}
+		contents = append(contents, content)
+	}
+	return contents, nil
+}
+
+// savePage saves a fetched page as a Markdown file of the web directory of the docs, its source URL in a
+// METADATA block. It returns the path of the file.
+func savePage(docsPath string, page *webpage.Page) (string, error) {
+	parsed, err := url.Parse(page.URL)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(docsPath, webDocsDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating %s: %w", dir, err)
+	}
+	// The slug drops the query and the punctuation and is truncated: the pages are told apart by a short
+	// hash of their URL (without the fragment, the same page)
+	parsed.Fragment = ""
+	slug := results.Slug(parsed.Host + " " + parsed.Path)
+	digest := sha256.Sum256([]byte(parsed.String()))
+	path := filepath.Join(dir, fmt.Sprintf("%s-%x.md", slug, digest[:4]))
+
+	content := fmt.Sprintf("<!--\nMETADATA:\nSource: %s\n-->\n\n", page.URL)
+	if !strings.HasPrefix(page.Markdown, "# ") {
+		content += "# " + page.Title + "\n\n"
+	}
+	content += page.Markdown + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("error saving %s: %w", path, err)
+	}
+	// The page saved by the older versions, named after the slug only, would be embedded twice
+	legacy := filepath.Join(dir, slug+".md")
+	if data, err := os.ReadFile(legacy); err == nil && strings.Contains(string(data), fmt.Sprintf("\nSource: %s\n", page.URL)) {
+		os.Remove(legacy)
+	}
+	return path, nil
+}
+
+// crawlSite crawls a docs site (generate-embeddings --url) within its host and saves the pages in the web
+// directory of the docs. The pages which cannot be fetched are skipped.
+func crawlSite(docsPath, start string, depth, maxPages int) error {
+	fmt.Printf("Crawling %s (depth %d, at most %d pages)\n", start, depth, maxPages)
+	yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	saved := 0
+	options := webpage.CrawlOptions{Depth: depth, MaxPages: maxPages, Timeout: fetchTimeout, MaxBytes: fetchMaxBytes}
+	err := webpage.Crawl(start, options, func(address string, page *webpage.Page, err error) {
+		if err != nil {
+			fmt.Println(yellowStyle.Render(fmt.Sprintf("⚠️  Skipped %s: %v", address, err)))
+			return
+		}
+		path, err := savePage(docsPath, page)
+		if err != nil {
+			fmt.Println(yellowStyle.Render(fmt.Sprintf("⚠️  Skipped %s: %v", address, err)))
+			return
+		}
+		saved++
+		fmt.Printf("🌐 Fetched %s → %s\n", page.URL, path)
+	})
+	if err != nil {
+		return fmt.Errorf("error crawling %s: %w", start, err)
+	}
+	if saved == 0 {
+		return fmt.Errorf("no page saved from %s", start)
+	}
+	fmt.Printf("Saved %d page(s) from %s\n", saved, start)
+	return nil
+}
+
+// embedPage saves a fetched page in the docs (/fetch --embed) and embeds it with an incremental generation,
+// so it stays in the vector store across the next generations
+func embedPage(configFile string, overrides config.Overrides, page *webpage.Page) (string, error) {
+	loaded, err := config.LoadConfig(configFile, overrides)
+	if err != nil {
+		return "", fmt.Errorf("error loading config file: %w", err)
+	}
+	docsPath := filepath.Join(filepath.Dir(configFile), "docs")
+	if loaded.Docs != "" {
+		docsPath = loaded.Docs
+	}
+	path, err := savePage(docsPath, page)
+	if err != nil {
+		return "", err
+	}
+
+	run := &embeddingsRun{
+		configFile:  configFile,
+		overrides:   overrides,
+		docsPath:    docsPath,
+		incremental: true,
+		concurrency: 1,
+	}
+	if err := run.generate(); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/fuzzy"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// filePickerRows is the number of files listed at once by the picker
+const filePickerRows = 12
+
+// errNoFileSelected is returned when the file picker is cancelled
+var errNoFileSelected = errors.New("no file selected")
+
+var (
+	pickerTitleStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Bold(true)
+	pickerCursorStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("4")).Bold(true)
+	pickerMatchStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	pickerDimStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+// filePicker is the Bubble Tea model of the file picker of /use and /from: the typed text filters the
+// files of the project (fuzzy matching), tab selects several files when multi is set
+type filePicker struct {
+	title    string
+	multi    bool
+	paths    []string
+	filter   textinput.Model
+	matches  []fuzzy.Result
+	cursor   int
+	offset   int
+	selected map[string]bool
+	chosen   []string
+	done     bool
+}
+
+// pickFiles lets the user choose files of the current directory (several of them when multi is set).
+// It returns errNoFileSelected when the picker is cancelled.
+func pickFiles(title string, multi bool) ([]string, error) {
+	if !isTerminal(os.Stdin) {
+		return nil, fmt.Errorf("the file picker requires a terminal: give the file path")
+	}
+	var paths []string
+	for _, path := range projectPaths(".") {
+		if !strings.HasSuffix(path, "/") {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no file in the current directory")
+	}
+
+	filter := textinput.New()
+	filter.Prompt = "> "
+	filter.Placeholder = "type to filter"
+	filter.Focus()
+	picker := &filePicker{title: title, multi: multi, paths: paths, filter: filter, selected: make(map[string]bool)}
+	picker.matches = fuzzy.Rank("", paths)
+
+	if _, err := tea.NewProgram(picker).Run(); err != nil {
+		return nil, fmt.Errorf("error running the file picker: %w", err)
+	}
+	if len(picker.chosen) == 0 {
+		return nil, errNoFileSelected
+	}
+	return picker.chosen, nil
+}
+
+func (p *filePicker) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (p *filePicker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			p.done = true
+			return p, tea.Quit
+		case "enter":
+			p.confirm()
+			p.done = true
+			return p, tea.Quit
+		case "up", "ctrl+p":
+			p.move(-1)
+			return p, nil
+		case "down", "ctrl+n":
+			p.move(1)
+			return p, nil
+		case "tab":
+			if p.multi && len(p.matches) > 0 {
+				path := p.paths[p.matches[p.cursor].Index]
+				p.selected[path] = !p.selected[path]
+				p.move(1)
+			}
+			return p, nil
+		}
+	}
+
+	previous := p.filter.Value()
+	var cmd tea.Cmd
+	p.filter, cmd = p.filter.Update(msg)
+	if p.filter.Value() != previous {
+		p.matches = fuzzy.Rank(p.filter.Value(), p.paths)
+		p.cursor, p.offset = 0, 0
+	}
+	return p, cmd
+}
+
+// move moves the cursor in the matching files, scrolling the list to keep it visible
+func (p *filePicker) move(delta int) {
+	if len(p.matches) == 0 {
+		return
+	}
+	p.cursor = min(max(p.cursor+delta, 0), len(p.matches)-1)
+	if p.cursor < p.offset {
+		p.offset = p.cursor
+	}
+	if p.cursor >= p.offset+filePickerRows {
+		p.offset = p.cursor - filePickerRows + 1
+	}
+}
+
+// confirm sets the chosen files: the selected ones (in the order of the project files), or the file
+// under the cursor
+func (p *filePicker) confirm() {
+	for _, path := range p.paths {
+		if p.selected[path] {
+			p.chosen = append(p.chosen, path)
+		}
+	}
+	if len(p.chosen) == 0 && len(p.matches) > 0 {
+		p.chosen = []string{p.paths[p.matches[p.cursor].Index]}
+	}
+}
+
+func (p *filePicker) View() string {
+	// The picker is cleared once closed
+	if p.done {
+		return ""
+	}
+	var view strings.Builder
+	view.WriteString(pickerTitleStyle.Render(p.title) + "\n")
+	view.WriteString(p.filter.View() + "\n")
+	end := min(p.offset+filePickerRows, len(p.matches))
+	for i := p.offset; i < end; i++ {
+		match := p.matches[i]
+		path := p.paths[match.Index]
+		line := "  "
+		if i == p.cursor {
+			line = pickerCursorStyle.Render("› ")
+		}
+		if p.multi {
+			if p.selected[path] {
+				line += pickerMatchStyle.Render("[x] ")
+			} else {
+				line += "[ ] "
+			}
+		}
+		view.WriteString(line + highlightMatch(path, match.Positions) + "\n")
+	}
+	if len(p.matches) == 0 {
+		view.WriteString(pickerDimStyle.Render("  no matching file") + "\n")
+	}
+
+	help := "↑/↓ move • enter confirm • esc cancel"
+	if p.multi {
+		help = "↑/↓ move • tab select • enter confirm • esc cancel"
+		if count := len(p.selectedPaths()); count > 0 {
+			help = fmt.Sprintf("%d selected • %s", count, help)
+		}
+	}
+	view.WriteString(pickerDimStyle.Render(fmt.Sprintf("%d/%d files • %s", len(p.matches), len(p.paths), help)) + "\n")
+	return view.String()
+}
+
+// selectedPaths returns the selected files
+func (p *filePicker) selectedPaths() []string {
+	var paths []string
+	for path, selected := range p.selected {
+		if selected {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// highlightMatch renders a path with its runes matching the filter highlighted
+func highlightMatch(path string, positions []int) string {
+	if len(positions) == 0 {
+		return path
+	}
+	var highlighted strings.Builder
+	for i, r := range []rune(path) {
+		if slices.Contains(positions, i) {
+			highlighted.WriteString(pickerMatchStyle.Render(string(r)))
+		} else {
+			highlighted.WriteRune(r)
+		}
+	}
+	return highlighted.String()
+}
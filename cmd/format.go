@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/tracing"
+	"github.com/openai/openai-go"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Output formats of the ask command
+const (
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+	formatPlain    = "plain"
+)
+
+// validateFormat checks the --format flag value
+func validateFormat(format string) error {
+	switch format {
+	case formatMarkdown, formatJSON, formatPlain:
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q (supported: markdown, json, plain)", format)
+	}
+}
+
+// tokenUsage is the token usage reported by the provider
+type tokenUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	TotalTokens      int64 `json:"total_tokens"`
+}
+
+// answerEnvelope is the machine-readable answer printed with --format json
+type answerEnvelope struct {
+	Question   string           `json:"question"`
+	Provider   string           `json:"provider,omitempty"`
+	Model      string           `json:"model"`
+	Chunks     []rag.Similarity `json:"chunks"`
+	Response   string           `json:"response"`
+	Usage      *tokenUsage      `json:"usage,omitempty"`
+	LatencyMs  int64            `json:"latency_ms"`
+	ResultFile string           `json:"result_file,omitempty"`
+	Offline    bool             `json:"offline,omitempty"`
+	// Cached is set when the answer comes from the answer cache
+	Cached bool `json:"cached,omitempty"`
+}
+
+// completeWithUsage runs a non-streaming completion and returns the response with its token usage
+// (the agents do not expose the usage, so the request goes through the provider client)
+func completeWithUsage(config *config.Config, messages []openai.ChatCompletionMessageParamUnion) (string, *tokenUsage, error) {
+	return completeWithFormat(config, messages, openai.ChatCompletionNewParamsResponseFormatUnion{})
+}
+
+// completeWithFormat runs a non-streaming completion with the given response format (the provider default
+// when zero) and returns the response with its token usage
+func completeWithFormat(config *config.Config, messages []openai.ChatCompletionMessageParamUnion, format openai.ChatCompletionNewParamsResponseFormatUnion) (string, *tokenUsage, error) {
+	client, err := provider.NewClient(config)
+	if err != nil {
+		return "", nil, err
+	}
+	messages, err = redactMessages(config, messages)
+	if err != nil {
+		return "", nil, err
+	}
+
+	ctx, span := tracing.Start(context.Background(), "chat completion", completionAttributes(config)...)
+	params := completionParams(config, messages)
+	params.ResponseFormat = format
+	completion, err := client.Chat.Completions.New(ctx, params)
+	if err == nil && len(completion.Choices) == 0 {
+		err = fmt.Errorf("no choices returned")
+	}
+	if err != nil {
+		tracing.End(span, err)
+		return "", nil, fmt.Errorf("error during completion: %w", err)
+	}
+	span.SetAttributes(
+		attribute.Int64("gen_ai.usage.input_tokens", completion.Usage.PromptTokens),
+		attribute.Int64("gen_ai.usage.output_tokens", completion.Usage.CompletionTokens),
+	)
+	tracing.End(span, nil)
+
+	usage := &tokenUsage{
+		PromptTokens:     completion.Usage.PromptTokens,
+		CompletionTokens: completion.Usage.CompletionTokens,
+		TotalTokens:      completion.Usage.TotalTokens,
+	}
+	return completion.Choices[0].Message.Content, usage, nil
+}
+
+// writeEnvelope prints the answer envelope as indented JSON
+func writeEnvelope(out io.Writer, envelope answerEnvelope) error {
+	if envelope.Chunks == nil {
+		envelope.Chunks = []rag.Similarity{}
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	return encoder.Encode(envelope)
+}
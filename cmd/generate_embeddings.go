@@ -2,15 +2,26 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"os"
+	"os/signal"
 	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
 
+	"github.com/budgies-nest/budgie-cli/pkg/chunking"
 	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/document"
+	"github.com/budgies-nest/budgie-cli/pkg/language"
+	"github.com/budgies-nest/budgie-cli/pkg/pathfilter"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/sitedocs"
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
 	"github.com/budgies-nest/budgie/agents"
 	"github.com/budgies-nest/budgie/helpers"
-	"github.com/budgies-nest/budgie/rag"
-	"github.com/openai/openai-go"
 	"github.com/spf13/cobra"
 )
 
@@ -25,6 +36,19 @@ func RunGenerateEmbeddings(cmd *cobra.Command, args []string) error {
 	overlap, _ := cmd.Flags().GetInt("overlap")
 	extension, _ := cmd.Flags().GetString("extension")
 	files, _ := cmd.Flags().GetBool("files")
+	incremental, _ := cmd.Flags().GetBool("incremental")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	rateLimit, _ := cmd.Flags().GetFloat64("rate-limit")
+	include, _ := cmd.Flags().GetStringSlice("include")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+	watchDocs, _ := cmd.Flags().GetBool("watch")
+	debounce, _ := cmd.Flags().GetDuration("debounce")
+	crawlURL, _ := cmd.Flags().GetString("url")
+	crawlDepth, _ := cmd.Flags().GetInt("depth")
+	crawlMaxPages, _ := cmd.Flags().GetInt("max-pages")
+	store, _ := cmd.Flags().GetString("store")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 	// Validate that only one chunking method is selected
 	chunkingMethods := 0
@@ -56,13 +80,104 @@ func RunGenerateEmbeddings(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("--overlap (%d) must be less than --chunk-size (%d)", overlap, chunkSize)
 	}
 
-	// Validate extension flag usage
-	if extension != "" && delimiter == "" && chunkSize == 0 && !files {
-		return fmt.Errorf("--extension flag can only be used with --delimiter, --chunk-size, or --files methods")
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1")
+	}
+	if rateLimit < 0 {
+		return fmt.Errorf("--rate-limit must be positive")
+	}
+	if crawlDepth < 0 || crawlMaxPages < 1 {
+		return fmt.Errorf("--depth must be positive and --max-pages at least 1")
+	}
+	if dryRun && (watchDocs || crawlURL != "") {
+		return fmt.Errorf("--dry-run cannot be used with --watch or --url")
+	}
+	if store != "" {
+		if _, err := storeEmbeddingsPath(configFile, store); err != nil {
+			return err
+		}
+		if embeddingsFile != "" {
+			return fmt.Errorf("--store cannot be used with --embeddings")
+		}
 	}
 
+	run := &embeddingsRun{
+		configFile:       configFile,
+		overrides:        configOverrides(cmd),
+		docsPath:         docsPath,
+		docsFlag:         cmd.Flags().Changed("docs"),
+		chunkingMethods:  chunkingMethods,
+		markdownSections: markdownSections,
+		delimiter:        delimiter,
+		chunkSize:        chunkSize,
+		overlap:          overlap,
+		extension:        extension,
+		files:            files,
+		incremental:      incremental,
+		concurrency:      concurrency,
+		rateLimit:        rateLimit,
+		include:          include,
+		exclude:          exclude,
+		crawlURL:         crawlURL,
+		crawlDepth:       crawlDepth,
+		crawlMaxPages:    crawlMaxPages,
+		store:            store,
+		embeddingsFile:   embeddingsFile,
+		dryRun:           dryRun,
+	}
+	if err := run.generate(); err != nil {
+		return err
+	}
+	// An interrupted run stops there
+	if !watchDocs || run.interrupted {
+		return nil
+	}
+	return run.watch(debounce)
+}
+
+// embeddingsRun holds the options of a generate-embeddings run, and the docs directory and file extensions
+// it processed (resolved from the config) and whether it was interrupted
+type embeddingsRun struct {
+	configFile       string
+	overrides        config.Overrides
+	docsPath         string
+	docsFlag         bool
+	chunkingMethods  int
+	markdownSections bool
+	delimiter        string
+	chunkSize        int
+	overlap          int
+	extension        string
+	files            bool
+	incremental      bool
+	concurrency      int
+	rateLimit        float64
+	include          []string
+	exclude          []string
+	// crawlURL is the docs site crawled into the docs before the first generation (--url)
+	crawlURL      string
+	crawlDepth    int
+	crawlMaxPages int
+	// store is the named store generated (--store), the default store when empty
+	store string
+	// embeddingsFile is the embeddings file generated (--embeddings, e.g. a shared store), the default store when empty
+	embeddingsFile string
+	// dryRun only chunks the files and estimates the embedding requests (--dry-run)
+	dryRun bool
+
+	extensions  []string
+	interrupted bool
+}
+
+// generate chunks the docs files, embeds them and saves the vector store and its manifest
+func (run *embeddingsRun) generate() error {
+	configFile, docsPath, incremental := run.configFile, run.docsPath, run.incremental
+	markdownSections, delimiter, chunkSize, overlap := run.markdownSections, run.delimiter, run.chunkSize, run.overlap
+	extension, files, concurrency, rateLimit := run.extension, run.files, run.concurrency, run.rateLimit
+	include, exclude, chunkingMethods := run.include, run.exclude, run.chunkingMethods
+
 	// Load config
-	config, err := config.LoadConfig(configFile)
+	config, err := config.LoadConfig(configFile, run.overrides)
 	if err != nil {
 		return fmt.Errorf("error loading config file: %w", err)
 	}
@@ -71,110 +186,455 @@ func RunGenerateEmbeddings(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("embedding-model not specified in config file")
 	}
 
+	filter := pathfilter.Filter{
+		Include: append(config.Include, include...),
+		Exclude: append(config.Exclude, exclude...),
+	}
+	if err := filter.Validate(); err != nil {
+		return err
+	}
+
+	// The docs directory of the config (e.g. set by `budgie ingest site-config`) unless --docs is given,
+	// a named store has its own docs directory
+	embeddingsPath := filepath.Join(filepath.Dir(configFile), "embeddings.json")
+	if run.embeddingsFile != "" {
+		embeddingsPath = run.embeddingsFile
+	}
+	if run.store != "" {
+		embeddingsPath, _ = storeEmbeddingsPath(configFile, run.store)
+		if !run.docsFlag {
+			docsPath = filepath.Join(filepath.Dir(embeddingsPath), "docs")
+		}
+		// The crawled pages are saved in the docs directory
+		if _, err := os.Stat(docsPath); err != nil && run.crawlURL == "" {
+			return fmt.Errorf("no docs for the %s store: add them to %s, or give --docs", run.store, docsPath)
+		}
+		if !run.dryRun {
+			if err := os.MkdirAll(filepath.Dir(embeddingsPath), 0755); err != nil {
+				return fmt.Errorf("error creating the %s store: %w", run.store, err)
+			}
+		}
+	} else if !run.docsFlag && config.Docs != "" {
+		docsPath = config.Docs
+	}
+	run.docsPath = docsPath
+	navigation, err := sitedocs.LoadNavigation(sitedocs.NavigationPath(configFile))
+	if err != nil {
+		return fmt.Errorf("error reading the docs navigation: %w", err)
+	}
+
+	rules, err := chunkingRules(config.Chunking, chunkingMethods > 0, markdownSections, delimiter, chunkSize, overlap, extension, files)
+	if err != nil {
+		return err
+	}
+
+	if run.store != "" {
+		fmt.Printf("Generating the %s store\n", run.store)
+	}
 	fmt.Printf("Generating embeddings from docs in: %s\n", docsPath)
 	fmt.Printf("Using embedding model: %s\n", config.EmbeddingModel)
-	if files {
-		fmt.Println("Using whole-file chunking (each file is one chunk)")
-	} else if chunkSize > 0 {
-		if overlap > 0 {
-			fmt.Printf("Using fixed-size text chunking with size: %d, overlap: %d\n", chunkSize, overlap)
-		} else {
-			fmt.Printf("Using fixed-size text chunking with size: %d\n", chunkSize)
+
+	// The crawled pages are saved in the docs and embedded with them, the watch mode does not crawl again
+	if run.crawlURL != "" {
+		if err := crawlSite(docsPath, run.crawlURL, run.crawlDepth, run.crawlMaxPages); err != nil {
+			return err
 		}
-	} else if delimiter != "" {
-		fmt.Printf("Using delimiter-based chunking with delimiter: %q\n", delimiter)
-	} else if markdownSections {
-		fmt.Println("Using markdown sections chunking")
-	} else {
-		fmt.Println("Using markdown hierarchy chunking (default)")
-	}
-
-	// Create budgie-search agent
-	agent, err := agents.NewAgent("budgie-search",
-		agents.WithDMR(config.BaseURL),
-		agents.WithEmbeddingParams(
-			openai.EmbeddingNewParams{
-				Model: openai.EmbeddingModel(config.EmbeddingModel),
-			},
-		),
-		agents.WithMemoryVectorStore(filepath.Join(filepath.Dir(configFile), "embeddings.json")),
-	)
+		run.crawlURL = ""
+	}
+
+	// The dry run only chunks the files, without embedding them nor touching the store
+	if run.dryRun {
+		return run.estimate(config, embeddingsPath, rules, filter)
+	}
+
+	manifestPath := rag.ManifestPath(embeddingsPath)
+
+	// Create budgie-search agent (existing embeddings are loaded for incremental runs)
+	agent, err := rag.CreateEmbeddingAgent(config, embeddingsPath)
 	if err != nil {
-		return fmt.Errorf("error creating agent: %w", err)
+		return err
 	}
+	defer rag.CloseStore(agent)
 
-	// Reset the vector store
-	agent.ResetMemoryVectorStore()
+	// Each embedding worker has its own agent
+	embedders := make([]*agents.Agent, concurrency)
+	for i := range embedders {
+		if embedders[i], err = rag.NewEmbedder(config); err != nil {
+			return err
+		}
+	}
+	if concurrency > 1 {
+		fmt.Printf("Embedding with %d workers\n", concurrency)
+	}
+
+	manifest, err := rag.LoadManifest(manifestPath)
+	if err != nil {
+		return fmt.Errorf("error loading embeddings manifest: %w", err)
+	}
 
-	// Determine file extension to search for
-	fileExtension := ".md" // default
-	if extension != "" && (delimiter != "" || chunkSize > 0 || files) {
-		fileExtension = extension
-		if !strings.HasPrefix(fileExtension, ".") {
-			fileExtension = "." + fileExtension
+	if incremental && manifest.EmbeddingModel != "" && manifest.EmbeddingModel != config.EmbeddingModel {
+		fmt.Printf("Embedding model changed (%s -> %s), regenerating all embeddings\n", manifest.EmbeddingModel, config.EmbeddingModel)
+		incremental = false
+	}
+	// Manifests written before the vector-store option existed describe json stores
+	previousStore := manifest.VectorStore
+	if previousStore == "" {
+		previousStore = rag.JSONStore
+	}
+	if incremental && len(manifest.Files) > 0 && previousStore != rag.StoreBackend(config) {
+		fmt.Printf("Vector store changed (%s -> %s), regenerating all embeddings\n", previousStore, rag.StoreBackend(config))
+		incremental = false
+	}
+	if incremental && len(manifest.Files) > 0 && manifest.Version < rag.ManifestVersion {
+		fmt.Println("Chunk ids changed since the embeddings were generated, regenerating all embeddings")
+		incremental = false
+	}
+
+	// The chunks identical to the stored ones reuse their embeddings (same embedding model)
+	vectors := newVectorCache()
+	if manifest.EmbeddingModel == config.EmbeddingModel {
+		if store, ok := rag.AgentStore(agent); ok {
+			if err := vectors.seed(store); err != nil {
+				return fmt.Errorf("error reading vector store: %w", err)
+			}
+		}
+	}
+
+	if incremental {
+		fmt.Println("Incremental mode: only new or changed files are embedded")
+	} else {
+		// Reset the vector store
+		if err := rag.ResetStore(agent); err != nil {
+			return fmt.Errorf("error resetting vector store: %w", err)
 		}
+		manifest.Files = make(map[string]rag.FileEntry)
 	}
+	manifest.Version = rag.ManifestVersion
+	manifest.EmbeddingModel = config.EmbeddingModel
+	manifest.VectorStore = rag.StoreBackend(config)
+	manifest.Docs = docsPath
 
-	// Find all files with the specified extension in docs directory
-	foundFiles, err := helpers.FindFiles(docsPath, fileExtension)
+	extensions := make([]string, 0, len(rules))
+	for ext := range rules {
+		extensions = append(extensions, ext)
+	}
+	sort.Strings(extensions)
+	run.extensions = extensions
+
+	// Find all files with the specified extensions in docs directory
+	foundFiles, totalFiles, err := findDocsFiles(docsPath, extensions, rules, filter)
 	if err != nil {
-		return fmt.Errorf("error finding files with extension %s: %w", fileExtension, err)
+		return err
 	}
 
-	fmt.Printf("Found %d files with extension %s\n", len(foundFiles), fileExtension)
+	progress := newEmbeddingProgress(totalFiles)
+	defer progress.Stop()
 
-	chunkCount := 0
-	for _, filePath := range foundFiles {
-		fmt.Printf("Processing: %s\n", filePath)
+	// Ctrl+C finishes the in-flight chunk and saves the work done so far, a second Ctrl+C aborts
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case <-signals:
+			signal.Stop(signals)
+			progress.Printf("\n⏹ Interrupted: finishing the current chunk and saving the embeddings (press Ctrl+C again to abort)\n")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
 
-		// Read file content
-		content, err := helpers.ReadTextFile(filePath)
-		if err != nil {
-			fmt.Printf("Error reading file %s: %v\n", filePath, err)
+	chunkCount := 0
+	unchangedCount := 0
+	processedFiles := 0
+	partialFiles := 0
+	skippedFiles := 0
+	failedChunks := 0
+	seenFiles := make(map[string]bool)
+	// Run the reader -> chunker -> embedder pipeline, this loop is the writer stage
+	embeddedFiles := embedChunks(ctx, embedders, rateLimit, vectors, chunkFiles(ctx, readFiles(ctx, extensions, rules, foundFiles), maps.Clone(manifest.Files), incremental), progress.ChunkEmbedded)
+	for file := range embeddedFiles {
+		progress.FileDone()
+		// Binary files (and documents without text) are skipped, their chunks embedded by a previous run are pruned
+		if errors.Is(file.err, sniff.ErrBinary) || errors.Is(file.err, document.ErrNoText) {
+			progress.Printf("Skipping %s: %v\n", file.path, file.err)
+			skippedFiles++
+			continue
+		}
+		seenFiles[file.path] = true
+		if file.err != nil {
+			progress.Printf("Error reading file %s: %v\n", file.path, file.err)
 			continue
 		}
 
-		// Create chunks based on selected chunking method
-		var chunks []string
-		if files {
-			// For files method, the entire file content is one chunk
-			chunks = []string{content}
-		} else if chunkSize > 0 {
-			chunks = rag.ChunkText(content, chunkSize, overlap)
-		} else if delimiter != "" {
-			chunks = rag.SplitTextWithDelimiter(content, delimiter)
-		} else if markdownSections {
-			chunks = rag.SplitMarkdownBySections(content)
-		} else {
-			// Default to hierarchy chunking (when markdownHierarchy is true or neither flag is set)
-			chunks = rag.ChunkWithMarkdownHierarchy(content)
+		previous, known := manifest.Files[file.path]
+		if file.unchanged {
+			// Files embedded before the chunk metadata existed get it from their stored chunks
+			if previous.Metadata == nil {
+				previous.Metadata = make(map[string]rag.ChunkMetadata)
+				for _, chunkID := range previous.Chunks {
+					if chunk, ok := rag.RecordPrompt(agent, chunkID); ok {
+						if chunkLanguage := language.Detect(chunk); chunkLanguage != "" {
+							previous.Metadata[chunkID] = rag.ChunkMetadata{Language: chunkLanguage}
+						}
+					}
+				}
+			}
+			// and from the METADATA block of the file and the docs navigation (which may have changed)
+			pageMetadata := pageChunkMetadata(file.chunkedFile, navigation, docsPath)
+			for _, chunkID := range previous.Chunks {
+				chunk := previous.Metadata[chunkID]
+				chunk.Category, chunk.Keywords, chunk.Navigation, chunk.URL = pageMetadata.Category, pageMetadata.Keywords, pageMetadata.Navigation, pageMetadata.URL
+				if chunk.Empty() {
+					delete(previous.Metadata, chunkID)
+				} else {
+					previous.Metadata[chunkID] = chunk
+				}
+			}
+			manifest.Files[file.path] = previous
+			unchangedCount++
+			continue
+		}
+		if known {
+			rag.DeleteRecords(agent, previous.Chunks)
 		}
 
-		fmt.Printf("  Created %d chunks\n", len(chunks))
+		progress.Processing(file.path, len(file.chunks))
 
-		// Create embeddings for each chunk
-		for idx, chunk := range chunks {
-			chunkID := fmt.Sprintf("%s-chunk-%d", filepath.Base(filePath), idx+1)
-			_, err = agent.CreateAndSaveEmbeddingFromText(
-				context.Background(),
-				chunk,
-				chunkID,
-			)
+		// Save the embeddings of the chunks
+		entry := rag.FileEntry{Hash: file.hash, Chunking: chunking.Describe(file.rule), Metadata: make(map[string]rag.ChunkMetadata)}
+		pageMetadata := pageChunkMetadata(file.chunkedFile, navigation, docsPath)
+		for idx, embedded := range file.embeddings {
+			chunkID := docsChunkID(docsPath, file.path, idx)
+			err := embedded.err
+			if err == nil {
+				if _, err = agent.SaveEmbedding(file.chunks[idx], embedded.embedding, chunkID); err != nil {
+					progress.ChunkFailed()
+				}
+			}
 			if err != nil {
-				fmt.Printf("Error creating embedding for chunk %s: %v\n", chunkID, err)
+				failedChunks++
+				progress.Printf("Error creating embedding for chunk %s: %v\n", chunkID, err)
+				// Leave the hash empty so the file is retried on the next incremental run
+				entry.Hash = ""
 				continue
 			}
+			entry.Chunks = append(entry.Chunks, chunkID)
+			chunkMetadata := pageMetadata
+			chunkMetadata.Language = file.languages[idx]
+			if !chunkMetadata.Empty() {
+				entry.Metadata[chunkID] = chunkMetadata
+			}
 			chunkCount++
 		}
+		// Keep the chunks embedded before the interruption, the file is retried on the next incremental run
+		if file.partial {
+			entry.Hash = ""
+			partialFiles++
+		}
+		manifest.Files[file.path] = entry
+		if entry.Hash != "" {
+			processedFiles++
+		}
 	}
+	interrupted := ctx.Err() != nil
+	run.interrupted = interrupted
+	progress.Stop()
 
-	// Persist embeddings to .budgie/embeddings.json
-	err = agent.PersistMemoryVectorStore()
+	// Delete the chunks of the files removed from the docs directory (unknown when interrupted)
+	removedCount := 0
+	for filePath, entry := range manifest.Files {
+		if !seenFiles[filePath] && !interrupted {
+			rag.DeleteRecords(agent, entry.Chunks)
+			delete(manifest.Files, filePath)
+			removedCount++
+		}
+	}
+
+	// Persist embeddings to .budgie/embeddings.json (or embeddings.db)
+	err = rag.PersistStore(agent)
 	if err != nil {
 		return fmt.Errorf("error persisting embeddings: %w", err)
 	}
 
-	embeddingsPath := filepath.Join(filepath.Dir(configFile), "embeddings.json")
-	fmt.Printf("Successfully generated %d embeddings and saved to %s\n", chunkCount, embeddingsPath)
+	// The dimension of the embeddings is checked by the searches
+	if store, ok := rag.AgentStore(agent); ok {
+		if manifest.Dimension, err = rag.StoreDimension(store); err != nil {
+			return fmt.Errorf("error reading vector store: %w", err)
+		}
+	}
+	if err := manifest.Save(manifestPath); err != nil {
+		return fmt.Errorf("error saving embeddings manifest: %w", err)
+	}
+
+	summary := [][]string{
+		{"Files embedded", fmt.Sprint(processedFiles)},
+		{"Chunks embedded", fmt.Sprint(chunkCount)},
+	}
+	if reused, saved := vectors.savings(); reused > 0 {
+		summary = append(summary, []string{"Duplicate chunks reused", fmt.Sprintf("%d (~%d tokens saved)", reused, saved)})
+	}
+	if failedChunks > 0 {
+		summary = append(summary, []string{"Failed chunks", fmt.Sprint(failedChunks)})
+	}
+	if incremental {
+		summary = append(summary, []string{"Unchanged files", fmt.Sprint(unchangedCount)})
+	}
+	if skippedFiles > 0 {
+		summary = append(summary, []string{"Binary files skipped", fmt.Sprint(skippedFiles)})
+	}
+	if interrupted {
+		remaining := totalFiles - len(seenFiles) - skippedFiles + partialFiles
+		summary = append(summary, []string{"Files remaining", fmt.Sprint(remaining)})
+	} else if incremental {
+		summary = append(summary, []string{"Removed files pruned", fmt.Sprint(removedCount)})
+	}
+	summary = append(summary, []string{"Elapsed", progress.Elapsed().String()})
+	printSummaryTable(summary)
+
+	if interrupted {
+		fmt.Printf("⏹ Interrupted: the embeddings generated so far are saved to %s\n", rag.StoreFile(config, embeddingsPath))
+		fmt.Printf("💡 Resume with: budgie generate-embeddings --incremental\n")
+		return nil
+	}
+
+	fmt.Printf("Successfully generated %d embeddings and saved to %s\n", chunkCount, rag.StoreFile(config, embeddingsPath))
 	return nil
-}
\ No newline at end of file
+}
+
+// findDocsFiles finds the files of each extension in the docs directory, left out by the include/exclude
+// patterns. It returns the files by extension and their total.
+func findDocsFiles(docsPath string, extensions []string, rules map[string]config.ChunkingRule, filter pathfilter.Filter) (map[string][]string, int, error) {
+	foundFiles := make(map[string][]string)
+	totalFiles := 0
+	for _, fileExtension := range extensions {
+		fmt.Printf("Using %s for %s files\n", chunking.Describe(rules[fileExtension]), fileExtension)
+
+		files, err := helpers.FindFiles(docsPath, fileExtension)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error finding files with extension %s: %w", fileExtension, err)
+		}
+
+		if !filter.Empty() {
+			found := len(files)
+			files = filterFiles(docsPath, files, filter)
+			fmt.Printf("Found %d files with extension %s (%d excluded by the include/exclude patterns)\n", len(files), fileExtension, found-len(files))
+		} else {
+			fmt.Printf("Found %d files with extension %s\n", len(files), fileExtension)
+		}
+		foundFiles[fileExtension] = files
+		totalFiles += len(files)
+	}
+	return foundFiles, totalFiles, nil
+}
+
+// chunkingRules returns the chunking rule of each file extension to process.
+// extensions is a comma-separated list of extensions, each optionally followed by a strategy
+// (e.g. "md,go:code,txt:sections"). An explicit chunking flag applies to the listed extensions
+// (.md by default), otherwise each extension uses its rule from the config file, or its default rule.
+// Without extensions nor chunking flag, the per-extension rules of the config file are used
+// (markdown hierarchy for .md files by default).
+func chunkingRules(configRules map[string]config.ChunkingRule, explicit, markdownSections bool, delimiter string, chunkSize, overlap int, extensions string, files bool) (map[string]config.ChunkingRule, error) {
+	normalizedRules := make(map[string]config.ChunkingRule)
+	for ext, rule := range configRules {
+		if err := chunking.Validate(rule); err != nil {
+			return nil, fmt.Errorf("invalid chunking rule for %s: %w", ext, err)
+		}
+		normalizedRules[normalizeExtension(ext)] = rule
+	}
+	if !explicit && extensions == "" && len(normalizedRules) > 0 {
+		return normalizedRules, nil
+	}
+
+	flagRule := config.ChunkingRule{Strategy: chunking.Hierarchy}
+	if files {
+		flagRule = config.ChunkingRule{Strategy: chunking.Files}
+	} else if chunkSize > 0 {
+		flagRule = config.ChunkingRule{Strategy: chunking.Size, Size: chunkSize, Overlap: overlap}
+	} else if delimiter != "" {
+		flagRule = config.ChunkingRule{Strategy: chunking.Delimiter, Delimiter: delimiter}
+	} else if markdownSections {
+		flagRule = config.ChunkingRule{Strategy: chunking.Sections}
+	}
+	if extensions == "" {
+		return map[string]config.ChunkingRule{".md": flagRule}, nil
+	}
+
+	rules := make(map[string]config.ChunkingRule)
+	for _, item := range strings.Split(extensions, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		ext, strategy, hasStrategy := strings.Cut(item, ":")
+		ext = normalizeExtension(strings.TrimSpace(ext))
+
+		rule := flagRule
+		switch {
+		case hasStrategy:
+			// The strategy parameters come from the chunking flags (--delimiter, --chunk-size, --overlap)
+			rule = config.ChunkingRule{Strategy: strings.TrimSpace(strategy), Delimiter: delimiter, Size: chunkSize, Overlap: overlap}
+			if err := chunking.Validate(rule); err != nil {
+				return nil, fmt.Errorf("invalid chunking strategy for %s: %w", ext, err)
+			}
+		case !explicit:
+			if configRule, ok := normalizedRules[ext]; ok {
+				rule = configRule
+			} else {
+				rule = chunking.DefaultRule(ext)
+			}
+		}
+		rules[ext] = rule
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("--extension requires at least one file extension")
+	}
+	return rules, nil
+}
+
+// pageChunkMetadata returns the metadata shared by all the chunks of a file: the fields of its METADATA block
+// (the source URL of the web pages) and its position in the docs site navigation
+func pageChunkMetadata(file chunkedFile, navigation *sitedocs.Navigation, docsPath string) rag.ChunkMetadata {
+	return rag.ChunkMetadata{
+		Category:   file.metadata.Category,
+		Keywords:   file.metadata.Keywords,
+		Navigation: navigation.For(docsPath, file.path),
+		URL:        file.metadata.Source,
+	}
+}
+
+// docsChunkID returns the id of a chunk of a docs file, built from the path of the file relative to the docs
+// directory like the chunks of the code collection ("guides/install.md#chunk-1"): the files with the same name
+// in different directories do not share ids
+func docsChunkID(docsPath, file string, idx int) string {
+	relPath, err := filepath.Rel(docsPath, file)
+	if err != nil {
+		relPath = file
+	}
+	return fmt.Sprintf("%s#chunk-%d", filepath.ToSlash(relPath), idx+1)
+}
+
+// filterFiles keeps the files selected by the include/exclude patterns (matched against their path relative to root)
+func filterFiles(root string, files []string, filter pathfilter.Filter) []string {
+	var selected []string
+	for _, file := range files {
+		relPath, err := filepath.Rel(root, file)
+		if err != nil {
+			relPath = file
+		}
+		if filter.Allowed(relPath) {
+			selected = append(selected, file)
+		}
+	}
+	return selected
+}
+
+// normalizeExtension makes sure a file extension starts with a dot
+func normalizeExtension(extension string) string {
+	if !strings.HasPrefix(extension, ".") {
+		return "." + extension
+	}
+	return extension
+}
@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/chunking"
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+)
+
+func TestChunkingRules(t *testing.T) {
+	configRules := map[string]config.ChunkingRule{
+		"go":  {Strategy: chunking.Files},
+		".md": {Strategy: chunking.Sections},
+	}
+	tests := []struct {
+		name       string
+		config     map[string]config.ChunkingRule
+		explicit   bool
+		sections   bool
+		delimiter  string
+		size       int
+		overlap    int
+		extensions string
+		files      bool
+		want       map[string]config.ChunkingRule
+		invalid    bool
+	}{
+		{
+			name: "defaults",
+			want: map[string]config.ChunkingRule{".md": {Strategy: chunking.Hierarchy}},
+		},
+		{
+			name:   "config rules",
+			config: configRules,
+			want:   map[string]config.ChunkingRule{".go": {Strategy: chunking.Files}, ".md": {Strategy: chunking.Sections}},
+		},
+		{
+			name:       "listed extensions use their config or default rule",
+			config:     configRules,
+			extensions: "go, txt,.pdf",
+			want: map[string]config.ChunkingRule{
+				".go":  {Strategy: chunking.Files},
+				".txt": chunking.DefaultRule(".txt"),
+				".pdf": chunking.DefaultRule(".pdf"),
+			},
+		},
+		{
+			name:     "chunking flag over the config",
+			config:   configRules,
+			explicit: true,
+			size:     500,
+			overlap:  50,
+			want:     map[string]config.ChunkingRule{".md": {Strategy: chunking.Size, Size: 500, Overlap: 50}},
+		},
+		{
+			name:       "chunking flag for the listed extensions",
+			explicit:   true,
+			delimiter:  "---",
+			extensions: "md,txt",
+			want: map[string]config.ChunkingRule{
+				".md":  {Strategy: chunking.Delimiter, Delimiter: "---"},
+				".txt": {Strategy: chunking.Delimiter, Delimiter: "---"},
+			},
+		},
+		{
+			name:       "strategy of an extension",
+			explicit:   true,
+			files:      true,
+			extensions: "md,go:code,txt:size",
+			size:       300,
+			want: map[string]config.ChunkingRule{
+				".md":  {Strategy: chunking.Files},
+				".go":  {Strategy: chunking.Code, Size: 300},
+				".txt": {Strategy: chunking.Size, Size: 300},
+			},
+		},
+		{name: "sections flag", explicit: true, sections: true, want: map[string]config.ChunkingRule{".md": {Strategy: chunking.Sections}}},
+		{name: "invalid config rule", config: map[string]config.ChunkingRule{".txt": {Strategy: chunking.Size}}, invalid: true},
+		{name: "unknown strategy", extensions: "md:paragraphs", invalid: true},
+		{name: "strategy without its parameters", extensions: "txt:delimiter", invalid: true},
+		{name: "no extension", extensions: " , ", invalid: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, err := chunkingRules(test.config, test.explicit, test.sections, test.delimiter, test.size, test.overlap, test.extensions, test.files)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("chunkingRules = %v, want an error", rules)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			// fmt prints the maps sorted by key
+			if fmt.Sprint(rules) != fmt.Sprint(test.want) {
+				t.Errorf("chunkingRules = %v, want %v", rules, test.want)
+			}
+		})
+	}
+}
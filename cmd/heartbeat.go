@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// heartbeat periodically reports the progress of a long completion on stderr, so the process
+// never looks hung (remote and tmux sessions, CI logs). A nil heartbeat does nothing.
+type heartbeat struct {
+	mu         sync.Mutex
+	start      time.Time
+	tokens     int
+	lastTokens int
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// startHeartbeat reports the progress every interval (disabled when interval <= 0)
+func startHeartbeat(interval time.Duration) *heartbeat {
+	if interval <= 0 {
+		return nil
+	}
+
+	h := &heartbeat{
+		start: time.Now(),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go func() {
+		defer close(h.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-ticker.C:
+				h.beat()
+			}
+		}
+	}()
+	return h
+}
+
+// Add records a streamed chunk of the answer
+func (h *heartbeat) Add(content string) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.tokens++
+}
+
+// Stop stops the heartbeat
+func (h *heartbeat) Stop() {
+	if h == nil {
+		return
+	}
+	close(h.stop)
+	<-h.done
+}
+
+// beat prints the status line
+func (h *heartbeat) beat() {
+	h.mu.Lock()
+	tokens := h.tokens
+	idle := tokens == h.lastTokens
+	h.lastTokens = tokens
+	h.mu.Unlock()
+
+	// On a terminal the streamed tokens already show the progress: only report silent periods
+	// (on a new line, the status line must not be glued to the streamed answer)
+	terminal := isTerminal(os.Stderr)
+	if idle || !terminal {
+		elapsed := time.Since(h.start).Round(time.Second)
+		status := fmt.Sprintf("⏳ Still generating: ~%d tokens so far, %s elapsed", tokens, elapsed)
+		if terminal {
+			status = "\n" + status
+		}
+		fmt.Fprintln(os.Stderr, status)
+	}
+}
+
+// isTerminal reports whether the file is a terminal
+func isTerminal(file *os.File) bool {
+	return isatty.IsTerminal(file.Fd())
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/history"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// recordHistory logs an answered question to the history log when the history config option is set.
+// A history error never fails the command.
+func recordHistory(config *config.Config, opts askOptions, command, question, answer string, rag bool) {
+	if !config.History {
+		return
+	}
+	err := history.Append(history.Path(opts.configFile), history.Entry{
+		Time:     time.Now(),
+		Command:  command,
+		Model:    config.Model,
+		RAG:      rag,
+		Question: question,
+		Answer:   answer,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error recording history: %v\n", err)
+	}
+}
+
+// RunHistoryList handles the history list command execution: it lists the last questions asked
+func RunHistoryList(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	limit, _ := cmd.Flags().GetInt("limit")
+
+	entries, err := loadHistory(configFile)
+	if err != nil {
+		return err
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	printHistory(entries)
+	return nil
+}
+
+// RunHistoryGrep handles the history grep command execution: it lists the questions whose question or
+// answer matches a pattern
+func RunHistoryGrep(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+
+	entries, err := loadHistory(configFile)
+	if err != nil {
+		return err
+	}
+	matches, err := history.Grep(entries, args[0])
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 && len(entries) > 0 {
+		fmt.Printf("No question or answer matching %q\n", args[0])
+		return nil
+	}
+	printHistory(matches)
+	return nil
+}
+
+// RunHistoryShow handles the history show command execution: it prints a question and its answer
+func RunHistoryShow(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+
+	entry, err := historyEntry(configFile, args[0])
+	if err != nil {
+		return err
+	}
+
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	fmt.Println(dimStyle.Render(fmt.Sprintf("#%d · %s · %s · %s", entry.ID, entry.Time.Local().Format("2006-01-02 15:04:05"), entry.Command, entry.Model)))
+	fmt.Println()
+	fmt.Println("❓ " + entry.Question)
+	fmt.Println()
+	fmt.Println(entry.Answer)
+	return nil
+}
+
+// RunHistoryRerun handles the history rerun command execution: it asks a past question again
+// (with RAG when it was asked with RAG)
+func RunHistoryRerun(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	outputPath, _ := cmd.Flags().GetString("output")
+	generate, _ := cmd.Flags().GetBool("generate")
+
+	entry, err := historyEntry(configFile, args[0])
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("🔁 Asking #%d again: %s\n", entry.ID, entryTitle(entry.Question))
+	return processQuestion(entry.Question, askOptions{
+		systemFiles:    systemFiles,
+		configFile:     configFile,
+		overrides:      configOverrides(cmd),
+		outputPath:     outputPath,
+		embeddingsFile: embeddingsFile,
+		generate:       generate,
+		ragEnabled:     entry.RAG,
+		format:         formatMarkdown,
+		pager:          pagerAuto,
+		modelCheck:     &modelCheck{},
+	})
+}
+
+// RunHistoryExport handles the history export command execution: it writes the history as a Markdown
+// document, one section per question (to stdout, or to the --output file)
+func RunHistoryExport(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	outputFile, _ := cmd.Flags().GetString("output")
+
+	entries, err := loadHistory(configFile)
+	if err != nil {
+		return err
+	}
+
+	var builder strings.Builder
+	builder.WriteString("# Budgie History\n\n")
+	for _, entry := range entries {
+		builder.WriteString(transcriptEntry(entry.Time.Local(), entry.Question, entry.Answer))
+	}
+
+	if outputFile == "" {
+		fmt.Print(builder.String())
+		return nil
+	}
+	if err := os.WriteFile(outputFile, []byte(builder.String()), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", outputFile, err)
+	}
+	fmt.Printf("✅ Exported %d question(s) to %s\n", len(entries), outputFile)
+	return nil
+}
+
+// loadHistory reads the history log of the project, telling how to enable it when it is empty
+func loadHistory(configFile string) ([]history.Entry, error) {
+	entries, err := history.Load(history.Path(configFile))
+	if err != nil {
+		return nil, fmt.Errorf("error reading history: %w", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No history yet: enable it with budgie config set history true")
+	}
+	return entries, nil
+}
+
+// historyEntry returns the history entry of an id given on the command line
+func historyEntry(configFile, arg string) (*history.Entry, error) {
+	id, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid history id %q", arg)
+	}
+	entry, err := history.Get(history.Path(configFile), id)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// printHistory prints one line per entry: its id, its time and the first line of its question
+func printHistory(entries []history.Entry) {
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	for _, entry := range entries {
+		rag := ""
+		if entry.RAG {
+			rag = " [rag]"
+		}
+		fmt.Printf("%5d  %s  %s%s\n", entry.ID, dimStyle.Render(entry.Time.Local().Format("2006-01-02 15:04")), entryTitle(entry.Question), dimStyle.Render(rag))
+	}
+}
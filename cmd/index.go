@@ -0,0 +1,207 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
+	"github.com/budgies-nest/budgie-cli/pkg/watch"
+	"github.com/budgies-nest/budgie/agents"
+	budgierag "github.com/budgies-nest/budgie/rag"
+	"github.com/spf13/cobra"
+)
+
+// codeStoreName is the file name of the code collection, stored next to the config file
+const codeStoreName = "code-embeddings.json"
+
+// skippedDirs are directories never indexed (dependencies and build outputs)
+var skippedDirs = []string{"node_modules", "vendor", "dist", "build", "target", "bin"}
+
+// skipIndexDir reports whether a directory should be excluded from the code index
+func skipIndexDir(path string) bool {
+	name := filepath.Base(path)
+	return strings.HasPrefix(name, ".") || slices.Contains(skippedDirs, name)
+}
+
+// parseExtensions turns a comma-separated list of extensions into a list of ".ext" values
+func parseExtensions(list string) []string {
+	var extensions []string
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.TrimSpace(ext)
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions = append(extensions, ext)
+	}
+	return extensions
+}
+
+// indexFile (re)creates the embeddings of a single source file in the code collection
+func indexFile(agent *agents.Agent, root, path string, chunkSize, overlap int) (int, error) {
+	relPath, err := filepath.Rel(root, path)
+	if err != nil {
+		relPath = path
+	}
+
+	// Drop the previous chunks of the file before re-embedding it
+	rag.DeleteRecordsWithPrefix(agent, relPath+"#")
+
+	content, err := sniff.ReadTextFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	chunks := budgierag.ChunkText(content, chunkSize, overlap)
+	count := 0
+	for idx, chunk := range chunks {
+		chunkID := fmt.Sprintf("%s#chunk-%d", relPath, idx+1)
+		_, err := agent.CreateAndSaveEmbeddingFromText(
+			context.Background(),
+			fmt.Sprintf("FILE: %s\n%s", relPath, chunk),
+			chunkID,
+		)
+		if err != nil {
+			return count, fmt.Errorf("error creating embedding for chunk %s: %w", chunkID, err)
+		}
+		count++
+	}
+	return count, nil
+}
+
+// RunIndex handles the index command execution
+func RunIndex(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	root, _ := cmd.Flags().GetString("root")
+	extensionList, _ := cmd.Flags().GetString("extensions")
+	chunkSize, _ := cmd.Flags().GetInt("chunk-size")
+	overlap, _ := cmd.Flags().GetInt("overlap")
+	background, _ := cmd.Flags().GetBool("background")
+	debounce, _ := cmd.Flags().GetDuration("debounce")
+
+	if overlap >= chunkSize {
+		return fmt.Errorf("--overlap (%d) must be less than --chunk-size (%d)", overlap, chunkSize)
+	}
+
+	extensions := parseExtensions(extensionList)
+	if len(extensions) == 0 {
+		return fmt.Errorf("at least one extension is required")
+	}
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+
+	storePath := filepath.Join(filepath.Dir(configFile), codeStoreName)
+	agent, err := rag.CreateEmbeddingAgent(config, storePath)
+	if err != nil {
+		return err
+	}
+	defer rag.CloseStore(agent)
+
+	// Full index of the project
+	if err := rag.ResetStore(agent); err != nil {
+		return fmt.Errorf("error resetting vector store: %w", err)
+	}
+
+	var sourceFiles []string
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && skipIndexDir(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if slices.Contains(extensions, filepath.Ext(path)) {
+			sourceFiles = append(sourceFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error scanning %s: %w", root, err)
+	}
+
+	fmt.Printf("Indexing %d source files from %s into %s\n", len(sourceFiles), root, rag.StoreFile(config, storePath))
+
+	chunkCount := 0
+	for _, path := range sourceFiles {
+		count, err := indexFile(agent, root, path, chunkSize, overlap)
+		if errors.Is(err, sniff.ErrBinary) {
+			fmt.Printf("Skipping %s: %v\n", path, err)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("Error indexing %s: %v\n", path, err)
+		}
+		chunkCount += count
+	}
+
+	if err := rag.PersistStore(agent); err != nil {
+		return fmt.Errorf("error persisting embeddings: %w", err)
+	}
+	if err := rag.RecordStoreModel(config, storePath, agent); err != nil {
+		return fmt.Errorf("error saving embeddings manifest: %w", err)
+	}
+	fmt.Printf("Successfully indexed %d chunks\n", chunkCount)
+
+	if !background {
+		return nil
+	}
+
+	fmt.Printf("👀 Watching %s for changes (Ctrl+C to stop)\n", root)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	return watch.Run(ctx, root, debounce, skipIndexDir, func(paths []string) {
+		changed := 0
+		for _, path := range paths {
+			if !slices.Contains(extensions, filepath.Ext(path)) {
+				continue
+			}
+			changed++
+
+			// Removed (or renamed) files only lose their chunks
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				relPath, _ := filepath.Rel(root, path)
+				deleted := rag.DeleteRecordsWithPrefix(agent, relPath+"#")
+				fmt.Printf("%s 🗑️  %s (%d chunks removed)\n", time.Now().Format("15:04:05"), relPath, deleted)
+				continue
+			}
+
+			count, err := indexFile(agent, root, path, chunkSize, overlap)
+			if errors.Is(err, sniff.ErrBinary) {
+				fmt.Printf("%s ⏭️  %s skipped: %v\n", time.Now().Format("15:04:05"), path, err)
+				continue
+			}
+			if err != nil {
+				fmt.Printf("Error indexing %s: %v\n", path, err)
+				continue
+			}
+			fmt.Printf("%s 🔄 %s (%d chunks)\n", time.Now().Format("15:04:05"), path, count)
+		}
+
+		if changed == 0 {
+			return
+		}
+		if err := rag.PersistStore(agent); err != nil {
+			fmt.Printf("Error persisting embeddings: %v\n", err)
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/sitedocs"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// RunIngestSiteConfig handles the ingest site-config command execution: it bootstraps the project from the
+// config of a docs site (mkdocs.yml or docusaurus.config.js). The docs directory of the site and the chunking
+// rules of its markdown flavor are set in the config file, and the navigation of its pages is saved next to it
+// (generate-embeddings records it as chunk metadata).
+func RunIngestSiteConfig(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	siteConfig := args[0]
+
+	loaded, err := config.LoadConfig(configFile, config.Overrides{})
+	if err != nil {
+		return fmt.Errorf("error loading config file (run budgie init first): %w", err)
+	}
+
+	site, err := sitedocs.Load(siteConfig)
+	if err != nil {
+		return err
+	}
+	docsDir := filepath.ToSlash(site.DocsDir)
+
+	// The rules of the site's markdown flavor replace the ones of the same extensions
+	rules := maps.Clone(loaded.Chunking)
+	if rules == nil {
+		rules = make(map[string]config.ChunkingRule)
+	}
+	maps.Copy(rules, site.ChunkingRules())
+	exclude := loaded.Exclude
+	for _, pattern := range site.Exclude() {
+		if !slices.Contains(exclude, pattern) {
+			exclude = append(exclude, pattern)
+		}
+	}
+
+	if err := config.SetField(configFile, "docs", docsDir); err != nil {
+		return fmt.Errorf("error updating config file: %w", err)
+	}
+	if err := config.SetField(configFile, "chunking", rules); err != nil {
+		return fmt.Errorf("error updating config file: %w", err)
+	}
+	if len(exclude) > 0 {
+		if err := config.SetField(configFile, "exclude", exclude); err != nil {
+			return fmt.Errorf("error updating config file: %w", err)
+		}
+	}
+
+	navigationPath := sitedocs.NavigationPath(configFile)
+	if err := site.NewNavigation().Save(navigationPath); err != nil {
+		return fmt.Errorf("error writing navigation file: %w", err)
+	}
+
+	var sections []string
+	for _, page := range site.Pages {
+		if len(page.Navigation) > 1 && !slices.Contains(sections, page.Navigation[0]) {
+			sections = append(sections, page.Navigation[0])
+		}
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	fmt.Println(greenStyle.Render(fmt.Sprintf("✅ Imported the %s site %s", site.Generator, siteConfig)))
+	fmt.Println()
+	fmt.Printf("  📁 Docs directory: %s\n", docsDir)
+	fmt.Printf("  📖 Pages: %d\n", len(site.Pages))
+	if len(sections) > 0 {
+		fmt.Printf("  🧭 Sections: %d\n", len(sections))
+		for _, section := range sections {
+			fmt.Printf("     - %s\n", section)
+		}
+	}
+	for _, ext := range slices.Sorted(maps.Keys(site.ChunkingRules())) {
+		fmt.Printf("  ✂️  %s files: %s\n", ext, rules[ext].Strategy)
+	}
+	fmt.Printf("  ⚙️  %s (docs, chunking, exclude)\n", configFile)
+	fmt.Printf("  🧭 %s\n", navigationPath)
+	fmt.Println()
+	fmt.Println("Next steps:")
+	fmt.Println("  1. Generate embeddings: budgie generate-embeddings")
+	fmt.Println("  2. Ask about a section: budgie ask --rag --filter section=<section> -q \"...\"")
+
+	return nil
+}
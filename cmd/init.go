@@ -14,12 +14,30 @@ func RunInit(cmd *cobra.Command, args []string, defaultConfigContent, defaultSys
 	budgieDir := ".budgie"
 	docsDir := filepath.Join(budgieDir, "docs")
 
+	force, _ := cmd.Flags().GetBool("force")
+	upgrade, _ := cmd.Flags().GetBool("upgrade")
+	if force && upgrade {
+		return fmt.Errorf("--force and --upgrade cannot be used together")
+	}
+
 	// Check if .budgie directory already exists
-	if _, err := os.Stat(budgieDir); !os.IsNotExist(err) {
-		return fmt.Errorf(".budgie directory already exists")
+	_, err := os.Stat(budgieDir)
+	exists := !os.IsNotExist(err)
+	if upgrade {
+		if !exists {
+			return fmt.Errorf("no .budgie directory to upgrade (run budgie init first)")
+		}
+		return upgradeProject(budgieDir, defaultConfigContent, defaultSystemContent, defaultDocsReadmeContent)
+	}
+	if exists && !force {
+		return fmt.Errorf(".budgie directory already exists (use --force to overwrite the default files or --upgrade to merge the new defaults)")
 	}
 
-	fmt.Println("🚀 Initializing Budgie CLI project...")
+	if exists {
+		fmt.Println("🚀 Overwriting the default files of the Budgie CLI project...")
+	} else {
+		fmt.Println("🚀 Initializing Budgie CLI project...")
+	}
 
 	// Create .budgie directory
 	if err := os.MkdirAll(budgieDir, 0755); err != nil {
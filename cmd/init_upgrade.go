@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// defaultFileSuffix is appended to the path of a modified project file to write the new default next to it
+const defaultFileSuffix = ".default"
+
+// upgradeProject merges the defaults of this budgie version into an existing project: the missing config
+// fields are added (the user values are kept), the missing files are created, and the new version of the
+// templates the user modified is written next to them (<file>.default) to merge by hand
+func upgradeProject(budgieDir, defaultConfigContent, defaultSystemContent, defaultDocsReadmeContent string) error {
+	fmt.Println("🚀 Upgrading the Budgie CLI project...")
+
+	if err := os.MkdirAll(filepath.Join(budgieDir, "docs"), 0755); err != nil {
+		return fmt.Errorf("error creating docs directory: %w", err)
+	}
+
+	configPath := filepath.Join(budgieDir, "budgie.config.json")
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if err := os.WriteFile(configPath, []byte(defaultConfigContent), 0644); err != nil {
+			return fmt.Errorf("error writing config file: %w", err)
+		}
+		fmt.Printf("  ⚙️  %s: created\n", configPath)
+	} else {
+		added, err := config.MergeDefaults(configPath, []byte(defaultConfigContent))
+		if err != nil {
+			return fmt.Errorf("error upgrading config file: %w", err)
+		}
+		if len(added) == 0 {
+			fmt.Printf("  ⚙️  %s: up to date\n", configPath)
+		} else {
+			fmt.Printf("  ⚙️  %s: added %s\n", configPath, strings.Join(added, ", "))
+		}
+	}
+
+	var modified []string
+	for _, file := range []struct{ path, content string }{
+		{filepath.Join(budgieDir, "budgie.system.md"), defaultSystemContent},
+		{filepath.Join(budgieDir, "docs", "README.md"), defaultDocsReadmeContent},
+	} {
+		status, err := upgradeTemplate(file.path, file.content)
+		if err != nil {
+			return err
+		}
+		if status == "modified" {
+			modified = append(modified, file.path)
+			status = fmt.Sprintf("kept your version, new default written to %s%s", file.path, defaultFileSuffix)
+		}
+		fmt.Printf("  📝 %s: %s\n", file.path, status)
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	fmt.Println(greenStyle.Render("✅ Project upgraded"))
+	if len(modified) > 0 {
+		fmt.Println()
+		fmt.Printf("Merge the changes of the %s files into your versions, then remove them.\n", defaultFileSuffix)
+	}
+	return nil
+}
+
+// upgradeTemplate creates a missing template file, or writes the new default next to a modified one.
+// It returns the status of the file: "created", "up to date" or "modified".
+func upgradeTemplate(path, content string) (string, error) {
+	existing, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return "", fmt.Errorf("error writing %s: %w", path, err)
+		}
+		return "created", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if bytes.Equal(bytes.TrimSpace(existing), bytes.TrimSpace([]byte(content))) {
+		os.Remove(path + defaultFileSuffix)
+		return "up to date", nil
+	}
+	if err := os.WriteFile(path+defaultFileSuffix, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("error writing %s%s: %w", path, defaultFileSuffix, err)
+	}
+	return "modified", nil
+}
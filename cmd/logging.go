@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/debuglog"
+	"github.com/spf13/cobra"
+)
+
+// defaultConfigFile is the config file of the commands run without --config
+const defaultConfigFile = ".budgie/budgie.config.json"
+
+// SetupLogging applies the --verbose and --debug root flags: the debug log (requests sent to the provider
+// with their payload, retrieved chunks and scores, duration of each phase) is written to stderr with
+// --verbose, and to a file of .budgie/logs with --debug
+func SetupLogging(cmd *cobra.Command) error {
+	verbose, _ := cmd.Flags().GetBool("verbose")
+	debug, _ := cmd.Flags().GetBool("debug")
+
+	switch {
+	case debug:
+		configFile, err := cmd.Flags().GetString("config")
+		if err != nil || configFile == "" {
+			configFile = defaultConfigFile
+		}
+		file, err := debuglog.OpenFile(configFile)
+		if err != nil {
+			return fmt.Errorf("error creating the debug log file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "📝 Debug log: %s\n", file.Name())
+		debuglog.Enable(file)
+	case verbose:
+		debuglog.Enable(os.Stderr)
+	default:
+		return nil
+	}
+
+	// Every provider client (including the ones created by the agents) uses the default transport
+	http.DefaultTransport = debuglog.Transport(http.DefaultTransport)
+	debuglog.Printf("budgie %s: %s", cmd.Root().Version, strings.Join(os.Args[1:], " "))
+	return nil
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/glamour/styles"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
+)
+
+// markdownWriter renders the streamed Markdown answer with glamour (headings, lists, tables, highlighted
+// code) block by block: a block is rendered as soon as it is complete, i.e. at the first blank line
+// outside a code fence, and the last one by Flush. A nil markdownWriter does nothing.
+type markdownWriter struct {
+	out      io.Writer
+	renderer *glamour.TermRenderer
+	// line is the incomplete last line, block the complete lines of the current block
+	line  strings.Builder
+	block strings.Builder
+	// fence is the opening fence of the current code block ("" outside code blocks)
+	fence    string
+	rendered int
+}
+
+// newMarkdownWriter returns a writer rendering the Markdown to out, or nil when the answer is printed as is
+// (--raw, or stdout is not a terminal). The style is GLAMOUR_STYLE (dark, light, dracula, notty... or
+// the path of a JSON style), dark or light after the background of the terminal by default.
+func newMarkdownWriter(raw bool, out io.Writer) *markdownWriter {
+	if raw || !isTerminal(os.Stdout) {
+		return nil
+	}
+	width, _, err := term.GetSize(os.Stdout.Fd())
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	// The background of the terminal is only queried once (lipgloss caches it)
+	style := os.Getenv("GLAMOUR_STYLE")
+	if style == "" {
+		style = styles.LightStyle
+		if lipgloss.HasDarkBackground() {
+			style = styles.DarkStyle
+		}
+	}
+	renderer, err := glamour.NewTermRenderer(glamour.WithStylePath(style), glamour.WithWordWrap(width))
+	if err != nil {
+		return nil
+	}
+	return &markdownWriter{out: out, renderer: renderer}
+}
+
+// Write adds a chunk of the answer, rendering the blocks it completes
+func (m *markdownWriter) Write(data []byte) (int, error) {
+	for _, part := range strings.SplitAfter(string(data), "\n") {
+		m.line.WriteString(part)
+		if !strings.HasSuffix(part, "\n") {
+			continue
+		}
+		line := m.line.String()
+		m.line.Reset()
+		m.block.WriteString(line)
+
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case m.fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			m.fence = trimmed[:3]
+		case m.fence != "" && strings.HasPrefix(trimmed, m.fence) && strings.Trim(trimmed, m.fence[:1]) == "":
+			m.fence = ""
+		case m.fence == "" && trimmed == "":
+			if err := m.render(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(data), nil
+}
+
+// Flush renders the last block of the answer
+func (m *markdownWriter) Flush() error {
+	if m == nil {
+		return nil
+	}
+	m.block.WriteString(m.line.String())
+	m.line.Reset()
+	m.fence = ""
+	return m.render()
+}
+
+// render prints the current block, separated from the previous one by a blank line
+func (m *markdownWriter) render() error {
+	block := m.block.String()
+	m.block.Reset()
+	if strings.TrimSpace(block) == "" {
+		return nil
+	}
+
+	rendered, err := m.renderer.Render(block)
+	if err != nil {
+		// Print the block as is rather than losing it
+		rendered = block
+	}
+	rendered = trimPadding(rendered)
+	if m.rendered > 0 {
+		rendered = "\n" + rendered
+	}
+	m.rendered++
+	_, err = io.WriteString(m.out, rendered+"\n")
+	return err
+}
+
+// trailingPadding matches the spaces (and their styles) padding a rendered line to the wrap width
+var trailingPadding = regexp.MustCompile(`(?:\x1b\[[0-9;]*m| )+$`)
+
+// trimPadding removes the blank lines around a rendered block and the padding of its lines (the margins
+// added by glamour), so the answer copied from the terminal has no trailing spaces
+func trimPadding(text string) string {
+	lines := strings.Split(text, "\n")
+	blank := func(line string) bool { return strings.TrimSpace(ansi.Strip(line)) == "" }
+	for len(lines) > 0 && blank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && blank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	for i, line := range lines {
+		lines[i] = trailingPadding.ReplaceAllString(line, "\x1b[0m")
+	}
+	return strings.Join(lines, "\n")
+}
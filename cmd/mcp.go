@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/mcpclient"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// RunMCPList handles the mcp list command execution: it connects to the MCP servers of the config
+// and lists the tools they give to the model
+func RunMCPList(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	if len(config.MCPServers) == 0 {
+		fmt.Printf("No MCP servers in %s (mcp-servers)\n", configFile)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpConnectTimeout)
+	defer cancel()
+	servers, err := mcpclient.ConnectAll(ctx, config.MCPServers, cmd.Root().Version)
+	if err != nil {
+		return err
+	}
+	defer mcpclient.CloseAll(servers)
+
+	boldStyle := lipgloss.NewStyle().Bold(true)
+	grayStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	for _, server := range servers {
+		trusted := ""
+		if server.Trusted {
+			trusted = ", trusted"
+		}
+		fmt.Println(boldStyle.Render(fmt.Sprintf("🔌 %s", server.Name)) +
+			grayStyle.Render(fmt.Sprintf(" (%s, %d tool(s)%s)", config.MCPServers[server.Name].TransportOrDefault(), len(server.Tools), trusted)))
+		for _, tool := range server.Tools {
+			fmt.Printf("  🔧 %s", tool.Name)
+			if tool.Description != "" {
+				fmt.Print(grayStyle.Render(" - " + firstLine(tool.Description)))
+			}
+			fmt.Println()
+		}
+		fmt.Println()
+	}
+	return nil
+}
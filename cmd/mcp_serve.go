@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// RunMCPServe handles the mcp-serve command execution: it exposes the RAG search of the project as MCP tools
+// over stdio, for the IDE assistants. search_docs returns the matching chunks, ask_docs answers a question
+// with the documentation as context.
+func RunMCPServe(cmd *cobra.Command, args []string) error {
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	configFile, _ := cmd.Flags().GetString("config")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+
+	opts := askOptions{
+		systemFiles:    systemFiles,
+		configFile:     configFile,
+		embeddingsFile: embeddingsFile,
+		overrides:      configOverrides(cmd),
+		ragEnabled:     true,
+		modelCheck:     &modelCheck{},
+		// stdout carries the protocol messages: the diagnostics printed while answering go to stderr
+		log: os.Stderr,
+	}
+
+	// Fail early on a broken project, the client only sees a closed connection
+	if _, err := config.LoadConfig(opts.configFile, opts.overrides); err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+
+	mcpServer := server.NewMCPServer("budgie", cmd.Root().Version, server.WithToolCapabilities(false))
+
+	mcpServer.AddTool(mcp.NewTool("search_docs",
+		mcp.WithDescription("Search the project documentation (budgie knowledge base) and return the most relevant excerpts with their source and similarity score"),
+		mcp.WithString("query", mcp.Required(), mcp.Description("What to look for in the documentation")),
+		mcp.WithNumber("top_k", mcp.Description("Maximum number of excerpts (defaults to the top-k setting of the project)")),
+		mcp.WithArray("filters", mcp.Description("Metadata filters, e.g. category=concurrency, keyword=goroutines or section=User Guide"), mcp.WithStringItems()),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		query, err := request.RequireString("query")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		_, similarities, err := mcpSearch(opts, query, request.GetInt("top_k", 0), request.GetStringSlice("filters", nil))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if len(similarities) == 0 {
+			return mcp.NewToolResultText("No relevant documentation found"), nil
+		}
+
+		var result strings.Builder
+		for i, similarity := range similarities {
+			fmt.Fprintf(&result, "## %d. %s (score: %.4f)\n\n%s\n\n", i+1, similarity.ID, similarity.Score, strings.TrimSpace(similarity.Content))
+		}
+		return mcp.NewToolResultText(strings.TrimSpace(result.String())), nil
+	})
+
+	mcpServer.AddTool(mcp.NewTool("ask_docs",
+		mcp.WithDescription("Answer a question about the project with its documentation (budgie knowledge base) as context, and list the sources used"),
+		mcp.WithString("question", mcp.Required(), mcp.Description("The question to answer")),
+		mcp.WithArray("filters", mcp.Description("Metadata filters, e.g. category=concurrency, keyword=goroutines or section=User Guide"), mcp.WithStringItems()),
+	), func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		question, err := request.RequireString("question")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		config, similarities, err := mcpSearch(opts, question, 0, request.GetStringSlice("filters", nil))
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		messages, err := baseMessages(opts)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if len(similarities) > 0 {
+			messages = append(messages, openai.UserMessage(ragContextHeader+strings.Join(rag.Contents(similarities), "\n\n")))
+		}
+		messages = append(messages, openai.UserMessage(question))
+
+		start := time.Now()
+		answer, usage, err := completeWithUsage(config, messages)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("error during completion: %v", err)), nil
+		}
+		recordUsage(config, opts, "mcp-serve", usage, time.Since(start), false)
+		recordQuestion(config, opts, question, answer, false)
+
+		if len(similarities) > 0 {
+			answer += "\n\nSources:"
+			for _, similarity := range similarities {
+				answer += fmt.Sprintf("\n- %s (score: %.4f)", similarity.ID, similarity.Score)
+			}
+		}
+		return mcp.NewToolResultText(answer), nil
+	})
+
+	fmt.Fprintf(os.Stderr, "🔌 budgie MCP server ready on stdio (tools: search_docs, ask_docs, knowledge base: %s)\n", opts.embeddingsFile)
+	return server.NewStdioServer(mcpServer).Listen(context.Background(), os.Stdin, os.Stdout)
+}
+
+// mcpSearch runs the RAG search of an mcp-serve tool call. The config is loaded for each call, so the
+// changes of the project (config, regenerated embeddings) are taken into account without restarting the server.
+func mcpSearch(opts askOptions, query string, topK int, filters []string) (*config.Config, []rag.Similarity, error) {
+	config, err := config.LoadConfig(opts.configFile, opts.overrides)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading config file: %w", err)
+	}
+	if topK > 0 {
+		config.TopK = topK
+	}
+	if opts.filters, err = rag.ParseMetadataFilters(filters); err != nil {
+		return nil, nil, err
+	}
+
+	useRAG, err := checkEmbeddingModel(config, opts)
+	if err != nil || !useRAG {
+		return config, nil, err
+	}
+	similarities, searched, errs := ragSearch(config, opts, query)
+	if !searched && len(errs) > 0 {
+		return config, nil, errs[0]
+	}
+	return config, similarities, nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
+)
+
+// modelListTimeout bounds the request listing the models of the provider when switching models
+const modelListTimeout = 5 * time.Second
+
+// maxTemperature is the highest temperature accepted by the OpenAI-compatible APIs
+const maxTemperature = 2.0
+
+// switchModel makes the chat model of the session the given one, for the next turns (/model). The model is
+// looked up in the models of the provider, where it can be given without its namespace and tag (qwen2.5 for
+// ai/qwen2.5:latest). When the provider does not list its models, the name is used as is.
+// It returns the name of the model switched to.
+func switchModel(config *config.Config, name string) (string, error) {
+	available, err := listModels(config)
+	if err == nil {
+		model, found := resolveModel(available, name)
+		if !found {
+			return "", fmt.Errorf("model %s not found on the provider (%s), available models: %s", name, pullHint(config, name), strings.Join(available, ", "))
+		}
+		name = model
+	}
+	config.Model = name
+	return name, nil
+}
+
+// listModels returns the ids of the models served by the provider
+func listModels(config *config.Config) ([]string, error) {
+	client, err := provider.NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), modelListTimeout)
+	defer cancel()
+	page, err := client.Models.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var available []string
+	for _, model := range page.Data {
+		available = append(available, model.ID)
+	}
+	return available, nil
+}
+
+// resolveModel finds a model in the models of the provider: by its name (see hasModel), or by its short
+// name without namespace and tag when a single model has it
+func resolveModel(available []string, name string) (string, bool) {
+	if hasModel(available, name) {
+		return name, true
+	}
+	var matches []string
+	for _, id := range available {
+		short := id[strings.LastIndex(id, "/")+1:]
+		short, _, _ = strings.Cut(short, ":")
+		if short == name {
+			matches = append(matches, id)
+		}
+	}
+	if len(matches) == 1 {
+		return matches[0], true
+	}
+	return "", false
+}
+
+// parseTemperature parses the temperature of /temp, between 0 and maxTemperature
+func parseTemperature(value string) (float64, error) {
+	temperature, err := strconv.ParseFloat(value, 64)
+	if err != nil || temperature < 0 || temperature > maxTemperature {
+		return 0, fmt.Errorf("invalid temperature %q (expected a number between 0 and %g)", value, maxTemperature)
+	}
+	return temperature, nil
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Choices offered when the embedding model changed
+const (
+	modelChangeContinue = "continue"
+	modelChangeReembed  = "reembed"
+	modelChangeAbort    = "abort"
+)
+
+// reembedLogName is the log file (next to the config file) of the background re-embedding
+const reembedLogName = "generate-embeddings.log"
+
+// errEmbeddingModelChanged is returned when the user aborts because the embedding model changed
+var errEmbeddingModelChanged = errors.New("aborted: the embeddings store was generated with another embedding model")
+
+// modelCheck remembers the choices made when the embedding model changed, by embeddings file,
+// so an interactive session only asks once per store (#rag:<store> switches to another store)
+type modelCheck struct {
+	mu     sync.Mutex
+	stores map[string]modelChoice
+}
+
+// modelChoice is the choice made for a store
+type modelChoice struct {
+	useRAG bool
+	err    error
+}
+
+// checkEmbeddingModel compares the configured embedding model with the one recorded in the embeddings store.
+// Comparing embeddings of different models produces near-random similarity scores, so when they differ the
+// user chooses to continue anyway, to re-embed the docs in the background (the RAG search is skipped
+// meanwhile) or to abort. Without a terminal, a warning is printed and the search continues.
+// It returns whether the RAG search should run.
+func checkEmbeddingModel(config *config.Config, opts askOptions) (bool, error) {
+	check := opts.modelCheck
+	if check == nil {
+		return embeddingModelChoice(config, opts)
+	}
+
+	check.mu.Lock()
+	defer check.mu.Unlock()
+	if choice, ok := check.stores[opts.embeddingsFile]; ok {
+		return choice.useRAG, choice.err
+	}
+	useRAG, err := embeddingModelChoice(config, opts)
+	if check.stores == nil {
+		check.stores = make(map[string]modelChoice)
+	}
+	check.stores[opts.embeddingsFile] = modelChoice{useRAG: useRAG, err: err}
+	return useRAG, err
+}
+
+// embeddingModelChoice asks what to do when the embedding model changed
+func embeddingModelChoice(config *config.Config, opts askOptions) (bool, error) {
+	storeModel, err := rag.StoreEmbeddingModel(opts.embeddingsFile)
+	if err != nil || storeModel == "" || storeModel == config.EmbeddingModel {
+		return true, nil
+	}
+
+	log := opts.logWriter()
+	yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	fmt.Fprintln(log, yellowStyle.Render(fmt.Sprintf("⚠️  The embeddings of %s were generated with %s but the configured embedding model is %s: the similarity scores are unreliable", opts.embeddingsFile, storeModel, config.EmbeddingModel)))
+	reembed, canReembed := reembedArgs(opts)
+	if !isTerminal(os.Stdin) {
+		if canReembed {
+			fmt.Fprintln(log, yellowStyle.Render("💡 Regenerate them with: budgie "+strings.Join(reembed, " ")))
+		}
+		return true, nil
+	}
+
+	options := []huh.Option[string]{huh.NewOption("Continue with the current embeddings (unreliable documentation search)", modelChangeContinue)}
+	// A remote store is regenerated by its owners
+	if canReembed {
+		options = append(options, huh.NewOption("Re-embed the docs in the background (answer without documentation meanwhile)", modelChangeReembed))
+	}
+	options = append(options, huh.NewOption("Abort", modelChangeAbort))
+	choice := modelChangeContinue
+	err = runField(huh.NewSelect[string]().
+		Title("What do you want to do?").
+		Options(options...).
+		Value(&choice), log)
+	if err != nil {
+		return false, errEmbeddingModelChanged
+	}
+
+	switch choice {
+	case modelChangeContinue:
+		return true, nil
+	case modelChangeReembed:
+		logPath, err := startReembedding(opts, reembed)
+		if err != nil {
+			return false, fmt.Errorf("error starting the background re-embedding: %w", err)
+		}
+		fmt.Fprintln(log, yellowStyle.Render(fmt.Sprintf("🔄 Re-embedding the docs in the background with %s (log: %s)", config.EmbeddingModel, logPath)))
+		return false, nil
+	default:
+		return false, errEmbeddingModelChanged
+	}
+}
+
+// reembedArgs returns the `budgie generate-embeddings --incremental` command line regenerating the checked
+// store (it regenerates everything since the embedding model changed): the named store of the project, or the
+// embeddings file (a shared store, --embeddings), from the docs directory recorded in its manifest. It reports
+// false for the remote shared stores, their cached copy is replaced by the next download.
+func reembedArgs(opts askOptions) ([]string, bool) {
+	args := []string{"generate-embeddings", "--config", opts.configFile, "--incremental"}
+	if path, err := storeEmbeddingsPath(opts.configFile, opts.store); opts.store != "" && err == nil && path == opts.embeddingsFile {
+		args = append(args, "--store", opts.store)
+	} else if fetchedStore(opts.embeddingsFile) {
+		return nil, false
+	} else {
+		args = append(args, "--embeddings", opts.embeddingsFile)
+	}
+	if manifest, err := rag.LoadManifest(rag.ManifestPath(opts.embeddingsFile)); err == nil && manifest.Docs != "" {
+		args = append(args, "--docs", manifest.Docs)
+	}
+	// Keep the profile and model overrides of the current command
+	return append(args, overrideArgs(opts.overrides)...), true
+}
+
+// startReembedding runs the re-embedding command line in the background
+func startReembedding(opts askOptions, args []string) (string, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+
+	logPath := filepath.Join(filepath.Dir(opts.configFile), reembedLogName)
+	logFile, err := os.Create(logPath)
+	if err != nil {
+		return "", err
+	}
+	defer logFile.Close()
+
+	command := exec.Command(executable, args...)
+	command.Stdout = logFile
+	command.Stderr = logFile
+	detach(command)
+	if err := command.Start(); err != nil {
+		return "", err
+	}
+	return logPath, command.Process.Release()
+}
+
+// overrideArgs returns the command line flags reproducing the config overrides
+func overrideArgs(overrides config.Overrides) []string {
+	var args []string
+	flag := func(name, value string) {
+		if value != "" {
+			args = append(args, "--"+name, value)
+		}
+	}
+	flag("profile", overrides.Profile)
+	flag("provider", overrides.Provider)
+	flag("embedding-model", overrides.EmbeddingModel)
+	flag("base-url", overrides.BaseURL)
+	return args
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// errOffline is the completion error when the chat model is skipped with --offline
+var errOffline = errors.New("the chat model is skipped in offline mode and no documentation excerpt was found")
+
+// offlineChunks is the number of excerpts shown by the offline answer when top-k is not set
+const offlineChunks = 3
+
+// backendUnreachable reports whether a completion error means the chat backend cannot be reached
+// (connection refused, unknown host, timeout...) rather than an error returned by the backend
+func backendUnreachable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// offlineAnswer builds the degraded extractive answer used when the chat backend is unreachable (or with --offline):
+// the most relevant chunks found by the similarity search or, when the embedding model cannot be reached either,
+// by a keyword search over the stored chunks. It returns false when no chunk is found.
+func offlineAnswer(config *config.Config, opts askOptions, question string, similarities []rag.Similarity) (string, []rag.Similarity, bool) {
+	limit := config.TopK
+	if limit <= 0 {
+		limit = offlineChunks
+	}
+
+	if len(similarities) == 0 {
+		store, text, _ := ragPrefix(question)
+		if store != "" {
+			if scoped, err := withStore(config, opts, store); err == nil {
+				opts = scoped
+			}
+		}
+		question = text
+		for _, storePath := range searchedStores(opts) {
+			// The keyword search does not compare embeddings: the embedding model does not matter
+			searchAgent, err := rag.CreateSearchAgent(config, storePath, true)
+			if err != nil || searchAgent == nil {
+				continue
+			}
+			similarities = append(similarities, rag.KeywordSearch(question, searchAgent, limit)...)
+			rag.CloseStore(searchAgent)
+		}
+	}
+	similarities = rag.TopK(similarities, limit)
+	if len(similarities) == 0 {
+		return "", nil, false
+	}
+
+	yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3")).Bold(true)
+	fmt.Fprintln(opts.logWriter(), yellowStyle.Render("⚠️  Offline answer: the chat model is unavailable, showing the most relevant documentation excerpts (no generated answer)"))
+	fmt.Fprintln(opts.logWriter())
+
+	var answer strings.Builder
+	answer.WriteString("> Offline answer: the chat model was unavailable. The most relevant documentation excerpts are shown below.\n")
+	for i, similarity := range similarities {
+		fmt.Fprintf(&answer, "\n### %d. %s (%.1f%%)\n\n%s\n", i+1, similarity.ID, similarity.Score*100, strings.TrimSpace(similarity.Content))
+	}
+	return answer.String(), similarities, true
+}
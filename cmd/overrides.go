@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// configOverrides reads the config override flags (--profile, --model, --temperature...) set on the command line
+func configOverrides(cmd *cobra.Command) config.Overrides {
+	var overrides config.Overrides
+	overrides.Profile, _ = cmd.Flags().GetString("profile")
+	overrides.Provider, _ = cmd.Flags().GetString("provider")
+	overrides.Model, _ = cmd.Flags().GetString("model")
+	overrides.EmbeddingModel, _ = cmd.Flags().GetString("embedding-model")
+	overrides.BaseURL, _ = cmd.Flags().GetString("base-url")
+
+	// Numeric values are only overridden when the flag is set (0 is a valid temperature)
+	if cmd.Flags().Changed("temperature") {
+		temperature, _ := cmd.Flags().GetFloat64("temperature")
+		overrides.Temperature = &temperature
+	}
+	if cmd.Flags().Changed("cosine-limit") {
+		cosineLimit, _ := cmd.Flags().GetFloat64("cosine-limit")
+		overrides.CosineLimit = &cosineLimit
+	}
+	return overrides
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/term"
+)
+
+// Pager modes (--pager)
+const (
+	pagerAuto   = "auto"
+	pagerAlways = "always"
+	pagerNever  = "never"
+)
+
+// validatePager checks the --pager flag value
+func validatePager(mode string) error {
+	switch mode {
+	case pagerAuto, pagerAlways, pagerNever:
+		return nil
+	default:
+		return fmt.Errorf("unsupported pager mode %q (supported: auto, always, never)", mode)
+	}
+}
+
+// pagerWriter echoes the streamed answer to the terminal until it no longer fits on one screen
+// (auto mode), then keeps the rest for the pager. In always mode nothing is echoed.
+// The whole answer is kept so the pager can show it from the top.
+type pagerWriter struct {
+	out      io.Writer
+	content  strings.Builder
+	width    int
+	height   int
+	rows     int
+	column   int
+	echoed   int
+	overflow bool
+}
+
+// newPagerWriter returns a pager writer for the mode, or nil when the answer should not be paged
+// (never mode, or stdout is not a terminal)
+func newPagerWriter(mode string, out io.Writer) *pagerWriter {
+	if mode == pagerNever || mode == "" || out != os.Stdout || !isTerminal(os.Stdout) {
+		return nil
+	}
+	width, height, err := term.GetSize(os.Stdout.Fd())
+	if err != nil || width <= 0 || height <= 0 {
+		return nil
+	}
+	return &pagerWriter{
+		out:      out,
+		width:    width,
+		height:   height,
+		overflow: mode == pagerAlways,
+	}
+}
+
+// Write keeps the content and echoes it while it fits on the screen
+func (p *pagerWriter) Write(data []byte) (int, error) {
+	p.content.Write(data)
+	if p.overflow {
+		return len(data), nil
+	}
+
+	// Count the terminal rows used so far, wrapped lines included
+	// (a few rows are kept for the prompt and the status lines)
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		p.column += ansi.StringWidth(strings.TrimSuffix(line, "\n"))
+		if strings.HasSuffix(line, "\n") {
+			p.rows += 1 + max(p.column-1, 0)/p.width
+			p.column = 0
+		}
+	}
+	if p.rows+1+p.column/p.width > p.height-3 {
+		p.overflow = true
+		return len(data), nil
+	}
+	p.echoed += len(data)
+	return p.out.Write(data)
+}
+
+// Paged reports whether the answer did not fit on one screen and should be shown in the pager
+func (p *pagerWriter) Paged() bool {
+	return p != nil && p.overflow
+}
+
+// Page pipes the whole answer into $PAGER (less by default), keeping its ANSI colors
+func (p *pagerWriter) Page() error {
+	pager := os.Getenv("PAGER")
+	if pager == "" {
+		pager = "less"
+	}
+	fields := strings.Fields(pager)
+	if len(fields) == 0 {
+		return nil
+	}
+
+	command := exec.Command(fields[0], fields[1:]...)
+	command.Stdin = strings.NewReader(p.content.String())
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+	command.Env = os.Environ()
+	if os.Getenv("LESS") == "" {
+		// Keep the colors, quit when the answer fits on one screen and leave it on the terminal
+		command.Env = append(command.Env, "LESS=FRX")
+	}
+	if err := command.Run(); err != nil {
+		// Fall back to printing what the terminal did not show yet
+		fmt.Fprint(p.out, p.content.String()[p.echoed:])
+		return fmt.Errorf("error running pager %s: %w", fields[0], err)
+	}
+	return nil
+}
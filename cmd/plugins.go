@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/plugins"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// PluginCommands returns the subcommands of the plugins of the user and of the default project
+// (.budgie/plugins/, once trusted), skipping the plugins named like a command of budgie. The arguments of a
+// plugin subcommand are passed as is.
+func PluginCommands(root *cobra.Command) []*cobra.Command {
+	available := loadPlugins(defaultConfigFile, os.Stderr)
+
+	reserved := []string{"help", "completion"}
+	for _, command := range root.Commands() {
+		reserved = append(reserved, command.Name())
+		reserved = append(reserved, command.Aliases...)
+	}
+
+	var commands []*cobra.Command
+	for _, plugin := range available {
+		if slices.Contains(reserved, plugin.Name) {
+			fmt.Fprintf(os.Stderr, "Warning: plugin %s is not registered as a command: %s is the name of a budgie command\n", plugin.Path, plugin.Name)
+			continue
+		}
+		commands = append(commands, &cobra.Command{
+			Use:                plugin.Name + " [args...]",
+			Short:              "Plugin " + plugin.Path,
+			DisableFlagParsing: true,
+			RunE: func(c *cobra.Command, args []string) error {
+				return runPluginCommand(plugin, args)
+			},
+		})
+	}
+	return commands
+}
+
+// loadPlugins returns the plugins of the user and of the workspace of a config file (see plugins.Load). The
+// untrusted plugins of the workspace are reported on log, with the way to trust them.
+func loadPlugins(configFile string, log io.Writer) []plugins.Plugin {
+	available, untrusted, err := plugins.Load(configFile)
+	if err != nil {
+		fmt.Fprintf(log, "Warning: error listing the plugins: %v\n", err)
+		return nil
+	}
+	if untrusted != "" {
+		if abs, err := filepath.Abs(untrusted); err == nil {
+			untrusted = abs
+		}
+		fmt.Fprintf(log, "Warning: the plugins of %s are not loaded: they run commands, trust them by adding \"trusted-plugins\": [%q] to %s\n", untrusted, untrusted, config.GlobalPath())
+	}
+	return available
+}
+
+// runPluginCommand runs a plugin as a subcommand and prints its output
+func runPluginCommand(plugin plugins.Plugin, args []string) error {
+	request := plugins.Request{Kind: plugins.KindCommand, Args: args, ConfigFile: defaultConfigFile}
+	if loaded, err := config.LoadConfig(defaultConfigFile, config.Overrides{}); err == nil {
+		request.Model = loaded.Model
+	}
+	response, err := plugin.Run(context.Background(), request)
+	if err != nil {
+		return err
+	}
+	if response.Output != "" {
+		fmt.Println(response.Output)
+	}
+	return nil
+}
+
+// slashPlugin returns the plugin of a slash command of interactive mode and the input typed after it.
+// The commands of budgie always win over the plugins named like them.
+func slashPlugin(available []plugins.Plugin, userInput string) (plugins.Plugin, string, bool) {
+	if !strings.HasPrefix(userInput, "/") {
+		return plugins.Plugin{}, "", false
+	}
+	name, input, _ := strings.Cut(strings.TrimPrefix(userInput, "/"), " ")
+	for _, command := range slashCommands {
+		if builtin, _, _ := strings.Cut(strings.TrimPrefix(command, "/"), " "); builtin == name {
+			return plugins.Plugin{}, "", false
+		}
+	}
+	plugin, found := plugins.Find(available, name)
+	return plugin, strings.TrimSpace(input), found
+}
+
+// runSlashPlugin runs a plugin as a slash command with the state of the conversation. It returns the
+// conversation with the messages added by the plugin, and the question the plugin asks (if any).
+func runSlashPlugin(config *config.Config, opts askOptions, plugin plugins.Plugin, input string, messages []openai.ChatCompletionMessageParamUnion, lastAnswer string) ([]openai.ChatCompletionMessageParamUnion, string, error) {
+	response, err := plugin.Run(context.Background(), plugins.Request{
+		Kind:       plugins.KindSlash,
+		Input:      input,
+		Args:       strings.Fields(input),
+		ConfigFile: opts.configFile,
+		Model:      config.Model,
+		Messages:   messages,
+		LastAnswer: lastAnswer,
+	})
+	if err != nil {
+		return messages, "", err
+	}
+	if response.Output != "" {
+		fmt.Println(response.Output)
+	}
+	if len(response.Messages) > 0 {
+		messages = append(messages, response.Messages...)
+		fmt.Printf("✅ %d message(s) added to the conversation by /%s\n", len(response.Messages), plugin.Name)
+	}
+	return messages, strings.TrimSpace(response.Question), nil
+}
+
+// pluginNames returns the slash commands of the plugins, for the suggestions of the interactive prompt
+func pluginNames(available []plugins.Plugin) []string {
+	names := make([]string, 0, len(available))
+	for _, plugin := range available {
+		names = append(names, "/"+plugin.Name)
+	}
+	return names
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// postProcessors returns the post-process commands of the config run on the answers (none with --no-post)
+func postProcessors(config *config.Config, opts askOptions) []string {
+	if opts.noPost {
+		return nil
+	}
+	return config.PostProcess
+}
+
+// postProcess pipes an answer through commands, in order: each command reads the answer on its standard
+// input and writes the new one to its standard output. A command printing nothing (e.g. a linter which only
+// fails on errors) keeps the answer. A failing command stops the pipe.
+func postProcess(commands []string, answer string) (string, error) {
+	for _, commandLine := range commands {
+		var stdout, stderr bytes.Buffer
+		command := shellCommand(commandLine)
+		command.Stdin = strings.NewReader(answer)
+		command.Stdout, command.Stderr = &stdout, &stderr
+		if err := command.Run(); err != nil {
+			message := strings.TrimSpace(stderr.String())
+			if message == "" {
+				message = err.Error()
+			}
+			return "", fmt.Errorf("post-processor `%s` failed: %s", commandLine, message)
+		}
+		if output := strings.TrimRight(stdout.String(), "\n"); strings.TrimSpace(output) != "" {
+			answer = output
+		}
+	}
+	return answer, nil
+}
+
+// applyPostProcessors post-processes an answer, or returns it unchanged with a warning on out when a command fails
+func applyPostProcessors(commands []string, answer string, out io.Writer) string {
+	processed, err := postProcess(commands, answer)
+	if err != nil {
+		yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+		fmt.Fprintln(out, yellowStyle.Render(fmt.Sprintf("⚠️  %v (the answer is kept as is)", err)))
+		return answer
+	}
+	return processed
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/ledger"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Choices of the previously answered question prompt
+const (
+	previousShow = "show"
+	previousAsk  = "ask"
+)
+
+// recordQuestion appends an answered question to the ledger, so later similar questions can reuse its answer.
+// A ledger error never fails the command.
+func recordQuestion(config *config.Config, opts askOptions, question, answer string, reused bool) {
+	err := ledger.With(ledger.Path(opts.configFile), func(db *ledger.DB) error {
+		return db.AddQuestion(ledger.Question{
+			Time:     time.Now(),
+			Model:    config.Model,
+			Question: question,
+			Answer:   answer,
+			Reused:   reused,
+		})
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error recording question: %v\n", err)
+	}
+}
+
+// findPreviousQuestion returns the most similar question answered before (similarity of at least
+// previous-threshold). The questions recorded without an embedding are embedded first, their
+// embedding is saved in the ledger so each question is only embedded once.
+func findPreviousQuestion(config *config.Config, opts askOptions, question string) (*ledger.Question, float64, error) {
+	embedder, err := rag.NewEmbedder(config)
+	if err != nil {
+		return nil, 0, err
+	}
+	ctx := context.Background()
+	if question, err = redactText(config, question); err != nil {
+		return nil, 0, err
+	}
+	embedding, err := rag.Embed(ctx, embedder, question)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error creating the question embedding: %w", err)
+	}
+
+	var best *ledger.Question
+	bestScore := 0.0
+	err = ledger.With(ledger.Path(opts.configFile), func(db *ledger.DB) error {
+		questions, err := db.Questions(time.Time{})
+		if err != nil {
+			return err
+		}
+		for _, previous := range questions {
+			if previous.Reused {
+				continue
+			}
+			if previous.EmbeddingModel != config.EmbeddingModel || len(previous.Embedding) == 0 {
+				text, err := redactText(config, previous.Question)
+				if err != nil {
+					return err
+				}
+				record, err := rag.Embed(ctx, embedder, text)
+				if err != nil {
+					return fmt.Errorf("error creating the embedding of a previous question: %w", err)
+				}
+				previous.EmbeddingModel, previous.Embedding = config.EmbeddingModel, record.Embedding
+				if err := db.UpdateQuestion(previous); err != nil {
+					return err
+				}
+			}
+			if score := rag.CosineSimilarity(embedding.Embedding, previous.Embedding); score >= config.PreviousThreshold && score > bestScore {
+				best, bestScore = &previous, score
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return best, bestScore, nil
+}
+
+// offerPreviousAnswer looks for a similar question answered before and offers to show its answer
+// instead of asking the model. It returns the answer when it was shown (the completion is skipped).
+// Without a terminal, the previous question is only mentioned.
+func offerPreviousAnswer(config *config.Config, opts askOptions, question string) (string, bool) {
+	log := opts.logWriter()
+	previous, score, err := findPreviousQuestion(config, opts, question)
+	if err != nil {
+		fmt.Fprintf(log, "Warning: error searching the previously answered questions: %v\n", err)
+		return "", false
+	}
+	if previous == nil {
+		return "", false
+	}
+
+	yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+	fmt.Fprintln(log, yellowStyle.Render(fmt.Sprintf("🕘 A similar question was answered on %s (similarity: %.2f, model: %s):", previous.Time.Format("2006-01-02 15:04"), score, previous.Model)))
+	fmt.Fprintln(log, yellowStyle.Render("   "+firstLine(previous.Question)))
+	if !isTerminal(os.Stdin) {
+		return "", false
+	}
+
+	choice := previousShow
+	err = runField(huh.NewSelect[string]().
+		Title("Show the previous answer?").
+		Options(
+			huh.NewOption("Show the previous answer", previousShow),
+			huh.NewOption("Ask the model again", previousAsk),
+		).
+		Value(&choice), log)
+	if err != nil || choice != previousShow {
+		return "", false
+	}
+
+	fmt.Fprintln(log)
+	fmt.Fprintln(opts.writer(), previous.Answer)
+	recordQuestion(config, opts, question, previous.Answer, true)
+	return previous.Answer, true
+}
+
+// firstLine returns the first line of a text, shortened to 100 characters
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(text), "\n")
+	if runes := []rune(line); len(runes) > 100 {
+		return string(runes[:100]) + "..."
+	}
+	return line
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/lipgloss/table"
+)
+
+// progressRefresh is the redraw interval of the progress bar
+const progressRefresh = 100 * time.Millisecond
+
+// embeddingProgress reports the progress of generate-embeddings: on a terminal, a progress bar
+// (files done, chunks embedded, failures and ETA) redrawn in place, otherwise one line per processed file.
+// It is safe for concurrent use (the embedding workers report their chunks).
+type embeddingProgress struct {
+	mu         sync.Mutex
+	terminal   bool
+	bar        progress.Model
+	start      time.Time
+	totalFiles int
+	files      int
+	chunks     int
+	failures   int
+	stop       chan struct{}
+	done       chan struct{}
+}
+
+// newEmbeddingProgress starts reporting the progress of the embedding of totalFiles files
+func newEmbeddingProgress(totalFiles int) *embeddingProgress {
+	p := &embeddingProgress{
+		terminal:   isTerminal(os.Stdout),
+		bar:        progress.New(progress.WithDefaultGradient(), progress.WithWidth(30)),
+		start:      time.Now(),
+		totalFiles: totalFiles,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if !p.terminal {
+		close(p.done)
+		return p
+	}
+
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(progressRefresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.draw()
+				p.mu.Unlock()
+			}
+		}
+	}()
+	return p
+}
+
+// ChunkEmbedded counts an embedded chunk, or a failure when err is set
+func (p *embeddingProgress) ChunkEmbedded(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.failures++
+		return
+	}
+	p.chunks++
+}
+
+// ChunkFailed counts a chunk which was embedded but could not be saved
+func (p *embeddingProgress) ChunkFailed() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.chunks--
+	p.failures++
+}
+
+// FileDone counts a file handled by the writer (embedded, unchanged or skipped)
+func (p *embeddingProgress) FileDone() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.files++
+}
+
+// Processing reports the file being saved (only printed when stdout is not a terminal)
+func (p *embeddingProgress) Processing(path string, chunks int) {
+	if p.terminal {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fmt.Printf("[%d/%d] Processing: %s\n", p.files, p.totalFiles, path)
+	fmt.Printf("  Created %d chunks\n", chunks)
+}
+
+// Printf prints a message above the progress bar
+func (p *embeddingProgress) Printf(format string, args ...any) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.terminal {
+		fmt.Print("\r\x1b[2K")
+	}
+	fmt.Printf(format, args...)
+	if p.terminal {
+		p.draw()
+	}
+}
+
+// Stop removes the progress bar
+func (p *embeddingProgress) Stop() {
+	select {
+	case <-p.stop:
+		return
+	default:
+	}
+	close(p.stop)
+	<-p.done
+	if p.terminal {
+		fmt.Print("\r\x1b[2K")
+	}
+}
+
+// Elapsed returns the time spent since the progress started
+func (p *embeddingProgress) Elapsed() time.Duration {
+	return time.Since(p.start).Round(time.Second)
+}
+
+// draw renders the progress bar line, p.mu must be held
+func (p *embeddingProgress) draw() {
+	percent := 1.0
+	if p.totalFiles > 0 {
+		percent = float64(p.files) / float64(p.totalFiles)
+	}
+
+	eta := "--"
+	if p.files > 0 && p.files < p.totalFiles {
+		elapsed := time.Since(p.start)
+		remaining := time.Duration(float64(elapsed) / float64(p.files) * float64(p.totalFiles-p.files))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	line := fmt.Sprintf("%s %d/%d files · %d chunks", p.bar.ViewAs(percent), p.files, p.totalFiles, p.chunks)
+	if p.failures > 0 {
+		redStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+		line += redStyle.Render(fmt.Sprintf(" · %d failures", p.failures))
+	}
+	line += " · ETA " + eta
+	fmt.Print("\r\x1b[2K" + line)
+}
+
+// printSummaryTable prints the summary of a run as a two-column table
+func printSummaryTable(rows [][]string) {
+	writeSummaryTable(os.Stdout, rows)
+}
+
+// writeSummaryTable writes the summary table of a run to w
+func writeSummaryTable(w io.Writer, rows [][]string) {
+	headerStyle := lipgloss.NewStyle().Bold(true).Padding(0, 1)
+	cellStyle := lipgloss.NewStyle().Padding(0, 1)
+	summary := table.New().
+		Border(lipgloss.RoundedBorder()).
+		BorderStyle(lipgloss.NewStyle().Foreground(lipgloss.Color("8"))).
+		StyleFunc(func(row, col int) lipgloss.Style {
+			if row == table.HeaderRow {
+				return headerStyle
+			}
+			if col == 1 {
+				return cellStyle.Align(lipgloss.Right)
+			}
+			return cellStyle
+		}).
+		Headers("Summary", "").
+		Rows(rows...)
+	fmt.Fprintln(w, summary.Render())
+}
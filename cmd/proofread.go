@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
+	"github.com/budgies-nest/budgie-cli/pkg/textdiff"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// proofreadTemperature is the operation whose temperature is used for the corrections (temperatures config map)
+const proofreadTemperature = config.TemperatureProofread
+
+// defaultProofreadTemplate is the system prompt of the corrections when the project has no template
+const defaultProofreadTemplate = `You are a careful proofreader. Correct the spelling, grammar, punctuation and typography of the text
+given by the user, in its own language, and fix the clumsy phrasings only when they are wrong or unclear.
+Do not rewrite the style, do not reorder, add nor remove content, and keep the line breaks where they are.
+The formatting is kept as is: Markdown structure, links, front-matter, placeholders, code blocks and inline code.
+Reply with the corrected text only, without notes nor code fences around it.`
+
+// RunProofread handles the proofread command execution: it corrects a file with the model, part by part
+// for the large files, shows the corrections as a diff and writes them to the file after confirmation
+// (or to another file with --output)
+func RunProofread(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	templateFile, _ := cmd.Flags().GetString("template")
+	output, _ := cmd.Flags().GetString("output")
+	yes, _ := cmd.Flags().GetBool("yes")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	partTokens, _ := cmd.Flags().GetInt("chunk-tokens")
+	filePath := args[0]
+
+	if partTokens <= 0 {
+		return fmt.Errorf("--chunk-tokens must be positive (got %d)", partTokens)
+	}
+	if output == "" && !dryRun && !yes && !isTerminal(os.Stdin) {
+		return fmt.Errorf("cannot confirm the corrections: stdin is not a terminal (use --yes to write them, --output to write them to another file or --dry-run to only show them)")
+	}
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	instructions, err := rewriteInstructions(templateFile, cmd.Flags().Changed("template"), defaultProofreadTemplate)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	content, err := sniff.ReadTextFile(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", filePath, err)
+	}
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("%s is empty", filePath)
+	}
+
+	fmt.Printf("🔎 Proofreading %s with %s...\n", filePath, config.Model)
+	proofreadConfig := *config
+	proofreadConfig.Temperature = config.TemperatureFor(proofreadTemperature)
+	system := []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(instructions)}
+	corrected, err := rewriteText(&proofreadConfig, configFile, "proofread", system, content, partTokens)
+	if err != nil {
+		return fmt.Errorf("error proofreading %s: %w", filePath, err)
+	}
+
+	diff := textdiff.Unified("a/"+filePath, "b/"+filePath, content, corrected)
+	if diff == "" {
+		greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2"))
+		fmt.Println(greenStyle.Render(fmt.Sprintf("✅ No corrections in %s", filePath)))
+		return nil
+	}
+	fmt.Println()
+	printDiff(diff)
+	fmt.Println()
+	if dryRun {
+		return nil
+	}
+
+	if output == "" {
+		output = filePath
+		if !yes {
+			confirmed := false
+			err := huh.NewConfirm().
+				Title(fmt.Sprintf("Write the corrections to %s?", filePath)).
+				Affirmative("Write").
+				Negative("Cancel").
+				Value(&confirmed).
+				Run()
+			if err != nil {
+				return fmt.Errorf("error getting confirmation: %w", err)
+			}
+			if !confirmed {
+				fmt.Println("❌ Corrections discarded")
+				return nil
+			}
+		}
+	}
+	if err := os.WriteFile(output, []byte(corrected), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error writing %s: %w", output, err)
+	}
+	fmt.Printf("📝 %s written\n", output)
+	return nil
+}
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/redact"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/packages/param"
+)
+
+// maxShownRedaction is the length above which a masked value is shortened by --show-redactions
+const maxShownRedaction = 60
+
+// shownRedactions are the values already printed by --show-redactions: the conversation is sent again at each
+// turn (and each retry), each value is only shown once
+var shownRedactions = struct {
+	sync.Mutex
+	values map[string]bool
+}{values: map[string]bool{}}
+
+// redactMessages masks the secrets of a conversation with the redact rules of the config before it is sent to
+// the model. The messages given are not modified.
+func redactMessages(config *config.Config, messages []openai.ChatCompletionMessageParamUnion) ([]openai.ChatCompletionMessageParamUnion, error) {
+	redactor, err := redact.New(config.Redact)
+	if err != nil || redactor.Empty() {
+		return messages, err
+	}
+	masker := &messageMasker{redactor: redactor}
+	redacted := make([]openai.ChatCompletionMessageParamUnion, len(messages))
+	for i, message := range messages {
+		redacted[i] = masker.message(message)
+	}
+	showRedactions(config, masker.redactions)
+	return redacted, nil
+}
+
+// redactText masks the secrets of a text sent to the model (e.g. the query of a similarity search)
+func redactText(config *config.Config, text string) (string, error) {
+	redactor, err := redact.New(config.Redact)
+	if err != nil {
+		return text, err
+	}
+	text, redactions := redactor.Redact(text)
+	showRedactions(config, redactions)
+	return text, nil
+}
+
+// showRedactions prints the masked values not shown yet, with ask --show-redactions. They are printed on stderr:
+// the redaction happens deep in the completions, which can print the answer alone on stdout
+func showRedactions(config *config.Config, redactions []redact.Redaction) {
+	if !config.Redact.Show {
+		return
+	}
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	shownRedactions.Lock()
+	defer shownRedactions.Unlock()
+	for _, redaction := range redactions {
+		key := redaction.Rule + "\x00" + redaction.Value
+		if shownRedactions.values[key] {
+			continue
+		}
+		shownRedactions.values[key] = true
+		value := strings.Join(strings.Fields(redaction.Value), " ")
+		if len(value) > maxShownRedaction {
+			value = value[:maxShownRedaction] + "…"
+		}
+		fmt.Fprintln(os.Stderr, dimStyle.Render(fmt.Sprintf("🔒 Redacted (%s): %s", redaction.Rule, value)))
+	}
+}
+
+// messageMasker masks the text contents of the messages, collecting the masked values
+type messageMasker struct {
+	redactor   *redact.Redactor
+	redactions []redact.Redaction
+}
+
+// message returns a copy of a message with its text contents masked
+func (m *messageMasker) message(message openai.ChatCompletionMessageParamUnion) openai.ChatCompletionMessageParamUnion {
+	switch {
+	case message.OfSystem != nil:
+		system := *message.OfSystem
+		system.Content.OfString = m.opt(system.Content.OfString)
+		system.Content.OfArrayOfContentParts = m.textParts(system.Content.OfArrayOfContentParts)
+		message.OfSystem = &system
+	case message.OfDeveloper != nil:
+		developer := *message.OfDeveloper
+		developer.Content.OfString = m.opt(developer.Content.OfString)
+		developer.Content.OfArrayOfContentParts = m.textParts(developer.Content.OfArrayOfContentParts)
+		message.OfDeveloper = &developer
+	case message.OfUser != nil:
+		user := *message.OfUser
+		user.Content.OfString = m.opt(user.Content.OfString)
+		if parts := user.Content.OfArrayOfContentParts; parts != nil {
+			user.Content.OfArrayOfContentParts = make([]openai.ChatCompletionContentPartUnionParam, len(parts))
+			for i, part := range parts {
+				if part.OfText != nil {
+					text := *part.OfText
+					text.Text = m.text(text.Text)
+					part.OfText = &text
+				}
+				user.Content.OfArrayOfContentParts[i] = part
+			}
+		}
+		message.OfUser = &user
+	case message.OfAssistant != nil:
+		assistant := *message.OfAssistant
+		assistant.Content.OfString = m.opt(assistant.Content.OfString)
+		if parts := assistant.Content.OfArrayOfContentParts; parts != nil {
+			assistant.Content.OfArrayOfContentParts = make([]openai.ChatCompletionAssistantMessageParamContentArrayOfContentPartUnion, len(parts))
+			for i, part := range parts {
+				if part.OfText != nil {
+					text := *part.OfText
+					text.Text = m.text(text.Text)
+					part.OfText = &text
+				}
+				assistant.Content.OfArrayOfContentParts[i] = part
+			}
+		}
+		message.OfAssistant = &assistant
+	case message.OfTool != nil:
+		tool := *message.OfTool
+		tool.Content.OfString = m.opt(tool.Content.OfString)
+		tool.Content.OfArrayOfContentParts = m.textParts(tool.Content.OfArrayOfContentParts)
+		message.OfTool = &tool
+	}
+	return message
+}
+
+// text masks a text
+func (m *messageMasker) text(text string) string {
+	text, redactions := m.redactor.Redact(text)
+	m.redactions = append(m.redactions, redactions...)
+	return text
+}
+
+// opt masks an optional text
+func (m *messageMasker) opt(text param.Opt[string]) param.Opt[string] {
+	if !text.Valid() {
+		return text
+	}
+	return openai.String(m.text(text.Value))
+}
+
+// textParts returns a copy of text parts with their text masked
+func (m *messageMasker) textParts(parts []openai.ChatCompletionContentPartTextParam) []openai.ChatCompletionContentPartTextParam {
+	if parts == nil {
+		return nil
+	}
+	masked := make([]openai.ChatCompletionContentPartTextParam, len(parts))
+	for i, part := range parts {
+		part.Text = m.text(part.Text)
+		masked[i] = part
+	}
+	return masked
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie/helpers"
+	"github.com/openai/openai-go"
+)
+
+// Every message of the conversation is masked before it is sent, the history itself keeps the values
+func TestRedactMessages(t *testing.T) {
+	cfg := &config.Config{Redact: config.RedactConfig{Builtins: []string{config.RedactSecrets}}}
+	secret := "DB_PASSWORD=hunter2secret"
+	messages := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage("System " + secret),
+		openai.UserMessage("User " + secret),
+		openai.AssistantMessage("Assistant " + secret),
+		openai.ToolMessage("Tool "+secret, "call-1"),
+		openai.UserMessage([]openai.ChatCompletionContentPartUnionParam{openai.TextContentPart("Part " + secret)}),
+	}
+
+	redacted, err := redactMessages(cfg, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(redacted) != len(messages) {
+		t.Fatalf("%d messages redacted, want %d", len(redacted), len(messages))
+	}
+	for i, message := range redacted {
+		fields, err := helpers.MessageToMap(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content := fields["content"]
+		if content == "" {
+			// The content parts are not flattened by MessageToMap
+			content = message.OfUser.Content.OfArrayOfContentParts[0].OfText.Text
+		}
+		if strings.Contains(content, "hunter2secret") || !strings.Contains(content, "[REDACTED:secrets]") {
+			t.Errorf("message %d = %q, want the password masked", i, content)
+		}
+
+		original, _ := helpers.MessageToMap(messages[i])
+		if original["content"] != "" && !strings.Contains(original["content"], "hunter2secret") {
+			t.Errorf("the original message %d was modified: %q", i, original["content"])
+		}
+	}
+	if text := messages[4].OfUser.Content.OfArrayOfContentParts[0].OfText.Text; !strings.Contains(text, "hunter2secret") {
+		t.Errorf("the original content part was modified: %q", text)
+	}
+}
+
+func TestRedactMessagesWithoutRules(t *testing.T) {
+	messages := []openai.ChatCompletionMessageParamUnion{openai.UserMessage("DB_PASSWORD=hunter2secret")}
+	redacted, err := redactMessages(&config.Config{}, messages)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if &redacted[0] != &messages[0] {
+		t.Error("the conversation was copied without redaction rules")
+	}
+}
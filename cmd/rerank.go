@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/tracing"
+	"github.com/openai/openai-go"
+)
+
+// rerankInstructions asks the model to score the relevance of the retrieved chunks
+const rerankInstructions = `You rate how useful documentation excerpts are to answer a question.
+For each excerpt, give a relevance score from 0 (unrelated) to 10 (answers the question).
+Reply with one line per excerpt, in the form <excerpt number>: <score>, and nothing else.`
+
+// rerankExcerptLength is the maximum number of characters of an excerpt given to the rerank model
+const rerankExcerptLength = 1500
+
+// rerankDefaultK is the number of chunks kept by the rerank stage when top-k is not set
+const rerankDefaultK = 5
+
+// rerankTemperature is the operation whose temperature is used for the rerank stage (temperatures config map)
+const rerankTemperature = config.TemperatureRerank
+
+// rerankScoreRegex matches the "<excerpt number>: <score>" lines of the rerank reply
+var rerankScoreRegex = regexp.MustCompile(`(?m)^\D*?(\d+)\s*[:=\-]\s*(\d+(?:\.\d+)?)`)
+
+// rerankCandidates returns the number of chunks retrieved before the rerank stage
+func rerankCandidates(config *config.Config) int {
+	return max(config.RAG.RerankCandidates, rerankK(config))
+}
+
+// rerankK returns the number of chunks kept by the rerank stage
+func rerankK(config *config.Config) int {
+	if config.TopK > 0 {
+		return config.TopK
+	}
+	return rerankDefaultK
+}
+
+// rerankChunks asks the chat model to score the relevance of the retrieved chunks to the question and keeps
+// the best ones (top-k), ordered by decreasing relevance (ties keep the similarity order). The chunks are
+// returned in similarity order, limited to top-k, when the model fails or its reply cannot be parsed.
+func rerankChunks(config *config.Config, question string, similarities []rag.Similarity) ([]rag.Similarity, error) {
+	k := rerankK(config)
+	if len(similarities) <= 1 {
+		return similarities, nil
+	}
+
+	var excerpts strings.Builder
+	fmt.Fprintf(&excerpts, "Question: %s\n\n", question)
+	for i, similarity := range similarities {
+		content := similarity.Content
+		if runes := []rune(content); len(runes) > rerankExcerptLength {
+			content = string(runes[:rerankExcerptLength]) + "..."
+		}
+		fmt.Fprintf(&excerpts, "Excerpt %d:\n%s\n\n", i+1, content)
+	}
+
+	rerankConfig := *config
+	rerankConfig.Temperature = config.TemperatureFor(rerankTemperature)
+	agent, err := newChatAgent(&rerankConfig, []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(rerankInstructions),
+		openai.UserMessage(excerpts.String()),
+	})
+	if err != nil {
+		return rag.TopK(similarities, k), fmt.Errorf("error reranking the chunks: %w", err)
+	}
+	ctx, span := tracing.Start(context.Background(), "rerank", completionAttributes(&rerankConfig)...)
+	reply, err := agent.ChatCompletion(ctx)
+	tracing.End(span, err)
+	if err != nil {
+		return rag.TopK(similarities, k), fmt.Errorf("error reranking the chunks: %w", err)
+	}
+
+	scores := make(map[int]float64)
+	for _, match := range rerankScoreRegex.FindAllStringSubmatch(reply, -1) {
+		index, _ := strconv.Atoi(match[1])
+		score, _ := strconv.ParseFloat(match[2], 64)
+		if index >= 1 && index <= len(similarities) {
+			scores[index-1] = score
+		}
+	}
+	if len(scores) == 0 {
+		return rag.TopK(similarities, k), fmt.Errorf("error reranking the chunks: unexpected reply %q", firstLine(reply))
+	}
+
+	order := make([]int, len(similarities))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]] > scores[order[j]]
+	})
+	reranked := make([]rag.Similarity, 0, min(k, len(order)))
+	for _, index := range order[:min(k, len(order))] {
+		reranked = append(reranked, similarities[index])
+	}
+	return reranked, nil
+}
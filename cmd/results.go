@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/ledger"
+	"github.com/budgies-nest/budgie-cli/pkg/results"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// recordResult records a result file in the ledger, so budgie results gc only removes the files generated
+// by budgie. A ledger error never fails the command.
+func recordResult(opts askOptions, path string) {
+	absPath, err := filepath.Abs(path)
+	if err == nil {
+		err = ledger.With(ledger.Path(opts.configFile), func(db *ledger.DB) error {
+			return db.AddResult(absPath)
+		})
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: error recording the result file: %v\n", err)
+	}
+}
+
+// RunResultsGC handles the results gc command execution: it removes the old result files recorded in the
+// ledger, and forgets the removed ones
+func RunResultsGC(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	outputPath, _ := cmd.Flags().GetString("output")
+	olderThan, _ := cmd.Flags().GetString("older-than")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	age, err := results.ParseAge(olderThan)
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-age)
+	var removed []string
+	err = ledger.With(ledger.Path(configFile), func(db *ledger.DB) error {
+		recorded, err := db.Results()
+		if err != nil {
+			return err
+		}
+		var paths, gone []string
+		for _, result := range recorded {
+			paths = append(paths, result.Path)
+			// The files deleted by hand are forgotten too
+			if _, err := os.Stat(result.Path); os.IsNotExist(err) {
+				gone = append(gone, result.Path)
+			}
+		}
+		removed, err = results.GC(outputPath, paths, cutoff, dryRun)
+		if dryRun {
+			return err
+		}
+		if forgetErr := db.DeleteResults(append(gone, removed...)...); err == nil {
+			err = forgetErr
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error cleaning up result files: %w", err)
+	}
+
+	if len(removed) == 0 {
+		fmt.Printf("✅ No result files older than %s in %s\n", olderThan, outputPath)
+		return nil
+	}
+
+	greyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	workDir, _ := os.Getwd()
+	for _, path := range removed {
+		// The ledger records absolute paths
+		if rel, err := filepath.Rel(workDir, path); err == nil && filepath.IsLocal(rel) {
+			path = rel
+		}
+		fmt.Println(greyStyle.Render("  " + path))
+	}
+
+	if dryRun {
+		fmt.Printf("🔍 %d result file(s) older than %s would be removed\n", len(removed), olderThan)
+		return nil
+	}
+	fmt.Printf("✅ Removed %d result file(s) older than %s\n", len(removed), olderThan)
+	return nil
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/tracing"
+	"github.com/budgies-nest/budgie/agents"
+	"github.com/openai/openai-go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// continueInstructions asks the model to resume an answer interrupted by a network failure
+const continueInstructions = "Your previous answer was interrupted. Continue it exactly where it stopped, without repeating anything."
+
+// retryableError reports whether a completion failure is transient: rate limiting (429),
+// server errors (5xx), timeouts and connections dropped in the middle of the stream
+func retryableError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// retryDelay returns the exponential backoff delay before the given retry (1 for the first retry)
+func retryDelay(config *config.Config, retry int) time.Duration {
+	return time.Duration(config.RetryBackoff * float64(time.Second) * float64(int(1)<<(retry-1)))
+}
+
+// streamWithRetry streams the completion of the conversation to onToken, retrying the retryable failures
+// up to retry-attempts times with an exponential backoff (onRetry is called before waiting).
+// When the stream fails in the middle of the answer, the next attempt asks the model to continue it,
+// so the tokens already printed are not repeated. It returns the whole answer.
+func streamWithRetry(ctx context.Context, config *config.Config, messages []openai.ChatCompletionMessageParamUnion, onToken func(string), onRetry func(retry int, delay time.Duration, err error)) (answer string, err error) {
+	ctx, span := tracing.Start(ctx, "chat completion", completionAttributes(config)...)
+	defer func() {
+		span.SetAttributes(attribute.Int("budgie.answer.length", len(answer)))
+		tracing.End(span, err)
+	}()
+
+	for attempt := 1; ; attempt++ {
+		turn := messages
+		if answer != "" {
+			turn = append(turn[:len(turn):len(turn)],
+				openai.AssistantMessage(answer),
+				openai.UserMessage(continueInstructions),
+			)
+		}
+
+		agent, err := newChatAgent(config, turn)
+		if err != nil {
+			return answer, err
+		}
+		_, err = agent.ChatCompletionStream(ctx, func(self *agents.Agent, content string, err error) error {
+			if err != nil {
+				return err
+			}
+			if answer == "" {
+				span.AddEvent("first token")
+			}
+			answer += content
+			onToken(content)
+			return nil
+		})
+		if err == nil || attempt >= config.RetryAttempts || !retryableError(err) {
+			return answer, err
+		}
+
+		delay := retryDelay(config, attempt)
+		span.AddEvent("retry", trace.WithAttributes(attribute.Int("budgie.attempt", attempt+1), attribute.String("error", err.Error())))
+		onRetry(attempt, delay, err)
+		select {
+		case <-ctx.Done():
+			return answer, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// completionAttributes returns the tracing attributes of a chat completion (OpenTelemetry GenAI conventions)
+func completionAttributes(config *config.Config) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.system", config.Provider),
+		attribute.String("gen_ai.request.model", config.Model),
+		attribute.Float64("gen_ai.request.temperature", config.Temperature),
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+)
+
+// defaultRewriteTokens is the size of the parts of a rewritten file: the answer is as long as the part,
+// it must fit in the completion tokens of the model
+const defaultRewriteTokens = 2000
+
+// rewriteInstructions returns the system prompt of a rewriting command: the template file, or the
+// built-in template when the default file does not exist
+func rewriteInstructions(templateFile string, explicit bool, builtin string) (string, error) {
+	content, err := os.ReadFile(templateFile)
+	switch {
+	case err == nil:
+		return string(content), nil
+	case explicit || !os.IsNotExist(err):
+		return "", fmt.Errorf("error reading template file: %w", err)
+	}
+	return builtin, nil
+}
+
+// rewriteText rewrites a text with the system messages, part by part when it is larger than partTokens.
+// The blank lines around each part are kept as is, so the parts join like the original text.
+func rewriteText(config *config.Config, configFile, command string, system []openai.ChatCompletionMessageParamUnion, content string, partTokens int) (string, error) {
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	parts := tokens.Split(content, partTokens)
+	var rewritten strings.Builder
+	for i, part := range parts {
+		core := strings.TrimSpace(part)
+		if core == "" {
+			rewritten.WriteString(part)
+			continue
+		}
+		start := time.Now()
+		answer, usage, _, err := completeWithRetry(config, append(slices.Clone(system), openai.UserMessage(core)))
+		if err != nil {
+			if len(parts) > 1 {
+				return "", fmt.Errorf("error rewriting part %d/%d: %w", i+1, len(parts), err)
+			}
+			return "", err
+		}
+		recordUsage(config, askOptions{configFile: configFile}, command, usage, time.Since(start), false)
+
+		leading := part[:strings.Index(part, core)]
+		trailing := part[len(leading)+len(core):]
+		rewritten.WriteString(leading + unwrapFence(answer, core) + trailing)
+		if len(parts) > 1 {
+			fmt.Printf("✅ Part %d/%d %s\n", i+1, len(parts), dimStyle.Render(fmt.Sprintf("(%d tokens, %s)", tokens.Estimate(core), time.Since(start).Round(10*time.Millisecond))))
+		}
+	}
+	return rewritten.String(), nil
+}
+
+// unwrapFence removes the code fence the models sometimes wrap the rewritten text with (unless the
+// original text was a code block itself)
+func unwrapFence(answer, original string) string {
+	answer = strings.TrimSpace(answer)
+	if !strings.HasPrefix(answer, "```") || strings.HasPrefix(original, "```") || !strings.HasSuffix(answer, "```") {
+		return answer
+	}
+	newline := strings.Index(answer, "\n")
+	if newline < 0 {
+		return answer
+	}
+	return strings.TrimSpace(strings.TrimSuffix(answer[newline+1:], "```"))
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/charmbracelet/huh"
+)
+
+// errRunCancelled is returned when the user refuses to run a /run command
+var errRunCancelled = errors.New("command cancelled")
+
+// runCommand runs a shell command line for the /run interactive command, after the user confirmed it, and
+// returns its combined stdout and stderr formatted as a context message for the conversation.
+// A command exiting with a non-zero status is not an error: its output is usually what the user wants explained.
+func runCommand(config *config.Config, commandLine string) (string, error) {
+	if !isTerminal(os.Stdin) {
+		return "", fmt.Errorf("cannot confirm the command: stdin is not a terminal")
+	}
+	confirmed := false
+	err := huh.NewConfirm().
+		Title(fmt.Sprintf("Run `%s`?", commandLine)).
+		Description("The output of the command is added to the conversation").
+		Affirmative("Run").
+		Negative("Cancel").
+		Value(&confirmed).
+		Run()
+	if err != nil {
+		return "", fmt.Errorf("error getting confirmation: %w", err)
+	}
+	if !confirmed {
+		return "", errRunCancelled
+	}
+
+	output, status, err := runShell(commandLine)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Print(output)
+	if output != "" && !strings.HasSuffix(output, "\n") {
+		fmt.Println()
+	}
+	fmt.Printf("🐚 %s\n", status)
+
+	content, err := limitAttachment(config, "The command output", strings.TrimRight(output, "\n"), true, os.Stdout)
+	if err != nil {
+		return "", err
+	}
+	if content == "" {
+		content = "(no output)"
+	}
+	return fmt.Sprintf("Output of the shell command `%s` (%s):\n\n```\n%s\n```", commandLine, status, content), nil
+}
+
+// runShell runs a command line with the shell and returns its combined stdout and stderr and its exit status
+func runShell(commandLine string) (string, string, error) {
+	var output bytes.Buffer
+	command := shellCommand(commandLine)
+	command.Stdout = &output
+	command.Stderr = &output
+	status := "exit status 0"
+	if err := command.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return "", "", fmt.Errorf("error running the command: %w", err)
+		}
+		status = exitErr.Error()
+	}
+	return output.String(), status, nil
+}
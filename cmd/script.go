@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/script"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+)
+
+// runScript runs the turns of a conversation file (--script) one after the other in the same conversation,
+// then writes the transcript of the run. The turns never prompt: the large attachments are truncated.
+// When a turn fails, the transcript of the turns answered so far is still written.
+func runScript(opts askOptions, path string) error {
+	conversation, err := script.Load(path)
+	if err != nil {
+		return fmt.Errorf("error loading script: %w", err)
+	}
+	if len(conversation.System) > 0 {
+		opts.systemFiles = conversation.System
+	}
+
+	config, err := loadAskConfig(opts)
+	if err != nil {
+		return err
+	}
+	opts.useContent, err = loadUseFile(config, opts, false)
+	if err != nil {
+		return err
+	}
+	if opts.contextContent, err = loadContextPack(config, opts); err != nil {
+		return err
+	}
+	if opts.urlContents, err = loadURLPages(config, opts, false); err != nil {
+		return err
+	}
+	if opts.diff != "" {
+		if opts.diffContent, err = loadDiff(config, opts.diff, false, opts.logWriter()); err != nil {
+			return err
+		}
+	}
+	messages, err := baseMessages(opts)
+	if err != nil {
+		return err
+	}
+
+	// The answers go to the transcript rather than to one result file each
+	generate := opts.generate || conversation.Transcript != ""
+	opts.generate, opts.appendFile = false, ""
+
+	var transcript strings.Builder
+	transcript.WriteString("# " + conversation.Title + "\n\n")
+
+	boldStyle := lipgloss.NewStyle().Bold(true)
+	var runErr error
+	answered := 0
+	for i, turn := range conversation.Turns {
+		if !opts.quiet {
+			fmt.Println(boldStyle.Render(fmt.Sprintf("💬 Turn %d/%d: %s", i+1, len(conversation.Turns), entryTitle(turn.Question))))
+		}
+
+		// The files of the turn stay in the conversation, like /use
+		for _, file := range turn.Use {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				runErr = fmt.Errorf("turn %d: error reading use file %s: %w", i+1, file, err)
+				break
+			}
+			limited, err := limitAttachment(config, file, string(content), false, opts.logWriter())
+			if err != nil {
+				runErr = fmt.Errorf("turn %d: %w", i+1, err)
+				break
+			}
+			messages = append(messages, openai.SystemMessage(limited))
+		}
+		if runErr != nil {
+			break
+		}
+
+		opts.ragEnabled = conversation.UsesRAG(turn)
+		var answer string
+		messages, answer, err = scriptTurn(config, opts, messages, turn.Question)
+		if err != nil {
+			runErr = fmt.Errorf("turn %d: %w", i+1, err)
+			break
+		}
+		transcript.WriteString(transcriptEntry(time.Now(), turn.Question, answer))
+		answered++
+		fmt.Println()
+	}
+
+	if !generate || answered == 0 {
+		return runErr
+	}
+	transcriptPath := conversation.Transcript
+	if transcriptPath == "" {
+		opts.question = conversation.Title
+		if transcriptPath, err = resultPath(config, opts, time.Now()); err != nil {
+			return fmt.Errorf("error saving the transcript: %w", err)
+		}
+	} else if err := os.MkdirAll(filepath.Dir(transcriptPath), 0755); err != nil {
+		return fmt.Errorf("error saving the transcript: %w", err)
+	}
+	if err := os.WriteFile(transcriptPath, []byte(strings.TrimRight(transcript.String(), "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("error saving the transcript: %w", err)
+	}
+	if !opts.quiet {
+		blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+		fmt.Println(blueStyle.Render(fmt.Sprintf("📜 Transcript saved to: %s", transcriptPath)))
+	}
+	return runErr
+}
+
+// scriptTurn asks a question of a script in the conversation and returns the conversation followed by
+// the exchange (with its RAG context, like in interactive mode), and the answer
+func scriptTurn(config *config.Config, opts askOptions, messages []openai.ChatCompletionMessageParamUnion, question string) ([]openai.ChatCompletionMessageParamUnion, string, error) {
+	if !opts.offline {
+		compacted, count, err := compactHistory(config, messages)
+		if err != nil {
+			fmt.Printf("Warning: Error compacting the conversation history: %v\n", err)
+		} else if count > 0 {
+			messages = compacted
+		}
+	}
+
+	question, err := limitAttachment(config, "Question", question, false, opts.logWriter())
+	if err != nil {
+		return messages, "", err
+	}
+	actualQuestion, similarities, err := searchContext(config, opts, question)
+	if err != nil {
+		return messages, "", err
+	}
+
+	turn := slices.Clone(messages)
+	if len(similarities) > 0 {
+		turn = append(turn, openai.SystemMessage(ragContextHeader+strings.Join(rag.Contents(similarities), "\n\n")))
+	}
+	if opts.calibrate && ragRequested(opts, question) {
+		turn = append(turn, openai.SystemMessage(calibrationMessage(config, similarities, opts.logWriter())))
+	}
+	turn = append(turn, openai.UserMessage(actualQuestion))
+	opts.question, opts.sources = actualQuestion, similarities
+
+	var answer string
+	start := time.Now()
+	if opts.offline {
+		err = errOffline
+	} else {
+		if opts.tools != nil {
+			if turn, err = opts.tools.callTools(config, turn); err != nil {
+				return messages, "", err
+			}
+		}
+		answer, _, err = streamCompletion(config, turn, opts)
+		if err == nil {
+			recordUsage(config, opts, "ask", nil, time.Since(start), true)
+			recordHistory(config, opts, "ask", actualQuestion, answer, ragRequested(opts, question))
+		}
+	}
+
+	// Fall back to an extractive answer when the chat backend is unreachable
+	if opts.offline || backendUnreachable(err) {
+		if extract, _, ok := offlineAnswer(config, opts, question, similarities); ok {
+			answer, err = extract, nil
+			fmt.Println(answer)
+		}
+	}
+	if err != nil {
+		return messages, "", err
+	}
+	printSources(opts, similarities)
+
+	return append(turn, openai.AssistantMessage(answer)), answer, nil
+}
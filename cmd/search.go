@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/glossary"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// RunSearch handles the search command execution
+func RunSearch(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	question, _ := cmd.Flags().GetString("question")
+	topK, _ := cmd.Flags().GetInt("top-k")
+	keywordWeight, _ := cmd.Flags().GetFloat64("keyword-weight")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+	sameLanguage, _ := cmd.Flags().GetBool("same-language")
+	filters, _ := cmd.Flags().GetStringArray("filter")
+
+	// The question can also be given as arguments
+	if question == "" {
+		question = strings.Join(args, " ")
+	}
+	if question == "" {
+		return fmt.Errorf("question is required (either via -q flag or as arguments)")
+	}
+	if keywordWeight < 0 || keywordWeight > 1 {
+		return fmt.Errorf("--keyword-weight must be between 0 and 1 (got %g)", keywordWeight)
+	}
+	conditions, err := rag.ParseMetadataFilters(filters)
+	if err != nil {
+		return err
+	}
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	if topK > 0 {
+		config.TopK = topK
+	}
+	if keywordWeight > 0 {
+		config.KeywordWeight = keywordWeight
+	}
+	if sameLanguage {
+		config.SameLanguage = true
+	}
+
+	// Similarity scores are meaningless when the embedding model changed
+	useRAG, err := checkEmbeddingModel(config, askOptions{configFile: configFile, embeddingsFile: embeddingsFile, overrides: configOverrides(cmd)})
+	if err != nil {
+		return err
+	}
+	if !useRAG {
+		return nil
+	}
+
+	// Expand project-specific terms with their glossary definitions
+	entries, err := glossary.Load(glossaryPath(configFile))
+	if err != nil {
+		return fmt.Errorf("error reading glossary file: %w", err)
+	}
+	searchQuery := glossary.Expand(question, entries)
+
+	var similarities []rag.Similarity
+	for _, storePath := range ragStorePaths(configFile, embeddingsFile) {
+		// The embedding model of the docs store was checked by checkEmbeddingModel
+		searchAgent, err := rag.CreateSearchAgent(config, storePath, storePath == embeddingsFile)
+		if err != nil {
+			return err
+		}
+		var filter rag.Filter
+		if config.SameLanguage {
+			filter = rag.LanguageFilter(storePath, question)
+		}
+		metadataFilter, err := rag.MetadataFilter(storePath, conditions)
+		if err != nil {
+			rag.CloseStore(searchAgent)
+			return fmt.Errorf("error reading the chunk metadata: %w", err)
+		}
+		found, err := rag.SearchSimilarities(searchQuery, searchAgent, config, rag.AllFilters(filter, metadataFilter))
+		rag.CloseStore(searchAgent)
+		if err != nil {
+			return err
+		}
+		similarities = append(similarities, found...)
+	}
+
+	similarities = rag.TopK(similarities, config.TopK)
+
+	if jsonOutput {
+		if similarities == nil {
+			similarities = []rag.Similarity{}
+		}
+		data, err := json.MarshalIndent(similarities, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error encoding results: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if len(similarities) == 0 {
+		fmt.Println("📚 No relevant documentation found")
+		return nil
+	}
+
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	contentStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+
+	for i, similarity := range similarities {
+		fmt.Println(greenStyle.Render(fmt.Sprintf("%d. [%.4f] %s", i+1, similarity.Score, similarity.ID)))
+		for _, line := range strings.Split(strings.TrimSpace(similarity.Content), "\n") {
+			fmt.Printf("   %s\n", contentStyle.Render(line))
+		}
+		fmt.Println()
+	}
+
+	return nil
+}
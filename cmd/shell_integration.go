@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// RunShellIntegration handles the shell-integration command execution:
+// it prints the quick-ask widget script of the requested (or current) shell
+func RunShellIntegration(cmd *cobra.Command, args []string, zshScript, bashScript string) error {
+	shell := filepath.Base(os.Getenv("SHELL"))
+	if len(args) > 0 {
+		shell = args[0]
+	}
+
+	switch shell {
+	case "zsh":
+		fmt.Print(zshScript)
+	case "bash":
+		fmt.Print(bashScript)
+	default:
+		return fmt.Errorf("unsupported shell %q (supported: zsh, bash)", shell)
+	}
+	return nil
+}
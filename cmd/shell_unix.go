@@ -0,0 +1,17 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"os"
+	"os/exec"
+)
+
+// shellCommand returns the command running a command line with the user's shell (sh when SHELL is not set)
+func shellCommand(commandLine string) *exec.Cmd {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "sh"
+	}
+	return exec.Command(shell, "-c", commandLine)
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package cmd
+
+import "os/exec"
+
+// shellCommand returns the command running a command line with cmd.exe
+func shellCommand(commandLine string) *exec.Cmd {
+	return exec.Command("cmd", "/C", commandLine)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/budgies-nest/budgie-cli/pkg/results"
+)
+
+// snapshotsDir is the folder (next to the config file) holding the system prompt and config snapshots
+const snapshotsDir = "snapshots"
+
+// resultMetadata describes the conditions of an answer: the question and its RAG sources, the model, the
+// system prompt and the effective config (hashed, with a snapshot saved in .budgie/snapshots), the version
+// of the embeddings store (hash of its manifest) and the hash of the --use attachment
+func resultMetadata(config *config.Config, opts askOptions, now time.Time) results.Metadata {
+	metadata := results.Metadata{
+		Date:           now,
+		Provider:       config.Provider,
+		Model:          config.Model,
+		Profile:        config.ActiveProfile,
+		Temperature:    config.Temperature,
+		Seed:           config.Seed,
+		EmbeddingModel: config.EmbeddingModel,
+		Question:       opts.question,
+		Sources:        rag.IDs(opts.sources),
+	}
+	dir := filepath.Join(filepath.Dir(opts.configFile), snapshotsDir)
+
+	if systemInstructions, err := readSystemInstructions(opts.systemFiles); err == nil {
+		metadata.SystemPrompt = results.Hash([]byte(systemInstructions))
+		if metadata.SystemPromptSnapshot, err = results.SaveSnapshot(dir, "system", ".md", []byte(systemInstructions)); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: error saving the system prompt snapshot: %v\n", err)
+		}
+	}
+
+	// The effective config includes the profile, the environment variables and the command line overrides
+	if effective, err := json.MarshalIndent(config, "", "  "); err == nil {
+		metadata.Config = results.Hash(effective)
+		if metadata.ConfigSnapshot, err = results.SaveSnapshot(dir, "config", ".json", effective); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: error saving the config snapshot: %v\n", err)
+		}
+	}
+
+	if opts.embeddingsFile != "" {
+		if manifest, err := os.ReadFile(rag.ManifestPath(opts.embeddingsFile)); err == nil {
+			metadata.Embeddings = results.Hash(manifest)
+		}
+	}
+
+	// The --context pack, the --url pages and the --use file
+	if attachments := opts.contextContent + strings.Join(opts.urlContents, "") + opts.useContent; attachments != "" {
+		metadata.Attachment = results.Hash([]byte(attachments))
+	}
+	return metadata
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// setSources records the source of the chunks found in a store: their file (see chunkSources), or the
+// URL of the web page they come from
+func setSources(storePath string, similarities []rag.Similarity) {
+	manifest, err := rag.LoadManifest(rag.ManifestPath(storePath))
+	if err != nil {
+		manifest = &rag.Manifest{}
+	}
+	source := chunkSources(manifest)
+	metadata := manifest.ChunkMetadata()
+	for i := range similarities {
+		similarities[i].Source = source(similarities[i].ID)
+		if url := metadata[similarities[i].ID].URL; url != "" {
+			similarities[i].Source = url
+		}
+	}
+}
+
+// citation is a source file of an answer and the ids of its chunks given as context
+type citation struct {
+	source string
+	ids    []string
+}
+
+// citations groups the chunks given as context by source file, in the order of their best score
+func citations(similarities []rag.Similarity) []citation {
+	var cited []citation
+	index := make(map[string]int)
+	for _, similarity := range similarities {
+		source := similarity.Source
+		if source == "" {
+			source = similarity.ID
+		}
+		i, ok := index[source]
+		if !ok {
+			i = len(cited)
+			index[source] = i
+			cited = append(cited, citation{source: source})
+		}
+		cited[i].ids = append(cited[i].ids, similarity.ID)
+	}
+	return cited
+}
+
+// sourcesSection returns the Markdown section listing the sources of an answer, appended to its result
+// file under the given heading ("" without RAG context)
+func sourcesSection(heading string, similarities []rag.Similarity) string {
+	if len(similarities) == 0 {
+		return ""
+	}
+	var builder strings.Builder
+	builder.WriteString("\n\n" + heading + " Sources\n")
+	for _, cited := range citations(similarities) {
+		fmt.Fprintf(&builder, "\n- `%s`: %s", cited.source, strings.Join(cited.ids, ", "))
+	}
+	return builder.String()
+}
+
+// printSources prints the sources of an answer after it streamed (unless --quiet), on the log writer so
+// the sources are not mixed with a JSON answer
+func printSources(opts askOptions, similarities []rag.Similarity) {
+	if opts.quiet || len(similarities) == 0 {
+		return
+	}
+	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	log := opts.logWriter()
+	fmt.Fprintln(log)
+	fmt.Fprintln(log, greenStyle.Render("📎 Sources:"))
+	for i, cited := range citations(similarities) {
+		fmt.Fprintf(log, "  [%d] %s %s\n", i+1, cited.source, dimStyle.Render("("+strings.Join(cited.ids, ", ")+")"))
+	}
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+)
+
+func TestCitations(t *testing.T) {
+	similarities := []rag.Similarity{
+		{ID: "install.md#chunk-2", Source: "docs/install.md"},
+		{ID: "usage.md#chunk-1", Source: "docs/usage.md"},
+		{ID: "install.md#chunk-1", Source: "docs/install.md"},
+		{ID: "orphan#chunk-1"},
+	}
+	var got []string
+	for _, cited := range citations(similarities) {
+		got = append(got, cited.source+" "+strings.Join(cited.ids, ","))
+	}
+	want := []string{"docs/install.md install.md#chunk-2,install.md#chunk-1", "docs/usage.md usage.md#chunk-1", "orphan#chunk-1 orphan#chunk-1"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("citations = %q, want %q", got, want)
+	}
+}
+
+// The sources are diagnostics: they are printed on the log writer, never in the answer output
+func TestPrintSources(t *testing.T) {
+	similarities := []rag.Similarity{{ID: "install.md#chunk-1", Source: "docs/install.md"}}
+	var out, log bytes.Buffer
+	printSources(askOptions{out: &out, log: &log}, similarities)
+	if out.Len() > 0 {
+		t.Errorf("the sources were printed on the output: %q", out.String())
+	}
+	if !strings.Contains(log.String(), "docs/install.md") {
+		t.Errorf("log = %q, want the sources", log.String())
+	}
+
+	log.Reset()
+	printSources(askOptions{out: &out, log: &log, quiet: true}, similarities)
+	if log.Len() > 0 || out.Len() > 0 {
+		t.Error("the sources were printed with --quiet")
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/ledger"
+	"github.com/budgies-nest/budgie-cli/pkg/results"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/cobra"
+)
+
+// modelStats aggregates the usage records of a model
+type modelStats struct {
+	Model            string `json:"model"`
+	Requests         int    `json:"requests"`
+	PromptTokens     int64  `json:"prompt_tokens"`
+	CompletionTokens int64  `json:"completion_tokens"`
+	TotalTokens      int64  `json:"total_tokens"`
+	AverageLatencyMs int64  `json:"average_latency_ms"`
+	GoodFeedback     int    `json:"good_feedback"`
+	BadFeedback      int    `json:"bad_feedback"`
+	Questions        int    `json:"questions"`
+	ReusedAnswers    int    `json:"reused_answers"`
+}
+
+// recordUsage appends a completion request to the usage ledger (usage is nil when the provider did not report it).
+// A ledger error never fails the command: it is only reported as a warning when warn is true.
+func recordUsage(config *config.Config, opts askOptions, command string, usage *tokenUsage, latency time.Duration, warn bool) {
+	record := ledger.Usage{
+		Time:      time.Now(),
+		Command:   command,
+		Provider:  config.Provider,
+		Model:     config.Model,
+		LatencyMs: latency.Milliseconds(),
+	}
+	if usage != nil {
+		record.PromptTokens = usage.PromptTokens
+		record.CompletionTokens = usage.CompletionTokens
+		record.TotalTokens = usage.TotalTokens
+	}
+
+	err := ledger.With(ledger.Path(opts.configFile), func(db *ledger.DB) error {
+		return db.AddUsage(record)
+	})
+	if err != nil && warn {
+		fmt.Fprintf(os.Stderr, "Warning: error recording usage: %v\n", err)
+	}
+}
+
+// recordFeedback appends the user rating of an answer to the feedback ledger
+func recordFeedback(config *config.Config, opts askOptions, rating, comment, question, answer string) error {
+	return ledger.With(ledger.Path(opts.configFile), func(db *ledger.DB) error {
+		return db.AddFeedback(ledger.Feedback{
+			Time:     time.Now(),
+			Model:    config.Model,
+			Rating:   rating,
+			Comment:  comment,
+			Question: question,
+			Answer:   answer,
+		})
+	})
+}
+
+// RunStats handles the stats command execution: it aggregates the usage and feedback ledger per model
+func RunStats(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	jsonOutput, _ := cmd.Flags().GetBool("json")
+
+	var since time.Time
+	if sinceFlag != "" {
+		age, err := results.ParseAge(sinceFlag)
+		if err != nil {
+			return err
+		}
+		since = time.Now().Add(-age)
+	}
+
+	var usages []ledger.Usage
+	var feedbacks []ledger.Feedback
+	var questions []ledger.Question
+	err := ledger.With(ledger.Path(configFile), func(db *ledger.DB) error {
+		var err error
+		if usages, err = db.Usages(since); err != nil {
+			return err
+		}
+		if feedbacks, err = db.Feedbacks(since); err != nil {
+			return err
+		}
+		questions, err = db.Questions(since)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error reading the usage ledger: %w", err)
+	}
+
+	byModel := make(map[string]*modelStats)
+	get := func(model string) *modelStats {
+		if byModel[model] == nil {
+			byModel[model] = &modelStats{Model: model}
+		}
+		return byModel[model]
+	}
+	var latencies = make(map[string]int64)
+	for _, usage := range usages {
+		stats := get(usage.Model)
+		stats.Requests++
+		stats.PromptTokens += usage.PromptTokens
+		stats.CompletionTokens += usage.CompletionTokens
+		stats.TotalTokens += usage.TotalTokens
+		latencies[usage.Model] += usage.LatencyMs
+	}
+	for _, feedback := range feedbacks {
+		stats := get(feedback.Model)
+		if feedback.Rating == "good" {
+			stats.GoodFeedback++
+		} else {
+			stats.BadFeedback++
+		}
+	}
+
+	// Questions answered by the model, or with the answer of a similar previous question
+	for _, question := range questions {
+		stats := get(question.Model)
+		if question.Reused {
+			stats.ReusedAnswers++
+		} else {
+			stats.Questions++
+		}
+	}
+
+	all := make([]modelStats, 0, len(byModel))
+	for model, stats := range byModel {
+		if stats.Requests > 0 {
+			stats.AverageLatencyMs = latencies[model] / int64(stats.Requests)
+		}
+		all = append(all, *stats)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Requests > all[j].Requests })
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(all)
+	}
+
+	if len(all) == 0 {
+		fmt.Println("📊 No usage recorded yet")
+		return nil
+	}
+
+	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
+	greyStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	fmt.Println(headerStyle.Render(fmt.Sprintf("📊 Usage of %d request(s)", len(usages))))
+	fmt.Println()
+	for _, stats := range all {
+		fmt.Println(headerStyle.Render(stats.Model))
+		fmt.Printf("  Requests:          %d\n", stats.Requests)
+		fmt.Printf("  Tokens:            %d (prompt: %d, completion: %d)\n", stats.TotalTokens, stats.PromptTokens, stats.CompletionTokens)
+		fmt.Printf("  Average latency:   %d ms\n", stats.AverageLatencyMs)
+		fmt.Printf("  Feedback:          👍 %d  👎 %d\n", stats.GoodFeedback, stats.BadFeedback)
+		if stats.ReusedAnswers > 0 {
+			fmt.Printf("  Questions:         %d (%d answered with a previous answer)\n", stats.Questions+stats.ReusedAnswers, stats.ReusedAnswers)
+		} else {
+			fmt.Printf("  Questions:         %d\n", stats.Questions)
+		}
+		fmt.Println()
+	}
+	fmt.Println(greyStyle.Render("Token counts only include the requests whose provider reported them (e.g. --format json)."))
+	return nil
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// readPipedInput returns the content piped to budgie (e.g. `cat error.log | budgie ask ...`).
+// It returns an empty string when stdin is a terminal, or is not a pipe or a redirected file.
+func readPipedInput() (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", nil
+	}
+
+	mode := info.Mode()
+	if mode&os.ModeNamedPipe == 0 && !mode.IsRegular() {
+		return "", nil
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return "", fmt.Errorf("error reading piped input: %w", err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// pipedContextMessage wraps the piped content so the model can tell it apart from the question
+func pipedContextMessage(content string) string {
+	return "Content piped from stdin:\n\n```\n" + content + "\n```"
+}
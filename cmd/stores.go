@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// storesDir is the directory of the named vector stores (knowledge bases), next to the config file
+const storesDir = "stores"
+
+// storeNameRegex matches the valid store names (they are directory names)
+var storeNameRegex = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// storeEmbeddingsPath returns the embeddings path of a named store: .budgie/stores/<name>/embeddings.json
+func storeEmbeddingsPath(configFile, name string) (string, error) {
+	if !storeNameRegex.MatchString(name) {
+		return "", fmt.Errorf("invalid store name %q (letters, digits, '.', '-' and '_')", name)
+	}
+	return filepath.Join(filepath.Dir(configFile), storesDir, name, "embeddings.json"), nil
+}
+
+// storeNames returns the names of the named stores of the project, sorted
+func storeNames(configFile string) []string {
+	entries, err := os.ReadDir(filepath.Join(filepath.Dir(configFile), storesDir))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() && storeNameRegex.MatchString(entry.Name()) {
+			names = append(names, entry.Name())
+		}
+	}
+	return names
+}
+
+// withStore scopes the retrieval to a named store (ask --store or #rag:<store>): its embeddings are searched
+// instead of the docs store, and the code collection is not searched. The stores of the project
+// (.budgie/stores/<name>) win over the shared stores of the config.
+func withStore(config *config.Config, opts askOptions, name string) (askOptions, error) {
+	path, err := storeEmbeddingsPath(opts.configFile, name)
+	if err != nil {
+		return opts, err
+	}
+	if _, err := os.Stat(filepath.Dir(path)); err != nil {
+		location, shared := config.Stores[name]
+		if !shared {
+			known := "none"
+			if names := append(storeNames(opts.configFile), sharedStoreNames(config)...); len(names) > 0 {
+				known = strings.Join(names, ", ")
+			}
+			return opts, fmt.Errorf("unknown store %q (stores: %s), generate it with budgie generate-embeddings --store %s", name, known, name)
+		}
+		if path, err = sharedStorePath(name, location, opts.logWriter()); err != nil {
+			return opts, err
+		}
+	}
+	opts.store, opts.embeddingsFile = name, path
+	return opts, nil
+}
+
+// sharedStoreNames returns the names of the shared stores of the config, sorted
+func sharedStoreNames(config *config.Config) []string {
+	return slices.Sorted(maps.Keys(config.Stores))
+}
+
+// fetchedStores are the remote stores already fetched by the process (URL -> cached embeddings): an
+// interactive session only checks them for updates once
+var fetchedStores = struct {
+	sync.Mutex
+	paths map[string]string
+}{paths: map[string]string{}}
+
+// sharedStorePath returns the embeddings path of a shared store: the embeddings file of a path (or the
+// embeddings.json file of a directory), or the cached copy of a URL, downloaded when it changed
+// (a failed download is reported on out)
+func sharedStorePath(name, location string, out io.Writer) (string, error) {
+	if !config.IsStoreURL(location) {
+		if info, err := os.Stat(location); err == nil && info.IsDir() {
+			location = filepath.Join(location, "embeddings.json")
+		}
+		if _, err := os.Stat(filepath.Dir(location)); err != nil {
+			return "", fmt.Errorf("shared store %s not found: %w", name, err)
+		}
+		return location, nil
+	}
+
+	fetchedStores.Lock()
+	defer fetchedStores.Unlock()
+	if path, ok := fetchedStores.paths[location]; ok {
+		return path, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating the store cache: %w", err)
+	}
+	// The cache of a store is named after its URL too: the projects may give the same name to other stores
+	digest := sha256.Sum256([]byte(location))
+	dir := filepath.Join(cacheDir, "budgie", storesDir, fmt.Sprintf("%s-%x", name, digest[:4]))
+	path, err := rag.FetchStore(location, dir)
+	if err != nil {
+		cached := filepath.Join(dir, "embeddings.json")
+		if _, statErr := os.Stat(cached); statErr != nil {
+			return "", err
+		}
+		yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+		fmt.Fprintln(out, yellowStyle.Render(fmt.Sprintf("⚠️  %v: using the cached copy of the %s store", err, name)))
+		path = cached
+	}
+	fetchedStores.paths[location] = path
+	return path, nil
+}
+
+// fetchedStore reports whether an embeddings file is the cached copy of a remote store
+func fetchedStore(path string) bool {
+	fetchedStores.Lock()
+	defer fetchedStores.Unlock()
+	return slices.Contains(slices.Collect(maps.Values(fetchedStores.paths)), path)
+}
+
+// ragPrefix splits the #rag prefix of a question: "#rag <question>" searches the stores of the project,
+// "#rag:<store> <question>" only a named store. It returns the store ("" with #rag), the question without
+// its prefix, and whether the question had one.
+func ragPrefix(question string) (string, string, bool) {
+	if rest, ok := strings.CutPrefix(question, "#rag "); ok {
+		return "", rest, true
+	}
+	if rest, ok := strings.CutPrefix(question, "#rag:"); ok {
+		if store, text, found := strings.Cut(rest, " "); found && store != "" {
+			return store, text, true
+		}
+	}
+	return "", question, false
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/structured"
+	"github.com/openai/openai-go"
+)
+
+// completeStructured runs a non-streaming completion constrained by a JSON schema (response_format, for
+// the providers supporting constrained decoding) and validates the answer against the schema. An invalid
+// answer is sent back to the model with the validation errors, up to retries times. It returns the
+// validated JSON and the usage of all the attempts.
+func completeStructured(config *config.Config, messages []openai.ChatCompletionMessageParamUnion, schema *structured.Schema, retries int) (string, *tokenUsage, error) {
+	format := openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
+			JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   schema.Name,
+				Schema: schema.Document,
+			},
+		},
+	}
+	messages = slices.Clone(messages)
+	total := &tokenUsage{}
+	for attempt := 1; ; attempt++ {
+		answer, usage, err := completeWithFormat(config, messages, format)
+		if err != nil {
+			return "", nil, err
+		}
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+		total.TotalTokens += usage.TotalTokens
+
+		validated, err := schema.Validate(answer)
+		if err == nil {
+			return validated, total, nil
+		}
+		if attempt > retries {
+			return "", total, fmt.Errorf("the answer does not match the JSON schema after %d attempt(s): %w", attempt, err)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: the answer does not match the JSON schema (attempt %d), asking again: %v\n", attempt, err)
+		messages = append(messages,
+			openai.AssistantMessage(answer),
+			openai.UserMessage(fmt.Sprintf("This answer does not match the JSON schema:\n\n%v\n\nAnswer again with only the corrected JSON.", err)),
+		)
+	}
+}
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/document"
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// defaultSummarizeTokens is the size of the parts when the attachment token limit is disabled
+const defaultSummarizeTokens = 32000
+
+// summarizePartInstructions asks the model to summarize a part of a file (the map step)
+const summarizePartInstructions = `You summarize a part of a larger document, the other parts are summarized separately.
+Keep the facts, decisions, names, figures and code identifiers, in the order of the text. Do not add an introduction
+nor a conclusion. Reply with the summary only, as Markdown.`
+
+// summarizeCombineInstructions asks the model to consolidate the summaries of the parts (the reduce step)
+const summarizeCombineInstructions = `You consolidate the summaries of the parts of one or more documents into a single summary.
+Merge the repetitions, keep the facts, decisions, names, figures and code identifiers, and organize the summary
+by topic. Reply with the summary only, as Markdown.`
+
+// summarizedFile is a text file to summarize
+type summarizedFile struct {
+	path    string
+	content string
+}
+
+// summaryPart is a part of a file summarized by its own completion
+type summaryPart struct {
+	file    string
+	index   int
+	total   int
+	content string
+	summary string
+	latency time.Duration
+	err     error
+}
+
+// label returns the name of the part in the progress lines and in the combined summaries
+func (p *summaryPart) label() string {
+	if p.total == 1 {
+		return p.file
+	}
+	return fmt.Sprintf("%s (part %d/%d)", p.file, p.index, p.total)
+}
+
+// RunSummarize handles the summarize command execution: it splits a file (or the text files of a
+// directory) into parts fitting in a completion, summarizes each part, then consolidates the summaries
+// (map-reduce), combining them in several rounds when they do not fit in a completion either
+func RunSummarize(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	partTokens, _ := cmd.Flags().GetInt("chunk-tokens")
+	focus, _ := cmd.Flags().GetString("focus")
+	output, _ := cmd.Flags().GetString("output")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+	root := args[0]
+
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1 (got %d)", concurrency)
+	}
+	opts := askOptions{configFile: configFile, overrides: configOverrides(cmd), quiet: true, modelCheck: &modelCheck{}}
+	// The summary is printed on stdout without --output, the diagnostics go to stderr
+	if output == "" {
+		opts.log = os.Stderr
+	}
+	log := opts.logWriter()
+	loaded, err := loadAskConfig(opts)
+	if err != nil {
+		return err
+	}
+	summaryConfig := *loaded
+	summaryConfig.Temperature = loaded.TemperatureFor(summarizeTemperature)
+	if partTokens == 0 {
+		partTokens = loaded.AttachmentTokenLimit
+	}
+	if partTokens <= 0 {
+		partTokens = defaultSummarizeTokens
+	}
+
+	files, skipped, err := summarizeFiles(root)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no text file to summarize in %s", root)
+	}
+	var parts []*summaryPart
+	totalTokens := 0
+	for _, file := range files {
+		totalTokens += tokens.Estimate(file.content)
+		split := tokens.Split(file.content, partTokens)
+		for i, part := range split {
+			parts = append(parts, &summaryPart{file: file.path, index: i + 1, total: len(split), content: part})
+		}
+	}
+	fmt.Fprintf(log, "📝 Summarizing %d file(s), about %d tokens in %d part(s), with %s\n", len(files), totalTokens, len(parts), summaryConfig.Model)
+	if skipped > 0 {
+		dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+		fmt.Fprintln(log, dimStyle.Render(fmt.Sprintf("%d binary or empty file(s) skipped", skipped)))
+	}
+
+	start := time.Now()
+	var total tokenUsage
+	var usageMutex sync.Mutex
+	summarize := func(instructions, content string) (string, error) {
+		messages := []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(instructions)}
+		if focus != "" {
+			messages = append(messages, openai.SystemMessage("Focus the summary on: "+focus))
+		}
+		messages = append(messages, openai.UserMessage(content))
+		completionStart := time.Now()
+		summary, usage, _, err := completeWithRetry(&summaryConfig, messages)
+		if err != nil {
+			return "", err
+		}
+		usageMutex.Lock()
+		defer usageMutex.Unlock()
+		recordUsage(&summaryConfig, opts, "summarize", usage, time.Since(completionStart), true)
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+		total.TotalTokens += usage.TotalTokens
+		return strings.TrimSpace(summary), nil
+	}
+
+	var summary string
+	rounds := 1
+	if len(parts) == 1 {
+		// A single part is summarized directly
+		if summary, err = summarize(summarizeCombineInstructions, summarizedText(parts[0].label(), parts[0].content)); err != nil {
+			return fmt.Errorf("error summarizing %s: %w", root, err)
+		}
+	} else {
+		if err := summarizeParts(parts, concurrency, summarize, log); err != nil {
+			return err
+		}
+		summaries := make([]string, len(parts))
+		for i, part := range parts {
+			summaries[i] = summarizedText(part.label(), part.summary)
+		}
+		if summary, rounds, err = combineSummaries(summaries, partTokens, summarize, log); err != nil {
+			return err
+		}
+		rounds++
+	}
+
+	if output != "" {
+		if err := os.WriteFile(output, []byte(summary+"\n"), 0644); err != nil {
+			return fmt.Errorf("error writing %s: %w", output, err)
+		}
+	}
+
+	fmt.Fprintln(log)
+	writeSummaryTable(log, [][]string{
+		{"Files", fmt.Sprint(len(files))},
+		{"Parts", fmt.Sprint(len(parts))},
+		{"Rounds", fmt.Sprint(rounds)},
+		{"Summarized tokens", fmt.Sprint(totalTokens)},
+		{"Summary words", fmt.Sprint(len(strings.Fields(summary)))},
+		{"Tokens used", fmt.Sprint(total.TotalTokens)},
+		{"Duration", time.Since(start).Round(time.Second).String()},
+	})
+	if output != "" {
+		blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+		fmt.Fprintln(log, blueStyle.Render(fmt.Sprintf("💾 Summary saved to: %s", output)))
+		return nil
+	}
+	fmt.Println(summary)
+	return nil
+}
+
+// summarizeParts summarizes the parts concurrently (the map step), printing each part on out as it completes
+func summarizeParts(parts []*summaryPart, concurrency int, summarize func(instructions, content string) (string, error), out io.Writer) error {
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	redStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	var mutex sync.Mutex
+	var workers sync.WaitGroup
+	pending := make(chan *summaryPart)
+	completed := 0
+	for range concurrency {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for part := range pending {
+				start := time.Now()
+				part.summary, part.err = summarize(summarizePartInstructions, summarizedText(part.label(), part.content))
+				part.latency = time.Since(start)
+
+				mutex.Lock()
+				completed++
+				progress := fmt.Sprintf("[%d/%d]", completed, len(parts))
+				if part.err != nil {
+					fmt.Fprintf(out, "❌ %s %s %s\n", progress, part.label(), redStyle.Render(part.err.Error()))
+				} else {
+					fmt.Fprintf(out, "✅ %s %s %s\n", progress, part.label(), dimStyle.Render(fmt.Sprintf("(%d tokens → %d words, %s)", tokens.Estimate(part.content), len(strings.Fields(part.summary)), part.latency.Round(10*time.Millisecond))))
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, part := range parts {
+		pending <- part
+	}
+	close(pending)
+	workers.Wait()
+
+	var errs []error
+	for _, part := range parts {
+		if part.err != nil {
+			errs = append(errs, fmt.Errorf("error summarizing %s: %w", part.label(), part.err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// combineSummaries consolidates the summaries of the parts (the reduce step): as long as they do not fit
+// in a completion, they are combined by groups which fit (by pairs when each one is larger than a completion), each round reported on out. It returns the summary and the number of rounds.
+func combineSummaries(summaries []string, limit int, summarize func(instructions, content string) (string, error), out io.Writer) (string, int, error) {
+	rounds := 0
+	for {
+		rounds++
+		var groups [][]string
+		size := 0
+		for _, summary := range summaries {
+			summaryTokens := tokens.Estimate(summary)
+			if len(groups) == 0 || size+summaryTokens > limit {
+				groups = append(groups, nil)
+				size = 0
+			}
+			groups[len(groups)-1] = append(groups[len(groups)-1], summary)
+			size += summaryTokens
+		}
+		if len(groups) == len(summaries) {
+			// No two summaries fit together: combining them by pairs still reduces them
+			groups = nil
+			for pair := range slices.Chunk(summaries, 2) {
+				groups = append(groups, pair)
+			}
+		}
+		if len(groups) == 1 {
+			summary, err := summarize(summarizeCombineInstructions, strings.Join(summaries, "\n\n"))
+			if err != nil {
+				return "", rounds, fmt.Errorf("error consolidating the summaries: %w", err)
+			}
+			return summary, rounds, nil
+		}
+		fmt.Fprintf(out, "🔁 Combining %d summaries in %d group(s)\n", len(summaries), len(groups))
+
+		combined := make([]string, len(groups))
+		for i, group := range groups {
+			summary, err := summarize(summarizeCombineInstructions, strings.Join(group, "\n\n"))
+			if err != nil {
+				return "", rounds, fmt.Errorf("error combining the summaries: %w", err)
+			}
+			combined[i] = summarizedText(fmt.Sprintf("Summaries group %d/%d", i+1, len(groups)), summary)
+		}
+		summaries = combined
+	}
+}
+
+// summarizedText introduces a text with the name of what it comes from
+func summarizedText(label, text string) string {
+	return fmt.Sprintf("### %s\n\n%s", label, strings.TrimSpace(text))
+}
+
+// summarizeFiles reads the file to summarize, or the text files of the directory (the hidden files and
+// directories are skipped). It returns the files with the number of skipped binary or empty files.
+func summarizeFiles(root string) ([]summarizedFile, int, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !info.IsDir() {
+		content, err := readSummarizedFile(root)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error reading %s: %w", root, err)
+		}
+		if strings.TrimSpace(content) == "" {
+			return nil, 1, nil
+		}
+		return []summarizedFile{{path: root, content: content}}, 0, nil
+	}
+
+	var files []summarizedFile
+	skipped := 0
+	err = filepath.WalkDir(root, func(current string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if current != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := readSummarizedFile(current)
+		if errors.Is(err, sniff.ErrBinary) || errors.Is(err, document.ErrNoText) || (err == nil && strings.TrimSpace(content) == "") {
+			skipped++
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading %s: %w", current, err)
+		}
+		files = append(files, summarizedFile{path: current, content: content})
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("error scanning %s: %w", root, err)
+	}
+	return files, skipped, nil
+}
+
+// readSummarizedFile reads the text of a file, extracting the text of the PDF and DOCX documents
+func readSummarizedFile(path string) (string, error) {
+	if document.Supported(path) {
+		return document.ReadText(path)
+	}
+	return sniff.ReadTextFile(path)
+}
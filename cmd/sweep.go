@@ -0,0 +1,264 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// sweepIndexName is the summary index written in the output directory of a sweep
+const sweepIndexName = "index.md"
+
+// sweepRun is a cell of the sweep matrix: a run of the question at a temperature (with a seed)
+type sweepRun struct {
+	temperature float64
+	run         int
+	seed        *int64
+	file        string
+	answer      string
+	usage       *tokenUsage
+	latency     time.Duration
+	err         error
+}
+
+// name returns the column of the run in the sweep matrix
+func (r *sweepRun) name() string {
+	if r.seed != nil {
+		return fmt.Sprintf("seed %d", *r.seed)
+	}
+	return fmt.Sprintf("run %d", r.run)
+}
+
+// RunSweep handles the sweep command execution: it answers the same question (with the same RAG context)
+// at each temperature, several times or with several seeds, writes each answer to a file of the output
+// directory and a summary index of the matrix, to pick the generation settings empirically
+func RunSweep(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	ragEnabled, _ := cmd.Flags().GetBool("rag")
+	topK, _ := cmd.Flags().GetInt("top-k")
+	question, _ := cmd.Flags().GetString("question")
+	temperatures, _ := cmd.Flags().GetFloat64Slice("temperatures")
+	runs, _ := cmd.Flags().GetInt("runs")
+	seeds, _ := cmd.Flags().GetInt64Slice("seeds")
+	outputDir, _ := cmd.Flags().GetString("output-dir")
+	concurrency, _ := cmd.Flags().GetInt("concurrency")
+
+	// The question can also be given as arguments
+	if question == "" {
+		question = strings.Join(args, " ")
+	}
+	if question == "" {
+		return fmt.Errorf("question is required (either via -q flag or as arguments)")
+	}
+	if len(temperatures) == 0 {
+		return fmt.Errorf("--temperatures is required (e.g. --temperatures 0,0.3,0.7)")
+	}
+	for _, temperature := range temperatures {
+		if temperature < 0 || temperature > 2 {
+			return fmt.Errorf("temperatures must be between 0 and 2 (got %g)", temperature)
+		}
+	}
+	if len(seeds) > 0 && cmd.Flags().Changed("runs") {
+		return fmt.Errorf("--runs and --seeds cannot be used together (each seed is a run)")
+	}
+	if runs < 1 {
+		return fmt.Errorf("--runs must be at least 1 (got %d)", runs)
+	}
+	if concurrency < 1 {
+		return fmt.Errorf("--concurrency must be at least 1 (got %d)", concurrency)
+	}
+
+	opts := askOptions{
+		systemFiles:    systemFiles,
+		configFile:     configFile,
+		overrides:      configOverrides(cmd),
+		embeddingsFile: embeddingsFile,
+		ragEnabled:     ragEnabled,
+		topK:           topK,
+		quiet:          true,
+		modelCheck:     &modelCheck{},
+		question:       question,
+	}
+	config, err := loadAskConfig(opts)
+	if err != nil {
+		return err
+	}
+
+	messages, err := baseMessages(opts)
+	if err != nil {
+		return err
+	}
+	// The context is retrieved once: every run gets the same chunks
+	var similarities []rag.Similarity
+	if ragEnabled {
+		if opts.ragEnabled, err = checkEmbeddingModel(config, opts); err != nil {
+			return err
+		}
+	}
+	if opts.ragEnabled {
+		found, _, errs := ragSearch(config, opts, question)
+		if len(errs) > 0 {
+			return errors.Join(errs...)
+		}
+		similarities = found
+		if len(similarities) > 0 {
+			messages = append(messages, openai.UserMessage(ragContextHeader+strings.Join(rag.Contents(similarities), "\n\n")))
+		}
+	}
+	messages = append(messages, openai.UserMessage(question))
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	opts.sources = similarities
+	metadata := resultMetadata(config, opts, time.Now())
+
+	var matrix []*sweepRun
+	for _, temperature := range temperatures {
+		if len(seeds) > 0 {
+			for i, seed := range seeds {
+				matrix = append(matrix, &sweepRun{temperature: temperature, run: i + 1, seed: &seed, file: fmt.Sprintf("t%g-seed%d.md", temperature, seed)})
+			}
+			continue
+		}
+		for run := 1; run <= runs; run++ {
+			matrix = append(matrix, &sweepRun{temperature: temperature, run: run, file: fmt.Sprintf("t%g-run%d.md", temperature, run)})
+		}
+	}
+	fmt.Printf("🌡️  Sweeping %d temperature(s) × %d run(s) with %s (%d at a time)\n", len(temperatures), len(matrix)/len(temperatures), config.Model, concurrency)
+
+	// mutex serializes the output and the ledger writes of the workers
+	var mutex sync.Mutex
+	var workers sync.WaitGroup
+	pending := make(chan *sweepRun)
+	completed := 0
+	for range concurrency {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for run := range pending {
+				runConfig := *config
+				runConfig.Temperature, runConfig.Seed = run.temperature, run.seed
+				start := time.Now()
+				run.answer, run.usage, _, run.err = completeWithRetry(&runConfig, slices.Clone(messages))
+				run.latency = time.Since(start)
+				if run.err == nil {
+					runMetadata := metadata
+					runMetadata.Date, runMetadata.Temperature, runMetadata.Seed = time.Now(), run.temperature, run.seed
+					content := runMetadata.FrontMatter() + run.answer + sourcesSection("##", similarities)
+					if err := os.WriteFile(filepath.Join(outputDir, run.file), []byte(content), 0644); err != nil {
+						run.err = fmt.Errorf("error writing %s: %w", run.file, err)
+					}
+				}
+
+				mutex.Lock()
+				completed++
+				if run.err == nil {
+					recordUsage(&runConfig, opts, "sweep", run.usage, run.latency, true)
+				}
+				printSweepRun(run, outputDir, completed, len(matrix))
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, run := range matrix {
+		pending <- run
+	}
+	close(pending)
+	workers.Wait()
+
+	indexPath := filepath.Join(outputDir, sweepIndexName)
+	if err := os.WriteFile(indexPath, []byte(sweepIndex(question, config.Model, temperatures, matrix)), 0644); err != nil {
+		return fmt.Errorf("error writing the sweep index: %w", err)
+	}
+
+	fmt.Println()
+	failed := 0
+	var rows [][]string
+	for _, temperature := range temperatures {
+		var latency time.Duration
+		answered, words := 0, 0
+		distinct := make(map[string]bool)
+		for _, run := range matrix {
+			if run.temperature != temperature {
+				continue
+			}
+			if run.err != nil {
+				failed++
+				continue
+			}
+			answered++
+			latency += run.latency
+			words += len(strings.Fields(run.answer))
+			distinct[strings.TrimSpace(run.answer)] = true
+		}
+		stats := "failed"
+		if answered > 0 {
+			stats = fmt.Sprintf("%d distinct of %d · ~%d words · %s avg", len(distinct), answered, words/answered, (latency / time.Duration(answered)).Round(10*time.Millisecond))
+		}
+		rows = append(rows, []string{fmt.Sprintf("Temperature %g", temperature), stats})
+	}
+	printSummaryTable(rows)
+	blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+	fmt.Println(blueStyle.Render(fmt.Sprintf("📊 Index saved to: %s", indexPath)))
+
+	if failed > 0 {
+		return fmt.Errorf("%d run(s) failed", failed)
+	}
+	return nil
+}
+
+// printSweepRun prints the outcome of a run of the sweep as it completes
+func printSweepRun(run *sweepRun, outputDir string, completed, total int) {
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	redStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	progress := fmt.Sprintf("[%d/%d]", completed, total)
+	title := fmt.Sprintf("temperature %g, %s", run.temperature, run.name())
+	if run.err != nil {
+		fmt.Printf("❌ %s %s %s\n", progress, title, redStyle.Render(run.err.Error()))
+		return
+	}
+	fmt.Printf("✅ %s %s %s\n", progress, title, dimStyle.Render(fmt.Sprintf("→ %s (%s)", filepath.Join(outputDir, run.file), run.latency.Round(10*time.Millisecond))))
+}
+
+// sweepIndex returns the summary index of a sweep: a Markdown table with a row per temperature and a
+// column per run, each cell linking to the answer file
+func sweepIndex(question, model string, temperatures []float64, matrix []*sweepRun) string {
+	var index strings.Builder
+	fmt.Fprintf(&index, "# Sweep: %s\n\n", question)
+	fmt.Fprintf(&index, "Model: %s, %s\n\n", model, time.Now().Format("2006-01-02 15:04"))
+
+	columns := len(matrix) / len(temperatures)
+	index.WriteString("| Temperature |")
+	for _, run := range matrix[:columns] {
+		fmt.Fprintf(&index, " %s |", run.name())
+	}
+	index.WriteString("\n|---|" + strings.Repeat("---|", columns) + "\n")
+	for i, temperature := range temperatures {
+		fmt.Fprintf(&index, "| %g |", temperature)
+		for _, run := range matrix[i*columns : (i+1)*columns] {
+			if run.err != nil {
+				index.WriteString(" failed |")
+				continue
+			}
+			tokens := int64(0)
+			if run.usage != nil {
+				tokens = run.usage.CompletionTokens
+			}
+			fmt.Fprintf(&index, " [%d words, %d tokens, %s](%s) |", len(strings.Fields(run.answer)), tokens, run.latency.Round(10*time.Millisecond), run.file)
+		}
+		index.WriteString("\n")
+	}
+	return index.String()
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/budgies-nest/budgie/helpers"
+	"github.com/openai/openai-go"
+)
+
+// systemFilePaths expands the --system paths into the system instruction files, in layering order:
+// a file is taken as is, a directory gives its .md files in name order
+func systemFilePaths(paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		var layers []string
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == ".md" {
+				layers = append(layers, filepath.Join(path, entry.Name()))
+			}
+		}
+		if len(layers) == 0 {
+			return nil, fmt.Errorf("no .md system instructions file in %s", path)
+		}
+		slices.Sort(layers)
+		files = append(files, layers...)
+	}
+	return files, nil
+}
+
+// readSystemInstructions reads the system instructions of the --system paths: the contents of their files
+// layered in order (e.g. a base prompt, then a persona, then the project rules), separated by blank lines
+func readSystemInstructions(paths []string) (string, error) {
+	files, err := systemFilePaths(paths)
+	if err != nil {
+		return "", err
+	}
+	layers := make([]string, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		layers = append(layers, string(content))
+	}
+	// A single file is kept as is, so its prompt (and its hash in the result files) does not change
+	if len(layers) == 1 {
+		return layers[0], nil
+	}
+	for i, layer := range layers {
+		layers[i] = strings.TrimSpace(layer)
+	}
+	return strings.Join(layers, "\n\n"), nil
+}
+
+// replaceSystemPrompt returns the conversation with its system instructions (its first message) replaced,
+// the rest of the conversation kept
+func replaceSystemPrompt(messages []openai.ChatCompletionMessageParamUnion, instructions string) []openai.ChatCompletionMessageParamUnion {
+	replaced := []openai.ChatCompletionMessageParamUnion{openai.SystemMessage(instructions)}
+	if len(messages) > 0 {
+		if fields, err := helpers.MessageToMap(messages[0]); err == nil && fields["role"] == "system" {
+			messages = messages[1:]
+		}
+	}
+	return append(replaced, messages...)
+}
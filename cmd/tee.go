@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+)
+
+// resultTee writes a streaming answer to its result file as the chunks arrive: the file is marked
+// partial (partial: true in its front matter) until the answer completes, so an interruption (ESC,
+// an error or a crash) keeps what was received. A nil resultTee does nothing.
+type resultTee struct {
+	file     *os.File
+	path     string
+	metadata func() string
+	written  bool
+	failed   bool
+	// sources is the Sources section written after the complete answer
+	sources string
+	// appending is set when the answer is appended to the --append notebook
+	appending bool
+}
+
+// startResultTee creates the result file of an answer and writes its partial front matter. With --append,
+// it starts the entry of the answer in the notebook instead.
+func startResultTee(config *config.Config, opts askOptions) (*resultTee, error) {
+	now := time.Now()
+	if opts.appendFile != "" {
+		file, err := openNotebook(opts.appendFile)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.WriteString(entryHeader(now, opts.question)); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return &resultTee{file: file, path: opts.appendFile, sources: sourcesSection("###", opts.sources), appending: true}, nil
+	}
+
+	path, err := resultPath(config, opts, now)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tee := &resultTee{file: file, path: path, metadata: func() string { return "" }, sources: sourcesSection("##", opts.sources)}
+	if opts.noFrontMatter {
+		return tee, nil
+	}
+	metadata := resultMetadata(config, opts, now)
+	metadata.Partial = true
+	if _, err := file.WriteString(metadata.FrontMatter()); err != nil {
+		file.Close()
+		os.Remove(path)
+		return nil, err
+	}
+	metadata.Partial = false
+	tee.metadata = metadata.FrontMatter
+	return tee, nil
+}
+
+// Write appends a chunk of the answer to the result file. A write error is reported once, the
+// complete answer is still written by Finalize.
+func (t *resultTee) Write(content string) {
+	if t == nil || t.failed {
+		return
+	}
+	if _, err := t.file.WriteString(content); err != nil {
+		t.failed = true
+		fmt.Fprintf(os.Stderr, "Warning: error writing the result file: %v\n", err)
+		return
+	}
+	t.written = true
+}
+
+// Finalize replaces the partial result file with the complete answer and returns its path
+func (t *resultTee) Finalize(response string) (string, error) {
+	if t == nil {
+		return "", nil
+	}
+	if t.appending {
+		defer t.file.Close()
+		if t.failed {
+			return "", fmt.Errorf("incomplete entry in %s", t.path)
+		}
+		if _, err := t.file.WriteString(t.sources + "\n\n"); err != nil {
+			return "", err
+		}
+		return t.path, nil
+	}
+	t.file.Close()
+
+	temp := filepath.Join(filepath.Dir(t.path), "."+filepath.Base(t.path)+".tmp")
+	if err := os.WriteFile(temp, []byte(t.metadata()+response+t.sources), 0644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(temp, t.path); err != nil {
+		os.Remove(temp)
+		return "", err
+	}
+	return t.path, nil
+}
+
+// Interrupt closes the result file of an interrupted answer and returns its path. The file is removed
+// (and "" returned) when no part of the answer was received. A notebook entry is marked interrupted.
+func (t *resultTee) Interrupt() string {
+	if t == nil {
+		return ""
+	}
+	if t.appending {
+		t.file.WriteString("\n\n*(interrupted)*\n\n")
+		t.file.Close()
+		return t.path
+	}
+	t.file.Close()
+	if !t.written {
+		os.Remove(t.path)
+		return ""
+	}
+	return t.path
+}
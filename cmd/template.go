@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/prompts"
+	"github.com/charmbracelet/huh"
+)
+
+// templateQuestion builds the question of `ask --template`: the prompt template of the project filled with
+// the --var values, the piped content for {{input}} (piped reports whether it was used) and, when stdin is a
+// terminal, the values asked for the remaining variables
+func templateQuestion(configFile, name string, values map[string]string, pipedContent string) (question string, piped bool, err error) {
+	template, err := prompts.Load(prompts.Dir(configFile), name)
+	if err != nil {
+		return "", false, err
+	}
+
+	var missing []string
+	for _, variable := range prompts.Variables(template) {
+		if _, ok := values[variable]; ok {
+			continue
+		}
+		if variable == prompts.InputVariable && pipedContent != "" {
+			values[variable] = pipedContent
+			piped = true
+			continue
+		}
+		missing = append(missing, variable)
+	}
+
+	if len(missing) > 0 {
+		if !isTerminal(os.Stdin) {
+			return "", false, fmt.Errorf("missing value for the variables of the %s template: %s (use --var key=value)", name, strings.Join(missing, ", "))
+		}
+		for _, variable := range missing {
+			var value string
+			err := huh.NewInput().
+				Title(fmt.Sprintf("%s:", variable)).
+				Description(fmt.Sprintf("Value of {{%s}} in the %s template", variable, name)).
+				Value(&value).
+				Run()
+			if err != nil {
+				return "", false, fmt.Errorf("error getting the value of %s: %w", variable, err)
+			}
+			values[variable] = value
+		}
+	}
+
+	return strings.TrimSpace(prompts.Fill(template, values)), piped, nil
+}
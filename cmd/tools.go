@@ -0,0 +1,346 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/mcpclient"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+	"github.com/budgies-nest/budgie-cli/pkg/tools"
+	"github.com/charmbracelet/huh"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+)
+
+// maxToolRounds is the maximum number of tool calling rounds before the model has to answer
+const maxToolRounds = 8
+
+// fetchTimeout is the timeout of the fetch tools
+const fetchTimeout = 30 * time.Second
+
+// fetchMaxBytes is the maximum size of the content read by the fetch tools
+const fetchMaxBytes = 1 << 20
+
+// noToolCalls is the error of the agents tools completion when the model answered without calling a tool
+const noToolCalls = "no tool calls detected"
+
+// Choices offered when the model calls a tool
+const (
+	toolAllowOnce   = "once"
+	toolAllowAlways = "always"
+	toolDeny        = "deny"
+)
+
+// mcpConnectTimeout is the timeout of the connection to the MCP servers
+const mcpConnectTimeout = 30 * time.Second
+
+// toolSession holds the tools of `ask --allow-tools` (the tools file and the MCP servers of the config)
+// and the ones the user allowed for the whole session. The tool calls and their approval are shown on out.
+type toolSession struct {
+	tools   []tools.Tool
+	servers []*mcpclient.Server
+	allowed map[string]bool
+	out     io.Writer
+}
+
+// loadToolSession reads the tools file next to the config file and connects to the MCP servers of the config
+func loadToolSession(configFile string, config *config.Config, version string, out io.Writer) (*toolSession, error) {
+	path := tools.Path(configFile)
+	definitions, err := tools.Load(path)
+	if err != nil {
+		return nil, fmt.Errorf("error loading tools: %w", err)
+	}
+	if len(definitions) == 0 && len(config.MCPServers) == 0 {
+		return nil, fmt.Errorf("--allow-tools requires tools defined in %s or mcp-servers in the config", path)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpConnectTimeout)
+	defer cancel()
+	servers, err := mcpclient.ConnectAll(ctx, config.MCPServers, version)
+	if err != nil {
+		return nil, err
+	}
+	for _, server := range servers {
+		fmt.Fprintf(out, "🔌 MCP server %s: %d tool(s)\n", server.Name, len(server.Tools))
+	}
+	return &toolSession{tools: definitions, servers: servers, allowed: make(map[string]bool), out: out}, nil
+}
+
+// Close disconnects the MCP servers
+func (s *toolSession) Close() {
+	mcpclient.CloseAll(s.servers)
+}
+
+// params returns the definitions of the tools, the ones of the tools file first
+// (the MCP tools of the same name are ignored)
+func (s *toolSession) params() []openai.ChatCompletionToolParam {
+	params := tools.Params(s.tools)
+	for _, server := range s.servers {
+		for _, param := range server.Params() {
+			if !slices.ContainsFunc(params, func(existing openai.ChatCompletionToolParam) bool {
+				return existing.Function.Name == param.Function.Name
+			}) {
+				params = append(params, param)
+			}
+		}
+	}
+	return params
+}
+
+// server returns the MCP server of a tool which is not defined in the tools file
+func (s *toolSession) server(name string) *mcpclient.Server {
+	if _, found := tools.Find(s.tools, name); found {
+		return nil
+	}
+	for _, server := range s.servers {
+		if server.Has(name) {
+			return server
+		}
+	}
+	return nil
+}
+
+// callTools lets the model call the tools before it answers: each round, the calls detected by the model
+// are approved by the user, run, and their results added to the conversation, until the model stops calling
+// tools. It returns the conversation with the tool calls and results, ready for the answer completion.
+func (s *toolSession) callTools(config *config.Config, messages []openai.ChatCompletionMessageParamUnion) ([]openai.ChatCompletionMessageParamUnion, error) {
+	agent, err := newChatAgent(config, messages)
+	if err != nil {
+		return messages, err
+	}
+	agent.AddTools(s.params())
+
+	for round := 0; round < maxToolRounds; round++ {
+		// The tool results of the previous round are masked like the rest of the conversation
+		if agent.Params.Messages, err = redactMessages(config, agent.Params.Messages); err != nil {
+			return messages, err
+		}
+		calls, err := agent.ToolsCompletion(context.Background())
+		if err != nil {
+			if err.Error() == noToolCalls {
+				break
+			}
+			return messages, fmt.Errorf("error detecting tool calls: %w", err)
+		}
+
+		assistant := openai.ChatCompletionAssistantMessageParam{}
+		for _, call := range calls {
+			assistant.ToolCalls = append(assistant.ToolCalls, call.ToParam())
+		}
+		agent.Params.Messages = append(agent.Params.Messages, openai.ChatCompletionMessageParamUnion{OfAssistant: &assistant})
+
+		for _, call := range calls {
+			result := s.runToolCall(config, call)
+			agent.Params.Messages = append(agent.Params.Messages, openai.ToolMessage(result, call.ID))
+		}
+
+		if round == maxToolRounds-1 {
+			yellowStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
+			fmt.Fprintln(s.out, yellowStyle.Render(fmt.Sprintf("⚠️  Stopped calling tools after %d rounds", maxToolRounds)))
+		}
+	}
+	return agent.Params.Messages, nil
+}
+
+// runToolCall approves and runs a tool call, it returns the result given to the model (the error message when it failed)
+func (s *toolSession) runToolCall(config *config.Config, call openai.ChatCompletionMessageToolCall) string {
+	var args map[string]any
+	if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+		return fmt.Sprintf("Error: invalid arguments: %v", err)
+	}
+	if server := s.server(call.Function.Name); server != nil {
+		return s.runMCPToolCall(config, server, call.Function.Name, args)
+	}
+	tool, found := tools.Find(s.tools, call.Function.Name)
+	if !found {
+		return fmt.Sprintf("Error: unknown tool %s", call.Function.Name)
+	}
+
+	action, err := describeToolCall(tool, args)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	fmt.Fprintf(s.out, "🔧 %s: %s\n", tool.Name, action)
+
+	if !s.approve(tool.Name, tool.Trusted) {
+		fmt.Fprintln(s.out, "🚫 Tool call denied")
+		return "Error: the user denied the tool call"
+	}
+
+	result, err := runTool(tool, args)
+	return toolResult(config, tool.Name, result, err, s.out)
+}
+
+// runMCPToolCall approves and runs a call of a tool of an MCP server
+func (s *toolSession) runMCPToolCall(config *config.Config, server *mcpclient.Server, name string, args map[string]any) string {
+	arguments, _ := json.Marshal(args)
+	fmt.Fprintf(s.out, "🔧 %s (MCP server %s): %s\n", name, server.Name, arguments)
+	if !s.approve(name, server.Trusted) {
+		fmt.Fprintln(s.out, "🚫 Tool call denied")
+		return "Error: the user denied the tool call"
+	}
+
+	result, err := server.Call(context.Background(), name, args)
+	return toolResult(config, name, result, err, s.out)
+}
+
+// toolResult returns the result of a tool call given to the model: its output, truncated above the
+// attachment token limit, or its error message
+func toolResult(config *config.Config, name, result string, err error, out io.Writer) string {
+	if err != nil {
+		fmt.Fprintf(out, "❌ %v\n", err)
+		return fmt.Sprintf("Error: %v", err)
+	}
+	fmt.Fprintf(out, "✅ %s returned about %d characters\n", name, len(result))
+
+	if limit := config.AttachmentTokenLimit; limit >= 0 && tokens.Estimate(result) > limit {
+		result = truncateContent(result, limit)
+	}
+	return result
+}
+
+// approve asks the user whether to run a call of the tool (trusted tools and the ones always allowed
+// are approved without asking). Calls are denied when stdin is not a terminal.
+func (s *toolSession) approve(name string, trusted bool) bool {
+	if trusted || s.allowed[name] {
+		return true
+	}
+	if !isTerminal(os.Stdin) {
+		return false
+	}
+
+	choice := toolDeny
+	err := runField(huh.NewSelect[string]().
+		Title(fmt.Sprintf("Allow the call of %s?", name)).
+		Options(
+			huh.NewOption("Allow once", toolAllowOnce),
+			huh.NewOption(fmt.Sprintf("Always allow %s in this session", name), toolAllowAlways),
+			huh.NewOption("Deny", toolDeny),
+		).
+		Value(&choice), s.out)
+	if err != nil {
+		return false
+	}
+	if choice == toolAllowAlways {
+		s.allowed[name] = true
+	}
+	return choice != toolDeny
+}
+
+// describeToolCall returns what a call will do (the file, URL or command line), validating its arguments
+func describeToolCall(tool tools.Tool, args map[string]any) (string, error) {
+	switch tool.Kind {
+	case tools.KindFile:
+		path, err := tools.StringArg(args, "path")
+		if err == nil {
+			err = checkWorkspacePath(path)
+		}
+		return "read " + path, err
+	case tools.KindFetch:
+		url, err := tools.StringArg(args, "url")
+		return "fetch " + url, err
+	default:
+		commandLine, err := tool.CommandLine(args)
+		return "run " + commandLine, err
+	}
+}
+
+// checkWorkspacePath makes sure a path chosen by the model is in the workspace (the current directory): the
+// files read by the file tools and the ones written by apply. The symbolic links are resolved, a link of the
+// workspace may point out of it.
+func checkWorkspacePath(path string) error {
+	local := filepath.FromSlash(path)
+	if !filepath.IsLocal(local) {
+		return fmt.Errorf("%s is outside the current directory", path)
+	}
+	workDir, err := os.Getwd()
+	if err == nil {
+		workDir, err = filepath.EvalSymlinks(workDir)
+	}
+	if err != nil {
+		return fmt.Errorf("error resolving the current directory: %w", err)
+	}
+	// The missing files are resolved from their closest existing folder
+	existing, missing := filepath.Join(workDir, local), ""
+	resolved, err := filepath.EvalSymlinks(existing)
+	for err != nil && existing != workDir {
+		existing, missing = filepath.Dir(existing), filepath.Join(filepath.Base(existing), missing)
+		resolved, err = filepath.EvalSymlinks(existing)
+	}
+	if err != nil {
+		return fmt.Errorf("error resolving %s: %w", path, err)
+	}
+	if relPath, err := filepath.Rel(workDir, filepath.Join(resolved, missing)); err != nil || !filepath.IsLocal(relPath) {
+		return fmt.Errorf("%s links outside the current directory", path)
+	}
+	return nil
+}
+
+// readWorkspaceFile reads a file of the workspace through an os.Root, which refuses the paths escaping the
+// workspace even when a link is changed after checkWorkspacePath
+func readWorkspaceFile(path string) ([]byte, error) {
+	root, err := os.OpenRoot(".")
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	file, err := root.Open(filepath.FromSlash(path))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// runTool runs an approved tool call
+func runTool(tool tools.Tool, args map[string]any) (string, error) {
+	switch tool.Kind {
+	case tools.KindFile:
+		path, _ := tools.StringArg(args, "path")
+		if err := checkWorkspacePath(path); err != nil {
+			return "", err
+		}
+		content, err := readWorkspaceFile(path)
+		if err != nil {
+			return "", fmt.Errorf("error reading file %s: %w", path, err)
+		}
+		return string(content), nil
+
+	case tools.KindFetch:
+		url, _ := tools.StringArg(args, "url")
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return "", fmt.Errorf("unsupported URL %s (http or https only)", url)
+		}
+		client := &http.Client{Timeout: fetchTimeout}
+		response, err := client.Get(url)
+		if err != nil {
+			return "", fmt.Errorf("error fetching %s: %w", url, err)
+		}
+		defer response.Body.Close()
+		body, err := io.ReadAll(io.LimitReader(response.Body, fetchMaxBytes))
+		if err != nil {
+			return "", fmt.Errorf("error fetching %s: %w", url, err)
+		}
+		if response.StatusCode >= 400 {
+			return "", fmt.Errorf("error fetching %s: %s", url, response.Status)
+		}
+		return string(body), nil
+
+	default:
+		commandLine, _ := tool.CommandLine(args)
+		output, status, err := runShell(commandLine)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s\n(%s)", strings.TrimRight(output, "\n"), status), nil
+	}
+}
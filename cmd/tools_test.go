@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The file tools and apply use the files of the workspace only, the symbolic links included
+func TestCheckWorkspacePath(t *testing.T) {
+	base := t.TempDir()
+	workspace := filepath.Join(base, "workspace")
+	outside := filepath.Join(base, "outside")
+	for _, dir := range []string{filepath.Join(workspace, "docs"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, content := range map[string]string{
+		filepath.Join(workspace, "docs", "guide.md"): "guide",
+		filepath.Join(outside, "secret.txt"):         "secret",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for link, target := range map[string]string{
+		filepath.Join(workspace, "guide-link.md"): filepath.Join("docs", "guide.md"),
+		filepath.Join(workspace, "secret.txt"):    filepath.Join(outside, "secret.txt"),
+		filepath.Join(workspace, "shared"):        outside,
+		filepath.Join(workspace, "parent"):        "..",
+	} {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symbolic links not supported: %v", err)
+		}
+	}
+	t.Chdir(workspace)
+
+	tests := []struct {
+		path  string
+		error string
+	}{
+		{"docs/guide.md", ""},
+		{"guide-link.md", ""},
+		{"docs/missing.md", ""},
+		{"../outside/secret.txt", "outside the current directory"},
+		{filepath.Join(outside, "secret.txt"), "outside the current directory"},
+		{"secret.txt", "links outside the current directory"},
+		{"shared/secret.txt", "links outside the current directory"},
+		{"parent/outside/secret.txt", "links outside the current directory"},
+		{"shared/new.txt", "links outside the current directory"},
+		{"shared/new/folder/file.txt", "links outside the current directory"},
+	}
+	for _, test := range tests {
+		err := checkWorkspacePath(test.path)
+		if test.error == "" {
+			if err != nil {
+				t.Errorf("checkWorkspacePath(%q) = %v, want the path accepted", test.path, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), test.error) {
+			t.Errorf("checkWorkspacePath(%q) = %v, want %q", test.path, err, test.error)
+		}
+	}
+
+	// The file is read through the workspace root: a link changed after the check cannot escape
+	if content, err := readWorkspaceFile("guide-link.md"); err != nil || string(content) != "guide" {
+		t.Errorf("readWorkspaceFile of a link of the workspace = %q, %v", content, err)
+	}
+	if content, err := readWorkspaceFile("secret.txt"); err == nil {
+		t.Errorf("readWorkspaceFile read %q through a link to the outside", content)
+	}
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/tracing"
+	"github.com/spf13/cobra"
+)
+
+// SetupTracing exports the tracing spans of the command (embedding calls, similarity searches, chat
+// completions) to the OTLP endpoint of the otel-endpoint config option or of BUDGIE_OTEL_ENDPOINT
+func SetupTracing(cmd *cobra.Command) error {
+	configFile, err := cmd.Flags().GetString("config")
+	if err != nil || configFile == "" {
+		configFile = defaultConfigFile
+	}
+
+	// The commands without a config (e.g. init) can still be traced with the environment variable
+	endpoint := os.Getenv(config.EnvOTelEndpoint)
+	if loaded, err := config.LoadConfig(configFile, config.Overrides{}); err == nil {
+		endpoint = loaded.OTelEndpoint
+	}
+	if endpoint == "" {
+		return nil
+	}
+
+	if err := tracing.Setup(endpoint, cmd.Root().Version); err != nil {
+		return fmt.Errorf("error setting up tracing: %w", err)
+	}
+	tracing.StartCommand(cmd.CommandPath())
+	return nil
+}
+
+// EndTracing ends the span of the command and exports the remaining spans
+func EndTracing(err error) {
+	tracing.Shutdown(err)
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// translateTemperature is the operation whose temperature is used for the translations (temperatures config map)
+const translateTemperature = config.TemperatureTranslate
+
+// defaultTranslateTemplate is the system prompt of the translations when the project has no template
+const defaultTranslateTemplate = `You are a professional translator. Translate the text given by the user into the requested language.
+Keep the meaning, the tone and the formatting: Markdown structure, links, front-matter keys, placeholders,
+code blocks and inline code are kept as is (only the comments and the prose around them are translated).
+Reply with the translated text only, without notes nor code fences around it.`
+
+// RunTranslate handles the translate command execution: it translates a file with the model, part by
+// part for the large files, and writes the translation next to the file (or over it with --in-place)
+func RunTranslate(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	templateFile, _ := cmd.Flags().GetString("template")
+	language, _ := cmd.Flags().GetString("to")
+	source, _ := cmd.Flags().GetString("from")
+	output, _ := cmd.Flags().GetString("output")
+	inPlace, _ := cmd.Flags().GetBool("in-place")
+	partTokens, _ := cmd.Flags().GetInt("chunk-tokens")
+	filePath := args[0]
+
+	if language == "" {
+		return fmt.Errorf("--to is required (e.g. --to fr or --to \"Brazilian Portuguese\")")
+	}
+	if inPlace && output != "" {
+		return fmt.Errorf("--in-place and --output cannot be used together")
+	}
+	if partTokens <= 0 {
+		return fmt.Errorf("--chunk-tokens must be positive (got %d)", partTokens)
+	}
+	switch {
+	case inPlace:
+		output = filePath
+	case output == "":
+		// Side by side with the original: guide.md is translated to guide.fr.md
+		extension := filepath.Ext(filePath)
+		output = strings.TrimSuffix(filePath, extension) + "." + languageSuffix(language) + extension
+	}
+
+	config, err := config.LoadConfig(configFile, configOverrides(cmd))
+	if err != nil {
+		return fmt.Errorf("error loading config file: %w", err)
+	}
+	instructions, err := rewriteInstructions(templateFile, cmd.Flags().Changed("template"), defaultTranslateTemplate)
+	if err != nil {
+		return err
+	}
+	content, err := sniff.ReadTextFile(filePath)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", filePath, err)
+	}
+	if strings.TrimSpace(content) == "" {
+		return fmt.Errorf("%s is empty", filePath)
+	}
+
+	target := "Translate the text into " + language
+	if source != "" {
+		target = fmt.Sprintf("Translate the text from %s into %s", source, language)
+	}
+	system := []openai.ChatCompletionMessageParamUnion{
+		openai.SystemMessage(instructions),
+		openai.SystemMessage(target + "."),
+	}
+
+	fmt.Printf("🌍 Translating %s into %s with %s...\n", filePath, language, config.Model)
+	translateConfig := *config
+	translateConfig.Temperature = config.TemperatureFor(translateTemperature)
+	translation, err := rewriteText(&translateConfig, configFile, "translate", system, content, partTokens)
+	if err != nil {
+		return fmt.Errorf("error translating %s: %w", filePath, err)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, []byte(translation), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("error writing %s: %w", output, err)
+	}
+	blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+	fmt.Println(blueStyle.Render(fmt.Sprintf("💾 Translation saved to: %s", output)))
+	return nil
+}
+
+// languageSuffix returns the file name suffix of a language: the code as is (fr, pt-BR), or the slug
+// of a language name ("Brazilian Portuguese" gives brazilian-portuguese)
+func languageSuffix(language string) string {
+	words := strings.Fields(language)
+	if len(words) == 1 {
+		return words[0]
+	}
+	return strings.ToLower(strings.Join(words, "-"))
+}
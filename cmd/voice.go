@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
+	"github.com/budgies-nest/budgie-cli/pkg/voice"
+	"github.com/eiannone/keyboard"
+	"github.com/mattn/go-isatty"
+	"github.com/openai/openai-go"
+)
+
+// voiceMicrophone is the --voice value recording the microphone (--voice alone)
+const voiceMicrophone = "mic"
+
+// errRecordingCancelled is returned when the recording is cancelled with Esc or Ctrl+C
+var errRecordingCancelled = errors.New("recording cancelled")
+
+// voiceTranscript returns the transcript of the microphone (source is voiceMicrophone) or of an audio file.
+// The recording prompt and the transcript are written to out.
+func voiceTranscript(config *config.Config, source string, quiet bool, out io.Writer) (string, error) {
+	path := source
+	if source == voiceMicrophone {
+		recorded, err := recordVoice(config, out)
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(recorded)
+		path = recorded
+	}
+
+	if !quiet {
+		fmt.Fprintln(out, "🎙️  Transcribing...")
+	}
+	transcript, err := transcribe(config, path)
+	if err != nil {
+		return "", err
+	}
+	if transcript == "" {
+		return "", fmt.Errorf("no speech recognized in %s", path)
+	}
+	if !quiet {
+		fmt.Fprintf(out, "🎙️  %s\n", transcript)
+	}
+	return transcript, nil
+}
+
+// recordVoice records the microphone to a temporary WAV file until Enter is pressed (Esc or Ctrl+C cancel)
+func recordVoice(config *config.Config, out io.Writer) (string, error) {
+	if !isatty.IsTerminal(os.Stdin.Fd()) {
+		return "", fmt.Errorf("recording the microphone requires a terminal: give an audio file with --voice=<file>")
+	}
+	recorder, err := voice.FindRecorder(config.Voice.RecordCommand)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.CreateTemp("", "budgie-voice-*.wav")
+	if err != nil {
+		return "", fmt.Errorf("error creating the recording file: %w", err)
+	}
+	file.Close()
+	path := file.Name()
+
+	// The terminal is in raw mode while recording: Ctrl+C is read as a key instead of interrupting the recorder
+	fmt.Fprintf(out, "🔴 Recording with %s... press Enter to stop, Esc to cancel\n", filepath.Base(recorder.Name))
+	keys, err := keyboard.GetKeys(10)
+	if err != nil {
+		os.Remove(path)
+		return "", fmt.Errorf("error reading the keyboard: %w", err)
+	}
+	defer keyboard.Close()
+
+	recording, err := recorder.Start(path)
+	if err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	for {
+		select {
+		case <-recording.Done():
+			err := recording.Stop()
+			os.Remove(path)
+			return "", err
+		case event := <-keys:
+			switch event.Key {
+			case keyboard.KeyEnter:
+				if err := recording.Stop(); err != nil {
+					os.Remove(path)
+					return "", err
+				}
+				return path, nil
+			case keyboard.KeyEsc, keyboard.KeyCtrlC:
+				recording.Cancel()
+				os.Remove(path)
+				return "", errRecordingCancelled
+			}
+		}
+	}
+}
+
+// transcribe sends an audio file to the Whisper-compatible endpoint of the config (voice.baseURL, the
+// provider one by default) and returns its text
+func transcribe(config *config.Config, path string) (string, error) {
+	client, err := transcriptionClient(config)
+	if err != nil {
+		return "", err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading audio file: %w", err)
+	}
+	defer file.Close()
+
+	params := openai.AudioTranscriptionNewParams{File: file, Model: openai.AudioModel(config.Voice.Model)}
+	if config.Voice.Language != "" {
+		params.Language = openai.String(config.Voice.Language)
+	}
+	transcription, err := client.Audio.Transcriptions.New(context.Background(), params)
+	if err != nil {
+		return "", fmt.Errorf("error transcribing %s with %s: %w", filepath.Base(path), config.Voice.Model, err)
+	}
+	return strings.TrimSpace(transcription.Text), nil
+}
+
+// transcriptionClient returns a client of the transcription endpoint: the voice settings of the config
+// override the base URL and the API key variable of the provider
+func transcriptionClient(config *config.Config) (openai.Client, error) {
+	voiceConfig := *config
+	if config.Voice.BaseURL != "" {
+		voiceConfig.BaseURL = config.Voice.BaseURL
+	}
+	if config.Voice.APIKeyEnv != "" {
+		voiceConfig.APIKeyEnv = config.Voice.APIKeyEnv
+	}
+	return provider.NewClient(&voiceConfig)
+}
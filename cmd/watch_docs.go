@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/watch"
+)
+
+// watch keeps the embeddings up to date while the docs are edited: once the docs files stop changing for
+// the debounce duration, an incremental run re-embeds the changed files and prunes the removed ones.
+// The config is read again for each run. It blocks until Ctrl+C.
+func (run *embeddingsRun) watch(debounce time.Duration) error {
+	run.incremental = true
+	fmt.Println()
+	fmt.Printf("👀 Watching %s for changes (Ctrl+C to stop)\n", run.docsPath)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Hidden directories (.git, editor folders) are not docs
+	skipHidden := func(path string) bool {
+		return strings.HasPrefix(filepath.Base(path), ".")
+	}
+	return watch.Run(ctx, run.docsPath, debounce, skipHidden, func(paths []string) {
+		var changed []string
+		for _, path := range paths {
+			if slices.Contains(run.extensions, filepath.Ext(path)) && !skipHidden(path) {
+				changed = append(changed, path)
+			}
+		}
+		if len(changed) == 0 {
+			return
+		}
+
+		fmt.Println()
+		fmt.Printf("%s 🔄 %d file(s) changed, updating the embeddings\n", time.Now().Format("15:04:05"), len(changed))
+		if err := run.generate(); err != nil {
+			fmt.Printf("❌ %v\n", err)
+		}
+		if run.interrupted {
+			stop()
+			return
+		}
+		fmt.Printf("👀 Watching %s for changes (Ctrl+C to stop)\n", run.docsPath)
+	})
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/outline"
+	"github.com/budgies-nest/budgie-cli/pkg/prompts"
+	"github.com/budgies-nest/budgie-cli/pkg/rag"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/openai/openai-go"
+	"github.com/spf13/cobra"
+)
+
+// writeInstructions is the system message of the generation of a section
+const writeInstructions = `You are writing a long-form Markdown document section by section, following its outline.
+Write only the content of the requested section: do not repeat its heading, do not write the other sections
+and do not add a conclusion to the document. Use headings below the level of the section only when needed.`
+
+// RunWrite handles the write command execution: it generates a document from an outline (a Markdown file, or
+// a prompt template filled with --var) section by section, one completion per heading, each with the RAG
+// context of its heading, then assembles the sections into a Markdown file with the front-matter of the
+// result files
+func RunWrite(cmd *cobra.Command, args []string) error {
+	configFile, _ := cmd.Flags().GetString("config")
+	systemFiles, _ := cmd.Flags().GetStringArray("system")
+	embeddingsFile, _ := cmd.Flags().GetString("embeddings")
+	ragEnabled, _ := cmd.Flags().GetBool("rag")
+	topK, _ := cmd.Flags().GetInt("top-k")
+	templateName, _ := cmd.Flags().GetString("template")
+	vars, _ := cmd.Flags().GetStringArray("var")
+	output, _ := cmd.Flags().GetString("output")
+	frontMatter, _ := cmd.Flags().GetBool("front-matter")
+
+	if (len(args) == 0) == (templateName == "") {
+		return fmt.Errorf("an outline file or --template is required (not both)")
+	}
+	values, err := prompts.ParseVars(vars)
+	if err != nil {
+		return err
+	}
+	if len(values) > 0 && templateName == "" {
+		return fmt.Errorf("--var requires --template")
+	}
+
+	var content, name string
+	if templateName != "" {
+		if content, _, err = templateQuestion(configFile, templateName, values, ""); err != nil {
+			return err
+		}
+		name = templateName
+	} else {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			return fmt.Errorf("error reading outline: %w", err)
+		}
+		content, name = string(data), strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+	}
+	plan := outline.Parse(content, name)
+	if len(plan.Sections) == 0 {
+		return fmt.Errorf("the outline has no sections (one Markdown heading per section, e.g. ## Installation)")
+	}
+	if output == "" {
+		output = name + "-draft.md"
+	}
+
+	opts := askOptions{
+		systemFiles:    systemFiles,
+		configFile:     configFile,
+		overrides:      configOverrides(cmd),
+		embeddingsFile: embeddingsFile,
+		ragEnabled:     ragEnabled,
+		topK:           topK,
+		quiet:          true,
+		modelCheck:     &modelCheck{},
+		question:       plan.Title,
+	}
+	config, err := loadAskConfig(opts)
+	if err != nil {
+		return err
+	}
+	base, err := baseMessages(opts)
+	if err != nil {
+		return err
+	}
+	base = append(base, openai.SystemMessage(writeInstructions))
+	if ragEnabled {
+		if opts.ragEnabled, err = checkEmbeddingModel(config, opts); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("✍️  Writing %q: %d section(s) with %s\n", plan.Title, len(plan.Sections), config.Model)
+	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	start := time.Now()
+	var document strings.Builder
+	var sources []rag.Similarity
+	var total tokenUsage
+	previous := ""
+	for i, section := range plan.Sections {
+		// The retrieval is scoped to the section, within the document
+		query := strings.Join(append(append([]string{plan.Title}, section.Path...), section.Heading, section.Notes), "\n")
+		messages := slices.Clone(base)
+		var similarities []rag.Similarity
+		if opts.ragEnabled {
+			found, _, errs := ragSearch(config, opts, query)
+			if len(errs) > 0 {
+				return errors.Join(errs...)
+			}
+			similarities = found
+			if len(similarities) > 0 {
+				messages = append(messages, openai.UserMessage(ragContextHeader+strings.Join(rag.Contents(similarities), "\n\n")))
+			}
+		}
+		messages = append(messages, openai.UserMessage(sectionPrompt(plan, section, previous)))
+
+		sectionStart := time.Now()
+		answer, usage, _, err := completeWithRetry(config, messages)
+		if err != nil {
+			return fmt.Errorf("error writing the section %q: %w", section.Heading, err)
+		}
+		recordUsage(config, opts, "write", usage, time.Since(sectionStart), true)
+		total.PromptTokens += usage.PromptTokens
+		total.CompletionTokens += usage.CompletionTokens
+		total.TotalTokens += usage.TotalTokens
+
+		answer = strings.TrimSpace(stripSectionHeading(answer, section.Heading))
+		previous = answer
+		sources = append(sources, similarities...)
+		fmt.Fprintf(&document, "%s %s\n\n", strings.Repeat("#", section.Level), section.Heading)
+		if answer != "" {
+			document.WriteString(answer + "\n\n")
+		}
+		fmt.Printf("✅ [%d/%d] %s %s\n", i+1, len(plan.Sections), section.Heading, dimStyle.Render(fmt.Sprintf("(%d words, %s)", len(strings.Fields(answer)), time.Since(sectionStart).Round(10*time.Millisecond))))
+	}
+
+	// A chunk retrieved for several sections is cited once
+	seen := make(map[string]bool)
+	sources = slices.DeleteFunc(sources, func(similarity rag.Similarity) bool {
+		duplicate := seen[similarity.ID]
+		seen[similarity.ID] = true
+		return duplicate
+	})
+	result := "# " + plan.Title + "\n\n" + strings.TrimSpace(document.String()) + sourcesSection("##", sources) + "\n"
+	if frontMatter {
+		opts.sources = sources
+		result = resultMetadata(config, opts, time.Now()).FrontMatter() + result
+	}
+	if err := os.WriteFile(output, []byte(result), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", output, err)
+	}
+
+	fmt.Println()
+	printSummaryTable([][]string{
+		{"Sections", fmt.Sprint(len(plan.Sections))},
+		{"Words", fmt.Sprint(len(strings.Fields(document.String())))},
+		{"Tokens", fmt.Sprint(total.TotalTokens)},
+		{"Duration", time.Since(start).Round(time.Second).String()},
+	})
+	blueStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("4"))
+	fmt.Println(blueStyle.Render(fmt.Sprintf("💾 Document saved to: %s", output)))
+	return nil
+}
+
+// sectionPrompt asks for the content of a section of the outline, with the end of the previous section
+// for the transition
+func sectionPrompt(plan outline.Outline, section outline.Section, previous string) string {
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Document: %s\n\n", plan.Title)
+	if plan.Brief != "" {
+		fmt.Fprintf(&prompt, "Brief:\n%s\n\n", plan.Brief)
+	}
+	fmt.Fprintf(&prompt, "Outline:\n%s\n", plan.Headings())
+	if previous != "" {
+		const tail = 1500
+		if len(previous) > tail {
+			previous = "..." + previous[len(previous)-tail:]
+		}
+		fmt.Fprintf(&prompt, "End of the previous section:\n%s\n\n", previous)
+	}
+	heading := strings.Join(append(slices.Clone(section.Path), section.Heading), " > ")
+	fmt.Fprintf(&prompt, "Write the section %q (heading level %d).", heading, section.Level)
+	if section.Notes != "" {
+		fmt.Fprintf(&prompt, " It covers:\n%s", section.Notes)
+	}
+	if section.Parent {
+		prompt.WriteString("\nIts subsections are written separately: only write a short introduction.")
+	}
+	return prompt.String()
+}
+
+// stripSectionHeading removes the heading of the section when the model repeated it
+func stripSectionHeading(answer, heading string) string {
+	trimmed := strings.TrimSpace(answer)
+	first, rest, _ := strings.Cut(trimmed, "\n")
+	if strings.HasPrefix(first, "#") && strings.EqualFold(strings.TrimSpace(strings.TrimLeft(first, "#")), heading) {
+		return rest
+	}
+	return answer
+}
@@ -5,6 +5,7 @@ import (
 	_ "embed"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/budgies-nest/budgie-cli/cmd"
 	"github.com/charmbracelet/fang"
@@ -20,6 +21,12 @@ var defaultSystemContent string
 //go:embed templates/docs-readme.md
 var defaultDocsReadmeContent string
 
+//go:embed templates/shell-integration.zsh
+var zshIntegrationContent string
+
+//go:embed templates/shell-integration.bash
+var bashIntegrationContent string
+
 //go:embed version.txt
 var versionContent string
 
@@ -32,6 +39,26 @@ func main() {
 		Version: strings.TrimSpace(versionContent),
 	}
 
+	rootCmd.PersistentFlags().String("profile", "", "Name of the config profile to use (overrides the default profile of the config file)")
+	rootCmd.PersistentFlags().String("provider", "", "Override the provider of the config file")
+	rootCmd.PersistentFlags().String("model", "", "Override the chat model of the config file")
+	rootCmd.PersistentFlags().String("embedding-model", "", "Override the embedding model of the config file")
+	rootCmd.PersistentFlags().String("base-url", "", "Override the base URL of the config file")
+	rootCmd.PersistentFlags().Float64("temperature", 0, "Override the temperature of the config file")
+	rootCmd.PersistentFlags().Float64("cosine-limit", 0, "Override the similarity threshold (cosine-limit) of the config file")
+	rootCmd.PersistentFlags().Bool("verbose", false, "Log the requests sent to the provider with their payload, the retrieved chunks with their scores and the duration of each phase to stderr")
+	rootCmd.PersistentFlags().Bool("debug", false, "Write the --verbose log to a file of .budgie/logs instead of stderr")
+	rootCmd.RegisterFlagCompletionFunc("profile", cmd.CompleteProfiles)
+	rootCmd.PersistentPreRunE = func(c *cobra.Command, args []string) error {
+		if cmd.IsCompletionCommand(c) {
+			return nil
+		}
+		if err := cmd.SetupLogging(c); err != nil {
+			return err
+		}
+		return cmd.SetupTracing(c)
+	}
+
 	var askCmd = &cobra.Command{
 		Use:   "ask",
 		Short: "Ask a question to the AI agent",
@@ -39,18 +66,84 @@ func main() {
 		RunE:  cmd.RunAsk,
 	}
 
-	askCmd.Flags().StringP("system", "s", ".budgie/budgie.system.md", "Path to system instructions file")
+	askCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order")
 	askCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
 	askCmd.Flags().StringP("output", "o", ".", "Path where to generate result files")
 	askCmd.Flags().BoolP("generate", "g", true, "Generate result file")
-	askCmd.Flags().StringP("question", "q", "", "User question (required)")
+	askCmd.Flags().String("output-name", "", "Template of the result file names, e.g. \"{{date}}-{{slug}}.md\" (variables: date, time, timestamp, slug, model, profile, session; overrides output-name from config)")
+	askCmd.Flags().Bool("raw", false, "Print the streamed answer as raw Markdown instead of rendering it (always raw when stdout is not a terminal)")
+	askCmd.Flags().Bool("no-post", false, "Show and save the answer as generated, without running the post-process commands of the config")
+	askCmd.Flags().Bool("no-cache", false, "Ask the model even when the answer cache has the answer (the cached answer is refreshed)")
+	askCmd.Flags().Bool("show-redactions", false, "Print the values masked by the redact rules of the config before the prompt is sent")
+	askCmd.Flags().Bool("quiet", false, "Only print the answer and the errors: no emojis, search banners, retrieved chunks, progress or result file messages (for scripts)")
+	askCmd.Flags().String("append", "", "Append the answers to this Markdown notebook, each under a header with its time and question, instead of generating result files")
+	askCmd.Flags().Bool("front-matter", true, "Start the result files with a YAML front-matter recording the question, the model, the temperature and the RAG sources of the answer")
+	askCmd.Flags().StringP("question", "q", "", "User question (required unless using --prompt, --from or piped stdin)")
 	askCmd.Flags().BoolP("prompt", "p", false, "Interactive TUI prompt mode")
 	askCmd.Flags().StringP("use", "u", "", "Path to file to include as additional system message")
 	askCmd.Flags().StringP("from", "f", "", "Path to file containing the user question/message")
+	askCmd.Flags().StringArray("url", nil, "Fetch this web page and add its readable text (converted to Markdown) as context, repeatable")
+	askCmd.Flags().String("context", "", "Add the file tree and the file contents of this directory as context (respects .gitignore, skips hidden, binary and large files, within attachment-token-limit)")
 	askCmd.Flags().BoolP("rag", "r", false, "Enable RAG (Retrieval-Augmented Generation) mode for enhanced responses with document context")
 	askCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+	askCmd.Flags().String("store", "", "Search only this named store of .budgie/stores (implies --rag), e.g. api-docs")
+	askCmd.Flags().IntP("top-k", "k", 0, "Maximum number of RAG chunks to use (overrides top-k from config)")
+	askCmd.Flags().Bool("rerank", false, "Ask the chat model to score the relevance of the retrieved RAG chunks and keep the top-k best ones (overrides rag.rerank from config)")
+	askCmd.Flags().Float64("keyword-weight", 0, "Weight of the BM25 keyword score in the hybrid RAG retrieval, between 0 and 1 (overrides keyword-weight from config)")
+	askCmd.Flags().Bool("same-language", false, "Only retrieve the RAG chunks written in the language of the question (chunks without a detected language are kept)")
+	askCmd.Flags().StringArray("filter", nil, "Only retrieve the RAG chunks whose metadata matches key=value[,value...] (keys: category, keyword, section), repeatable")
+	askCmd.Flags().Bool("check-previous", false, "Before asking the model, look for a similar question answered before and offer to show its answer")
+	askCmd.Flags().Bool("allow-tools", false, "Let the model call the tools defined in .budgie/tools.json (file read, web fetch, shell) and the tools of the MCP servers of the config before answering, each call approved interactively")
+	askCmd.Flags().StringP("template", "t", "", "Name of the prompt template of .budgie/prompts/ (<name>.md) used as the question, its {{variables}} filled from --var, piped stdin ({{input}}) or interactive prompts")
+	askCmd.Flags().StringArray("var", nil, "Value of a prompt template variable as key=value (repeatable)")
+	askCmd.Flags().String("diff", "", "Add the git diff to the prompt: the staged changes (working tree changes when nothing is staged) with --diff alone, or a commit or range, e.g. --diff main..HEAD")
+	askCmd.Flags().Lookup("diff").NoOptDefVal = "staged"
+	askCmd.Flags().Bool("clarify", false, "Detect ambiguous questions with a cheap model check and ask one clarifying question first (requires a terminal)")
+	askCmd.Flags().Bool("calibrate", false, "Ask the model to rate its confidence and to say when the question is not covered by the documentation (RAG mode)")
+	askCmd.Flags().String("session", "", "Name of the interactive session to resume and save (stored in .budgie/sessions/<name>.json)")
+	askCmd.Flags().Bool("offline", false, "Skip the chat model and answer with the most relevant documentation excerpts (used automatically when the chat backend is unreachable)")
+	askCmd.Flags().String("format", "markdown", "Output format in single question mode: markdown (streamed), plain (raw answer only) or json (envelope with question, model, RAG chunks, response, token usage and latency)")
+	askCmd.Flags().String("json-schema", "", "JSON schema file (or inline JSON schema) the answer must match: the schema is sent to the model, the answer is validated and only the JSON is printed")
+	askCmd.Flags().Int("schema-retries", 2, "Number of times an answer not matching --json-schema is sent back to the model with the validation errors")
+	askCmd.Flags().Duration("heartbeat", 30*time.Second, "Interval of the progress status line (stderr) during long completions (0 to disable)")
+	askCmd.Flags().String("pager", "auto", "Show the completed answer in $PAGER (colors preserved): auto (when it does not fit on one screen and stdout is a terminal), always or never")
+	askCmd.Flags().Lookup("pager").NoOptDefVal = "always"
+	askCmd.Flags().String("script", "", "Path of a YAML conversation file whose turns are asked one after the other in the same conversation, the transcript saved to one file")
+	askCmd.Flags().String("copy", "", "Copy the answer to the system clipboard: answer (the whole answer, with --copy alone) or code (its first fenced code block)")
+	askCmd.Flags().Lookup("copy").NoOptDefVal = "answer"
+	askCmd.Flags().String("voice", "", "Dictate the question: record it from the microphone with --voice alone (Enter to stop), or transcribe an audio file, e.g. --voice=memo.m4a (Whisper-compatible endpoint, see voice in the config)")
+	askCmd.Flags().Lookup("voice").NoOptDefVal = "mic"
+	askCmd.Flags().String("ws", "", "Mirror streamed tokens and status events over a websocket listening on this address (e.g. :9000, on the loopback interface unless a host is given)")
+	askCmd.Flags().StringArray("ws-origin", nil, "Origin of the web pages allowed to connect to the websocket, besides localhost (repeatable, e.g. https://dashboard.example.com)")
+	askCmd.Flags().Bool("ws-any-origin", false, "Allow the web pages of any origin to connect to the websocket")
+	askCmd.MarkFlagFilename("use")
+	askCmd.MarkFlagFilename("from")
+	askCmd.MarkFlagDirname("context")
+	askCmd.MarkFlagFilename("script", "yaml", "yml")
+	askCmd.RegisterFlagCompletionFunc("template", cmd.CompleteTemplates)
+	askCmd.RegisterFlagCompletionFunc("session", cmd.CompleteSessions)
+	askCmd.RegisterFlagCompletionFunc("store", cmd.CompleteStores)
+	askCmd.RegisterFlagCompletionFunc("format", cobra.FixedCompletions([]string{"markdown", "plain", "json"}, cobra.ShellCompDirectiveNoFileComp))
+	askCmd.RegisterFlagCompletionFunc("pager", cobra.FixedCompletions([]string{"auto", "always", "never"}, cobra.ShellCompDirectiveNoFileComp))
+	askCmd.RegisterFlagCompletionFunc("copy", cobra.FixedCompletions([]string{"answer", "code"}, cobra.ShellCompDirectiveNoFileComp))
 
-	askCmd.MarkFlagsOneRequired("question", "prompt", "from")
+	var chatCmd = &cobra.Command{
+		Use:   "chat",
+		Short: "Full-screen chat with a scrollable history",
+		Long:  "Open a full-screen chat with a scrollable history, a multi-line input box and keybindings to stop, regenerate and copy answers.",
+		RunE:  cmd.RunChat,
+	}
+
+	chatCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order")
+	chatCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	chatCmd.Flags().StringP("output", "o", ".", "Path where to generate result files")
+	chatCmd.Flags().BoolP("generate", "g", false, "Generate a result file for each answer")
+	chatCmd.Flags().StringP("use", "u", "", "Path to file to include as additional system message")
+	chatCmd.MarkFlagFilename("use")
+	chatCmd.Flags().BoolP("rag", "r", false, "Enable RAG (Retrieval-Augmented Generation) mode for enhanced responses with document context")
+	chatCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+	chatCmd.Flags().IntP("top-k", "k", 0, "Maximum number of RAG chunks to use (overrides top-k from config)")
+	chatCmd.Flags().Bool("rerank", false, "Ask the chat model to score the relevance of the retrieved RAG chunks and keep the top-k best ones (overrides rag.rerank from config)")
 
 	var generateEmbeddingsCmd = &cobra.Command{
 		Use:   "generate-embeddings",
@@ -60,14 +153,546 @@ func main() {
 	}
 
 	generateEmbeddingsCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
-	generateEmbeddingsCmd.Flags().StringP("docs", "d", ".budgie/docs", "Path to docs directory containing markdown files")
+	generateEmbeddingsCmd.Flags().StringP("docs", "d", ".budgie/docs", "Path to docs directory containing markdown files (overrides docs from config)")
 	generateEmbeddingsCmd.Flags().BoolP("markdown-hierarchy", "m", false, "Use markdown hierarchy chunking")
 	generateEmbeddingsCmd.Flags().BoolP("markdown-sections", "s", false, "Use markdown sections chunking")
 	generateEmbeddingsCmd.Flags().StringP("delimiter", "D", "", "Use delimiter-based chunking with specified delimiter")
 	generateEmbeddingsCmd.Flags().IntP("chunk-size", "z", 0, "Use fixed-size text chunking with specified size")
 	generateEmbeddingsCmd.Flags().IntP("overlap", "o", 0, "Overlap length for fixed-size chunking (requires --chunk-size)")
-	generateEmbeddingsCmd.Flags().StringP("extension", "e", "", "File extension to process (for --delimiter, --chunk-size, and --files methods, default: .md)")
+	generateEmbeddingsCmd.Flags().StringP("extension", "e", "", "Comma-separated file extensions to process, each optionally followed by a chunking strategy (e.g. md,go:code,txt:sections, default: .md)")
 	generateEmbeddingsCmd.Flags().BoolP("files", "f", false, "Use whole-file chunking (each file is one chunk)")
+	generateEmbeddingsCmd.Flags().StringSlice("include", nil, "Only embed the files matching these glob patterns (repeatable, added to include from config)")
+	generateEmbeddingsCmd.Flags().StringSlice("exclude", nil, "Skip the files and directories matching these glob patterns, e.g. node_modules or \"tests/fixtures/**\" (repeatable, added to exclude from config)")
+	generateEmbeddingsCmd.Flags().BoolP("incremental", "i", false, "Only embed new or changed files and prune the chunks of removed files")
+	generateEmbeddingsCmd.Flags().IntP("concurrency", "j", 1, "Number of chunks embedded in parallel")
+	generateEmbeddingsCmd.Flags().Float64("rate-limit", 0, "Maximum number of embedding requests per second (0 for no limit)")
+	generateEmbeddingsCmd.Flags().BoolP("watch", "w", false, "After generating, keep watching the docs directory and incrementally re-embed the changed files")
+	generateEmbeddingsCmd.Flags().Duration("debounce", 2*time.Second, "Delay without file changes before re-embedding (with --watch)")
+	generateEmbeddingsCmd.Flags().String("url", "", "Crawl a docs site (same host) into the web directory of the docs before embedding, e.g. https://docs.example.com")
+	generateEmbeddingsCmd.Flags().Int("depth", 1, "Number of links followed from the --url page")
+	generateEmbeddingsCmd.Flags().Int("max-pages", 50, "Maximum number of pages crawled with --url")
+	generateEmbeddingsCmd.Flags().String("store", "", "Generate the named store .budgie/stores/<name> (its docs default to .budgie/stores/<name>/docs) instead of the default store")
+	generateEmbeddingsCmd.Flags().String("embeddings", "", "Path of the embeddings file to generate instead of the default store, e.g. a shared store (cannot be used with --store)")
+	generateEmbeddingsCmd.Flags().Bool("dry-run", false, "Chunk the files and print the chunks, estimated tokens, cost and time per file, without embedding them nor touching the store")
+	generateEmbeddingsCmd.RegisterFlagCompletionFunc("store", cmd.CompleteStores)
+
+	var chunksCmd = &cobra.Command{
+		Use:   "chunks <file>",
+		Short: "Preview the chunks of a file",
+		Long:  "Print the chunks a file would be split into by generate-embeddings, with their boundaries highlighted, to tune the chunking before regenerating the embeddings. The file uses the chunking rule of its extension from the config (or its default rule) unless --strategy, --chunk-size or --delimiter select another one.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunChunks,
+	}
+
+	chunksCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	chunksCmd.Flags().StringP("strategy", "S", "", "Chunking strategy: hierarchy, sections, delimiter, size, files, code or pages (markdown-hierarchy and markdown-sections are accepted)")
+	chunksCmd.Flags().StringP("delimiter", "D", "", "Delimiter of the delimiter strategy")
+	chunksCmd.Flags().IntP("chunk-size", "z", 0, "Chunk size of the size strategy (size of the split pages with pages, target size with code)")
+	chunksCmd.Flags().IntP("overlap", "o", 0, "Overlap length of the size and pages strategies")
+	chunksCmd.Flags().Bool("stats", false, "Only print the size of each chunk")
+	chunksCmd.RegisterFlagCompletionFunc("strategy", cobra.FixedCompletions([]string{"hierarchy", "sections", "delimiter", "size", "files", "code", "pages"}, cobra.ShellCompDirectiveNoFileComp))
+
+	var searchCmd = &cobra.Command{
+		Use:   "search [question]",
+		Short: "Search the embeddings without calling the chat model",
+		Long:  "Run the RAG similarity search and print the matching chunks with their similarity scores, without calling the chat model. Useful to debug retrieval quality or to pipe chunks into other tools.",
+		RunE:  cmd.RunSearch,
+	}
+
+	searchCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	searchCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+	searchCmd.Flags().StringP("question", "q", "", "Question to search for (can also be given as arguments)")
+	searchCmd.Flags().IntP("top-k", "k", 0, "Maximum number of chunks to return (overrides top-k from config)")
+	searchCmd.Flags().Float64("keyword-weight", 0, "Weight of the BM25 keyword score in the hybrid retrieval, between 0 and 1 (overrides keyword-weight from config)")
+	searchCmd.Flags().BoolP("json", "j", false, "Print the results as JSON")
+	searchCmd.Flags().Bool("same-language", false, "Only return the chunks written in the language of the question")
+	searchCmd.Flags().StringArray("filter", nil, "Only return the chunks whose metadata matches key=value[,value...] (keys: category, keyword, section), repeatable")
+
+	var indexCmd = &cobra.Command{
+		Use:   "index",
+		Short: "Index the project source files into the code collection",
+		Long:  "Embed the project source files into the code collection (.budgie/code-embeddings.json) searched by RAG questions. With --background, keep watching the project and re-embed files as they change.",
+		RunE:  cmd.RunIndex,
+	}
+
+	indexCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	indexCmd.Flags().StringP("root", "r", ".", "Root directory of the project to index")
+	indexCmd.Flags().StringP("extensions", "e", ".go,.py,.js,.ts,.java,.rs,.rb,.c,.h,.cpp,.cs,.php,.kt,.swift,.sh", "Comma-separated list of source file extensions to index")
+	indexCmd.Flags().IntP("chunk-size", "z", 1024, "Chunk size used to split source files")
+	indexCmd.Flags().IntP("overlap", "o", 128, "Overlap length between chunks")
+	indexCmd.Flags().BoolP("background", "b", false, "Keep watching the project and incrementally re-embed changed files")
+	indexCmd.Flags().Duration("debounce", 2*time.Second, "Delay without file changes before re-embedding (with --background)")
+
+	var embeddingsCmd = &cobra.Command{
+		Use:   "embeddings",
+		Short: "Inspect and manage the embeddings",
+		Long:  "Inspect and manage the vector store generated by generate-embeddings (or the code collection with --embeddings .budgie/code-embeddings.json).",
+	}
+
+	embeddingsCmd.PersistentFlags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	embeddingsCmd.PersistentFlags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file")
+
+	var embeddingsStatsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Show the content of the vector store",
+		Long:  "Report the number of chunks, the chunks per file, the average chunk length, the embedding dimension, the size on disk and the orphaned chunks whose source file no longer exists.",
+		RunE:  cmd.RunEmbeddingsStats,
+	}
+
+	embeddingsStatsCmd.Flags().BoolP("json", "j", false, "Print the statistics as JSON")
+
+	var embeddingsDeleteCmd = &cobra.Command{
+		Use:   "delete <file|glob>...",
+		Short: "Delete the embeddings of files",
+		Long:  "Delete the chunks of the source files matching the paths or glob patterns (matched against the recorded path or the path relative to the docs directory, e.g. guide/old.md, \"*.gen.md\" or \"archive/**\"), without regenerating the store.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE:  cmd.RunEmbeddingsDelete,
+	}
+
+	embeddingsDeleteCmd.Flags().Bool("dry-run", false, "List the files whose chunks would be deleted without deleting them")
+
+	var embeddingsPruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Delete the embeddings of removed files",
+		Long:  "Delete the chunks of the source files which no longer exist (e.g. removed from .budgie/docs), without regenerating the store.",
+		RunE:  cmd.RunEmbeddingsPrune,
+	}
+
+	embeddingsPruneCmd.Flags().Bool("dry-run", false, "List the files whose chunks would be deleted without deleting them")
+
+	var embeddingsExportCmd = &cobra.Command{
+		Use:   "export <archive>",
+		Short: "Export the vector store to an archive",
+		Long:  "Write the vector store, its manifest and how it was generated (embedding model, dimension, chunking) to a .tar.zst, .tar.gz or .tar archive, to share a prebuilt knowledge base (e.g. as a CI artifact) or distribute it with a project.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunEmbeddingsExport,
+	}
+
+	var embeddingsImportCmd = &cobra.Command{
+		Use:   "import <archive>",
+		Short: "Import the vector store of an archive",
+		Long:  "Replace the vector store with the one of an archive written by embeddings export, in the configured vector-store backend (the keyword index is rebuilt).",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunEmbeddingsImport,
+	}
+
+	embeddingsImportCmd.Flags().Bool("force", false, "Replace the existing vector store")
+
+	embeddingsCmd.AddCommand(embeddingsStatsCmd)
+	embeddingsCmd.AddCommand(embeddingsDeleteCmd)
+	embeddingsCmd.AddCommand(embeddingsPruneCmd)
+	embeddingsCmd.AddCommand(embeddingsExportCmd)
+	embeddingsCmd.AddCommand(embeddingsImportCmd)
+
+	var statsCmd = &cobra.Command{
+		Use:   "stats",
+		Short: "Show the usage and feedback statistics per model",
+		Long:  "Aggregate the usage ledger (.budgie/budgie.db) shared by all the budgie processes of the project: requests, tokens, average latency and feedback per model.",
+		RunE:  cmd.RunStats,
+	}
+
+	statsCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	statsCmd.Flags().String("since", "", "Only include the requests of this period (e.g. 7d, 2w, 12h)")
+	statsCmd.Flags().BoolP("json", "j", false, "Print the statistics as JSON")
+
+	var configCmd = &cobra.Command{
+		Use:   "config",
+		Short: "Manage the configuration",
+		Long:  "Manage the budgie configuration file and its profiles.",
+	}
+
+	configCmd.PersistentFlags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+
+	var configUseCmd = &cobra.Command{
+		Use:   "use <profile>",
+		Short: "Set the default profile",
+		Long:  "Set the profile used by all the commands when --profile is not given.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunConfigUse,
+	}
+	configUseCmd.ValidArgsFunction = cmd.CompleteProfiles
+
+	var configProfilesCmd = &cobra.Command{
+		Use:   "profiles",
+		Short: "List the profiles",
+		Long:  "List the profiles of the configuration file and show the default one.",
+		RunE:  cmd.RunConfigProfiles,
+	}
+
+	var configGetCmd = &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the value of a config key",
+		Long:  "Print the value of a config key used by the commands: the value of the config file, or its default. Nested keys are separated with dots, e.g. rag.rerank or temperatures.commit.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunConfigGet,
+	}
+
+	var configSetCmd = &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a config key",
+		Long:  "Set a key of the config file, e.g. budgie config set temperature 0.2. The value is validated against the type of the key (text, number, true/false, comma-separated list or JSON object) and the resulting config is checked before it is saved. Nested keys are separated with dots, e.g. temperatures.commit or profiles.fast.model.",
+		Args:  cobra.ExactArgs(2),
+		RunE:  cmd.RunConfigSet,
+	}
+
+	var configUnsetCmd = &cobra.Command{
+		Use:   "unset <key>",
+		Short: "Remove a config key",
+		Long:  "Remove a key from the config file, the commands use its default value.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunConfigUnset,
+	}
+
+	var configListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the config keys and their values",
+		Long:  "List every config key with the value used by the commands, the keys not set in the config file are dimmed.",
+		RunE:  cmd.RunConfigList,
+	}
+
+	configCmd.AddCommand(configUseCmd)
+	configCmd.AddCommand(configProfilesCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+
+	var resultsCmd = &cobra.Command{
+		Use:   "results",
+		Short: "Manage the generated result files",
+		Long:  "Manage the result files generated by the ask command.",
+	}
+
+	var resultsGCCmd = &cobra.Command{
+		Use:   "gc",
+		Short: "Remove old result files",
+		Long:  "Remove the result files generated by budgie (recorded in the ledger) older than the given age from the output directory and its dated/session subfolders, then the folders left empty. The other files of the output directory are never removed.",
+		RunE:  cmd.RunResultsGC,
+	}
+
+	resultsGCCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file (the ledger recording the result files is next to it)")
+	resultsGCCmd.Flags().StringP("output", "o", ".", "Path where the result files are generated")
+	resultsGCCmd.Flags().String("older-than", "30d", "Remove the result files older than this age (e.g. 30d, 2w, 12h)")
+	resultsGCCmd.Flags().Bool("dry-run", false, "List the result files that would be removed without removing them")
+
+	resultsCmd.AddCommand(resultsGCCmd)
+
+	var historyCmd = &cobra.Command{
+		Use:   "history",
+		Short: "Search and replay the questions asked before",
+		Long:  "List, search, show, re-run and export the questions and answers logged to .budgie/history.jsonl (enable the log with budgie config set history true).",
+	}
+	historyCmd.PersistentFlags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+
+	var historyListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the last questions",
+		Args:  cobra.NoArgs,
+		RunE:  cmd.RunHistoryList,
+	}
+	historyListCmd.Flags().IntP("limit", "n", 20, "Number of questions listed (0 for all)")
+
+	var historyGrepCmd = &cobra.Command{
+		Use:   "grep <pattern>",
+		Short: "List the questions whose question or answer matches a pattern",
+		Long:  "List the questions whose question or answer matches a regular expression (case-insensitive).",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunHistoryGrep,
+	}
+
+	var historyShowCmd = &cobra.Command{
+		Use:   "show <id>",
+		Short: "Show a question and its answer",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunHistoryShow,
+	}
+	historyShowCmd.ValidArgsFunction = cmd.CompleteHistoryIDs
+
+	var historyRerunCmd = &cobra.Command{
+		Use:   "rerun <id>",
+		Short: "Ask a question of the history again",
+		Long:  "Ask a question of the history again with the current model and documentation (with RAG when it was asked with RAG).",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunHistoryRerun,
+	}
+	historyRerunCmd.ValidArgsFunction = cmd.CompleteHistoryIDs
+	historyRerunCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order")
+	historyRerunCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+	historyRerunCmd.Flags().StringP("output", "o", ".", "Path where to generate result files")
+	historyRerunCmd.Flags().BoolP("generate", "g", true, "Generate result file")
+
+	var historyExportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export the history to Markdown",
+		Long:  "Write the questions and answers of the history as a Markdown document, one section per question.",
+		Args:  cobra.NoArgs,
+		RunE:  cmd.RunHistoryExport,
+	}
+	historyExportCmd.Flags().StringP("output", "o", "", "Path of the Markdown file (default: stdout)")
+
+	historyCmd.AddCommand(historyListCmd)
+	historyCmd.AddCommand(historyGrepCmd)
+	historyCmd.AddCommand(historyShowCmd)
+	historyCmd.AddCommand(historyRerunCmd)
+	historyCmd.AddCommand(historyExportCmd)
+
+	var ingestCmd = &cobra.Command{
+		Use:   "ingest",
+		Short: "Bootstrap the project from existing documentation",
+		Long:  "Import existing documentation into the budgie project.",
+	}
+
+	ingestCmd.PersistentFlags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+
+	var ingestSiteConfigCmd = &cobra.Command{
+		Use:   "site-config <mkdocs.yml|docusaurus.config.js>",
+		Short: "Import the docs of a MkDocs or Docusaurus site",
+		Long:  "Read the config of a MkDocs or Docusaurus site, set its docs directory and the chunking rules of its markdown flavor in the budgie config, and save the navigation of its pages (recorded as chunk metadata by generate-embeddings, filter with --filter section=<title>).",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunIngestSiteConfig,
+	}
+
+	ingestCmd.AddCommand(ingestSiteConfigCmd)
+
+	var mcpCmd = &cobra.Command{
+		Use:   "mcp",
+		Short: "Manage the Model Context Protocol servers",
+		Long:  "Manage the Model Context Protocol servers declared in the mcp-servers section of the config, whose tools are available to ask --allow-tools.",
+	}
+
+	mcpCmd.PersistentFlags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+
+	var mcpListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List the MCP servers and their tools",
+		Long:  "Connect to the MCP servers of the config (stdio, sse or http transport) and list the tools they give to the model.",
+		RunE:  cmd.RunMCPList,
+	}
+
+	mcpCmd.AddCommand(mcpListCmd)
+
+	var mcpServeCmd = &cobra.Command{
+		Use:   "mcp-serve",
+		Short: "Expose the documentation search as an MCP server",
+		Long:  "Serve the search_docs (matching chunks) and ask_docs (answer with the documentation as context) tools over MCP stdio, so IDE assistants (Claude Desktop, VS Code agents...) can query the local .budgie knowledge base.",
+		RunE:  cmd.RunMCPServe,
+	}
+
+	mcpServeCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order (used by ask_docs)")
+	mcpServeCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	mcpServeCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+
+	var commitCmd = &cobra.Command{
+		Use:   "commit",
+		Short: "Write the commit message of the staged changes",
+		Long:  "Send the staged diff to the model with the commit message template (.budgie/commit.system.md, or the built-in one), then commit with the message after confirmation, or only print it with --print. Set conventional-commits in the config for Conventional Commits messages.",
+		RunE:  cmd.RunCommit,
+	}
+
+	commitCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	commitCmd.Flags().StringP("template", "t", ".budgie/commit.system.md", "Path to the commit message system prompt template (the built-in template is used when the default file does not exist)")
+	commitCmd.Flags().String("hint", "", "Context of the changes given to the model (e.g. the issue fixed)")
+	commitCmd.Flags().Bool("print", false, "Only print the commit message on stdout, without committing")
+	commitCmd.Flags().BoolP("yes", "y", false, "Commit without confirmation")
+
+	var compareCmd = &cobra.Command{
+		Use:   "compare [question]",
+		Short: "Compare the answers of several profiles or models",
+		Long:  "Ask the same question, with the same RAG context, to several profiles or models at the same time, and print their answers side by side (or as diffs against the first one with --diff) along with the latency and tokens of each run, e.g. budgie compare -q \"...\" --profile fast --profile quality.",
+		RunE:  cmd.RunCompare,
+	}
+
+	compareCmd.Flags().StringP("question", "q", "", "Question to ask")
+	compareCmd.Flags().StringArray("profile", nil, "Profile to compare, repeatable")
+	compareCmd.Flags().StringArray("model", nil, "Chat model to compare (with the default profile), repeatable")
+	compareCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order")
+	compareCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	compareCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+	compareCmd.Flags().BoolP("rag", "r", false, "Enable RAG (Retrieval-Augmented Generation) mode: the same chunks are given to every run")
+	compareCmd.Flags().IntP("top-k", "k", 0, "Maximum number of RAG chunks to use (overrides top-k from config)")
+	compareCmd.Flags().Bool("diff", false, "Print the answers as diffs against the first one instead of side by side")
+	compareCmd.RegisterFlagCompletionFunc("profile", cmd.CompleteProfiles)
+
+	var sweepCmd = &cobra.Command{
+		Use:   "sweep [question]",
+		Short: "Answer a question at several temperatures",
+		Long:  "Answer the same question, with the same RAG context, at each temperature of --temperatures, several times (--runs) or with several seeds (--seeds). Each answer is written to a file of the output directory along with an index.md matrix of the runs, to pick the generation settings empirically.",
+		RunE:  cmd.RunSweep,
+	}
+
+	sweepCmd.Flags().StringP("question", "q", "", "Question to ask")
+	sweepCmd.Flags().Float64Slice("temperatures", nil, "Comma-separated temperatures to sweep, e.g. 0,0.3,0.7")
+	sweepCmd.Flags().Int("runs", 1, "Number of answers per temperature")
+	sweepCmd.Flags().Int64Slice("seeds", nil, "Comma-separated seeds, one answer per seed and temperature (instead of --runs)")
+	sweepCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order")
+	sweepCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	sweepCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+	sweepCmd.Flags().BoolP("rag", "r", false, "Enable RAG (Retrieval-Augmented Generation) mode: the same chunks are given to every run")
+	sweepCmd.Flags().IntP("top-k", "k", 0, "Maximum number of RAG chunks to use (overrides top-k from config)")
+	sweepCmd.Flags().String("output-dir", "sweep", "Directory where the answers and the index are written")
+	sweepCmd.Flags().IntP("concurrency", "j", 4, "Number of answers generated at the same time")
+
+	var writeCmd = &cobra.Command{
+		Use:   "write [outline.md]",
+		Short: "Write a document section by section from an outline",
+		Long:  "Write a long-form Markdown document from an outline file (or a prompt template filled with --var): the level 1 heading is the title, every other heading is a section and the text under it describes the section. Each section is generated by its own completion, with the RAG context of its heading, and the sections are assembled into a single Markdown file with a front-matter.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cmd.RunWrite,
+	}
+
+	writeCmd.Flags().StringP("template", "t", "", "Name of the prompt template of .budgie/prompts/ (<name>.md) used as the outline, instead of an outline file")
+	writeCmd.Flags().StringArray("var", nil, "Value of a prompt template variable as key=value (repeatable)")
+	writeCmd.Flags().StringP("output", "o", "", "Path of the document (default: <outline>-draft.md)")
+	writeCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order")
+	writeCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	writeCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+	writeCmd.Flags().BoolP("rag", "r", false, "Enable RAG (Retrieval-Augmented Generation) mode: each section gets the chunks of its heading")
+	writeCmd.Flags().IntP("top-k", "k", 0, "Maximum number of RAG chunks to use per section (overrides top-k from config)")
+	writeCmd.Flags().Bool("front-matter", true, "Start the document with a YAML front-matter recording the title, the model, the temperature and the RAG sources")
+	writeCmd.RegisterFlagCompletionFunc("template", cmd.CompleteTemplates)
+
+	var summarizeCmd = &cobra.Command{
+		Use:   "summarize <file-or-directory>",
+		Short: "Summarize a large file or the files of a directory",
+		Long:  "Summarize a file (or the text files of a directory) of any size: the text is split into parts fitting in a completion, each part is summarized, then the summaries are consolidated into a single summary (map-reduce). The summary is printed on stdout, or written to --output.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunSummarize,
+	}
+
+	summarizeCmd.Flags().String("focus", "", "What the summary should focus on, e.g. \"the breaking changes\"")
+	summarizeCmd.Flags().StringP("output", "o", "", "Path of the file where the summary is written (default: stdout)")
+	summarizeCmd.Flags().Int("chunk-tokens", 0, "Approximate size in tokens of the parts summarized by each completion, to fit the context window of the model (default: attachment-token-limit from config)")
+	summarizeCmd.Flags().IntP("concurrency", "j", 4, "Number of parts summarized at the same time")
+	summarizeCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+
+	var translateCmd = &cobra.Command{
+		Use:   "translate <file>",
+		Short: "Translate a file into another language",
+		Long:  "Translate a text file with the chat model, part by part for the large files, keeping its formatting (Markdown, code blocks, front-matter). The translation is written next to the file (guide.md is translated to guide.fr.md with --to fr), to --output, or over the file with --in-place.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunTranslate,
+	}
+
+	translateCmd.Flags().String("to", "", "Language of the translation, as a code or a name (e.g. fr, de, \"Brazilian Portuguese\")")
+	translateCmd.Flags().String("from", "", "Language of the file (default: detected by the model)")
+	translateCmd.Flags().StringP("output", "o", "", "Path of the translation (default: <file>.<language>.<ext> next to the file)")
+	translateCmd.Flags().Bool("in-place", false, "Replace the file with its translation")
+	translateCmd.Flags().Int("chunk-tokens", 2000, "Approximate size in tokens of the parts translated by each completion")
+	translateCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	translateCmd.Flags().StringP("template", "t", ".budgie/translate.system.md", "Path to the translation system prompt template (the built-in template is used when the default file does not exist)")
+
+	var proofreadCmd = &cobra.Command{
+		Use:   "proofread <file>",
+		Short: "Correct the spelling and grammar of a file",
+		Long:  "Correct the spelling, grammar and typography of a text file with the chat model, part by part for the large files, keeping its style and formatting. The corrections are shown as a diff, then written to the file after confirmation (or to --output).",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunProofread,
+	}
+
+	proofreadCmd.Flags().StringP("output", "o", "", "Path of the corrected file, instead of correcting the file in place")
+	proofreadCmd.Flags().BoolP("yes", "y", false, "Write the corrections without confirmation")
+	proofreadCmd.Flags().Bool("dry-run", false, "Only show the diff of the corrections, without writing them")
+	proofreadCmd.Flags().Int("chunk-tokens", 2000, "Approximate size in tokens of the parts corrected by each completion")
+	proofreadCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	proofreadCmd.Flags().StringP("template", "t", ".budgie/proofread.system.md", "Path to the proofreading system prompt template (the built-in template is used when the default file does not exist)")
+
+	var evalCmd = &cobra.Command{
+		Use:   "eval <questions.yaml>",
+		Short: "Measure the quality of the RAG retrieval",
+		Long:  "Run the RAG retrieval for the questions of a YAML evaluation set, each with the sources expected among the retrieved chunks and/or the keywords expected in the answer, and report the recall@k, the MRR and the keyword hit rate. With --generate, the questions are also answered by the chat model and the keywords are looked for in the answers.",
+		Args:  cobra.ExactArgs(1),
+		RunE:  cmd.RunEval,
+	}
+
+	evalCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order (with --generate)")
+	evalCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	evalCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+	evalCmd.Flags().IntP("top-k", "k", 0, "Maximum number of RAG chunks to retrieve (overrides top-k from config)")
+	evalCmd.Flags().Float64("keyword-weight", 0, "Weight of the BM25 keyword score in the hybrid retrieval, between 0 and 1 (overrides keyword-weight from config)")
+	evalCmd.Flags().Bool("rerank", false, "Ask the chat model to score the relevance of the retrieved RAG chunks (overrides rag.rerank from config)")
+	evalCmd.Flags().BoolP("generate", "g", false, "Also answer the questions with the chat model and look for the keywords in the answers")
+	evalCmd.Flags().Bool("json", false, "Print the evaluation report as JSON")
+
+	var batchCmd = &cobra.Command{
+		Use:   "batch",
+		Short: "Answer many questions from a file or a directory",
+		Long:  "Answer the questions of a file (one per line) or of a directory (one per .md or .txt file) concurrently, with retries, write each answer to a file of the output directory and a summary report (batch-report.json).",
+		Args:  cobra.NoArgs,
+		RunE:  cmd.RunBatch,
+	}
+
+	batchCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order")
+	batchCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	batchCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+	batchCmd.Flags().BoolP("rag", "r", false, "Enable RAG (Retrieval-Augmented Generation) mode for enhanced responses with document context")
+	batchCmd.Flags().IntP("top-k", "k", 0, "Maximum number of RAG chunks to use (overrides top-k from config)")
+	batchCmd.Flags().StringP("input", "i", "", "File with one question per line, or directory with one question per .md or .txt file")
+	batchCmd.MarkFlagFilename("input")
+	batchCmd.Flags().String("output-dir", "answers", "Directory where the answers and the report are written")
+	batchCmd.Flags().IntP("concurrency", "j", 4, "Number of questions answered at the same time")
+	batchCmd.Flags().Int("retry-attempts", 0, "Number of attempts of a completion failing with a retryable error (overrides retry-attempts from config)")
+	batchCmd.Flags().Bool("skip-existing", false, "Skip the questions whose answer file exists (e.g. to retry the failed questions of a previous run)")
+
+	var applyCmd = &cobra.Command{
+		Use:   "apply [file]",
+		Short: "Write the code blocks of an answer into the files they are annotated with",
+		Long:  "Write the fenced code blocks annotated with a file path (```go title=pkg/foo.go or ```go:pkg/foo.go) of the last answer of the history, or of a result file, into the workspace. The diff of each file is shown before a confirmation.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cmd.RunApply,
+	}
+
+	applyCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	applyCmd.Flags().Int("history", 0, "Id of the history answer to apply (default: the last answer)")
+	applyCmd.Flags().BoolP("yes", "y", false, "Write the files without confirmation")
+	applyCmd.Flags().Bool("dry-run", false, "Only show the diffs, without writing the files")
+
+	var doctorCmd = &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the project setup and report how to fix the problems",
+		Long:  "Validate the config file, check that the provider is reachable and serves the chat and embedding models, and that the embeddings store matches the embedding model and its dimension. Each problem comes with its fix.",
+		RunE:  cmd.RunDoctor,
+	}
+
+	doctorCmd.Flags().StringArrayP("system", "s", []string{".budgie/budgie.system.md"}, "Path to system instructions file or directory of .md files, repeatable: the files are layered in order")
+	doctorCmd.Flags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+	doctorCmd.Flags().StringP("embeddings", "e", ".budgie/embeddings.json", "Path to embeddings file for RAG similarity search")
+
+	var authCmd = &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the API keys of the providers",
+		Long:  "Store the API keys of the providers in the OS keychain (Keychain on macOS, Credential Manager on Windows, Secret Service on Linux) instead of environment variables. The environment variable of a provider, when set, is used instead of the stored key (e.g. in CI).",
+	}
+
+	authCmd.PersistentFlags().StringP("config", "c", ".budgie/budgie.config.json", "Path to configuration file")
+
+	var authLoginCmd = &cobra.Command{
+		Use:   "login [provider]",
+		Short: "Store the API key of a provider in the OS keychain",
+		Long:  "Store the API key of a provider (the provider of the config file by default) in the OS keychain. The key is asked without echo, or read from stdin when it is piped, e.g. echo $OPENAI_API_KEY | budgie auth login openai.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cmd.RunAuthLogin,
+	}
+	authLoginCmd.ValidArgsFunction = cmd.CompleteProviders
+
+	var authLogoutCmd = &cobra.Command{
+		Use:   "logout [provider]",
+		Short: "Remove the API key of a provider from the OS keychain",
+		Long:  "Remove the API key of a provider (the provider of the config file by default) from the OS keychain.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cmd.RunAuthLogout,
+	}
+	authLogoutCmd.ValidArgsFunction = cmd.CompleteProviders
+
+	var authStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "Show where the API keys are read from",
+		Long:  "Show, for each provider requiring an API key, whether its key is read from its environment variable, from the OS keychain, or is missing.",
+		RunE:  cmd.RunAuthStatus,
+	}
+
+	authCmd.AddCommand(authLoginCmd)
+	authCmd.AddCommand(authLogoutCmd)
+	authCmd.AddCommand(authStatusCmd)
 
 	var initCmd = &cobra.Command{
 		Use:   "init",
@@ -78,6 +703,20 @@ func main() {
 		},
 	}
 
+	initCmd.Flags().Bool("force", false, "Overwrite the default files (config, system instructions, docs README) of an existing project")
+	initCmd.Flags().Bool("upgrade", false, "Merge the new defaults into an existing project: add the missing config fields and files, keeping your values")
+
+	var shellIntegrationCmd = &cobra.Command{
+		Use:       "shell-integration [zsh|bash]",
+		Short:     "Print the shell widget binding a hotkey to budgie",
+		Long:      "Print the zsh or bash widget script (defaults to the current $SHELL). Install it with eval \"$(budgie shell-integration zsh)\" in your shell rc file, then press Ctrl+X Ctrl+A to ask budgie about the current command line, or about the last command when the line is empty.",
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: []string{"zsh", "bash"},
+		RunE: func(c *cobra.Command, args []string) error {
+			return cmd.RunShellIntegration(c, args, zshIntegrationContent, bashIntegrationContent)
+		},
+	}
+
 	var versionCmd = &cobra.Command{
 		Use:   "version",
 		Short: "Show the version of budgie",
@@ -88,11 +727,39 @@ func main() {
 	}
 
 	rootCmd.AddCommand(askCmd)
+	rootCmd.AddCommand(chatCmd)
 	rootCmd.AddCommand(generateEmbeddingsCmd)
+	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(chunksCmd)
+	rootCmd.AddCommand(indexCmd)
+	rootCmd.AddCommand(embeddingsCmd)
+	rootCmd.AddCommand(statsCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(resultsCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(ingestCmd)
+	rootCmd.AddCommand(mcpCmd)
+	rootCmd.AddCommand(mcpServeCmd)
+	rootCmd.AddCommand(commitCmd)
+	rootCmd.AddCommand(batchCmd)
+	rootCmd.AddCommand(evalCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(sweepCmd)
+	rootCmd.AddCommand(writeCmd)
+	rootCmd.AddCommand(summarizeCmd)
+	rootCmd.AddCommand(translateCmd)
+	rootCmd.AddCommand(proofreadCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(shellIntegrationCmd)
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(cmd.PluginCommands(rootCmd)...)
 
-	if err := fang.Execute(context.TODO(), rootCmd); err != nil {
+	err := fang.Execute(context.TODO(), rootCmd)
+	cmd.EndTracing(err)
+	if err != nil {
 		os.Exit(1)
 	}
 }
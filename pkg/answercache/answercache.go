@@ -0,0 +1,89 @@
+package answercache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/ledger"
+)
+
+// Entry is a cached answer
+type Entry struct {
+	Created  time.Time `json:"created"`
+	Model    string    `json:"model"`
+	Question string    `json:"question,omitempty"`
+	Answer   string    `json:"answer"`
+}
+
+// Key returns the content address of a prompt: the hash of the prompt (any JSON value, e.g. the messages sent
+// to the model) along with the parameters of the completion. The whitespace of the texts is normalized, so
+// re-indented context or trailing newlines still hit the cache.
+func Key(prompt any, params map[string]any) (string, error) {
+	data, err := json.Marshal(prompt)
+	if err != nil {
+		return "", err
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return "", err
+	}
+	document := map[string]any{"prompt": normalize(normalized)}
+	for name, value := range params {
+		document[name] = value
+	}
+	// The keys of the maps are sorted: the same prompt always has the same address
+	data, err = json.Marshal(document)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalize collapses the whitespace of the strings of a JSON value
+func normalize(value any) any {
+	switch v := value.(type) {
+	case string:
+		return strings.Join(strings.Fields(v), " ")
+	case []any:
+		for i := range v {
+			v[i] = normalize(v[i])
+		}
+	case map[string]any:
+		for name := range v {
+			v[name] = normalize(v[name])
+		}
+	}
+	return value
+}
+
+// Get returns the answer cached under a key in the cache of the ledger when it is younger than ttl
+// (any age when ttl <= 0)
+func Get(db *ledger.DB, key string, ttl time.Duration) (*Entry, bool, error) {
+	value, found, err := db.CacheGet(key, ttl)
+	if err != nil || !found {
+		return nil, false, err
+	}
+	var entry Entry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil {
+		return nil, false, fmt.Errorf("error reading the cached answer: %w", err)
+	}
+	return &entry, true, nil
+}
+
+// Put caches an answer under a key in the cache of the ledger (the ledger transactions keep the other budgie
+// processes from reading a partial answer)
+func Put(db *ledger.DB, key string, entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if err := db.CachePut(key, string(data)); err != nil {
+		return fmt.Errorf("error writing the answer cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package chunking
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/document"
+	"github.com/budgies-nest/budgie/rag"
+)
+
+// Chunking strategies
+const (
+	Hierarchy = "hierarchy"
+	Sections  = "sections"
+	Delimiter = "delimiter"
+	Size      = "size"
+	Files     = "files"
+	Code      = "code"
+	Pages     = "pages"
+)
+
+// defaultCodeChunkSize is the target size of a code chunk when the rule does not set one
+const defaultCodeChunkSize = 1500
+
+// Default fixed-size chunking of the text files without a rule
+const (
+	defaultTextChunkSize    = 1000
+	defaultTextChunkOverlap = 100
+)
+
+// defaultPageChunkSize is the size above which a PDF page is split by the default rule of the PDF files
+const defaultPageChunkSize = 2000
+
+// codeExtensions are the source file extensions chunked at top-level declarations by default
+var codeExtensions = map[string]bool{
+	".go": true, ".py": true, ".js": true, ".ts": true, ".java": true, ".rs": true, ".rb": true,
+	".c": true, ".h": true, ".cpp": true, ".cs": true, ".php": true, ".kt": true, ".swift": true, ".sh": true,
+}
+
+// DefaultRule returns the rule used for an extension (e.g. ".go") without a configured rule:
+// markdown hierarchy for markdown files and DOCX documents (their headings are extracted as Markdown),
+// page chunking for PDF documents, code chunking for source code and fixed-size chunks otherwise
+func DefaultRule(extension string) config.ChunkingRule {
+	switch {
+	case extension == ".md" || extension == ".markdown" || extension == ".docx":
+		return config.ChunkingRule{Strategy: Hierarchy}
+	case extension == ".pdf":
+		return config.ChunkingRule{Strategy: Pages, Size: defaultPageChunkSize, Overlap: defaultTextChunkOverlap}
+	case codeExtensions[extension]:
+		return config.ChunkingRule{Strategy: Code}
+	default:
+		return config.ChunkingRule{Strategy: Size, Size: defaultTextChunkSize, Overlap: defaultTextChunkOverlap}
+	}
+}
+
+// Validate checks that a rule has the parameters its strategy needs
+func Validate(rule config.ChunkingRule) error {
+	switch rule.Strategy {
+	case Hierarchy, Sections, Files, Code, "":
+		return nil
+	case Delimiter:
+		if rule.Delimiter == "" {
+			return fmt.Errorf("the delimiter strategy requires a delimiter")
+		}
+		return nil
+	case Size:
+		if rule.Size <= 0 {
+			return fmt.Errorf("the size strategy requires a positive size")
+		}
+		if rule.Overlap >= rule.Size {
+			return fmt.Errorf("overlap (%d) must be less than size (%d)", rule.Overlap, rule.Size)
+		}
+		return nil
+	case Pages:
+		if rule.Size > 0 && rule.Overlap >= rule.Size {
+			return fmt.Errorf("overlap (%d) must be less than size (%d)", rule.Overlap, rule.Size)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown chunking strategy %q (supported: hierarchy, sections, delimiter, size, files, code, pages)", rule.Strategy)
+	}
+}
+
+// Describe returns a human readable description of a rule
+func Describe(rule config.ChunkingRule) string {
+	switch rule.Strategy {
+	case Files:
+		return "whole-file chunking (each file is one chunk)"
+	case Size:
+		if rule.Overlap > 0 {
+			return fmt.Sprintf("fixed-size text chunking with size: %d, overlap: %d", rule.Size, rule.Overlap)
+		}
+		return fmt.Sprintf("fixed-size text chunking with size: %d", rule.Size)
+	case Delimiter:
+		return fmt.Sprintf("delimiter-based chunking with delimiter: %q", rule.Delimiter)
+	case Sections:
+		return "markdown sections chunking"
+	case Code:
+		return "code chunking (top-level declarations)"
+	case Pages:
+		if rule.Size > 0 {
+			return fmt.Sprintf("page chunking (pages above %d characters split, overlap: %d)", rule.Size, rule.Overlap)
+		}
+		return "page chunking (each page is one chunk)"
+	default:
+		return "markdown hierarchy chunking"
+	}
+}
+
+// Chunk splits content according to the rule
+func Chunk(content string, rule config.ChunkingRule) []string {
+	switch rule.Strategy {
+	case Files:
+		return []string{content}
+	case Size:
+		return rag.ChunkText(content, rule.Size, rule.Overlap)
+	case Delimiter:
+		return rag.SplitTextWithDelimiter(content, rule.Delimiter)
+	case Sections:
+		return rag.SplitMarkdownBySections(content)
+	case Code:
+		return chunkCode(content, rule.Size)
+	case Pages:
+		return chunkPages(content, rule.Size, rule.Overlap)
+	default:
+		return rag.ChunkWithMarkdownHierarchy(content)
+	}
+}
+
+// chunkPages splits an extracted document by page (see document.PageBreak), each chunk starting with its
+// page number. The pages above size are split by size (never when size is 0), the blank pages are skipped.
+func chunkPages(content string, size, overlap int) []string {
+	var chunks []string
+	for i, page := range document.Pages(content) {
+		page = strings.TrimSpace(page)
+		if page == "" {
+			continue
+		}
+		label := fmt.Sprintf("[Page %d]\n", i+1)
+		if size <= 0 || len(page) <= size {
+			chunks = append(chunks, label+page)
+			continue
+		}
+		for _, part := range rag.ChunkText(page, size, overlap) {
+			if text := strings.TrimSpace(part); text != "" {
+				chunks = append(chunks, label+text)
+			}
+		}
+	}
+	return chunks
+}
+
+// chunkCode splits source code at top-level declarations (unindented lines following a blank line)
+// and groups consecutive blocks until they reach the target size
+func chunkCode(content string, size int) []string {
+	if size <= 0 {
+		size = defaultCodeChunkSize
+	}
+
+	// Split the source into top-level blocks
+	var blocks []string
+	var current strings.Builder
+	previousBlank := true
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		topLevel := trimmed != "" && line[0] != ' ' && line[0] != '\t' && !strings.HasPrefix(trimmed, "}") && !strings.HasPrefix(trimmed, ")")
+		if topLevel && previousBlank && current.Len() > 0 {
+			blocks = append(blocks, current.String())
+			current.Reset()
+		}
+		current.WriteString(line)
+		previousBlank = trimmed == ""
+	}
+	if current.Len() > 0 {
+		blocks = append(blocks, current.String())
+	}
+
+	// Group blocks up to the target size, oversized blocks are split by size
+	var chunks []string
+	var chunk strings.Builder
+	flush := func() {
+		if text := strings.TrimSpace(chunk.String()); text != "" {
+			chunks = append(chunks, text)
+		}
+		chunk.Reset()
+	}
+	for _, block := range blocks {
+		if len(block) > 2*size {
+			flush()
+			for _, part := range rag.ChunkText(block, size, size/10) {
+				if text := strings.TrimSpace(part); text != "" {
+					chunks = append(chunks, text)
+				}
+			}
+			continue
+		}
+		if chunk.Len() > 0 && chunk.Len()+len(block) > size {
+			flush()
+		}
+		chunk.WriteString(block)
+	}
+	flush()
+
+	return chunks
+}
@@ -0,0 +1,166 @@
+package chunking
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/document"
+)
+
+func TestDefaultRule(t *testing.T) {
+	tests := []struct {
+		extension string
+		want      string
+	}{
+		{".md", Hierarchy},
+		{".markdown", Hierarchy},
+		{".docx", Hierarchy},
+		{".pdf", Pages},
+		{".go", Code},
+		{".py", Code},
+		{".txt", Size},
+		{".yaml", Size},
+	}
+	for _, test := range tests {
+		rule := DefaultRule(test.extension)
+		if rule.Strategy != test.want {
+			t.Errorf("DefaultRule(%q) = %s, want %s", test.extension, rule.Strategy, test.want)
+		}
+		if err := Validate(rule); err != nil {
+			t.Errorf("the default rule of %s is invalid: %v", test.extension, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		rule  config.ChunkingRule
+		valid bool
+	}{
+		{config.ChunkingRule{}, true},
+		{config.ChunkingRule{Strategy: Hierarchy}, true},
+		{config.ChunkingRule{Strategy: Sections}, true},
+		{config.ChunkingRule{Strategy: Files}, true},
+		{config.ChunkingRule{Strategy: Code}, true},
+		{config.ChunkingRule{Strategy: Delimiter, Delimiter: "---"}, true},
+		{config.ChunkingRule{Strategy: Delimiter}, false},
+		{config.ChunkingRule{Strategy: Size, Size: 500, Overlap: 50}, true},
+		{config.ChunkingRule{Strategy: Size}, false},
+		{config.ChunkingRule{Strategy: Size, Size: 100, Overlap: 100}, false},
+		{config.ChunkingRule{Strategy: Pages}, true},
+		{config.ChunkingRule{Strategy: Pages, Size: 1000, Overlap: 100}, true},
+		{config.ChunkingRule{Strategy: Pages, Size: 100, Overlap: 200}, false},
+		{config.ChunkingRule{Strategy: "paragraphs"}, false},
+	}
+	for _, test := range tests {
+		if err := Validate(test.rule); (err == nil) != test.valid {
+			t.Errorf("Validate(%+v) = %v, want valid: %v", test.rule, err, test.valid)
+		}
+	}
+}
+
+func TestChunk(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		rule    config.ChunkingRule
+		want    []string
+	}{
+		{"files", "one\ntwo", config.ChunkingRule{Strategy: Files}, []string{"one\ntwo"}},
+		{"delimiter", "one---two---three", config.ChunkingRule{Strategy: Delimiter, Delimiter: "---"}, []string{"one", "two", "three"}},
+		{"size with overlap", "abcdefghij", config.ChunkingRule{Strategy: Size, Size: 4, Overlap: 1}, []string{"abcd", "defg", "ghij", "j"}},
+		{
+			"pages",
+			"First page" + document.PageBreak + "  " + document.PageBreak + "Third page",
+			config.ChunkingRule{Strategy: Pages},
+			[]string{"[Page 1]\nFirst page", "[Page 3]\nThird page"},
+		},
+		{
+			"long page split",
+			"short" + document.PageBreak + "abcdefghij",
+			config.ChunkingRule{Strategy: Pages, Size: 6, Overlap: 2},
+			[]string{"[Page 1]\nshort", "[Page 2]\nabcdef", "[Page 2]\nefghij", "[Page 2]\nij"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chunks := Chunk(test.content, test.rule)
+			if fmt.Sprintf("%q", chunks) != fmt.Sprintf("%q", test.want) {
+				t.Errorf("Chunk = %q, want %q", chunks, test.want)
+			}
+		})
+	}
+}
+
+func TestChunkCode(t *testing.T) {
+	source := `package main
+
+import "fmt"
+
+// greet prints a greeting
+func greet(name string) {
+	fmt.Println("Hello", name)
+
+	fmt.Println("Bye")
+}
+
+func main() {
+	greet("world")
+}
+`
+	tests := []struct {
+		name string
+		size int
+		want []string
+	}{
+		{
+			"small blocks grouped",
+			60,
+			[]string{
+				"package main\n\nimport \"fmt\"",
+				"// greet prints a greeting\nfunc greet(name string) {\n\tfmt.Println(\"Hello\", name)\n\n\tfmt.Println(\"Bye\")\n}",
+				"func main() {\n\tgreet(\"world\")\n}",
+			},
+		},
+		{"everything fits", 0, []string{strings.TrimSpace(source)}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			chunks := Chunk(source, config.ChunkingRule{Strategy: Code, Size: test.size})
+			if fmt.Sprintf("%q", chunks) != fmt.Sprintf("%q", test.want) {
+				t.Errorf("Chunk = %q, want %q", chunks, test.want)
+			}
+		})
+	}
+
+	// The blocks over twice the size are split by size
+	long := "var data = []string{\n" + strings.Repeat("\t\"value\",\n", 20) + "}\n"
+	for _, chunk := range Chunk(long, config.ChunkingRule{Strategy: Code, Size: 50}) {
+		if len(chunk) > 50 {
+			t.Errorf("chunk of %d characters, over the size of 50", len(chunk))
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		rule config.ChunkingRule
+		want string
+	}{
+		{config.ChunkingRule{}, "markdown hierarchy chunking"},
+		{config.ChunkingRule{Strategy: Size, Size: 500}, "fixed-size text chunking with size: 500"},
+		{config.ChunkingRule{Strategy: Size, Size: 500, Overlap: 50}, "fixed-size text chunking with size: 500, overlap: 50"},
+		{config.ChunkingRule{Strategy: Pages}, "page chunking (each page is one chunk)"},
+	}
+	for _, test := range tests {
+		if got := Describe(test.rule); got != test.want {
+			t.Errorf("Describe(%+v) = %q, want %q", test.rule, got, test.want)
+		}
+	}
+	// The description identifies the rule in the file hashes of the incremental generation
+	if Describe(config.ChunkingRule{Strategy: Size, Size: 500}) == Describe(config.ChunkingRule{Strategy: Size, Size: 800}) {
+		t.Error("2 different sizes have the same description")
+	}
+}
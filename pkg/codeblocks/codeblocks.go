@@ -0,0 +1,94 @@
+package codeblocks
+
+import (
+	"regexp"
+	"strings"
+)
+
+// pathAttribute matches the attribute of an info string naming the file of a code block:
+// title=pkg/foo.go, file="pkg/foo.go", filename=... or path=...
+var pathAttribute = regexp.MustCompile(`\b(?:title|file|filename|path)=(?:"([^"]*)"|'([^']*)'|(\S+))`)
+
+// Block is a fenced code block of a Markdown text
+type Block struct {
+	// Info is the info string following the opening fence (e.g. "go" or "bash"), Language its first word
+	Info     string
+	Language string
+	// Path is the file the block is annotated with (```go title=pkg/foo.go or ```go:pkg/foo.go), "" when none
+	Path string
+	// Content is the code between the fences, ending with a newline
+	Content string
+}
+
+// Parse returns the fenced code blocks (``` or ~~~) of a Markdown text, in order.
+// A block left open at the end of the text runs to the end of the text.
+func Parse(text string) []Block {
+	var blocks []Block
+	var current *Block
+	var fence string
+	var content strings.Builder
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if current == nil {
+			if marker := openingFence(trimmed); marker != "" {
+				info := strings.TrimSpace(strings.TrimLeft(trimmed, marker[:1]))
+				language, _, _ := strings.Cut(info, " ")
+				language, path := infoPath(language, info)
+				current, fence = &Block{Info: info, Language: language, Path: path}, marker
+				content.Reset()
+			}
+			continue
+		}
+
+		// The closing fence is at least as long as the opening one, without info string
+		if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+			current.Content = content.String()
+			blocks = append(blocks, *current)
+			current = nil
+			continue
+		}
+		content.WriteString(line)
+	}
+
+	if current != nil {
+		current.Content = content.String()
+		if current.Content != "" && !strings.HasSuffix(current.Content, "\n") {
+			current.Content += "\n"
+		}
+		blocks = append(blocks, *current)
+	}
+	return blocks
+}
+
+// openingFence returns the fence (``` or ~~~, possibly longer) opening a code block on a trimmed line, or ""
+func openingFence(line string) string {
+	for _, char := range []string{"`", "~"} {
+		if count := len(line) - len(strings.TrimLeft(line, char)); count >= 3 {
+			return strings.Repeat(char, count)
+		}
+	}
+	return ""
+}
+
+// infoPath returns the language and the file path of a code block from the first word and the whole
+// info string of its opening fence
+func infoPath(language, info string) (string, string) {
+	if match := pathAttribute.FindStringSubmatch(info); match != nil {
+		return language, match[1] + match[2] + match[3]
+	}
+	// The go:pkg/foo.go form (but not a URL)
+	if lang, path, ok := strings.Cut(language, ":"); ok && lang != "" && path != "" && !strings.HasPrefix(path, "//") {
+		return lang, path
+	}
+	return language, ""
+}
+
+// First returns the first code block of a Markdown text
+func First(text string) (Block, bool) {
+	blocks := Parse(text)
+	if len(blocks) == 0 {
+		return Block{}, false
+	}
+	return blocks[0], true
+}
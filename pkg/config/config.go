@@ -2,20 +2,311 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
 )
 
 // Config represents the application configuration
 type Config struct {
+	Provider       string  `json:"provider"`
 	Model          string  `json:"model"`
 	EmbeddingModel string  `json:"embedding-model"`
 	CosineLimit    float64 `json:"cosine-limit"`
+	TopK           int     `json:"top-k,omitempty"`
+	SameLanguage   bool    `json:"same-language,omitempty"`
 	Temperature    float64 `json:"temperature"`
 	BaseURL        string  `json:"baseURL"`
+	APIKeyEnv      string  `json:"api-key-env,omitempty"`
+
+	// Seed asks the provider for reproducible answers (best effort, not every provider supports it)
+	Seed *int64 `json:"seed,omitempty"`
+
+	// RAG holds the options of the retrieval stages
+	RAG RAGConfig `json:"rag,omitempty"`
+
+	// KeywordWeight is the weight of the BM25 keyword score in the hybrid retrieval,
+	// between 0 (vector search only, default) and 1
+	KeywordWeight float64 `json:"keyword-weight,omitempty"`
+
+	// VectorStore is the vector store backend: "json" (default, embeddings.json loaded in memory)
+	// or "bbolt" (embeddings.db database read from the disk, for large corpora)
+	VectorStore string `json:"vector-store,omitempty"`
+
+	// EmbeddingPrice is the price of the embedding model in USD per million tokens, used by the estimates of
+	// generate-embeddings --dry-run (the prices of the OpenAI embedding models are known)
+	EmbeddingPrice float64 `json:"embedding-price,omitempty"`
+
+	// Stores are the shared knowledge bases queried with --store or #rag:<name>, by name: the path of an
+	// embeddings file (or of its directory) outside the project, or its http(s) URL. The stores of the
+	// global config (see GlobalPath) are available in every project.
+	Stores map[string]string `json:"stores,omitempty"`
+
+	// ResultsLayout organizes the generated result files: "flat" (default), "date" (<output>/YYYY/MM/DD)
+	// or "session" (<output>/sessions/<session>)
+	ResultsLayout string `json:"results-layout,omitempty"`
+
+	// OutputName is the template of the result file names, e.g. "{{date}}-{{slug}}.md"
+	// (default: "result-{{timestamp}}.md")
+	OutputName string `json:"output-name,omitempty"`
+
+	// Temperatures overrides the temperature of the secondary operations (summarize, clarify, rerank, commit),
+	// the answers use Temperature
+	Temperatures map[string]float64 `json:"temperatures,omitempty"`
+
+	// ClarifyModel is the (cheap) model used to detect ambiguous questions (defaults to Model)
+	ClarifyModel string `json:"clarify-model,omitempty"`
+
+	// HistoryTokenBudget is the approximate size of the interactive conversation history above which
+	// the older exchanges are summarized (default: 8000, -1 disables the compaction)
+	HistoryTokenBudget int `json:"history-token-budget,omitempty"`
+
+	// RetryAttempts is the number of attempts of a streamed completion failing with a retryable error
+	// (429, 5xx, timeouts), RetryBackoff the delay in seconds before the first retry, doubled on each retry
+	RetryAttempts int     `json:"retry-attempts,omitempty"`
+	RetryBackoff  float64 `json:"retry-backoff,omitempty"`
+
+	// AttachmentTokenLimit is the approximate size above which the files attached with --use or /use
+	// and the piped content require a confirmation or are truncated (default: 32000, -1 disables it)
+	AttachmentTokenLimit int `json:"attachment-token-limit,omitempty"`
+
+	// CheckPrevious searches the answered questions (usage ledger) for a question similar to the new one
+	// (embedding similarity of at least PreviousThreshold, default: 0.9) and offers to show its answer
+	CheckPrevious     bool    `json:"check-previous,omitempty"`
+	PreviousThreshold float64 `json:"previous-threshold,omitempty"`
+
+	// History logs every question/answer pair to .budgie/history.jsonl (see budgie history)
+	History bool `json:"history,omitempty"`
+
+	// Confidence calibration (ask --calibrate): retrieval is weak when fewer than
+	// ConfidenceMinChunks chunks have a similarity score of at least ConfidenceMinScore
+	ConfidenceMinScore  float64 `json:"confidence-min-score,omitempty"`
+	ConfidenceMinChunks int     `json:"confidence-min-chunks,omitempty"`
+
+	// Profile is the name of the default profile, Profiles the named model configurations
+	// overriding the fields above (e.g. "fast", "quality", "local")
+	Profile  string             `json:"profile,omitempty"`
+	Profiles map[string]Profile `json:"profiles,omitempty"`
+
+	// ActiveProfile is the name of the profile applied when loading the config
+	ActiveProfile string `json:"-"`
+
+	// Chunking maps a file extension (e.g. ".md") to the chunking rule used by generate-embeddings
+	Chunking map[string]ChunkingRule `json:"chunking,omitempty"`
+
+	// Docs is the docs directory embedded by generate-embeddings when --docs is not given
+	// (default: .budgie/docs), e.g. the docs source of a MkDocs or Docusaurus site
+	Docs string `json:"docs,omitempty"`
+
+	// Include and Exclude are the glob patterns of the docs files embedded by generate-embeddings
+	// (e.g. "node_modules", "tests/fixtures/**", "*.gen.md"), completed by the --include and --exclude flags
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+
+	// MCPServers are the Model Context Protocol servers, by name, whose tools are available to ask --allow-tools
+	MCPServers map[string]MCPServer `json:"mcp-servers,omitempty"`
+
+	// ConventionalCommits makes `budgie commit` write Conventional Commits messages (feat: ..., fix(scope): ...)
+	ConventionalCommits bool `json:"conventional-commits,omitempty"`
+
+	// OTelEndpoint is the OTLP/HTTP endpoint (e.g. http://localhost:4318) receiving the tracing spans of the
+	// embedding calls, similarity searches and chat completions (tracing is disabled when empty)
+	OTelEndpoint string `json:"otel-endpoint,omitempty"`
+
+	// PostProcess are the shell commands the answers of ask are piped through, in order, before they are shown
+	// and saved (e.g. "prettier --parser markdown"), skipped with --no-post
+	PostProcess []string `json:"post-process,omitempty"`
+
+	// Voice configures the speech-to-text input (ask --voice)
+	Voice VoiceConfig `json:"voice,omitempty"`
+
+	// Redact masks the secrets of the prompts (API keys, emails...) before they are sent to the model
+	Redact RedactConfig `json:"redact,omitempty"`
+
+	// Cache keeps the answers of ask in .budgie/cache/: a question asked again with the same prompt (retrieved
+	// context included), model, temperature and seed is answered from the cache
+	Cache CacheConfig `json:"cache,omitempty"`
+}
+
+// CacheConfig holds the options of the answer cache
+type CacheConfig struct {
+	Enabled bool `json:"enabled,omitempty"`
+	// TTL is how long an answer is reused, as a Go duration (e.g. "30m", "168h", default: 24h, "0": forever)
+	TTL string `json:"ttl,omitempty"`
+}
+
+// DefaultCacheTTL is how long an answer is reused when the cache has no ttl
+const DefaultCacheTTL = 24 * time.Hour
+
+// Expiry returns how long an answer is reused (0: forever)
+func (c CacheConfig) Expiry() time.Duration {
+	if c.TTL == "" {
+		return DefaultCacheTTL
+	}
+	ttl, _ := time.ParseDuration(c.TTL)
+	return ttl
+}
+
+// RAGConfig holds the options of the retrieval stages
+type RAGConfig struct {
+	// Rerank asks the chat model to score the relevance of the RerankCandidates best chunks (default: 20)
+	// and keeps the top-k best ones (default: 5 when top-k is not set)
+	Rerank           bool `json:"rerank,omitempty"`
+	RerankCandidates int  `json:"rerank-candidates,omitempty"`
+
+	// MaxContextTokens is the approximate size of the retrieved chunks given to the model as context
+	// (default: 8000, -1 disables it): the lowest-scoring chunks are truncated or dropped to fit
+	MaxContextTokens int `json:"max-context-tokens,omitempty"`
+}
+
+// VoiceConfig holds the options of the speech-to-text input
+type VoiceConfig struct {
+	// Model is the transcription model (default: whisper-1)
+	Model string `json:"model,omitempty"`
+	// BaseURL is the Whisper-compatible endpoint (serving /audio/transcriptions), the baseURL of the
+	// provider when empty. APIKeyEnv is the variable of its API key, the one of the provider when empty.
+	BaseURL   string `json:"baseURL,omitempty"`
+	APIKeyEnv string `json:"api-key-env,omitempty"`
+	// Language is the ISO-639-1 code of the spoken language (detected by the model when empty)
+	Language string `json:"language,omitempty"`
+	// RecordCommand records the microphone to the {file} argument (e.g. "rec -q {file}"), the first recorder
+	// found (rec, arecord, ffmpeg) is used when empty
+	RecordCommand string `json:"record-command,omitempty"`
+}
+
+// RedactConfig holds the redaction rules of the prompts (nothing is masked when it is empty)
+type RedactConfig struct {
+	// Builtins are the built-in rules applied: api-keys, private-keys, secrets, emails
+	Builtins []string `json:"builtins,omitempty"`
+	// Patterns are the project rules
+	Patterns []RedactPattern `json:"patterns,omitempty"`
+	// Show prints the masked values (ask --show-redactions)
+	Show bool `json:"-"`
+}
+
+// RedactPattern is a redaction rule: the matches of Regex (only its first group when it has groups) are
+// replaced with Replacement (default: [REDACTED:<name>])
+type RedactPattern struct {
+	Name        string `json:"name"`
+	Regex       string `json:"regex"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// Built-in redaction rules (redact.builtins)
+const (
+	RedactAPIKeys     = "api-keys"
+	RedactPrivateKeys = "private-keys"
+	RedactSecrets     = "secrets"
+	RedactEmails      = "emails"
+)
+
+// MCP server transports
+const (
+	MCPStdio = "stdio"
+	MCPSSE   = "sse"
+	MCPHTTP  = "http"
+)
+
+// MCPServer is a Model Context Protocol server: a command started by budgie (stdio transport) or
+// the URL of a running server (sse or streamable http transport)
+type MCPServer struct {
+	// Transport is "stdio" (default with a command), "sse" (default with a URL) or "http"
+	Transport string            `json:"transport,omitempty"`
+	Command   string            `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	URL       string            `json:"url,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	// Tools limits the tools of the server given to the model (all of them when empty)
+	Tools []string `json:"tools,omitempty"`
+	// Trusted runs the tool calls without asking for approval
+	Trusted bool `json:"trusted,omitempty"`
+}
+
+// TransportOrDefault returns the transport of the server, guessed from its command or URL when not set
+func (s MCPServer) TransportOrDefault() string {
+	if s.Transport != "" {
+		return s.Transport
+	}
+	if s.Command == "" && s.URL != "" {
+		return MCPSSE
+	}
+	return MCPStdio
+}
+
+// Operations with a scoped temperature (temperatures config map)
+const (
+	TemperatureSummarize = "summarize"
+	TemperatureClarify   = "clarify"
+	TemperatureRerank    = "rerank"
+	TemperatureCommit    = "commit"
+	TemperatureTranslate = "translate"
+	TemperatureProofread = "proofread"
+)
+
+// defaultTemperatures are the temperatures of the operations which must be deterministic by default,
+// the other operations use the temperature of the answers
+var defaultTemperatures = map[string]float64{
+	TemperatureSummarize: 0,
+	TemperatureClarify:   0,
+	TemperatureRerank:    0,
+	TemperatureTranslate: 0,
+	TemperatureProofread: 0,
+}
+
+// TemperatureFor returns the temperature of an operation: its scoped temperature if configured,
+// otherwise its default one (or the temperature of the answers)
+func (c *Config) TemperatureFor(operation string) float64 {
+	if temperature, ok := c.Temperatures[operation]; ok {
+		return temperature
+	}
+	if temperature, ok := defaultTemperatures[operation]; ok {
+		return temperature
+	}
+	return c.Temperature
+}
+
+// Profile is a named model configuration: its fields override the top-level ones when set
+type Profile struct {
+	Provider       string   `json:"provider,omitempty"`
+	Model          string   `json:"model,omitempty"`
+	EmbeddingModel string   `json:"embedding-model,omitempty"`
+	Temperature    *float64 `json:"temperature,omitempty"`
+	BaseURL        string   `json:"baseURL,omitempty"`
+	APIKeyEnv      string   `json:"api-key-env,omitempty"`
+}
+
+// ChunkingRule describes how the files of an extension are split into chunks
+type ChunkingRule struct {
+	Strategy  string `json:"strategy"`
+	Delimiter string `json:"delimiter,omitempty"`
+	Size      int    `json:"size,omitempty"`
+	Overlap   int    `json:"overlap,omitempty"`
 }
 
-// LoadConfig loads configuration from a JSON file
-func LoadConfig(filename string) (*Config, error) {
+// UnmarshalJSON accepts either a strategy name ("hierarchy") or a full rule object
+func (r *ChunkingRule) UnmarshalJSON(data []byte) error {
+	var strategy string
+	if err := json.Unmarshal(data, &strategy); err == nil {
+		r.Strategy = strategy
+		return nil
+	}
+
+	type rule ChunkingRule
+	var full rule
+	if err := json.Unmarshal(data, &full); err != nil {
+		return err
+	}
+	*r = ChunkingRule(full)
+	return nil
+}
+
+// LoadConfig loads configuration from a JSON file, then applies the profile and the overrides.
+// Precedence: command line overrides > BUDGIE_* environment variables > profile > config file.
+func LoadConfig(filename string, overrides Overrides) (*Config, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
@@ -26,11 +317,160 @@ func LoadConfig(filename string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := resolveStores(config.Stores, filename); err != nil {
+		return nil, err
+	}
+	if err := config.mergeGlobalStores(filename); err != nil {
+		return nil, err
+	}
+
+	env, err := FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	overrides = env.Merge(overrides)
+
+	profile := overrides.Profile
+	if profile == "" {
+		profile = config.Profile
+	}
+	if profile != "" {
+		if err := config.applyProfile(profile); err != nil {
+			return nil, err
+		}
+	}
+	overrides.apply(&config)
+
+	for operation := range config.Temperatures {
+		switch operation {
+		case TemperatureSummarize, TemperatureClarify, TemperatureRerank, TemperatureCommit, TemperatureTranslate, TemperatureProofread:
+		default:
+			return nil, fmt.Errorf("unknown operation %q in temperatures (supported: summarize, clarify, rerank, commit, translate, proofread)", operation)
+		}
+	}
+
+	if config.KeywordWeight < 0 || config.KeywordWeight > 1 {
+		return nil, fmt.Errorf("keyword-weight must be between 0 and 1 (got %g)", config.KeywordWeight)
+	}
+
+	for _, builtin := range config.Redact.Builtins {
+		switch builtin {
+		case RedactAPIKeys, RedactPrivateKeys, RedactSecrets, RedactEmails:
+		default:
+			return nil, fmt.Errorf("unknown rule %q in redact.builtins (supported: api-keys, private-keys, secrets, emails)", builtin)
+		}
+	}
+	for i, pattern := range config.Redact.Patterns {
+		if pattern.Name == "" || pattern.Regex == "" {
+			return nil, fmt.Errorf("redact.patterns[%d]: name and regex are required", i)
+		}
+		if _, err := regexp.Compile(pattern.Regex); err != nil {
+			return nil, fmt.Errorf("redact.patterns.%s: invalid regex: %w", pattern.Name, err)
+		}
+	}
+
+	if config.Cache.TTL != "" {
+		if ttl, err := time.ParseDuration(config.Cache.TTL); err != nil || ttl < 0 {
+			return nil, fmt.Errorf("cache.ttl must be a duration like 30m or 24h (got %q)", config.Cache.TTL)
+		}
+	}
+
+	for name, server := range config.MCPServers {
+		switch server.TransportOrDefault() {
+		case MCPStdio:
+			if server.Command == "" {
+				return nil, fmt.Errorf("mcp-servers.%s: command is required with the stdio transport", name)
+			}
+		case MCPSSE, MCPHTTP:
+			if server.URL == "" {
+				return nil, fmt.Errorf("mcp-servers.%s: url is required with the %s transport", name, server.TransportOrDefault())
+			}
+		default:
+			return nil, fmt.Errorf("mcp-servers.%s: unknown transport %q (supported: stdio, sse, http)", name, server.Transport)
+		}
+	}
 
 	// Set default cosine limit if not specified
 	if config.CosineLimit == 0 {
 		config.CosineLimit = 0.7
 	}
 
+	// Strong retrieval requires chunks clearly above the retrieval limit by default
+	if config.ConfidenceMinScore == 0 {
+		config.ConfidenceMinScore = min(config.CosineLimit+0.1, 1.0)
+	}
+	if config.ConfidenceMinChunks == 0 {
+		config.ConfidenceMinChunks = 1
+	}
+
+	if config.HistoryTokenBudget == 0 {
+		config.HistoryTokenBudget = 8000
+	}
+	if config.RetryAttempts == 0 {
+		config.RetryAttempts = 3
+	}
+	if config.RetryBackoff == 0 {
+		config.RetryBackoff = 1
+	}
+	if config.AttachmentTokenLimit == 0 {
+		config.AttachmentTokenLimit = 32000
+	}
+	if config.PreviousThreshold == 0 {
+		config.PreviousThreshold = 0.9
+	}
+	if config.RAG.RerankCandidates == 0 {
+		config.RAG.RerankCandidates = 20
+	}
+	if config.RAG.MaxContextTokens == 0 {
+		config.RAG.MaxContextTokens = 8000
+	}
+	if config.Voice.Model == "" {
+		config.Voice.Model = "whisper-1"
+	}
+
+	// Default to Docker Model Runner
+	if config.Provider == "" {
+		config.Provider = "dmr"
+	}
+
 	return &config, nil
-}
\ No newline at end of file
+}
+
+// applyProfile overrides the config fields with the ones set in the named profile
+func (c *Config) applyProfile(name string) error {
+	profile, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(c.ProfileNames(), ", "))
+	}
+
+	if profile.Provider != "" {
+		c.Provider = profile.Provider
+	}
+	if profile.Model != "" {
+		c.Model = profile.Model
+	}
+	if profile.EmbeddingModel != "" {
+		c.EmbeddingModel = profile.EmbeddingModel
+	}
+	if profile.Temperature != nil {
+		c.Temperature = *profile.Temperature
+	}
+	if profile.BaseURL != "" {
+		c.BaseURL = profile.BaseURL
+	}
+	if profile.APIKeyEnv != "" {
+		c.APIKeyEnv = profile.APIKeyEnv
+	}
+	c.ActiveProfile = name
+	return nil
+}
+
+// ProfileNames returns the sorted names of the profiles
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
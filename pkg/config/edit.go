@@ -0,0 +1,131 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// field is a top-level field of the config file, kept in file order
+type field struct {
+	key   string
+	value json.RawMessage
+}
+
+// SetField sets a top-level field of the config file (removing it when value is nil),
+// keeping the other fields and their order untouched
+func SetField(filename, key string, value any) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	fields, err := readFields(data)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+
+	var raw json.RawMessage
+	if value != nil {
+		if raw, err = json.Marshal(value); err != nil {
+			return err
+		}
+	}
+
+	updated := make([]field, 0, len(fields)+1)
+	found := false
+	for _, f := range fields {
+		if f.key == key {
+			found = true
+			if raw == nil {
+				continue
+			}
+			f.value = raw
+		}
+		updated = append(updated, f)
+	}
+	if !found && raw != nil {
+		updated = append(updated, field{key: key, value: raw})
+	}
+
+	return os.WriteFile(filename, writeFields(updated), 0644)
+}
+
+// readFields decodes the top-level fields of a JSON object in file order
+func readFields(data []byte) ([]field, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil, fmt.Errorf("the config file must contain a JSON object")
+	}
+
+	var fields []field
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		fields = append(fields, field{key: key, value: value})
+	}
+	return fields, nil
+}
+
+// writeFields encodes the fields as an indented JSON object
+func writeFields(fields []field) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("{\n")
+	for i, f := range fields {
+		key, _ := json.Marshal(f.key)
+		var value bytes.Buffer
+		if err := json.Indent(&value, f.value, "  ", "  "); err != nil {
+			value.Reset()
+			value.Write(f.value)
+		}
+		fmt.Fprintf(&buffer, "  %s: %s", key, value.String())
+		if i < len(fields)-1 {
+			buffer.WriteString(",")
+		}
+		buffer.WriteString("\n")
+	}
+	buffer.WriteString("}\n")
+	return buffer.Bytes()
+}
+
+// MergeDefaults adds the top-level fields of the default config missing from the config file, after its
+// own fields, keeping the values of the file untouched. It returns the keys of the added fields.
+func MergeDefaults(filename string, defaults []byte) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := readFields(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+	defaultFields, err := readFields(defaults)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the default config: %w", err)
+	}
+
+	existing := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		existing[f.key] = true
+	}
+	var added []string
+	for _, f := range defaultFields {
+		if !existing[f.key] {
+			fields = append(fields, f)
+			added = append(added, f.key)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	return added, os.WriteFile(filename, writeFields(fields), 0644)
+}
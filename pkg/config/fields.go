@@ -0,0 +1,234 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// Keys returns the keys of the top-level fields of the config file, in the order of the Config struct
+func Keys() []string {
+	var keys []string
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		if key := jsonKey(configType.Field(i)); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// FileKeys returns the keys of the top-level fields set in the config file, in file order
+func FileKeys(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := readFields(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+	keys := make([]string, len(fields))
+	for i, f := range fields {
+		keys[i] = f.key
+	}
+	return keys, nil
+}
+
+// Values returns the fields of the config as generic JSON values, by key
+func (c *Config) Values() (map[string]any, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var values map[string]any
+	err = json.Unmarshal(data, &values)
+	return values, err
+}
+
+// Lookup returns the value of a dotted key (e.g. "temperature", "rag.rerank", "temperatures.commit")
+// in generic JSON values
+func Lookup(values map[string]any, key string) (any, bool) {
+	var value any = values
+	for _, part := range strings.Split(key, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = object[part]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// ParseValue converts the command-line value of a dotted key to the type of its Config field:
+// strings are taken as is, lists accept comma-separated values, the other types are parsed as JSON
+func ParseValue(key, value string) (any, error) {
+	fieldType, err := keyType(key)
+	if err != nil {
+		return nil, err
+	}
+
+	target := reflect.New(fieldType)
+	if fieldType.Kind() == reflect.String {
+		return value, nil
+	}
+	if err := json.Unmarshal([]byte(value), target.Interface()); err == nil {
+		return target.Elem().Interface(), nil
+	}
+	if fieldType.Kind() == reflect.Slice && fieldType.Elem().Kind() == reflect.String {
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		return items, nil
+	}
+	// Types with a short string form (e.g. a chunking rule given as its strategy name)
+	quoted, _ := json.Marshal(value)
+	if err := json.Unmarshal(quoted, target.Interface()); err == nil {
+		return target.Elem().Interface(), nil
+	}
+	return nil, fmt.Errorf("invalid value %q for %s (expected %s)", value, key, typeName(fieldType))
+}
+
+// SetValue sets the value of a dotted key in the config file (removing it when value is nil), keeping the
+// other fields and their order untouched. The nested objects of the path are created when missing.
+func SetValue(filename, key string, value any) error {
+	if _, err := keyType(key); err != nil {
+		return err
+	}
+	top, rest, nested := strings.Cut(key, ".")
+	if !nested {
+		return SetField(filename, top, value)
+	}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	fields, err := readFields(data)
+	if err != nil {
+		return fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+	object := map[string]any{}
+	for _, f := range fields {
+		if f.key == top {
+			if err := json.Unmarshal(f.value, &object); err != nil || object == nil {
+				return fmt.Errorf("%s is not an object in %s", top, filename)
+			}
+		}
+	}
+
+	var generic any
+	if value != nil {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(encoded, &generic); err != nil {
+			return err
+		}
+	}
+
+	parts := strings.Split(rest, ".")
+	parent := object
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := parent[part].(map[string]any)
+		if !ok {
+			if value == nil {
+				return SetField(filename, top, object)
+			}
+			child = map[string]any{}
+			parent[part] = child
+		}
+		parent = child
+	}
+	if value == nil {
+		delete(parent, parts[len(parts)-1])
+	} else {
+		parent[parts[len(parts)-1]] = generic
+	}
+
+	if len(object) == 0 {
+		return SetField(filename, top, nil)
+	}
+	return SetField(filename, top, object)
+}
+
+// CheckKey checks that a dotted key is a field of the config
+func CheckKey(key string) error {
+	_, err := keyType(key)
+	return err
+}
+
+// keyType returns the type of the Config field of a dotted key: the parts after the first one
+// are the fields of a nested object or the keys of a map
+func keyType(key string) (reflect.Type, error) {
+	current := reflect.TypeOf(Config{})
+	for i, part := range strings.Split(key, ".") {
+		for current.Kind() == reflect.Pointer {
+			current = current.Elem()
+		}
+		switch current.Kind() {
+		case reflect.Struct:
+			found := false
+			for j := 0; j < current.NumField(); j++ {
+				if jsonKey(current.Field(j)) == part {
+					current = current.Field(j).Type
+					found = true
+					break
+				}
+			}
+			if !found {
+				if i == 0 {
+					return nil, fmt.Errorf("unknown config key %q (run budgie config list for the keys)", key)
+				}
+				return nil, fmt.Errorf("unknown config key %q: no field %s", key, part)
+			}
+		case reflect.Map:
+			if part == "" {
+				return nil, fmt.Errorf("invalid config key %q", key)
+			}
+			current = current.Elem()
+		default:
+			return nil, fmt.Errorf("invalid config key %q: %s is not an object", key, strings.Join(strings.Split(key, ".")[:i], "."))
+		}
+	}
+	for current.Kind() == reflect.Pointer {
+		current = current.Elem()
+	}
+	return current, nil
+}
+
+// jsonKey returns the key of a struct field in the config file ("" for the fields not stored in the file)
+func jsonKey(field reflect.StructField) string {
+	key, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if key == "-" || !field.IsExported() {
+		return ""
+	}
+	if key == "" {
+		return field.Name
+	}
+	return key
+}
+
+// typeName describes the type of a config field for the validation errors
+func typeName(fieldType reflect.Type) string {
+	switch fieldType.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	case reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		return "a list of values (comma-separated or a JSON array)"
+	default:
+		return "a JSON object"
+	}
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvGlobalConfig is the environment variable giving the path of the global config
+const EnvGlobalConfig = "BUDGIE_GLOBAL_CONFIG"
+
+// GlobalPath returns the path of the global config, shared by all the projects: $BUDGIE_GLOBAL_CONFIG, or
+// budgie/budgie.config.json in the user config directory (~/.config on Linux). It is "" when there is none.
+func GlobalPath() string {
+	if path := os.Getenv(EnvGlobalConfig); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "budgie", "budgie.config.json")
+}
+
+// IsStoreURL reports whether the location of a shared store is an http(s) URL
+func IsStoreURL(location string) bool {
+	return strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://")
+}
+
+// resolveStores makes the store paths of a config file absolute: "~/" is the home directory, and the
+// relative paths are relative to the directory of the config file. The URLs are kept as is.
+func resolveStores(stores map[string]string, filename string) error {
+	for name, location := range stores {
+		switch {
+		case location == "":
+			return fmt.Errorf("stores.%s: path or URL required", name)
+		case IsStoreURL(location):
+		case strings.HasPrefix(location, "~/"):
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return fmt.Errorf("stores.%s: %w", name, err)
+			}
+			stores[name] = filepath.Join(home, location[2:])
+		case !filepath.IsAbs(location):
+			dir, err := filepath.Abs(filepath.Dir(filename))
+			if err != nil {
+				return fmt.Errorf("stores.%s: %w", name, err)
+			}
+			stores[name] = filepath.Join(dir, location)
+		}
+	}
+	return nil
+}
+
+// mergeGlobalStores adds the stores of the global config to the ones of the config file: a store of the
+// config file wins over the global store of the same name
+func (c *Config) mergeGlobalStores(filename string) error {
+	global := GlobalPath()
+	if global == "" || sameFile(global, filename) {
+		return nil
+	}
+	var globalConfig struct {
+		Stores map[string]string `json:"stores"`
+	}
+	if err := readGlobal(global, &globalConfig); err != nil {
+		return err
+	}
+	if err := resolveStores(globalConfig.Stores, global); err != nil {
+		return fmt.Errorf("global config %s: %w", global, err)
+	}
+	for name, location := range globalConfig.Stores {
+		if _, ok := c.Stores[name]; ok {
+			continue
+		}
+		if c.Stores == nil {
+			c.Stores = make(map[string]string)
+		}
+		c.Stores[name] = location
+	}
+	return nil
+}
+
+// TrustedPluginDirs returns the plugin directories of the workspaces trusted in the global config
+// (trusted-plugins), as absolute paths. A project config cannot trust its own plugins: they run any command.
+func TrustedPluginDirs() ([]string, error) {
+	global := GlobalPath()
+	if global == "" {
+		return nil, nil
+	}
+	var globalConfig struct {
+		TrustedPlugins []string `json:"trusted-plugins"`
+	}
+	if err := readGlobal(global, &globalConfig); err != nil {
+		return nil, err
+	}
+	dirs := make([]string, 0, len(globalConfig.TrustedPlugins))
+	for _, dir := range globalConfig.TrustedPlugins {
+		if strings.HasPrefix(dir, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("trusted-plugins: %w", err)
+			}
+			dir = filepath.Join(home, dir[2:])
+		}
+		if !filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("global config %s: trusted-plugins: %s is not an absolute path", global, dir)
+		}
+		dirs = append(dirs, filepath.Clean(dir))
+	}
+	return dirs, nil
+}
+
+// readGlobal parses the global config into value (left as is when there is no global config)
+func readGlobal(global string, value any) error {
+	data, err := os.ReadFile(global)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading the global config: %w", err)
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("error parsing the global config %s: %w", global, err)
+	}
+	return nil
+}
+
+// sameFile reports whether two paths are the same existing file
+func sameFile(path1, path2 string) bool {
+	info1, err := os.Stat(path1)
+	if err != nil {
+		return false
+	}
+	info2, err := os.Stat(path2)
+	return err == nil && os.SameFile(info1, info2)
+}
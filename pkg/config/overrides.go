@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Overrides are config values given on the command line.
+// They take precedence over the BUDGIE_* environment variables, which take precedence over the config file.
+// Empty strings and nil pointers mean "not set".
+type Overrides struct {
+	Profile        string
+	Provider       string
+	Model          string
+	EmbeddingModel string
+	BaseURL        string
+	Temperature    *float64
+	CosineLimit    *float64
+	TopK           *int
+	OTelEndpoint   string
+}
+
+// Environment variables overriding the config file
+const (
+	EnvProfile        = "BUDGIE_PROFILE"
+	EnvProvider       = "BUDGIE_PROVIDER"
+	EnvModel          = "BUDGIE_MODEL"
+	EnvEmbeddingModel = "BUDGIE_EMBEDDING_MODEL"
+	EnvBaseURL        = "BUDGIE_BASE_URL"
+	EnvTemperature    = "BUDGIE_TEMPERATURE"
+	EnvCosineLimit    = "BUDGIE_COSINE_LIMIT"
+	EnvTopK           = "BUDGIE_TOP_K"
+	EnvOTelEndpoint   = "BUDGIE_OTEL_ENDPOINT"
+)
+
+// FromEnv reads the overrides set in the BUDGIE_* environment variables
+func FromEnv() (Overrides, error) {
+	overrides := Overrides{
+		Profile:        os.Getenv(EnvProfile),
+		Provider:       os.Getenv(EnvProvider),
+		Model:          os.Getenv(EnvModel),
+		EmbeddingModel: os.Getenv(EnvEmbeddingModel),
+		BaseURL:        os.Getenv(EnvBaseURL),
+		OTelEndpoint:   os.Getenv(EnvOTelEndpoint),
+	}
+
+	for _, env := range []struct {
+		name  string
+		value **float64
+	}{
+		{EnvTemperature, &overrides.Temperature},
+		{EnvCosineLimit, &overrides.CosineLimit},
+	} {
+		raw := os.Getenv(env.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return Overrides{}, fmt.Errorf("invalid %s value %q: %w", env.name, raw, err)
+		}
+		*env.value = &value
+	}
+
+	if raw := os.Getenv(EnvTopK); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil {
+			return Overrides{}, fmt.Errorf("invalid %s value %q: %w", EnvTopK, raw, err)
+		}
+		overrides.TopK = &value
+	}
+
+	return overrides, nil
+}
+
+// Merge returns the overrides with the values of other applied on top
+func (o Overrides) Merge(other Overrides) Overrides {
+	if other.Profile != "" {
+		o.Profile = other.Profile
+	}
+	if other.Provider != "" {
+		o.Provider = other.Provider
+	}
+	if other.Model != "" {
+		o.Model = other.Model
+	}
+	if other.EmbeddingModel != "" {
+		o.EmbeddingModel = other.EmbeddingModel
+	}
+	if other.BaseURL != "" {
+		o.BaseURL = other.BaseURL
+	}
+	if other.Temperature != nil {
+		o.Temperature = other.Temperature
+	}
+	if other.CosineLimit != nil {
+		o.CosineLimit = other.CosineLimit
+	}
+	if other.TopK != nil {
+		o.TopK = other.TopK
+	}
+	if other.OTelEndpoint != "" {
+		o.OTelEndpoint = other.OTelEndpoint
+	}
+	return o
+}
+
+// apply overrides the config fields with the values that are set
+func (o Overrides) apply(c *Config) {
+	if o.Provider != "" {
+		c.Provider = o.Provider
+	}
+	if o.Model != "" {
+		c.Model = o.Model
+	}
+	if o.EmbeddingModel != "" {
+		c.EmbeddingModel = o.EmbeddingModel
+	}
+	if o.BaseURL != "" {
+		c.BaseURL = o.BaseURL
+	}
+	if o.Temperature != nil {
+		c.Temperature = *o.Temperature
+	}
+	if o.CosineLimit != nil {
+		c.CosineLimit = *o.CosineLimit
+	}
+	if o.TopK != nil {
+		c.TopK = *o.TopK
+	}
+	if o.OTelEndpoint != "" {
+		c.OTelEndpoint = o.OTelEndpoint
+	}
+}
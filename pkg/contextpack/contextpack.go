@@ -0,0 +1,287 @@
+package contextpack
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/pathfilter"
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+)
+
+// DefaultMaxFileSize is the size above which a file is listed in the tree without its content
+const DefaultMaxFileSize = 64 * 1024
+
+// maxTreeEntries bounds the lines of the file tree
+const maxTreeEntries = 500
+
+// keyFiles are the files describing a project, included first
+var keyFiles = []string{
+	"README.md", "README", "go.mod", "package.json", "pyproject.toml", "requirements.txt", "Cargo.toml",
+	"pom.xml", "build.gradle", "Makefile", "Dockerfile", "compose.yaml", "docker-compose.yml",
+}
+
+// Options are the limits of a context pack
+type Options struct {
+	// MaxFileSize is the size in bytes above which a file is not included (DefaultMaxFileSize when 0)
+	MaxFileSize int64
+	// MaxTokens is the budget of the included file contents (no limit when negative or 0)
+	MaxTokens int
+}
+
+// File is a file of the pack: a text file of the directory, not ignored
+type File struct {
+	// Path is the slash-separated path relative to the directory
+	Path string
+	Size int64
+	// Included reports whether the content of the file is in the pack
+	Included bool
+	// Reason tells why the content of the file is not included
+	Reason  string
+	content string
+}
+
+// Pack is the context of a directory: its file tree and the contents of a selection of its files
+type Pack struct {
+	Root  string
+	Files []File
+	// Ignored is the number of files and directories skipped by .gitignore or as hidden
+	Ignored int
+}
+
+// Build walks a directory and selects the files of its pack. The files and directories ignored by the
+// .gitignore files of the directory, the hidden ones (e.g. .git, .env) and the binary files are skipped.
+// The contents are included by priority (the key project files like README.md and go.mod, then the
+// shallowest files) until the token budget is spent.
+func Build(root string, options Options) (*Pack, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+	if options.MaxFileSize <= 0 {
+		options.MaxFileSize = DefaultMaxFileSize
+	}
+
+	pack := &Pack{Root: root}
+	var rules []rule
+	err = filepath.WalkDir(root, func(current string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, current)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			rules = append(rules, readGitignore(current, "")...)
+			return nil
+		}
+
+		if strings.HasPrefix(d.Name(), ".") || ignored(rules, rel, d.IsDir()) {
+			pack.Ignored++
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			rules = append(rules, readGitignore(current, rel)...)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		file := File{Path: rel, Size: info.Size()}
+		if file.Size > options.MaxFileSize {
+			file.Reason = "too large"
+		} else {
+			content, err := sniff.ReadTextFile(current)
+			if err != nil {
+				// The binary files are not part of the pack
+				return nil
+			}
+			file.content = content
+		}
+		pack.Files = append(pack.Files, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning %s: %w", root, err)
+	}
+
+	// Include the contents by priority within the token budget
+	order := make([]int, 0, len(pack.Files))
+	for i, file := range pack.Files {
+		if file.Reason == "" {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return priority(pack.Files[order[a]].Path) < priority(pack.Files[order[b]].Path)
+	})
+	spent := 0
+	for _, i := range order {
+		file := &pack.Files[i]
+		size := tokens.Estimate(file.content)
+		if options.MaxTokens > 0 && spent+size > options.MaxTokens {
+			file.Reason, file.content = "over the token budget", ""
+			continue
+		}
+		spent += size
+		file.Included = true
+	}
+	return pack, nil
+}
+
+// Included returns the number of files whose content is in the pack
+func (p *Pack) Included() int {
+	count := 0
+	for _, file := range p.Files {
+		if file.Included {
+			count++
+		}
+	}
+	return count
+}
+
+// Tree returns the file tree of the pack, a file per line indented by depth, the files whose content is
+// not included marked with the reason
+func (p *Pack) Tree() string {
+	var builder strings.Builder
+	builder.WriteString(filepath.Base(filepath.Clean(p.Root)) + "/\n")
+	written := make(map[string]bool)
+	for i, file := range p.Files {
+		if i == maxTreeEntries {
+			fmt.Fprintf(&builder, "... (%d more files)\n", len(p.Files)-i)
+			break
+		}
+		// The parent directories not written yet
+		segments := strings.Split(file.Path, "/")
+		for depth := 1; depth < len(segments); depth++ {
+			dir := strings.Join(segments[:depth], "/")
+			if !written[dir] {
+				written[dir] = true
+				fmt.Fprintf(&builder, "%s%s/\n", strings.Repeat("  ", depth), segments[depth-1])
+			}
+		}
+		line := strings.Repeat("  ", len(segments)) + segments[len(segments)-1]
+		if !file.Included {
+			line += fmt.Sprintf(" (%s)", file.Reason)
+		}
+		builder.WriteString(line + "\n")
+	}
+	return builder.String()
+}
+
+// Message formats the pack as context for the questions: the file tree, then the included files
+func (p *Pack) Message() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Project context of %s (%d files, %d included below).\n\nFile tree:\n\n```\n%s```\n",
+		p.Root, len(p.Files), p.Included(), p.Tree())
+	for _, file := range p.Files {
+		if !file.Included {
+			continue
+		}
+		language := strings.TrimPrefix(path.Ext(file.Path), ".")
+		fmt.Fprintf(&builder, "\n## %s\n\n```%s\n%s\n```\n", file.Path, language, strings.TrimRight(file.content, "\n"))
+	}
+	return builder.String()
+}
+
+// priority ranks a file for inclusion: the key project files first (in the order of keyFiles), then by
+// depth
+func priority(relPath string) int {
+	if index := slices.Index(keyFiles, relPath); index >= 0 {
+		return index
+	}
+	return len(keyFiles) + strings.Count(relPath, "/")
+}
+
+// rule is a pattern of a .gitignore file
+type rule struct {
+	// base is the directory of the .gitignore file, relative to the root ("" for the root)
+	base     string
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// readGitignore reads the patterns of the .gitignore file of a directory (none when it does not exist).
+// rel is the path of the directory relative to the root.
+func readGitignore(dir, rel string) []rule {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var rules []rule
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := rule{base: rel}
+		if strings.HasPrefix(line, "!") {
+			r.negate, line = true, line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimSuffix(line, "/")
+		}
+		// A pattern with a slash (other than a trailing one) is relative to the .gitignore directory
+		r.anchored = strings.Contains(line, "/")
+		r.pattern = strings.TrimPrefix(line, "/")
+		if r.pattern != "" {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// ignored reports whether a path (relative to the root) is ignored: the last matching rule wins
+func ignored(rules []rule, relPath string, isDir bool) bool {
+	result := false
+	for _, r := range rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		sub := relPath
+		if r.base != "" {
+			if !strings.HasPrefix(relPath, r.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(relPath, r.base+"/")
+		}
+		var matched bool
+		switch {
+		case r.anchored && strings.Contains(r.pattern, "/"):
+			matched = pathfilter.Match(r.pattern, sub)
+		case r.anchored:
+			matched, _ = path.Match(r.pattern, sub)
+		default:
+			matched, _ = path.Match(r.pattern, path.Base(sub))
+		}
+		if matched {
+			result = !r.negate
+		}
+	}
+	return result
+}
@@ -0,0 +1,99 @@
+package debuglog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DirName is the name of the directory of the --debug log files in the .budgie directory
+const DirName = "logs"
+
+// logger writes the debug log, nil when it is disabled
+var logger *log.Logger
+
+// Enable writes the debug log to w: the requests sent to the provider with their payload, the retrieved
+// chunks and the duration of each phase
+func Enable(w io.Writer) {
+	logger = log.New(w, "[debug] ", log.Ltime|log.Lmicroseconds)
+}
+
+// Enabled reports whether the debug log is written
+func Enabled() bool {
+	return logger != nil
+}
+
+// Printf writes a line to the debug log
+func Printf(format string, args ...any) {
+	if logger != nil {
+		logger.Printf(format, args...)
+	}
+}
+
+// Phase writes the start of a phase to the debug log and returns the function writing its duration
+func Phase(name string) func() {
+	if logger == nil {
+		return func() {}
+	}
+	start := time.Now()
+	logger.Printf("%s: started", name)
+	return func() {
+		logger.Printf("%s: done in %s", name, time.Since(start).Round(time.Millisecond))
+	}
+}
+
+// OpenFile creates a log file in the logs directory next to the config file (<dir>/logs/budgie-<timestamp>.log)
+func OpenFile(configFile string) (*os.File, error) {
+	dir := filepath.Join(filepath.Dir(configFile), DirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	name := fmt.Sprintf("budgie-%s.log", time.Now().Format("2006-01-02-15-04-05"))
+	return os.OpenFile(filepath.Join(dir, name), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// Transport wraps an HTTP transport to write the requests (method, URL and payload, without the headers
+// holding the API keys), the response status and the duration of each request to the debug log
+func Transport(next http.RoundTripper) http.RoundTripper {
+	return roundTripper{next: next}
+}
+
+type roundTripper struct {
+	next http.RoundTripper
+}
+
+// RoundTrip logs the request and its response
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if logger == nil {
+		return t.next.RoundTrip(req)
+	}
+
+	payload := ""
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			data, _ := io.ReadAll(body)
+			body.Close()
+			payload = string(bytes.TrimSpace(data))
+		}
+	}
+	if payload != "" {
+		logger.Printf("→ %s %s\n%s", req.Method, req.URL, payload)
+	} else {
+		logger.Printf("→ %s %s", req.Method, req.URL)
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		logger.Printf("← %s %s: %v (%s)", req.Method, req.URL, err, time.Since(start).Round(time.Millisecond))
+		return resp, err
+	}
+	// The duration of a streamed response is the time to its first bytes (the headers)
+	logger.Printf("← %s %s: %s (%s)", req.Method, req.URL, resp.Status, time.Since(start).Round(time.Millisecond))
+	return resp, err
+}
@@ -0,0 +1,70 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// PageBreak separates the pages of an extracted text (a form feed, like pdftotext)
+const PageBreak = "\f"
+
+// Extensions are the extensions of the documents whose text is extracted
+var Extensions = []string{".pdf", ".docx"}
+
+// ErrNoText is returned for the documents without any extractable text (e.g. a scanned PDF)
+var ErrNoText = errors.New("no extractable text")
+
+// Supported reports whether the text of a file is extracted by ReadText (PDF and DOCX documents)
+func Supported(path string) bool {
+	return slices.Contains(Extensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// ReadText extracts the text of a PDF or DOCX document, its pages separated by PageBreak. The headings of
+// a DOCX document become Markdown headings.
+func ReadText(path string) (string, error) {
+	var text string
+	var err error
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		text, err = readPDF(path)
+	case ".docx":
+		text, err = readDOCX(path)
+	default:
+		return "", fmt.Errorf("unsupported document %s (supported: %s)", path, strings.Join(Extensions, ", "))
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(strings.ReplaceAll(text, PageBreak, "")) == "" {
+		return "", ErrNoText
+	}
+	return text, nil
+}
+
+// Pages splits an extracted text into its pages
+func Pages(text string) []string {
+	return strings.Split(text, PageBreak)
+}
+
+// cleanText trims the spaces at the end of the lines and collapses the runs of blank lines
+func cleanText(text string) string {
+	lines := strings.Split(text, "\n")
+	var kept []string
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" {
+			if blank || len(kept) == 0 {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		kept = append(kept, line)
+	}
+	return strings.TrimSpace(strings.Join(kept, "\n"))
+}
@@ -0,0 +1,134 @@
+package document
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// headingStyleRegex matches the paragraph styles of the headings (Heading1, heading 2, Title...)
+var headingStyleRegex = regexp.MustCompile(`(?i)^(?:heading\s*(\d)|title)$`)
+
+// readDOCX extracts the paragraphs of the body of a DOCX document (word/document.xml). The headings are
+// written as Markdown headings, the list items with a dash, and the page breaks as PageBreak.
+func readDOCX(path string) (string, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening %s: %w", path, err)
+	}
+	defer archive.Close()
+
+	var body io.ReadCloser
+	for _, file := range archive.File {
+		if file.Name == "word/document.xml" {
+			if body, err = file.Open(); err != nil {
+				return "", fmt.Errorf("error reading %s: %w", path, err)
+			}
+			break
+		}
+	}
+	if body == nil {
+		return "", fmt.Errorf("%s is not a DOCX document (no word/document.xml)", path)
+	}
+	defer body.Close()
+
+	var text, paragraph strings.Builder
+	heading, listItem, inText := 0, false, false
+	// pageText reports whether text was written since the last page break (consecutive breaks are merged)
+	pageText := false
+	pageBreak := func() {
+		if pageText {
+			text.WriteString(PageBreak)
+			pageText = false
+		}
+	}
+	decoder := xml.NewDecoder(body)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("error reading %s: %w", path, err)
+		}
+		switch element := token.(type) {
+		case xml.StartElement:
+			switch element.Name.Local {
+			case "p":
+				paragraph.Reset()
+				heading, listItem = 0, false
+			case "pStyle":
+				if match := headingStyleRegex.FindStringSubmatch(wordAttribute(element, "val")); match != nil {
+					heading = 1
+					if match[1] != "" {
+						heading, _ = strconv.Atoi(match[1])
+					}
+				}
+			case "numPr":
+				listItem = true
+			case "t":
+				inText = true
+			case "tab":
+				paragraph.WriteString("\t")
+			case "br", "cr":
+				if wordAttribute(element, "type") == "page" {
+					flushParagraph(&text, &paragraph, heading, listItem, &pageText)
+					pageBreak()
+				} else {
+					paragraph.WriteString("\n")
+				}
+			case "lastRenderedPageBreak":
+				flushParagraph(&text, &paragraph, heading, listItem, &pageText)
+				pageBreak()
+			}
+		case xml.EndElement:
+			switch element.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				flushParagraph(&text, &paragraph, heading, listItem, &pageText)
+			}
+		case xml.CharData:
+			if inText {
+				paragraph.Write(element)
+			}
+		}
+	}
+
+	pages := Pages(text.String())
+	for i, page := range pages {
+		pages[i] = cleanText(page)
+	}
+	return strings.Join(pages, PageBreak), nil
+}
+
+// flushParagraph writes the text of a paragraph as a Markdown block
+func flushParagraph(text, paragraph *strings.Builder, heading int, listItem bool, pageText *bool) {
+	content := strings.TrimSpace(paragraph.String())
+	paragraph.Reset()
+	if content == "" {
+		return
+	}
+	switch {
+	case heading > 0:
+		content = strings.Repeat("#", heading) + " " + strings.ReplaceAll(content, "\n", " ")
+	case listItem:
+		content = "- " + content
+	}
+	text.WriteString(content + "\n\n")
+	*pageText = true
+}
+
+// wordAttribute returns the value of an attribute of a WordprocessingML element (w:val, w:type...)
+func wordAttribute(element xml.StartElement, name string) string {
+	for _, attribute := range element.Attr {
+		if attribute.Name.Local == name {
+			return attribute.Value
+		}
+	}
+	return ""
+}
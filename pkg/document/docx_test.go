@@ -0,0 +1,181 @@
+package document
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeDOCX writes a DOCX document whose body holds the given WordprocessingML paragraphs
+func writeDOCX(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "document.docx")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	part, err := archive.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+		body + `</w:body></w:document>`
+	if _, err := part.Write([]byte(document)); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// paragraph returns a paragraph of runs of text, with the given paragraph properties
+func paragraph(properties string, runs ...string) string {
+	var p strings.Builder
+	p.WriteString("<w:p>")
+	if properties != "" {
+		p.WriteString("<w:pPr>" + properties + "</w:pPr>")
+	}
+	for _, run := range runs {
+		p.WriteString("<w:r>" + run + "</w:r>")
+	}
+	p.WriteString("</w:p>")
+	return p.String()
+}
+
+func TestReadDOCX(t *testing.T) {
+	pageBreak := `<w:br w:type="page"/>`
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{
+			"paragraphs",
+			paragraph("", "<w:t>First </w:t>", "<w:t>paragraph</w:t>") + paragraph("", "<w:t>Second</w:t>"),
+			"First paragraph\n\nSecond",
+		},
+		{
+			"headings",
+			paragraph(`<w:pStyle w:val="Title"/>`, "<w:t>Guide</w:t>") +
+				paragraph(`<w:pStyle w:val="Heading2"/>`, "<w:t>Setup</w:t>") +
+				paragraph(`<w:pStyle w:val="heading 3"/>`, "<w:t>Linux</w:t>") +
+				paragraph(`<w:pStyle w:val="Quote"/>`, "<w:t>Not a heading</w:t>"),
+			"# Guide\n\n## Setup\n\n### Linux\n\nNot a heading",
+		},
+		{
+			"list items",
+			paragraph(`<w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr>`, "<w:t>One</w:t>") +
+				paragraph(`<w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr>`, "<w:t>Two</w:t>") +
+				paragraph("", "<w:t>After</w:t>"),
+			"- One\n\n- Two\n\nAfter",
+		},
+		{
+			"tabs and line breaks",
+			paragraph("", "<w:t>Name</w:t><w:tab/><w:t>Value</w:t>", "<w:br/><w:t>Next line</w:t>"),
+			"Name\tValue\nNext line",
+		},
+		{
+			"heading on several lines",
+			paragraph(`<w:pStyle w:val="Heading1"/>`, "<w:t>Long</w:t><w:br/><w:t>title</w:t>"),
+			"# Long title",
+		},
+		{
+			"page breaks",
+			paragraph("", "<w:t>Page one</w:t>", pageBreak, "<w:t>Page two</w:t>") +
+				paragraph("", "<w:lastRenderedPageBreak/><w:t>Page three</w:t>"),
+			"Page one\fPage two\fPage three",
+		},
+		{
+			"consecutive page breaks merged",
+			paragraph("", "<w:t>Before</w:t>", pageBreak, pageBreak) + paragraph("", pageBreak, "<w:t>After</w:t>"),
+			"Before\fAfter",
+		},
+		{
+			"empty paragraphs and text out of runs",
+			paragraph("") + paragraph("", "<w:t>  </w:t>") + paragraph("", "<w:t>Kept</w:t>", "<w:instrText>PAGE</w:instrText>"),
+			"Kept",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text, err := ReadText(writeDOCX(t, test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != test.want {
+				t.Errorf("text = %q, want %q", text, test.want)
+			}
+		})
+	}
+}
+
+func TestReadDOCXErrors(t *testing.T) {
+	dir := t.TempDir()
+	notZip := filepath.Join(dir, "notes.docx")
+	if err := os.WriteFile(notZip, []byte("plain text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadText(notZip); err == nil {
+		t.Error("a text file was read as a DOCX document")
+	}
+
+	// A zip archive without the body of a document
+	other := filepath.Join(dir, "other.docx")
+	file, err := os.Create(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(file)
+	if _, err := archive.Create("content.xml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if _, err := ReadText(other); err == nil || !strings.Contains(err.Error(), "not a DOCX document") {
+		t.Errorf("ReadText of a zip archive = %v, want an error", err)
+	}
+
+	if _, err := ReadText(filepath.Join(dir, "notes.odt")); err == nil || !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("ReadText of an unsupported document = %v", err)
+	}
+}
+
+func TestSupported(t *testing.T) {
+	for path, want := range map[string]bool{
+		"guide.pdf":      true,
+		"docs/Guide.PDF": true,
+		"report.docx":    true,
+		"report.doc":     false,
+		"notes.md":       false,
+		"pdf":            false,
+	} {
+		if got := Supported(path); got != want {
+			t.Errorf("Supported(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestCleanText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"", ""},
+		{"one  \ntwo\t", "one\ntwo"},
+		{"\n\n  \none\n\n\n\ntwo\n\n", "one\n\ntwo"},
+		{"  indented\n", "indented"},
+	}
+	for _, test := range tests {
+		if got := cleanText(test.text); got != test.want {
+			t.Errorf("cleanText(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
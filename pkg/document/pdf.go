@@ -0,0 +1,608 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// objectRegex matches the headers of the indirect objects of a PDF file (12 0 obj)
+var objectRegex = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// trailerRegex matches the trailer dictionaries of the cross-reference tables
+var trailerRegex = regexp.MustCompile(`trailer\s*<<`)
+
+// inlineImageEndRegex matches the end of the data of an inline image
+var inlineImageEndRegex = regexp.MustCompile(`\sEI\b`)
+
+// maxResolveDepth bounds the chains of references and the nesting of the form XObjects
+const maxResolveDepth = 16
+
+// pdfFile holds the objects of a PDF file by object number (the last definition wins, as in the
+// incremental updates)
+type pdfFile struct {
+	objects  map[int]any
+	trailers []pdfDict
+}
+
+// readPDF extracts the text of the pages of a PDF file, in the order of the page tree. The objects are
+// found by scanning the file (object streams included) rather than from the cross-reference table, so the
+// damaged files are read too. The encrypted files are not supported.
+func readPDF(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(bytes.TrimLeft(data, " \t\r\n"), []byte("%PDF-")) {
+		return "", fmt.Errorf("%s is not a PDF file", path)
+	}
+	file := parsePDF(data)
+	for _, trailer := range file.trailers {
+		if _, encrypted := trailer["Encrypt"]; encrypted {
+			return "", fmt.Errorf("%s is encrypted (not supported)", path)
+		}
+	}
+
+	var pages []string
+	for _, page := range file.pages() {
+		pages = append(pages, cleanText(file.pageText(page)))
+	}
+	return strings.Join(pages, PageBreak), nil
+}
+
+// parsePDF reads the indirect objects and the trailers of a PDF file
+func parsePDF(data []byte) *pdfFile {
+	file := &pdfFile{objects: make(map[int]any)}
+	// The headers matched in the data of a stream are not objects
+	streamEnd := 0
+	for _, match := range objectRegex.FindAllSubmatchIndex(data, -1) {
+		if match[0] < streamEnd {
+			continue
+		}
+		objectNumber, _ := strconv.Atoi(string(data[match[2]:match[3]]))
+		lexer := &pdfLexer{data: data, pos: match[1], refs: true}
+		value := lexer.readObject()
+		if dict, ok := value.(pdfDict); ok {
+			lexer.skipSpace()
+			if lexer.hasPrefix("stream") {
+				var stream *pdfStream
+				stream, streamEnd = readStreamData(data, lexer.pos+len("stream"), dict)
+				value = stream
+			}
+			// The cross-reference streams hold the trailer entries
+			if dict["Type"] == pdfName("XRef") {
+				file.trailers = append(file.trailers, dict)
+			}
+		}
+		file.objects[objectNumber] = value
+	}
+	for _, index := range trailerRegex.FindAllIndex(data, -1) {
+		lexer := &pdfLexer{data: data, pos: index[0] + len("trailer"), refs: true}
+		if dict, ok := lexer.readObject().(pdfDict); ok {
+			file.trailers = append(file.trailers, dict)
+		}
+	}
+
+	// The objects compressed in object streams (the ones defined out of them win)
+	for _, value := range file.objects {
+		stream, ok := value.(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		content, err := file.decode(stream)
+		if err != nil {
+			continue
+		}
+		count, first := int(number(stream.dict["N"])), int(number(file.resolve(stream.dict["First"])))
+		header := &pdfLexer{data: content}
+		for i := 0; i < count && first <= len(content); i++ {
+			objectNumber, offset := int(number(header.readObject())), int(number(header.readObject()))
+			if _, defined := file.objects[objectNumber]; defined || first+offset > len(content) {
+				continue
+			}
+			lexer := &pdfLexer{data: content, pos: first + offset, refs: true}
+			file.objects[objectNumber] = lexer.readObject()
+		}
+	}
+	return file
+}
+
+// readStreamData reads the data of a stream starting at the stream keyword end: its /Length when it is
+// direct and consistent, up to the endstream keyword otherwise. It returns the stream and the position of
+// the end of its data.
+func readStreamData(data []byte, start int, dict pdfDict) (*pdfStream, int) {
+	if bytes.HasPrefix(data[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(data) && (data[start] == '\n' || data[start] == '\r') {
+		start++
+	}
+	if length, ok := dict["Length"].(float64); ok {
+		end := start + int(length)
+		if end <= len(data) && bytes.HasPrefix(bytes.TrimLeft(data[end:], " \r\n"), []byte("endstream")) {
+			return &pdfStream{dict: dict, data: data[start:end]}, end
+		}
+	}
+	end := bytes.Index(data[start:], []byte("endstream"))
+	if end < 0 {
+		return &pdfStream{dict: dict, data: data[start:]}, len(data)
+	}
+	return &pdfStream{dict: dict, data: bytes.TrimRight(data[start:start+end], "\r\n")}, start + end
+}
+
+// resolve follows the indirect references
+func (f *pdfFile) resolve(value any) any {
+	for range maxResolveDepth {
+		ref, ok := value.(pdfRef)
+		if !ok {
+			return value
+		}
+		value = f.objects[ref.number]
+	}
+	return nil
+}
+
+// dict returns a dictionary value (resolved), nil for other values
+func (f *pdfFile) dict(value any) pdfDict {
+	switch resolved := f.resolve(value).(type) {
+	case pdfDict:
+		return resolved
+	case *pdfStream:
+		return resolved.dict
+	}
+	return nil
+}
+
+// decode returns the decoded data of a stream (FlateDecode, ASCIIHexDecode and ASCII85Decode filters)
+func (f *pdfFile) decode(stream *pdfStream) ([]byte, error) {
+	var filters []any
+	switch filter := f.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{filter}
+	case pdfArray:
+		filters = filter
+	}
+	data := stream.data
+	for _, filter := range filters {
+		switch f.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			reader, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			// The truncated streams keep what could be decompressed
+			decoded, err := io.ReadAll(reader)
+			if err != nil && len(decoded) == 0 {
+				return nil, err
+			}
+			data = decoded
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			lexer := &pdfLexer{data: append([]byte("<"), data...)}
+			data = lexer.readHexString()
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			encoded, _, _ := bytes.Cut(bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~")), []byte("~>"))
+			decoded := make([]byte, 4*len(encoded))
+			n, _, err := ascii85.Decode(decoded, encoded, true)
+			if err != nil {
+				return nil, err
+			}
+			data = decoded[:n]
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", filter)
+		}
+	}
+	return data, nil
+}
+
+// pdfPage is a page of the page tree, with its resources (inherited from its ancestors when missing)
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages returns the pages in the order of the page tree of the catalog, or in object number order when the
+// page tree cannot be read
+func (f *pdfFile) pages() []pdfPage {
+	var pages []pdfPage
+	var walk func(node pdfDict, resources pdfDict, depth int)
+	walk = func(node pdfDict, resources pdfDict, depth int) {
+		if node == nil || depth > maxResolveDepth {
+			return
+		}
+		if own := f.dict(node["Resources"]); own != nil {
+			resources = own
+		}
+		if node["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: node, resources: resources})
+			return
+		}
+		kids, _ := f.resolve(node["Kids"]).(pdfArray)
+		for _, kid := range kids {
+			walk(f.dict(kid), resources, depth+1)
+		}
+	}
+	for _, trailer := range f.trailers {
+		if catalog := f.dict(trailer["Root"]); catalog != nil {
+			walk(f.dict(catalog["Pages"]), nil, 0)
+			if len(pages) > 0 {
+				return pages
+			}
+		}
+	}
+
+	// Fallback: the page objects by number
+	numbers := make([]int, 0, len(f.objects))
+	for objectNumber := range f.objects {
+		numbers = append(numbers, objectNumber)
+	}
+	slices.Sort(numbers)
+	for _, objectNumber := range numbers {
+		if dict := f.dict(f.objects[objectNumber]); dict != nil && dict["Type"] == pdfName("Page") {
+			resources := f.dict(dict["Resources"])
+			for parent, depth := f.dict(dict["Parent"]), 0; resources == nil && parent != nil && depth < maxResolveDepth; parent, depth = f.dict(parent["Parent"]), depth+1 {
+				resources = f.dict(parent["Resources"])
+			}
+			pages = append(pages, pdfPage{dict: dict, resources: resources})
+		}
+	}
+	return pages
+}
+
+// pageText returns the text of a page (its content streams concatenated)
+func (f *pdfFile) pageText(page pdfPage) string {
+	var content []byte
+	contents := f.resolve(page.dict["Contents"])
+	streams, ok := contents.(pdfArray)
+	if !ok {
+		streams = pdfArray{contents}
+	}
+	for _, value := range streams {
+		if stream, ok := f.resolve(value).(*pdfStream); ok {
+			if data, err := f.decode(stream); err == nil {
+				content = append(content, data...)
+				content = append(content, '\n')
+			}
+		}
+	}
+	extractor := &textExtractor{file: f}
+	extractor.run(content, page.resources, 0)
+	return extractor.text.String()
+}
+
+// textExtractor interprets the text operators of content streams
+type textExtractor struct {
+	file  *pdfFile
+	text  strings.Builder
+	font  *pdfFont
+	lastY float64
+	hasY  bool
+}
+
+// run interprets a content stream with its resources. The form XObjects are interpreted too.
+func (e *textExtractor) run(content []byte, resources pdfDict, depth int) {
+	fonts := make(map[pdfName]*pdfFont)
+	lexer := &pdfLexer{data: content}
+	var operands []any
+	for !lexer.eof() {
+		value := lexer.readObject()
+		operator, ok := value.(pdfKeyword)
+		if !ok {
+			operands = append(operands, value)
+			continue
+		}
+		operand := func(i int) any {
+			if i < len(operands) {
+				return operands[i]
+			}
+			return nil
+		}
+		switch operator {
+		case "Tf":
+			name, _ := operand(0).(pdfName)
+			if _, loaded := fonts[name]; !loaded {
+				fonts[name] = e.file.loadFont(e.file.dict(e.file.dict(resources["Font"])[name]))
+			}
+			e.font = fonts[name]
+		case "Td", "TD":
+			if math.Abs(number(operand(1))) > 0.1 {
+				e.newLine()
+			} else {
+				e.space()
+			}
+		case "Tm":
+			y := number(operand(5))
+			if e.hasY && math.Abs(y-e.lastY) > 0.1 {
+				e.newLine()
+			} else {
+				e.space()
+			}
+			e.lastY, e.hasY = y, true
+		case "T*":
+			e.newLine()
+		case "Tj":
+			e.show(operand(0))
+		case "'":
+			e.newLine()
+			e.show(operand(0))
+		case "\"":
+			e.newLine()
+			e.show(operand(2))
+		case "TJ":
+			array, _ := operand(0).(pdfArray)
+			for _, item := range array {
+				// A large negative adjustment (in thousandths of an em) is a space between words
+				if adjustment, ok := item.(float64); ok {
+					if adjustment < -150 {
+						e.space()
+					}
+					continue
+				}
+				e.show(item)
+			}
+		case "ET":
+			e.space()
+		case "ID":
+			// The data of an inline image, up to the EI operator
+			if end := inlineImageEndRegex.FindIndex(content[lexer.pos:]); end != nil {
+				lexer.pos += end[1]
+			} else {
+				lexer.pos = len(content)
+			}
+		case "Do":
+			name, _ := operand(0).(pdfName)
+			xobject, ok := e.file.resolve(e.file.dict(resources["XObject"])[name]).(*pdfStream)
+			if ok && xobject.dict["Subtype"] == pdfName("Form") && depth < maxResolveDepth {
+				if data, err := e.file.decode(xobject); err == nil {
+					formResources := e.file.dict(xobject.dict["Resources"])
+					if formResources == nil {
+						formResources = resources
+					}
+					e.run(data, formResources, depth+1)
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// show writes the text of a string operand with the current font
+func (e *textExtractor) show(value any) {
+	s, ok := value.(pdfString)
+	if !ok {
+		return
+	}
+	font := e.font
+	if font == nil {
+		font = &pdfFont{codeLength: 1}
+	}
+	e.text.WriteString(font.decode(s))
+}
+
+// newLine ends the current line (once)
+func (e *textExtractor) newLine() {
+	if text := e.text.String(); text != "" && !strings.HasSuffix(text, "\n") {
+		e.text.WriteString("\n")
+	}
+}
+
+// space separates the next text from the current one (once)
+func (e *textExtractor) space() {
+	if text := e.text.String(); text != "" && !strings.HasSuffix(text, " ") && !strings.HasSuffix(text, "\n") {
+		e.text.WriteString(" ")
+	}
+}
+
+// pdfFont decodes the strings shown with a font: with its ToUnicode map when it has one, as WinAnsi
+// otherwise (the composite fonts without ToUnicode map cannot be decoded)
+type pdfFont struct {
+	codeLength int
+	toUnicode  map[uint32]string
+	composite  bool
+	// differences are the characters of the codes redefined by the /Differences of the encoding
+	differences map[byte]string
+}
+
+// loadFont reads the decoding of a font dictionary
+func (f *pdfFile) loadFont(dict pdfDict) *pdfFont {
+	font := &pdfFont{codeLength: 1}
+	if dict == nil {
+		return font
+	}
+	if dict["Subtype"] == pdfName("Type0") {
+		font.composite, font.codeLength = true, 2
+	}
+	if encoding := f.dict(dict["Encoding"]); encoding != nil {
+		differences, _ := f.resolve(encoding["Differences"]).(pdfArray)
+		code := 0
+		for _, item := range differences {
+			switch value := item.(type) {
+			case float64:
+				code = int(value)
+			case pdfName:
+				if text, ok := glyphText(string(value)); ok && code < 256 {
+					if font.differences == nil {
+						font.differences = make(map[byte]string)
+					}
+					font.differences[byte(code)] = text
+				}
+				code++
+			}
+		}
+	}
+	if stream, ok := f.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := f.decode(stream); err == nil {
+			font.toUnicode = make(map[uint32]string)
+			if length := parseCMap(data, font.toUnicode); length > 0 {
+				font.codeLength = length
+			}
+		}
+	}
+	return font
+}
+
+// decode returns the text of a string
+func (font *pdfFont) decode(s pdfString) string {
+	var builder strings.Builder
+	if font.toUnicode == nil {
+		if font.composite {
+			return ""
+		}
+		for _, b := range s {
+			builder.WriteString(font.simple(b))
+		}
+		return builder.String()
+	}
+	for i := 0; i+font.codeLength <= len(s); i += font.codeLength {
+		code := codeValue(s[i : i+font.codeLength])
+		if text, ok := font.toUnicode[code]; ok {
+			builder.WriteString(text)
+		} else if font.codeLength == 1 {
+			builder.WriteString(font.simple(s[i]))
+		}
+	}
+	return builder.String()
+}
+
+// simple decodes a byte of a simple font: with the differences of its encoding, as WinAnsi otherwise
+func (font *pdfFont) simple(b byte) string {
+	if text, ok := font.differences[b]; ok {
+		return text
+	}
+	return winAnsi(b)
+}
+
+// glyphNames are the characters of the common glyph names of the /Differences (the ligatures and the
+// punctuation), the single letter and digit names and the uniXXXX names are decoded by glyphText
+var glyphNames = map[string]string{
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl", "space": " ", "quoteright": "’",
+	"quoteleft": "‘", "quotedblleft": "“", "quotedblright": "”", "quotesingle": "'", "quotedbl": "\"",
+	"endash": "–", "emdash": "—", "bullet": "•", "ellipsis": "…", "hyphen": "-", "minus": "-",
+	"period": ".", "comma": ",", "colon": ":", "semicolon": ";", "exclam": "!", "question": "?",
+	"parenleft": "(", "parenright": ")", "bracketleft": "[", "bracketright": "]", "slash": "/",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4", "five": "5", "six": "6", "seven": "7",
+	"eight": "8", "nine": "9", "dollar": "$", "percent": "%", "ampersand": "&", "asterisk": "*", "plus": "+",
+	"equal": "=", "at": "@", "numbersign": "#", "underscore": "_", "degree": "°", "copyright": "©",
+	"registered": "®", "trademark": "™", "section": "§", "dagger": "†", "daggerdbl": "‡", "Euro": "€",
+}
+
+// glyphText returns the text of a glyph name
+func glyphText(name string) (string, bool) {
+	if text, ok := glyphNames[name]; ok {
+		return text, true
+	}
+	if len(name) == 1 {
+		return name, true
+	}
+	if code, ok := strings.CutPrefix(name, "uni"); ok && len(code) == 4 {
+		if value, err := strconv.ParseUint(code, 16, 16); err == nil {
+			return string(rune(value)), true
+		}
+	}
+	return "", false
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap. It returns the length in bytes of
+// the codes (from the codespace range, 0 when unknown).
+func parseCMap(data []byte, mapping map[uint32]string) int {
+	codeLength := 0
+	lexer := &pdfLexer{data: data}
+	var operands []any
+	for !lexer.eof() {
+		value := lexer.readObject()
+		keyword, ok := value.(pdfKeyword)
+		if !ok {
+			operands = append(operands, value)
+			continue
+		}
+		switch keyword {
+		case "endcodespacerange":
+			if low, ok := first(operands).(pdfString); ok && codeLength == 0 {
+				codeLength = len(low)
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				source, _ := operands[i].(pdfString)
+				if target, ok := operands[i+1].(pdfString); ok && len(source) > 0 {
+					mapping[codeValue(source)] = utf16Text(target)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				low, _ := operands[i].(pdfString)
+				high, _ := operands[i+1].(pdfString)
+				if len(low) == 0 || len(high) == 0 {
+					continue
+				}
+				start, end := codeValue(low), codeValue(high)
+				for code := start; code <= end && code-start < 65536; code++ {
+					switch target := operands[i+2].(type) {
+					case pdfString:
+						// The last byte of the target is incremented along the range
+						incremented := append(pdfString{}, target...)
+						if len(incremented) > 0 {
+							incremented[len(incremented)-1] += byte(code - start)
+						}
+						mapping[code] = utf16Text(incremented)
+					case pdfArray:
+						if int(code-start) < len(target) {
+							if text, ok := target[code-start].(pdfString); ok {
+								mapping[code] = utf16Text(text)
+							}
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return codeLength
+}
+
+// first returns the first value of a list (nil when empty)
+func first(values []any) any {
+	if len(values) == 0 {
+		return nil
+	}
+	return values[0]
+}
+
+// codeValue returns the value of a character code of 1 to 4 bytes
+func codeValue(code []byte) uint32 {
+	var padded [4]byte
+	copy(padded[4-min(len(code), 4):], code)
+	return binary.BigEndian.Uint32(padded[:])
+}
+
+// utf16Text decodes the UTF-16BE text of a CMap target
+func utf16Text(data pdfString) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// winAnsiSpecials are the characters of the WinAnsi encoding which differ from Latin-1
+var winAnsiSpecials = map[byte]string{
+	0x80: "€", 0x85: "…", 0x91: "‘", 0x92: "’", 0x93: "“", 0x94: "”", 0x95: "•", 0x96: "–", 0x97: "—", 0x99: "™",
+}
+
+// winAnsi decodes a byte of the WinAnsi encoding (the control characters are dropped)
+func winAnsi(b byte) string {
+	if special, ok := winAnsiSpecials[b]; ok {
+		return special
+	}
+	if b < 0x20 && b != '\n' && b != '\t' {
+		return ""
+	}
+	return string(rune(b))
+}
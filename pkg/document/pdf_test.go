@@ -0,0 +1,237 @@
+package document
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// stream returns a stream object with the length of its data
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+// deflate compresses the data of a FlateDecode stream
+func deflate(t *testing.T, data string) string {
+	t.Helper()
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	if _, err := writer.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return compressed.String()
+}
+
+// writePDF writes a PDF file of the given objects (numbered from 1) and trailer
+func writePDF(t *testing.T, objects []string, trailer string) string {
+	t.Helper()
+	var data strings.Builder
+	data.WriteString("%PDF-1.7\n")
+	for i, object := range objects {
+		fmt.Fprintf(&data, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	if trailer != "" {
+		fmt.Fprintf(&data, "trailer\n%s\n", trailer)
+	}
+	data.WriteString("%%EOF\n")
+	path := filepath.Join(t.TempDir(), "document.pdf")
+	if err := os.WriteFile(path, []byte(data.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// pageTree returns the catalog and the page tree of pages whose content streams are the next objects,
+// with the Helvetica font F1 (objects 1 to 3, the pages from object 4)
+func pageTree(contents ...string) []string {
+	kids := make([]string, len(contents))
+	for i := range contents {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 3 0 R >> >> >>", strings.Join(kids, " "), len(contents)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+	for i, content := range contents {
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /Contents %d 0 R >>", 5+2*i), content)
+	}
+	return objects
+}
+
+func TestReadPDF(t *testing.T) {
+	toUnicode := "/CIDInit /ProcSet findresource begin\n1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"2 beginbfchar <0001> <0042> <0002> <00E9> endbfchar\n" +
+		"1 beginbfrange <0003> <0005> <0061> endbfrange\nendcmap"
+
+	tests := []struct {
+		name    string
+		objects []string
+		want    string
+	}{
+		{
+			"lines and pages",
+			pageTree(
+				stream("", "BT /F1 12 Tf 72 720 Td (Hello) Tj 0 -14 Td (World) Tj ET"),
+				stream("", "BT /F1 12 Tf [(Second) -250 (pa) 20 (ge)] TJ T* (Next line) Tj ET"),
+			),
+			"Hello\nWorld\fSecond page\nNext line",
+		},
+		{
+			"flate stream",
+			pageTree(stream("/Filter /FlateDecode", deflate(t, "BT /F1 12 Tf 72 720 Td (Compressed text) Tj ET"))),
+			"Compressed text",
+		},
+		{
+			"ascii hex stream",
+			pageTree(stream("/Filter /ASCIIHexDecode", fmt.Sprintf("%X>", "BT /F1 12 Tf (Hex text) Tj ET"))),
+			"Hex text",
+		},
+		{
+			"literal string escapes",
+			pageTree(stream("", `BT /F1 12 Tf (a \(b\) c\\d caf\351 \223quoted\224) Tj ET`)),
+			`a (b) c\d café “quoted”`,
+		},
+		{
+			"hex string",
+			pageTree(stream("", "BT /F1 12 Tf <48656C6C6F> Tj ET")),
+			"Hello",
+		},
+		{
+			"text matrix lines",
+			pageTree(stream("", "BT /F1 12 Tf 1 0 0 1 72 720 Tm (First) Tj 1 0 0 1 200 720 Tm (same line) Tj 1 0 0 1 72 700 Tm (Second) Tj ET")),
+			"First same line\nSecond",
+		},
+		{
+			"inline image skipped",
+			pageTree(stream("", "BT /F1 12 Tf (Before) Tj ET BI /W 2 /H 2 ID \x00(Tj)\x01 EI BT (After) Tj ET")),
+			"Before After",
+		},
+		{
+			"differences of the encoding",
+			[]string{
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [4 0 R] /Count 1 >>",
+				"<< /Type /Font /Subtype /Type1 /Encoding << /Differences [1 /fi /uni00E9] >> >>",
+				"<< /Type /Page /Parent 2 0 R /Contents 5 0 R /Resources << /Font << /F2 3 0 R >> >> >>",
+				stream("", "BT /F2 12 Tf (\001nd \002) Tj ET"),
+			},
+			"find é",
+		},
+		{
+			"composite font with a ToUnicode map",
+			[]string{
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [4 0 R] /Count 1 /Resources << /Font << /F1 3 0 R >> >> >>",
+				"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 6 0 R >>",
+				"<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>",
+				stream("", "BT /F1 12 Tf <0001000300040005> Tj <0002> Tj ET"),
+				stream("", toUnicode),
+			},
+			"Babcé",
+		},
+		{
+			"form xobject",
+			[]string{
+				"<< /Type /Catalog /Pages 2 0 R >>",
+				"<< /Type /Pages /Kids [4 0 R] /Count 1 >>",
+				"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+				"<< /Type /Page /Parent 2 0 R /Contents 5 0 R /Resources << /Font << /F1 3 0 R >> /XObject << /X1 6 0 R >> >> >>",
+				stream("", "BT /F1 12 Tf (Page) Tj ET /X1 Do"),
+				stream("/Type /XObject /Subtype /Form", "BT /F1 12 Tf 0 -20 Td (In the form) Tj ET"),
+			},
+			"Page\nIn the form",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			text, err := ReadText(writePDF(t, test.objects, "<< /Root 1 0 R >>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if text != test.want {
+				t.Errorf("text = %q, want %q", text, test.want)
+			}
+		})
+	}
+}
+
+// The pages are found by object number when the file has no readable trailer
+func TestReadPDFWithoutTrailer(t *testing.T) {
+	objects := pageTree(
+		stream("", "BT /F1 12 Tf (One) Tj ET"),
+		stream("", "BT /F1 12 Tf (Two) Tj ET"),
+	)
+	text, err := ReadText(writePDF(t, objects, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "One\fTwo" {
+		t.Errorf("text = %q, want the 2 pages", text)
+	}
+}
+
+// The objects compressed in an object stream are read
+func TestReadPDFObjectStream(t *testing.T) {
+	catalog := "<< /Type /Catalog /Pages 8 0 R >>"
+	pages := "<< /Type /Pages /Kids [2 0 R] /Count 1 /Resources << /Font << /F1 3 0 R >> >> >>"
+	header := fmt.Sprintf("7 0 8 %d ", len(catalog)+1)
+	compressed := header + catalog + " " + pages
+	objects := []string{
+		stream(fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(header)), deflate(t, compressed)),
+		"<< /Type /Page /Parent 8 0 R /Contents 4 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		stream("", "BT /F1 12 Tf (From the object stream) Tj ET"),
+	}
+	text, err := ReadText(writePDF(t, objects, "<< /Root 7 0 R >>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "From the object stream" {
+		t.Errorf("text = %q, want the page of the compressed page tree", text)
+	}
+}
+
+func TestReadPDFErrors(t *testing.T) {
+	dir := t.TempDir()
+	notPDF := filepath.Join(dir, "notes.pdf")
+	if err := os.WriteFile(notPDF, []byte("plain text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ReadText(notPDF); err == nil || !strings.Contains(err.Error(), "not a PDF") {
+		t.Errorf("a text file was read as a PDF: %v", err)
+	}
+
+	encrypted := writePDF(t, pageTree(stream("", "BT (Secret) Tj ET")), "<< /Root 1 0 R /Encrypt << /Filter /Standard >> >>")
+	if _, err := ReadText(encrypted); err == nil || !strings.Contains(err.Error(), "encrypted") {
+		t.Errorf("an encrypted PDF was read: %v", err)
+	}
+
+	// A scanned page: an image without text
+	scanned := writePDF(t, pageTree(stream("", "q 612 0 0 792 0 0 cm /Im1 Do Q")), "<< /Root 1 0 R >>")
+	if _, err := ReadText(scanned); !errors.Is(err, ErrNoText) {
+		t.Errorf("ReadText of a PDF without text = %v, want ErrNoText", err)
+	}
+}
+
+func TestParseCMap(t *testing.T) {
+	mapping := make(map[uint32]string)
+	length := parseCMap([]byte("1 begincodespacerange <00> <FF> endcodespacerange\n"+
+		"1 beginbfchar <01> <D83DDE00> endbfchar\n"+
+		"2 beginbfrange <10> <12> <0041> <20> <21> [<0078> <0079>] endbfrange"), mapping)
+	if length != 1 {
+		t.Errorf("code length = %d, want 1", length)
+	}
+	want := map[uint32]string{0x01: "😀", 0x10: "A", 0x11: "B", 0x12: "C", 0x20: "x", 0x21: "y"}
+	if fmt.Sprint(mapping) != fmt.Sprint(want) {
+		t.Errorf("mapping = %v, want %v", mapping, want)
+	}
+}
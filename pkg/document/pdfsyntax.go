@@ -0,0 +1,261 @@
+package document
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// The values of the PDF syntax: numbers are float64, booleans bool and null nil
+type (
+	pdfName    string
+	pdfKeyword string
+	pdfString  []byte
+	pdfArray   []any
+	pdfDict    map[pdfName]any
+	pdfRef     struct{ number, generation int }
+	pdfStream  struct {
+		dict pdfDict
+		data []byte
+	}
+)
+
+// pdfLexer reads PDF objects (in a file or an object stream) and the operands and operators of the
+// content streams
+type pdfLexer struct {
+	data []byte
+	pos  int
+	// refs enables the indirect references (1 0 R), which do not exist in the content streams
+	refs bool
+}
+
+// isPDFSpace reports whether a byte is a PDF whitespace
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+// isPDFDelimiter reports whether a byte is a PDF delimiter
+func isPDFDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte("()<>[]{}/%"), c) >= 0
+}
+
+// skipSpace skips the whitespaces and the comments
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isPDFSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// eof reports whether the data is read
+func (l *pdfLexer) eof() bool {
+	l.skipSpace()
+	return l.pos >= len(l.data)
+}
+
+// hasPrefix reports whether the next bytes are the given ones
+func (l *pdfLexer) hasPrefix(prefix string) bool {
+	return bytes.HasPrefix(l.data[l.pos:], []byte(prefix))
+}
+
+// readObject reads the next value, or a keyword (an operator of a content stream, endobj...). The closing
+// delimiters out of place are returned as keywords.
+func (l *pdfLexer) readObject() any {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil
+	}
+	switch c := l.data[l.pos]; {
+	case l.hasPrefix("<<"):
+		l.pos += 2
+		dict := make(pdfDict)
+		for !l.eof() && !l.hasPrefix(">>") {
+			key, ok := l.readObject().(pdfName)
+			if !ok {
+				continue
+			}
+			dict[key] = l.readObject()
+		}
+		l.pos = min(l.pos+2, len(l.data))
+		return dict
+	case c == '<':
+		return l.readHexString()
+	case c == '(':
+		return l.readLiteralString()
+	case c == '[':
+		l.pos++
+		var array pdfArray
+		for !l.eof() && l.data[l.pos] != ']' {
+			array = append(array, l.readObject())
+		}
+		l.pos = min(l.pos+1, len(l.data))
+		return array
+	case c == '/':
+		l.pos++
+		return pdfName(l.readName())
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.readNumber()
+	case isPDFDelimiter(c):
+		l.pos++
+		return pdfKeyword(string(c))
+	default:
+		word := l.readRegular()
+		switch word {
+		case "true":
+			return true
+		case "false":
+			return false
+		case "null":
+			return nil
+		}
+		return pdfKeyword(word)
+	}
+}
+
+// readRegular reads a run of regular characters
+func (l *pdfLexer) readRegular() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// readName reads a name without its slash, decoding its #xx escapes
+func (l *pdfLexer) readName() string {
+	raw := l.readRegular()
+	if !bytes.ContainsRune([]byte(raw), '#') {
+		return raw
+	}
+	var name []byte
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if value, err := strconv.ParseUint(raw[i+1:i+3], 16, 8); err == nil {
+				name = append(name, byte(value))
+				i += 2
+				continue
+			}
+		}
+		name = append(name, raw[i])
+	}
+	return string(name)
+}
+
+// readNumber reads a number, or an indirect reference (number generation R) when they are enabled
+func (l *pdfLexer) readNumber() any {
+	word := l.readRegular()
+	value, err := strconv.ParseFloat(word, 64)
+	if err != nil {
+		return pdfKeyword(word)
+	}
+	if !l.refs || bytes.ContainsAny([]byte(word), ".+-") {
+		return value
+	}
+	// number generation R
+	saved := l.pos
+	l.skipSpace()
+	generation, err := strconv.Atoi(l.readRegular())
+	if err == nil {
+		l.skipSpace()
+		if l.readRegular() == "R" {
+			return pdfRef{number: int(value), generation: generation}
+		}
+	}
+	l.pos = saved
+	return value
+}
+
+// readHexString reads a <hex> string
+func (l *pdfLexer) readHexString() pdfString {
+	l.pos++
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos = min(l.pos+1, len(l.data))
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	value := make(pdfString, 0, len(digits)/2)
+	for i := 0; i < len(digits); i += 2 {
+		b, err := strconv.ParseUint(string(digits[i:i+2]), 16, 8)
+		if err != nil {
+			continue
+		}
+		value = append(value, byte(b))
+	}
+	return value
+}
+
+// readLiteralString reads a (literal) string: its parentheses are balanced and its escapes decoded
+func (l *pdfLexer) readLiteralString() pdfString {
+	l.pos++
+	var value pdfString
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return value
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return value
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// A backslash at the end of a line continues the string
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					octal := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						octal = octal*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(octal)
+				}
+			}
+		}
+		value = append(value, c)
+	}
+	return value
+}
+
+// number returns the value of a number operand (0 for other values)
+func number(value any) float64 {
+	n, _ := value.(float64)
+	return n
+}
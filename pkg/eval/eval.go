@@ -0,0 +1,131 @@
+package eval
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Set is an evaluation set: the questions of the documentation with the sources expected to be retrieved
+// and the keywords expected in their answers
+type Set struct {
+	Cases []Case `yaml:"questions"`
+}
+
+// Case is a question of an evaluation set
+type Case struct {
+	Question string `yaml:"question"`
+	// Sources are the files expected among the retrieved chunks: a path, the end of a path (pizzas.md) or a
+	// glob pattern (docs/api/*.md)
+	Sources List `yaml:"sources"`
+	// Keywords are the words expected in the answer (case-insensitive)
+	Keywords List `yaml:"keywords"`
+}
+
+// List is a list of values, written as a YAML list or as a single value
+type List []string
+
+// UnmarshalYAML accepts a single value as well as a list
+func (l *List) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = List{node.Value}
+		return nil
+	}
+	var values []string
+	if err := node.Decode(&values); err != nil {
+		return err
+	}
+	*l = values
+	return nil
+}
+
+// UnmarshalYAML accepts the list of the questions without the questions key
+func (s *Set) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		return node.Decode(&s.Cases)
+	}
+	type set Set
+	return node.Decode((*set)(s))
+}
+
+// Load reads an evaluation set. Every question needs expected sources or keywords.
+func Load(filename string) (*Set, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var set Set
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", filename, err)
+	}
+	if len(set.Cases) == 0 {
+		return nil, fmt.Errorf("%s has no questions", filename)
+	}
+	for i, c := range set.Cases {
+		if strings.TrimSpace(c.Question) == "" {
+			return nil, fmt.Errorf("question %d of %s is empty", i+1, filename)
+		}
+		if len(c.Sources) == 0 && len(c.Keywords) == 0 {
+			return nil, fmt.Errorf("question %d of %s has no expected sources nor keywords", i+1, filename)
+		}
+	}
+	return &set, nil
+}
+
+// MatchSource tells whether a retrieved source (file path or URL) is an expected one
+func MatchSource(expected, source string) bool {
+	expected = strings.TrimPrefix(path.Clean(strings.ReplaceAll(expected, "\\", "/")), "./")
+	source = strings.TrimPrefix(path.Clean(strings.ReplaceAll(source, "\\", "/")), "./")
+	if source == expected || strings.HasSuffix(source, "/"+expected) {
+		return true
+	}
+	if matched, _ := path.Match(expected, source); matched {
+		return true
+	}
+	matched, _ := path.Match(expected, path.Base(source))
+	return matched
+}
+
+// Recall returns the share of the expected sources found among the retrieved ones
+func Recall(expected, retrieved []string) float64 {
+	if len(expected) == 0 {
+		return 0
+	}
+	found := 0
+	for _, want := range expected {
+		for _, source := range retrieved {
+			if MatchSource(want, source) {
+				found++
+				break
+			}
+		}
+	}
+	return float64(found) / float64(len(expected))
+}
+
+// Rank returns the rank (from 1) of the first retrieved source that is an expected one, 0 when none is
+func Rank(expected, retrieved []string) int {
+	for i, source := range retrieved {
+		for _, want := range expected {
+			if MatchSource(want, source) {
+				return i + 1
+			}
+		}
+	}
+	return 0
+}
+
+// KeywordHits returns the number of keywords found in a text (case-insensitive)
+func KeywordHits(keywords []string, text string) int {
+	text = strings.ToLower(text)
+	hits := 0
+	for _, keyword := range keywords {
+		if strings.Contains(text, strings.ToLower(keyword)) {
+			hits++
+		}
+	}
+	return hits
+}
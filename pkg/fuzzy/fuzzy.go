@@ -0,0 +1,84 @@
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scores of the matched characters: a match right after the previous one or at the start of a path
+// segment or word is worth more, each skipped character costs a point
+const (
+	matchScore       = 1
+	consecutiveBonus = 4
+	boundaryBonus    = 3
+	gapPenalty       = 1
+)
+
+// Result is a text matching the pattern
+type Result struct {
+	// Index is the position of the text in the ranked texts
+	Index int
+	Score int
+	// Positions are the indexes of the matched runes of the text
+	Positions []int
+}
+
+// Match reports whether the runes of the pattern appear in order in the text, ignoring the case and the
+// spaces of the pattern. It returns the score of the match (higher is better) and the positions of the
+// matched runes.
+func Match(pattern, text string) (int, []int, bool) {
+	needle := []rune(strings.ToLower(strings.Join(strings.Fields(pattern), "")))
+	haystack := []rune(text)
+	if len(needle) == 0 {
+		return 0, nil, true
+	}
+
+	score, last := 0, -1
+	positions := make([]int, 0, len(needle))
+	for i, r := range haystack {
+		if len(positions) == len(needle) {
+			break
+		}
+		if unicode.ToLower(r) != needle[len(positions)] {
+			continue
+		}
+		score += matchScore
+		switch {
+		case last >= 0 && i == last+1:
+			score += consecutiveBonus
+		case last >= 0:
+			score -= gapPenalty * min(i-last-1, 3)
+		}
+		if i == 0 || strings.ContainsRune("/\\_-. ", haystack[i-1]) || (unicode.IsLower(haystack[i-1]) && unicode.IsUpper(r)) {
+			score += boundaryBonus
+		}
+		positions = append(positions, i)
+		last = i
+	}
+	if len(positions) < len(needle) {
+		return 0, nil, false
+	}
+	return score, positions, true
+}
+
+// Rank returns the texts matching the pattern, the best matches first (the shortest texts first between
+// equal scores, then in their order)
+func Rank(pattern string, texts []string) []Result {
+	var results []Result
+	for i, text := range texts {
+		if score, positions, ok := Match(pattern, text); ok {
+			results = append(results, Result{Index: i, Score: score, Positions: positions})
+		}
+	}
+	if strings.TrimSpace(pattern) == "" {
+		return results
+	}
+	sort.SliceStable(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return len(texts[results[a].Index]) < len(texts[results[b].Index])
+	})
+	return results
+}
@@ -0,0 +1,90 @@
+package glossary
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Entry is a project-specific term and its definition
+type Entry struct {
+	Term       string
+	Definition string
+}
+
+// entryRegex matches "- **Term**: definition", "- Term: definition" and "Term: definition" lines
+var entryRegex = regexp.MustCompile(`^\s*(?:[-*]\s+)?(?:\*\*(.+?)\*\*|([^:*#][^:]*?))\s*:\s+(.+)$`)
+
+// Load parses a glossary file. It returns no entries when the file does not exist.
+func Load(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(string(data)), nil
+}
+
+// Parse extracts the glossary entries from a Markdown content
+func Parse(content string) []Entry {
+	var entries []Entry
+	for _, line := range strings.Split(content, "\n") {
+		match := entryRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		term := match[1]
+		if term == "" {
+			term = match[2]
+		}
+		entries = append(entries, Entry{
+			Term:       strings.TrimSpace(term),
+			Definition: strings.TrimSpace(match[3]),
+		})
+	}
+	return entries
+}
+
+// Compact renders the glossary as a compact context message (one line per term)
+func Compact(entries []Entry) string {
+	var builder strings.Builder
+	builder.WriteString("Project glossary (use these definitions for project-specific terms):\n")
+	for _, entry := range entries {
+		builder.WriteString("- " + entry.Term + ": " + entry.Definition + "\n")
+	}
+	return builder.String()
+}
+
+// Matches returns the entries whose term appears in the text (case-insensitive, whole word)
+func Matches(text string, entries []Entry) []Entry {
+	var found []Entry
+	for _, entry := range entries {
+		termRegex, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(entry.Term) + `\b`)
+		if err != nil {
+			continue
+		}
+		if termRegex.MatchString(text) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+// Expand appends the definitions of the glossary terms found in the question,
+// so the similarity search also matches documentation using the expanded wording
+func Expand(question string, entries []Entry) string {
+	found := Matches(question, entries)
+	if len(found) == 0 {
+		return question
+	}
+
+	var builder strings.Builder
+	builder.WriteString(question)
+	builder.WriteString("\n")
+	for _, entry := range found {
+		builder.WriteString("\n" + entry.Term + ": " + entry.Definition)
+	}
+	return builder.String()
+}
@@ -0,0 +1,103 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+)
+
+// FileName is the name of the history log in the .budgie directory
+const FileName = "history.jsonl"
+
+// Entry is a question/answer pair of the history log. Its id is its line number in the log,
+// which is append-only.
+type Entry struct {
+	ID       int       `json:"-"`
+	Time     time.Time `json:"time"`
+	Command  string    `json:"command"`
+	Model    string    `json:"model"`
+	RAG      bool      `json:"rag,omitempty"`
+	Question string    `json:"question"`
+	Answer   string    `json:"answer"`
+}
+
+// Path returns the history log path next to the given config file
+func Path(configFile string) string {
+	return filepath.Join(filepath.Dir(configFile), FileName)
+}
+
+// Append adds an entry at the end of the log (one JSON object per line)
+func Append(path string, entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+// Load reads the entries of the log, oldest first (an empty list when the log does not exist)
+func Load(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error parsing %s line %d: %w", path, line, err)
+		}
+		entry.ID = line
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Get returns the entry of an id
+func Get(path string, id int) (*Entry, error) {
+	entries, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.ID == id {
+			return &entry, nil
+		}
+	}
+	return nil, fmt.Errorf("no history entry %d (run budgie history list)", id)
+}
+
+// Grep returns the entries whose question or answer matches a regular expression (case-insensitive)
+func Grep(entries []Entry, pattern string) ([]Entry, error) {
+	expr, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	var matches []Entry
+	for _, entry := range entries {
+		if expr.MatchString(entry.Question) || expr.MatchString(entry.Answer) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,64 @@
+package language
+
+import (
+	"regexp"
+	"strings"
+)
+
+// stopWords are the most frequent words of each supported language (ISO 639-1 codes).
+// Words shared by several languages are left out, they do not help to tell them apart.
+var stopWords = map[string][]string{
+	"en": {"the", "and", "is", "are", "of", "to", "in", "that", "it", "with", "for", "this", "be", "on", "how", "what", "which", "you", "can", "from", "by", "an", "or", "was", "not", "have", "has", "will", "when", "why", "do", "does"},
+	"fr": {"le", "la", "les", "des", "est", "et", "un", "une", "du", "que", "qui", "dans", "pour", "avec", "sur", "pas", "ce", "cette", "sont", "au", "aux", "comment", "quel", "quelle", "pourquoi", "je", "vous", "nous", "être", "avoir", "mais", "ou"},
+	"es": {"el", "los", "las", "es", "y", "del", "que", "en", "con", "por", "para", "una", "como", "pero", "su", "sus", "está", "son", "qué", "cómo", "cuál", "porque", "yo", "usted", "nosotros", "muy", "también", "hay", "lo", "al"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "mit", "für", "auf", "dem", "den", "von", "zu", "sich", "auch", "wie", "was", "warum", "ich", "sie", "wir", "oder", "aber", "wird", "sind", "bei", "nach", "kann"},
+	"it": {"il", "lo", "gli", "della", "delle", "che", "è", "e", "di", "con", "per", "una", "sono", "come", "perché", "cosa", "anche", "questo", "questa", "non", "nel", "nella", "io", "noi", "voi", "ma", "più", "alla", "degli", "quale"},
+	"pt": {"o", "os", "as", "é", "e", "do", "da", "dos", "das", "que", "em", "com", "para", "uma", "não", "como", "mas", "são", "está", "isso", "este", "esta", "porque", "qual", "eu", "você", "nós", "também", "no", "na"},
+	"nl": {"de", "het", "een", "en", "is", "van", "dat", "niet", "met", "voor", "op", "zijn", "wordt", "ook", "hoe", "wat", "waarom", "ik", "jij", "wij", "maar", "bij", "naar", "kan", "deze", "dit", "er", "aan", "om", "heeft"},
+}
+
+// minMatches is the number of stop words a text must contain before its language is trusted
+const minMatches = 2
+
+var (
+	wordRegex = regexp.MustCompile(`\p{L}+`)
+	lookup    = buildLookup()
+)
+
+// buildLookup maps each stop word to the languages it belongs to
+func buildLookup() map[string][]string {
+	lookup := make(map[string][]string)
+	for language, words := range stopWords {
+		for _, word := range words {
+			lookup[word] = append(lookup[word], language)
+		}
+	}
+	return lookup
+}
+
+// Detect returns the language (ISO 639-1 code: en, fr, es, de, it, pt, nl) of a text,
+// or an empty string when it cannot be determined with confidence (too short, code, unsupported language)
+func Detect(text string) string {
+	scores := make(map[string]int)
+	for _, word := range wordRegex.FindAllString(strings.ToLower(text), -1) {
+		for _, language := range lookup[word] {
+			scores[language]++
+		}
+	}
+
+	best, bestScore, secondScore := "", 0, 0
+	for language, score := range scores {
+		switch {
+		case score > bestScore:
+			best, secondScore, bestScore = language, bestScore, score
+		case score > secondScore:
+			secondScore = score
+		}
+	}
+
+	// Ties and texts with too few stop words are undetermined
+	if bestScore < minMatches || bestScore == secondScore {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,304 @@
+package ledger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// FileName is the name of the ledger database in the .budgie directory
+const FileName = "budgie.db"
+
+// lockTimeout is how long to wait for another budgie process to release the database
+const lockTimeout = 5 * time.Second
+
+// Buckets of the ledger database
+var (
+	usageBucket    = []byte("usage")
+	feedbackBucket = []byte("feedback")
+	cacheBucket    = []byte("cache")
+	questionBucket = []byte("questions")
+	resultBucket   = []byte("results")
+)
+
+// DB is the ledger database: the usage, feedback, question, cache and result file records shared by all the budgie
+// processes of a project (e.g. an editor integration and a terminal). Every write is a bbolt
+// transaction and the file is locked while open, so simultaneous processes cannot corrupt it.
+type DB struct {
+	bolt *bolt.DB
+}
+
+// Path returns the ledger database path next to the given config file
+func Path(configFile string) string {
+	return filepath.Join(filepath.Dir(configFile), FileName)
+}
+
+// Open opens (or creates) the ledger database, waiting for the other budgie processes to release it
+func Open(path string) (*DB, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: lockTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("error opening ledger %s: %w", path, err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, bucket := range [][]byte{usageBucket, feedbackBucket, cacheBucket, questionBucket, resultBucket} {
+			if _, err := tx.CreateBucketIfNotExists(bucket); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initializing ledger %s: %w", path, err)
+	}
+
+	return &DB{bolt: db}, nil
+}
+
+// With opens the ledger, runs fn and closes the ledger right away,
+// so the lock is only held for the duration of the operation
+func With(path string, fn func(db *DB) error) error {
+	db, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return fn(db)
+}
+
+// Close closes the ledger database and releases its lock
+func (db *DB) Close() error {
+	return db.bolt.Close()
+}
+
+// Usage is a completion request recorded in the usage ledger.
+// The token counts are 0 when the provider did not report them.
+type Usage struct {
+	Time             time.Time `json:"time"`
+	Command          string    `json:"command"`
+	Provider         string    `json:"provider,omitempty"`
+	Model            string    `json:"model"`
+	PromptTokens     int64     `json:"prompt_tokens"`
+	CompletionTokens int64     `json:"completion_tokens"`
+	TotalTokens      int64     `json:"total_tokens"`
+	LatencyMs        int64     `json:"latency_ms"`
+}
+
+// Feedback is a rating given by the user to an answer
+type Feedback struct {
+	Time     time.Time `json:"time"`
+	Model    string    `json:"model"`
+	Rating   string    `json:"rating"`
+	Comment  string    `json:"comment,omitempty"`
+	Question string    `json:"question"`
+	Answer   string    `json:"answer"`
+}
+
+// Question is an answered question. The embedding of the question is computed the first time
+// the history is searched for a similar question (with EmbeddingModel), Reused is set when the
+// answer of a previous question was shown instead of asking the model.
+type Question struct {
+	ID             uint64    `json:"-"`
+	Time           time.Time `json:"time"`
+	Model          string    `json:"model"`
+	Question       string    `json:"question"`
+	Answer         string    `json:"answer"`
+	Reused         bool      `json:"reused,omitempty"`
+	EmbeddingModel string    `json:"embedding-model,omitempty"`
+	Embedding      []float64 `json:"embedding,omitempty"`
+}
+
+// Result is a result file generated by budgie, by absolute path: budgie results gc only removes these
+type Result struct {
+	Time time.Time `json:"time"`
+	Path string    `json:"-"`
+}
+
+// cacheEntry is a cached value with its creation time
+type cacheEntry struct {
+	Time  time.Time `json:"time"`
+	Value string    `json:"value"`
+}
+
+// AddUsage appends a usage record to the ledger
+func (db *DB) AddUsage(usage Usage) error {
+	return db.append(usageBucket, usage)
+}
+
+// Usages returns the usage records since the given time, oldest first
+func (db *DB) Usages(since time.Time) ([]Usage, error) {
+	var usages []Usage
+	err := db.each(usageBucket, func(data []byte) error {
+		var usage Usage
+		if err := json.Unmarshal(data, &usage); err != nil {
+			return err
+		}
+		if !usage.Time.Before(since) {
+			usages = append(usages, usage)
+		}
+		return nil
+	})
+	return usages, err
+}
+
+// AddFeedback appends a feedback record to the ledger
+func (db *DB) AddFeedback(feedback Feedback) error {
+	return db.append(feedbackBucket, feedback)
+}
+
+// Feedbacks returns the feedback records since the given time, oldest first
+func (db *DB) Feedbacks(since time.Time) ([]Feedback, error) {
+	var feedbacks []Feedback
+	err := db.each(feedbackBucket, func(data []byte) error {
+		var feedback Feedback
+		if err := json.Unmarshal(data, &feedback); err != nil {
+			return err
+		}
+		if !feedback.Time.Before(since) {
+			feedbacks = append(feedbacks, feedback)
+		}
+		return nil
+	})
+	return feedbacks, err
+}
+
+// AddQuestion appends an answered question to the ledger
+func (db *DB) AddQuestion(question Question) error {
+	return db.append(questionBucket, question)
+}
+
+// Questions returns the answered questions since the given time, oldest first
+func (db *DB) Questions(since time.Time) ([]Question, error) {
+	var questions []Question
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(questionBucket).ForEach(func(key, data []byte) error {
+			var question Question
+			if err := json.Unmarshal(data, &question); err != nil {
+				return err
+			}
+			if !question.Time.Before(since) {
+				question.ID = binary.BigEndian.Uint64(key)
+				questions = append(questions, question)
+			}
+			return nil
+		})
+	})
+	return questions, err
+}
+
+// UpdateQuestion replaces a question record (e.g. to store the embedding of the question)
+func (db *DB) UpdateQuestion(question Question) error {
+	data, err := json.Marshal(question)
+	if err != nil {
+		return err
+	}
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, question.ID)
+		return tx.Bucket(questionBucket).Put(key, data)
+	})
+}
+
+// CacheGet returns the cached value of a key when it is younger than maxAge (any age when maxAge <= 0)
+func (db *DB) CacheGet(key string, maxAge time.Duration) (string, bool, error) {
+	var entry cacheEntry
+	found := false
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(cacheBucket).Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(data, &entry)
+	})
+	if err != nil || !found {
+		return "", false, err
+	}
+	if maxAge > 0 && time.Since(entry.Time) > maxAge {
+		return "", false, nil
+	}
+	return entry.Value, true, nil
+}
+
+// CachePut stores a value in the cache
+func (db *DB) CachePut(key, value string) error {
+	data, err := json.Marshal(cacheEntry{Time: time.Now(), Value: value})
+	if err != nil {
+		return err
+	}
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(cacheBucket).Put([]byte(key), data)
+	})
+}
+
+// AddResult records a generated result file
+func (db *DB) AddResult(path string) error {
+	data, err := json.Marshal(Result{Time: time.Now()})
+	if err != nil {
+		return err
+	}
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(resultBucket).Put([]byte(path), data)
+	})
+}
+
+// Results returns the recorded result files, by path
+func (db *DB) Results() ([]Result, error) {
+	var results []Result
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(resultBucket).ForEach(func(key, data []byte) error {
+			var result Result
+			if err := json.Unmarshal(data, &result); err != nil {
+				return err
+			}
+			result.Path = string(key)
+			results = append(results, result)
+			return nil
+		})
+	})
+	return results, err
+}
+
+// DeleteResults forgets result files (e.g. removed by budgie results gc)
+func (db *DB) DeleteResults(paths ...string) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		for _, path := range paths {
+			if err := tx.Bucket(resultBucket).Delete([]byte(path)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// append stores a record under the next sequence number of the bucket, keeping the records in insertion order
+func (db *DB) append(bucket []byte, record any) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		id, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, id)
+		return b.Put(key, data)
+	})
+}
+
+// each calls fn with every record of the bucket, in insertion order
+func (db *DB) each(bucket []byte, fn func(data []byte) error) error {
+	return db.bolt.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(_, data []byte) error {
+			return fn(data)
+		})
+	})
+}
@@ -0,0 +1,147 @@
+package mcpclient
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie/helpers"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/client/transport"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/openai/openai-go"
+)
+
+// clientName is the name budgie gives itself to the MCP servers
+const clientName = "budgie-cli"
+
+// Server is a connected MCP server and the tools it offers to the model
+type Server struct {
+	Name    string
+	Trusted bool
+	Tools   []mcp.Tool
+	client  *client.Client
+}
+
+// Connect starts (stdio transport) or connects to (sse and http transports) an MCP server,
+// initializes the session and lists its tools, limited to the ones of the server config when set
+func Connect(ctx context.Context, name string, server config.MCPServer, version string) (*Server, error) {
+	var mcpClient *client.Client
+	var err error
+	switch server.TransportOrDefault() {
+	case config.MCPStdio:
+		env := make([]string, 0, len(server.Env))
+		for key, value := range server.Env {
+			env = append(env, key+"="+value)
+		}
+		mcpClient, err = client.NewStdioMCPClient(server.Command, env, server.Args...)
+	case config.MCPSSE:
+		mcpClient, err = client.NewSSEMCPClient(server.URL, transport.WithHeaders(server.Headers))
+		if err == nil {
+			err = mcpClient.Start(ctx)
+		}
+	case config.MCPHTTP:
+		mcpClient, err = client.NewStreamableHttpClient(server.URL, transport.WithHTTPHeaders(server.Headers))
+		if err == nil {
+			err = mcpClient.Start(ctx)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to the MCP server %s: %w", name, err)
+	}
+
+	initRequest := mcp.InitializeRequest{}
+	initRequest.Params.ProtocolVersion = mcp.LATEST_PROTOCOL_VERSION
+	initRequest.Params.ClientInfo = mcp.Implementation{Name: clientName, Version: version}
+	if _, err := mcpClient.Initialize(ctx, initRequest); err != nil {
+		mcpClient.Close()
+		return nil, fmt.Errorf("error initializing the MCP server %s: %w", name, err)
+	}
+
+	result, err := mcpClient.ListTools(ctx, mcp.ListToolsRequest{})
+	if err != nil {
+		mcpClient.Close()
+		return nil, fmt.Errorf("error listing the tools of the MCP server %s: %w", name, err)
+	}
+	tools := result.Tools
+	if len(server.Tools) > 0 {
+		tools = slices.DeleteFunc(tools, func(tool mcp.Tool) bool {
+			return !slices.Contains(server.Tools, tool.Name)
+		})
+	}
+	sort.Slice(tools, func(i, j int) bool { return tools[i].Name < tools[j].Name })
+
+	return &Server{Name: name, Trusted: server.Trusted, Tools: tools, client: mcpClient}, nil
+}
+
+// Params returns the definitions of the server tools for the chat completion API
+func (s *Server) Params() []openai.ChatCompletionToolParam {
+	return helpers.ConvertMCPToolsToOpenAITools(&mcp.ListToolsResult{Tools: s.Tools})
+}
+
+// Has reports whether the server offers the tool
+func (s *Server) Has(name string) bool {
+	return slices.ContainsFunc(s.Tools, func(tool mcp.Tool) bool { return tool.Name == name })
+}
+
+// Call calls a tool of the server and returns the text of its result.
+// A result flagged as an error by the server is returned as an error.
+func (s *Server) Call(ctx context.Context, name string, args map[string]any) (string, error) {
+	request := mcp.CallToolRequest{}
+	request.Params.Name = name
+	request.Params.Arguments = args
+	result, err := s.client.CallTool(ctx, request)
+	if err != nil {
+		return "", fmt.Errorf("error calling %s on the MCP server %s: %w", name, s.Name, err)
+	}
+
+	var texts []string
+	for _, content := range result.Content {
+		if text, ok := mcp.AsTextContent(content); ok {
+			texts = append(texts, text.Text)
+		} else {
+			texts = append(texts, fmt.Sprintf("[%T content]", content))
+		}
+	}
+	text := strings.Join(texts, "\n")
+	if result.IsError {
+		return "", fmt.Errorf("%s failed on the MCP server %s: %s", name, s.Name, text)
+	}
+	return text, nil
+}
+
+// Close ends the session (and stops the server of the stdio transport)
+func (s *Server) Close() error {
+	return s.client.Close()
+}
+
+// ConnectAll connects to the servers of the config, sorted by name. On error, the servers already
+// connected are closed.
+func ConnectAll(ctx context.Context, servers map[string]config.MCPServer, version string) ([]*Server, error) {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var connected []*Server
+	for _, name := range names {
+		server, err := Connect(ctx, name, servers[name], version)
+		if err != nil {
+			CloseAll(connected)
+			return nil, err
+		}
+		connected = append(connected, server)
+	}
+	return connected, nil
+}
+
+// CloseAll closes the servers
+func CloseAll(servers []*Server) {
+	for _, server := range servers {
+		server.Close()
+	}
+}
@@ -0,0 +1,107 @@
+package outline
+
+import (
+	"strings"
+)
+
+// Outline is the plan of a document: its title, its brief and its sections
+type Outline struct {
+	Title string
+	// Brief is the text before the first section: the instructions shared by all the sections
+	Brief    string
+	Sections []Section
+}
+
+// Section is a heading of the outline with the notes written under it
+type Section struct {
+	Level   int
+	Heading string
+	Notes   string
+	// Path are the headings of the parent sections, outermost first
+	Path []string
+	// Parent is set when the next section is a subsection of this one
+	Parent bool
+}
+
+// Parse reads a Markdown outline: the level 1 heading is the title of the document (fallback when there
+// is none), every other heading is a section, the text under a heading describes what the section is about.
+// The headings inside code blocks are ignored.
+func Parse(content, fallback string) Outline {
+	outline := Outline{Title: fallback}
+	var brief, notes []string
+	var stack []Section
+	fence := ""
+	current := -1
+	flush := func() {
+		if current >= 0 {
+			outline.Sections[current].Notes = strings.TrimSpace(strings.Join(notes, "\n"))
+		}
+		notes = nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			fence = trimmed[:3]
+		case fence != "" && strings.HasPrefix(trimmed, fence):
+			fence = ""
+		case fence == "":
+			if level, heading := parseHeading(line); level > 0 {
+				if level == 1 && len(outline.Sections) == 0 {
+					outline.Title = heading
+					continue
+				}
+				flush()
+				for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+					stack = stack[:len(stack)-1]
+				}
+				path := make([]string, len(stack))
+				for i, parent := range stack {
+					path[i] = parent.Heading
+				}
+				if current >= 0 && level > outline.Sections[current].Level {
+					outline.Sections[current].Parent = true
+				}
+				section := Section{Level: level, Heading: heading, Path: path}
+				outline.Sections = append(outline.Sections, section)
+				stack = append(stack, section)
+				current = len(outline.Sections) - 1
+				continue
+			}
+		}
+		if current >= 0 {
+			notes = append(notes, line)
+		} else {
+			brief = append(brief, line)
+		}
+	}
+	flush()
+	outline.Brief = strings.TrimSpace(strings.Join(brief, "\n"))
+	return outline
+}
+
+// parseHeading returns the level and text of an ATX heading (0 when the line is not a heading)
+func parseHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ' && line[level] != '\t') {
+		return 0, ""
+	}
+	heading := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(line[level:]), "#"))
+	if heading == "" {
+		return 0, ""
+	}
+	return level, heading
+}
+
+// Headings returns the outline as the list of its headings, indented by level
+func (o Outline) Headings() string {
+	var builder strings.Builder
+	for _, section := range o.Sections {
+		builder.WriteString(strings.Repeat("  ", max(section.Level-2, 0)) + "- " + section.Heading + "\n")
+	}
+	return builder.String()
+}
@@ -0,0 +1,115 @@
+package pathfilter
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Filter selects files with glob patterns. A pattern without a slash matches the name of
+// any file or directory (e.g. "node_modules", "*.gen.md"), a pattern with a slash matches
+// the path relative to the scanned directory, where "**" matches any number of directories
+// (e.g. "tests/fixtures/**", "**/generated/*.md").
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// Validate checks the syntax of the patterns
+func (f Filter) Validate() error {
+	for _, pattern := range append(append([]string{}, f.Include...), f.Exclude...) {
+		for _, segment := range strings.Split(normalize(pattern), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Empty reports whether the filter selects every file
+func (f Filter) Empty() bool {
+	return len(f.Include) == 0 && len(f.Exclude) == 0
+}
+
+// Allowed reports whether a file (path relative to the scanned directory) is selected:
+// it must not be excluded (nor one of its parent directories) and, when include patterns
+// are set, it must match one of them
+func (f Filter) Allowed(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	for _, pattern := range f.Exclude {
+		if Match(pattern, relPath) || matchParent(pattern, relPath) {
+			return false
+		}
+	}
+	if len(f.Include) == 0 {
+		return true
+	}
+	for _, pattern := range f.Include {
+		if Match(pattern, relPath) || matchParent(pattern, relPath) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether a slash-separated relative path matches the pattern
+func Match(pattern, relPath string) bool {
+	pattern = normalize(pattern)
+	segments := strings.Split(relPath, "/")
+	if !strings.Contains(pattern, "/") {
+		// Name pattern: matches any segment of the path
+		for _, segment := range segments {
+			if matched, _ := path.Match(pattern, segment); matched {
+				return true
+			}
+		}
+		return false
+	}
+	return matchSegments(strings.Split(pattern, "/"), segments)
+}
+
+// matchParent reports whether one of the parent directories of the path matches the pattern
+// (e.g. "tests/fixtures" excludes "tests/fixtures/sample.md")
+func matchParent(pattern, relPath string) bool {
+	if !strings.Contains(normalize(pattern), "/") {
+		return false
+	}
+	for dir := path.Dir(relPath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if Match(pattern, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches path segments against pattern segments, "**" matching zero or more segments
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
+
+// normalize makes a pattern slash-separated, without leading "./" or "/" and trailing "/"
+func normalize(pattern string) string {
+	pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+	pattern = strings.TrimPrefix(pattern, "./")
+	pattern = strings.TrimPrefix(pattern, "/")
+	return strings.TrimSuffix(pattern, "/")
+}
@@ -0,0 +1,198 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/openai/openai-go"
+)
+
+// Version is the version of the plugin protocol, sent in each request
+const Version = 1
+
+// Kinds of plugin requests
+const (
+	// KindSlash is a slash command of interactive mode (/<name> [input])
+	KindSlash = "slash"
+	// KindCommand is a subcommand of budgie (budgie <name> [args...])
+	KindCommand = "command"
+)
+
+// nameRegex matches the valid plugin names (usable as slash commands and subcommands)
+var nameRegex = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// Plugin is an executable of the plugins directory. Its name is the name of the file without extension,
+// e.g. .budgie/plugins/jira.sh registers the /jira slash command and the `budgie jira` subcommand.
+type Plugin struct {
+	Name string
+	Path string
+}
+
+// Request is the JSON document written to the standard input of a plugin
+type Request struct {
+	Version int    `json:"version"`
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	// Input is the text typed after the slash command
+	Input string `json:"input,omitempty"`
+	// Args are the words of the input of a slash command, or the arguments of a subcommand
+	Args       []string `json:"args"`
+	ConfigFile string   `json:"config_file"`
+	Model      string   `json:"model,omitempty"`
+	// Messages is the conversation (the same messages as the saved sessions)
+	Messages   []openai.ChatCompletionMessageParamUnion `json:"messages"`
+	LastAnswer string                                   `json:"last_answer,omitempty"`
+}
+
+// Response is the JSON document written by a plugin to its standard output. A plugin printing anything
+// else than a JSON object has its whole output taken as the Output.
+type Response struct {
+	// Output is printed to the user
+	Output string `json:"output,omitempty"`
+	// Messages are appended to the conversation (slash commands)
+	Messages []openai.ChatCompletionMessageParamUnion `json:"messages,omitempty"`
+	// Question is asked right away in the conversation (slash commands)
+	Question string `json:"question,omitempty"`
+	// Error reports a failure of the plugin
+	Error string `json:"error,omitempty"`
+}
+
+// Dir returns the directory of the plugins, stored next to the config file
+func Dir(configFile string) string {
+	return filepath.Join(filepath.Dir(configFile), "plugins")
+}
+
+// List returns the plugins of the directory (its executable files), sorted by name. When two files have
+// the same name without extension, the first one in name order is kept.
+func List(dir string) ([]Plugin, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var plugins []Plugin
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if !nameRegex.MatchString(name) || names[name] {
+			continue
+		}
+		names[name] = true
+		plugins = append(plugins, Plugin{Name: name, Path: filepath.Join(dir, entry.Name())})
+	}
+	sort.Slice(plugins, func(i, j int) bool { return plugins[i].Name < plugins[j].Name })
+	return plugins, nil
+}
+
+// Load returns the plugins of the user (the plugins directory next to the global config) and the ones of the
+// workspace (next to configFile), a workspace plugin winning over the user plugin of the same name. The
+// plugins of a workspace run as soon as the repository is cloned: they are only loaded when their directory
+// is trusted in the global config (see config.TrustedPluginDirs), untrusted is their directory otherwise.
+func Load(configFile string) (available []Plugin, untrusted string, err error) {
+	var user []Plugin
+	userDir := ""
+	if global := config.GlobalPath(); global != "" {
+		userDir = Dir(global)
+		if user, err = List(userDir); err != nil {
+			return nil, "", err
+		}
+	}
+
+	workspaceDir := Dir(configFile)
+	if userDir != "" && sameDir(workspaceDir, userDir) {
+		return user, "", nil
+	}
+	workspace, err := List(workspaceDir)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(workspace) > 0 {
+		trusted, err := config.TrustedPluginDirs()
+		if err != nil {
+			return nil, "", err
+		}
+		if !slices.ContainsFunc(trusted, func(dir string) bool { return sameDir(dir, workspaceDir) }) {
+			untrusted, workspace = workspaceDir, nil
+		}
+	}
+
+	available = workspace
+	for _, plugin := range user {
+		if _, found := Find(workspace, plugin.Name); !found {
+			available = append(available, plugin)
+		}
+	}
+	sort.Slice(available, func(i, j int) bool { return available[i].Name < available[j].Name })
+	return available, untrusted, nil
+}
+
+// sameDir reports whether two paths are the same existing directory
+func sameDir(dir1, dir2 string) bool {
+	info1, err := os.Stat(dir1)
+	if err != nil {
+		return false
+	}
+	info2, err := os.Stat(dir2)
+	return err == nil && os.SameFile(info1, info2)
+}
+
+// Find returns the plugin of a name
+func Find(plugins []Plugin, name string) (Plugin, bool) {
+	for _, plugin := range plugins {
+		if plugin.Name == name {
+			return plugin, true
+		}
+	}
+	return Plugin{}, false
+}
+
+// Run runs the plugin with the request on its standard input and returns its response. The standard error
+// of the plugin is passed through to budgie's. A plugin exiting with a non-zero status fails.
+func (p Plugin) Run(ctx context.Context, request Request) (*Response, error) {
+	request.Version, request.Name = Version, p.Name
+	if request.Args == nil {
+		request.Args = []string{}
+	}
+	if request.Messages == nil {
+		request.Messages = []openai.ChatCompletionMessageParamUnion{}
+	}
+	input, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding the plugin request: %w", err)
+	}
+
+	command := exec.CommandContext(ctx, p.Path)
+	command.Stdin = bytes.NewReader(input)
+	command.Stderr = os.Stderr
+	output, err := command.Output()
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s failed: %w", p.Name, err)
+	}
+
+	var response Response
+	trimmed := bytes.TrimSpace(output)
+	if !bytes.HasPrefix(trimmed, []byte("{")) || json.Unmarshal(trimmed, &response) != nil {
+		return &Response{Output: strings.TrimRight(string(output), "\n")}, nil
+	}
+	if response.Error != "" {
+		return nil, fmt.Errorf("plugin %s failed: %s", p.Name, response.Error)
+	}
+	return &response, nil
+}
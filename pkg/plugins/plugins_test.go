@@ -0,0 +1,138 @@
+package plugins
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+)
+
+// writePlugin writes a plugin script (executable or not)
+func writePlugin(t *testing.T, dir, name string, executable bool) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mode := os.FileMode(0644)
+	if executable {
+		mode = 0755
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho "+name+"\n"), mode); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// names returns the name and the directory of each plugin
+func names(plugins []Plugin) []string {
+	var result []string
+	for _, plugin := range plugins {
+		result = append(result, plugin.Name+"@"+filepath.Base(filepath.Dir(filepath.Dir(plugin.Path))))
+	}
+	return result
+}
+
+func TestList(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "plugins")
+	writePlugin(t, dir, "jira.sh", true)
+	writePlugin(t, dir, "jira.py", true)
+	writePlugin(t, dir, "deploy", true)
+	writePlugin(t, dir, "notes.txt", false)
+	writePlugin(t, dir, "-bad.sh", true)
+	if err := os.Mkdir(filepath.Join(dir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	plugins, err := List(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, plugin := range plugins {
+		got = append(got, plugin.Name+":"+filepath.Base(plugin.Path))
+	}
+	// jira.py comes first in name order
+	if want := "[deploy:deploy jira:jira.py]"; fmt.Sprint(got) != want {
+		t.Errorf("List = %v, want %s", got, want)
+	}
+
+	if plugins, err := List(filepath.Join(dir, "missing")); err != nil || plugins != nil {
+		t.Errorf("List of a missing directory = %v, %v", plugins, err)
+	}
+}
+
+// The plugins of a workspace are only loaded once their directory is trusted in the global config
+func TestLoad(t *testing.T) {
+	base := t.TempDir()
+	global := filepath.Join(base, "user", "budgie.config.json")
+	t.Setenv(config.EnvGlobalConfig, global)
+	writePlugin(t, filepath.Join(base, "user", "plugins"), "jira.sh", true)
+	writePlugin(t, filepath.Join(base, "user", "plugins"), "standup.sh", true)
+	workspaceDir := filepath.Join(base, "project", ".budgie", "plugins")
+	writePlugin(t, workspaceDir, "jira.sh", true)
+	writePlugin(t, workspaceDir, "deploy.sh", true)
+	configFile := filepath.Join(base, "project", ".budgie", "budgie.config.json")
+
+	writeGlobal := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(global, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		global    string
+		want      string
+		untrusted bool
+		invalid   bool
+	}{
+		{"no global config", "", "[jira@user standup@user]", true, false},
+		{"workspace not trusted", `{"trusted-plugins": ["/elsewhere/.budgie/plugins"]}`, "[jira@user standup@user]", true, false},
+		{"workspace trusted", fmt.Sprintf(`{"trusted-plugins": [%q]}`, workspaceDir), "[deploy@.budgie jira@.budgie standup@user]", false, false},
+		{"trusted path not clean", fmt.Sprintf(`{"trusted-plugins": [%q]}`, workspaceDir+"/"), "[deploy@.budgie jira@.budgie standup@user]", false, false},
+		{"relative trusted path", `{"trusted-plugins": [".budgie/plugins"]}`, "", false, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			os.Remove(global)
+			if test.global != "" {
+				writeGlobal(test.global)
+			}
+			available, untrusted, err := Load(configFile)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("Load = %v, want an error", names(available))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(names(available)) != test.want {
+				t.Errorf("Load = %v, want %s", names(available), test.want)
+			}
+			if (untrusted != "") != test.untrusted || (test.untrusted && untrusted != workspaceDir) {
+				t.Errorf("untrusted = %q, want untrusted: %v", untrusted, test.untrusted)
+			}
+		})
+	}
+
+	// A project config cannot trust its own plugins
+	if err := os.WriteFile(configFile, []byte(fmt.Sprintf(`{"trusted-plugins": [%q]}`, workspaceDir)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(global)
+	if _, untrusted, err := Load(configFile); err != nil || untrusted != workspaceDir {
+		t.Errorf("Load with the trust in the project config = %q, %v, want the workspace untrusted", untrusted, err)
+	}
+
+	// The global config used as the project config loads the user plugins once
+	available, untrusted, err := Load(global)
+	if err != nil || untrusted != "" || fmt.Sprint(names(available)) != "[jira@user standup@user]" {
+		t.Errorf("Load of the global config = %v, %q, %v", names(available), untrusted, err)
+	}
+}
@@ -0,0 +1,95 @@
+package prompts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// InputVariable is the variable filled with the content piped to budgie
+const InputVariable = "input"
+
+// variableRegex matches the {{variable}} placeholders of a template
+var variableRegex = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_-]+)\s*\}\}`)
+
+// Dir returns the directory of the prompt templates, stored next to the config file
+func Dir(configFile string) string {
+	return filepath.Join(filepath.Dir(configFile), "prompts")
+}
+
+// Load reads the template of a name (the Markdown file <name>.md of the directory)
+func Load(dir, name string) (string, error) {
+	path := filepath.Join(dir, strings.TrimSuffix(name, ".md")+".md")
+	content, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		names, _ := List(dir)
+		if len(names) == 0 {
+			return "", fmt.Errorf("prompt template %s not found (no templates in %s)", name, dir)
+		}
+		return "", fmt.Errorf("prompt template %s not found in %s (available: %s)", name, dir, strings.Join(names, ", "))
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading prompt template %s: %w", path, err)
+	}
+	return string(content), nil
+}
+
+// List returns the names of the templates of the directory, sorted
+func List(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && filepath.Ext(entry.Name()) == ".md" {
+			names = append(names, strings.TrimSuffix(entry.Name(), ".md"))
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Variables returns the variables of a template, in order of appearance
+func Variables(template string) []string {
+	var variables []string
+	seen := make(map[string]bool)
+	for _, match := range variableRegex.FindAllStringSubmatch(template, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			variables = append(variables, match[1])
+		}
+	}
+	return variables
+}
+
+// Fill replaces the variables of a template with their values. The variables without a value are left as is.
+func Fill(template string, values map[string]string) string {
+	return variableRegex.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := variableRegex.FindStringSubmatch(placeholder)[1]
+		if value, ok := values[name]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// ParseVars parses the key=value values of the --var flags
+func ParseVars(vars []string) (map[string]string, error) {
+	values := make(map[string]string, len(vars))
+	for _, v := range vars {
+		key, value, found := strings.Cut(v, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid --var %q (expected key=value)", v)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,50 @@
+package provider
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// keychainService is the service of the API keys stored in the OS keychain, by provider name
+const keychainService = "budgie-cli"
+
+// OriginKeychain is the origin of the API keys read from the OS keychain
+const OriginKeychain = "keychain"
+
+// ErrNoStoredKey is returned when no API key of a provider is stored in the OS keychain
+var ErrNoStoredKey = errors.New("no API key stored in the keychain")
+
+// StoreAPIKey stores the API key of a provider in the OS keychain (Keychain on macOS, Credential Manager
+// on Windows, Secret Service on Linux)
+func StoreAPIKey(name, apiKey string) error {
+	if err := keyring.Set(keychainService, name, apiKey); err != nil {
+		return fmt.Errorf("error storing the API key in the OS keychain: %w", err)
+	}
+	return nil
+}
+
+// StoredAPIKey returns the API key of a provider stored in the OS keychain (ErrNoStoredKey when there is none)
+func StoredAPIKey(name string) (string, error) {
+	apiKey, err := keyring.Get(keychainService, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNoStoredKey
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading the OS keychain: %w", err)
+	}
+	return apiKey, nil
+}
+
+// DeleteAPIKey removes the API key of a provider from the OS keychain (ErrNoStoredKey when there is none)
+func DeleteAPIKey(name string) error {
+	err := keyring.Delete(keychainService, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrNoStoredKey
+	}
+	if err != nil {
+		return fmt.Errorf("error removing the API key from the OS keychain: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package provider
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie/agents"
+	"github.com/budgies-nest/budgie/enums/base"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+// Supported providers
+const (
+	DMR       = "dmr"
+	OpenAI    = "openai"
+	Anthropic = "anthropic"
+	Ollama    = "ollama"
+	Azure     = "azure"
+)
+
+// Provider describes how to reach an OpenAI-compatible chat/embeddings endpoint
+type Provider struct {
+	Name           string
+	DefaultBaseURL string
+	APIKeyEnv      string
+}
+
+var providers = map[string]Provider{
+	DMR:       {Name: DMR, DefaultBaseURL: base.DockerModelRunnerLocalURL},
+	OpenAI:    {Name: OpenAI, DefaultBaseURL: base.OpenAIURL, APIKeyEnv: "OPENAI_API_KEY"},
+	Anthropic: {Name: Anthropic, DefaultBaseURL: "https://api.anthropic.com/v1/", APIKeyEnv: "ANTHROPIC_API_KEY"},
+	Ollama:    {Name: Ollama, DefaultBaseURL: "http://localhost:11434/v1"},
+	Azure:     {Name: Azure, APIKeyEnv: "AZURE_OPENAI_API_KEY"},
+}
+
+// Get returns the provider registered under the given name ("dmr" when empty)
+func Get(name string) (Provider, error) {
+	if name == "" {
+		name = DMR
+	}
+	p, ok := providers[name]
+	if !ok {
+		return Provider{}, fmt.Errorf("unknown provider %q (supported: dmr, openai, anthropic, ollama, azure)", name)
+	}
+	return p, nil
+}
+
+// BaseURL returns the configured base URL, falling back to the provider default
+func BaseURL(config *config.Config) (string, error) {
+	p, err := Get(config.Provider)
+	if err != nil {
+		return "", err
+	}
+	if config.BaseURL != "" {
+		return config.BaseURL, nil
+	}
+	if p.DefaultBaseURL == "" {
+		return "", fmt.Errorf("baseURL is required for the %s provider", p.Name)
+	}
+	return p.DefaultBaseURL, nil
+}
+
+// APIKey returns the API key of the configured provider: the value of its environment variable when it is
+// set (e.g. in CI), otherwise the key stored in the OS keychain by budgie auth login.
+// The variable name can be overridden with the "api-key-env" config field.
+func APIKey(config *config.Config) (string, error) {
+	apiKey, _, err := LookupAPIKey(config)
+	return apiKey, err
+}
+
+// APIKeyEnv returns the environment variable of the API key of the configured provider ("" when the
+// provider does not use an API key)
+func APIKeyEnv(config *config.Config) (string, error) {
+	p, err := Get(config.Provider)
+	if err != nil {
+		return "", err
+	}
+	if config.APIKeyEnv != "" {
+		return config.APIKeyEnv, nil
+	}
+	return p.APIKeyEnv, nil
+}
+
+// LookupAPIKey returns the API key of the configured provider (see APIKey) and its origin: the name of the
+// environment variable, or OriginKeychain. Both are empty when the provider does not use an API key.
+func LookupAPIKey(config *config.Config) (string, string, error) {
+	p, err := Get(config.Provider)
+	if err != nil {
+		return "", "", err
+	}
+	envVar, _ := APIKeyEnv(config)
+	if envVar == "" {
+		return "", "", nil
+	}
+
+	if apiKey := os.Getenv(envVar); apiKey != "" {
+		return apiKey, envVar, nil
+	}
+	if apiKey, err := StoredAPIKey(p.Name); err == nil && apiKey != "" {
+		return apiKey, OriginKeychain, nil
+	}
+	return "", "", fmt.Errorf("the %s provider requires an API key: run budgie auth login %s, or set the %s environment variable", p.Name, p.Name, envVar)
+}
+
+// Names returns the names of the supported providers, sorted
+func Names() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ClientOption returns the agent option connecting the agent to the configured provider
+func ClientOption(config *config.Config) (agents.AgentOption, error) {
+	baseURL, err := BaseURL(config)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := APIKey(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if apiKey == "" {
+		return agents.WithDMR(baseURL), nil
+	}
+	return agents.WithOpenAIURL(baseURL, apiKey), nil
+}
+
+// NewClient returns an OpenAI-compatible client connected to the configured provider,
+// for the requests the agents do not expose (e.g. token usage)
+func NewClient(config *config.Config) (openai.Client, error) {
+	baseURL, err := BaseURL(config)
+	if err != nil {
+		return openai.Client{}, err
+	}
+
+	apiKey, err := APIKey(config)
+	if err != nil {
+		return openai.Client{}, err
+	}
+
+	return openai.NewClient(
+		option.WithBaseURL(baseURL),
+		option.WithAPIKey(apiKey),
+	), nil
+}
@@ -0,0 +1,255 @@
+package rag
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	budgierag "github.com/budgies-nest/budgie/rag"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ArchiveFormat is the version of the store archives written by Archive.Write
+const ArchiveFormat = 1
+
+// The files of a store archive
+const (
+	archiveInfoFile       = "budgie-store.json"
+	archiveEmbeddingsFile = "embeddings.json"
+	archiveManifestFile   = "embeddings.hashes.json"
+)
+
+// ArchiveInfo describes the store of an archive: how it was generated, so the importing project can
+// search it with the same embedding model
+type ArchiveInfo struct {
+	Format         int       `json:"format"`
+	BudgieVersion  string    `json:"budgie-version,omitempty"`
+	Created        time.Time `json:"created"`
+	EmbeddingModel string    `json:"embedding-model"`
+	Dimension      int       `json:"dimension,omitempty"`
+	Chunks         int       `json:"chunks"`
+	Files          int       `json:"files"`
+	// Chunking are the chunking strategies of the files (e.g. "markdown sections, max 1000 chars")
+	Chunking []string `json:"chunking,omitempty"`
+}
+
+// Archive is the content of a store archive: its records (in the format of the json backend) and manifest
+type Archive struct {
+	Info     ArchiveInfo
+	Records  map[string]budgierag.VectorRecord
+	Manifest *Manifest
+}
+
+// NewArchive collects the records of a store along with its manifest
+func NewArchive(store Store, manifest *Manifest, budgieVersion string) (*Archive, error) {
+	archive := &Archive{
+		Info: ArchiveInfo{
+			Format:         ArchiveFormat,
+			BudgieVersion:  budgieVersion,
+			Created:        time.Now().UTC().Truncate(time.Second),
+			EmbeddingModel: manifest.EmbeddingModel,
+			Dimension:      manifest.Dimension,
+			Files:          len(manifest.Files),
+		},
+		Records:  make(map[string]budgierag.VectorRecord),
+		Manifest: manifest,
+	}
+	err := store.ForEach(func(record budgierag.VectorRecord) error {
+		archive.Records[record.Id] = record
+		if archive.Info.Dimension == 0 {
+			archive.Info.Dimension = len(record.Embedding)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading vector store: %w", err)
+	}
+	archive.Info.Chunks = len(archive.Records)
+
+	strategies := make(map[string]bool)
+	for _, entry := range manifest.Files {
+		if entry.Chunking != "" {
+			strategies[entry.Chunking] = true
+		}
+	}
+	for strategy := range strategies {
+		archive.Info.Chunking = append(archive.Info.Chunking, strategy)
+	}
+	sort.Strings(archive.Info.Chunking)
+	return archive, nil
+}
+
+// Write writes the archive to a tar file, compressed after its extension: .tar.zst (or .tzst) with zstd,
+// .tar.gz (or .tgz) with gzip, .tar uncompressed
+func (a *Archive) Write(path string) error {
+	if archiveCompression(path) == "" {
+		return fmt.Errorf("unsupported archive %s (.tar.zst, .tar.gz or .tar)", filepath.Base(path))
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", path, err)
+	}
+	defer file.Close()
+
+	compressed, err := compressor(path, file)
+	if err != nil {
+		return err
+	}
+	writer := tar.NewWriter(compressed)
+	manifest := *a.Manifest
+	// The importing project records its own backend
+	manifest.VectorStore = ""
+	entries := []struct {
+		name    string
+		content any
+	}{
+		{archiveInfoFile, a.Info},
+		{archiveEmbeddingsFile, budgierag.MemoryVectorStore{Records: a.Records}},
+		{archiveManifestFile, manifest},
+	}
+	for _, entry := range entries {
+		data, err := json.Marshal(entry.content)
+		if err != nil {
+			return err
+		}
+		header := &tar.Header{Name: entry.name, Mode: 0644, Size: int64(len(data)), ModTime: a.Info.Created}
+		if err := writer.WriteHeader(header); err != nil {
+			return fmt.Errorf("error writing %s: %w", path, err)
+		}
+		if _, err := writer.Write(data); err != nil {
+			return fmt.Errorf("error writing %s: %w", path, err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	if err := compressed.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return file.Close()
+}
+
+// ReadArchive reads a store archive written by Archive.Write
+func ReadArchive(path string) (*Archive, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	defer file.Close()
+
+	decompressed, err := decompressor(path, file)
+	if err != nil {
+		return nil, err
+	}
+	defer decompressed.Close()
+
+	archive := &Archive{Manifest: &Manifest{}}
+	records := &budgierag.MemoryVectorStore{}
+	found := make(map[string]bool)
+	reader := tar.NewReader(decompressed)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		var target any
+		switch header.Name {
+		case archiveInfoFile:
+			target = &archive.Info
+		case archiveEmbeddingsFile:
+			target = records
+		case archiveManifestFile:
+			target = archive.Manifest
+		default:
+			continue
+		}
+		if err := json.NewDecoder(reader).Decode(target); err != nil {
+			return nil, fmt.Errorf("error reading %s of %s: %w", header.Name, path, err)
+		}
+		found[header.Name] = true
+	}
+
+	for _, name := range []string{archiveInfoFile, archiveEmbeddingsFile, archiveManifestFile} {
+		if !found[name] {
+			return nil, fmt.Errorf("%s is not a budgie store archive (no %s)", path, name)
+		}
+	}
+	if archive.Info.Format > ArchiveFormat {
+		return nil, fmt.Errorf("%s has the format %d of a newer budgie (supported: %d)", path, archive.Info.Format, ArchiveFormat)
+	}
+	archive.Records = records.Records
+	if archive.Records == nil {
+		archive.Records = make(map[string]budgierag.VectorRecord)
+	}
+	if archive.Manifest.Files == nil {
+		archive.Manifest.Files = make(map[string]FileEntry)
+	}
+	return archive, nil
+}
+
+// compressor returns the compressing writer of an archive path
+func compressor(path string, w io.Writer) (io.WriteCloser, error) {
+	switch archiveCompression(path) {
+	case "zstd":
+		return zstd.NewWriter(w)
+	case "gzip":
+		return gzip.NewWriter(w), nil
+	case "tar":
+		return nopWriteCloser{w}, nil
+	}
+	return nil, fmt.Errorf("unsupported archive %s (.tar.zst, .tar.gz or .tar)", filepath.Base(path))
+}
+
+// decompressor returns the decompressing reader of an archive path
+func decompressor(path string, r io.Reader) (io.ReadCloser, error) {
+	switch archiveCompression(path) {
+	case "zstd":
+		decoder, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		return decoder.IOReadCloser(), nil
+	case "gzip":
+		reader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", path, err)
+		}
+		return reader, nil
+	case "tar":
+		return io.NopCloser(r), nil
+	}
+	return nil, fmt.Errorf("unsupported archive %s (.tar.zst, .tar.gz or .tar)", filepath.Base(path))
+}
+
+// archiveCompression returns the compression of an archive path after its extension
+func archiveCompression(path string) string {
+	name := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(name, ".tar.zst") || strings.HasSuffix(name, ".tzst"):
+		return "zstd"
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		return "gzip"
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// nopWriteCloser is a writer without compression
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
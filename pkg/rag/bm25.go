@@ -0,0 +1,138 @@
+package rag
+
+import (
+	"encoding/json"
+	"math"
+	"os"
+	"strings"
+
+	budgierag "github.com/budgies-nest/budgie/rag"
+)
+
+// BM25 parameters: term frequency saturation and length normalization
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// KeywordIndex is the BM25 index of the chunks of a vector store. It is updated when the store is
+// persisted and saved alongside the embeddings file, so the exact identifiers (function names,
+// flags...) missed by the cosine similarity can be found by the hybrid search.
+type KeywordIndex struct {
+	Chunks map[string]IndexedChunk `json:"chunks"`
+
+	frequencies map[string]int
+	avgLength   float64
+}
+
+// IndexedChunk is a chunk of the keyword index: its number of terms and the count of each term
+type IndexedChunk struct {
+	Length int            `json:"length"`
+	Terms  map[string]int `json:"terms"`
+}
+
+// KeywordIndexPath returns the keyword index path of an embeddings file (embeddings.json -> embeddings.keywords.json)
+func KeywordIndexPath(storePath string) string {
+	return strings.TrimSuffix(storePath, ".json") + ".keywords.json"
+}
+
+// BuildKeywordIndex indexes the records of a vector store
+func BuildKeywordIndex(store Store) (*KeywordIndex, error) {
+	index := &KeywordIndex{Chunks: make(map[string]IndexedChunk)}
+	err := store.ForEach(func(record budgierag.VectorRecord) error {
+		index.Chunks[record.Id] = indexChunk(record.Prompt)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	index.prepare()
+	return index, nil
+}
+
+// indexChunk counts the terms of the prompt of a chunk
+func indexChunk(prompt string) IndexedChunk {
+	chunk := IndexedChunk{Terms: make(map[string]int)}
+	for _, token := range keywordTokens(prompt) {
+		chunk.Terms[token]++
+		chunk.Length++
+	}
+	return chunk
+}
+
+// LoadKeywordIndex reads a keyword index file, it returns nil when the file does not exist
+func LoadKeywordIndex(path string) (*KeywordIndex, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var index KeywordIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	index.prepare()
+	return &index, nil
+}
+
+// Save writes the keyword index file
+func (idx *KeywordIndex) Save(path string) error {
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Scores returns the BM25 score of the chunks containing at least one term of the question, by chunk id
+func (idx *KeywordIndex) Scores(question string) map[string]float64 {
+	scores := make(map[string]float64)
+	total := float64(len(idx.Chunks))
+	for _, term := range Terms(question) {
+		frequency, ok := idx.frequencies[term]
+		if !ok {
+			continue
+		}
+		idf := math.Log(1 + (total-float64(frequency)+0.5)/(float64(frequency)+0.5))
+		for id, chunk := range idx.Chunks {
+			count := float64(chunk.Terms[term])
+			if count == 0 {
+				continue
+			}
+			norm := 1 - bm25B + bm25B*float64(chunk.Length)/idx.avgLength
+			scores[id] += idf * count * (bm25K1 + 1) / (count + bm25K1*norm)
+		}
+	}
+	return scores
+}
+
+// prepare computes the document frequency of each term and the average chunk length
+func (idx *KeywordIndex) prepare() {
+	idx.frequencies = make(map[string]int)
+	totalLength := 0
+	for _, chunk := range idx.Chunks {
+		totalLength += chunk.Length
+		for term := range chunk.Terms {
+			idx.frequencies[term]++
+		}
+	}
+	idx.avgLength = 1
+	if len(idx.Chunks) > 0 && totalLength > 0 {
+		idx.avgLength = float64(totalLength) / float64(len(idx.Chunks))
+	}
+}
+
+// keywordTokens returns the significant lowercase words of a text, repeated words included
+func keywordTokens(text string) []string {
+	var tokens []string
+	for _, word := range termRegex.FindAllString(strings.ToLower(text), -1) {
+		if len([]rune(word)) < 3 || stopWords[word] {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
@@ -0,0 +1,166 @@
+package rag
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie/agents"
+	budgierag "github.com/budgies-nest/budgie/rag"
+)
+
+// newKeywordIndex indexes prompts by id
+func newKeywordIndex(prompts map[string]string) *KeywordIndex {
+	index := &KeywordIndex{Chunks: make(map[string]IndexedChunk)}
+	for id, prompt := range prompts {
+		index.Chunks[id] = indexChunk(prompt)
+	}
+	index.prepare()
+	return index
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"How does the ListenForCancel function work?", []string{"listenforcancel", "function", "work"}},
+		{"Go go GO vet", []string{"vet"}},
+		{"max_tokens and max_tokens", []string{"max_tokens"}},
+		{"café crème brûlée", []string{"café", "crème", "brûlée"}},
+		{"the and of", nil},
+	}
+	for _, test := range tests {
+		if terms := Terms(test.text); fmt.Sprint(terms) != fmt.Sprint(test.want) {
+			t.Errorf("Terms(%q) = %v, want %v", test.text, terms, test.want)
+		}
+	}
+}
+
+func TestIndexChunk(t *testing.T) {
+	chunk := indexChunk("Retry the request, retry again: the retry count is 3")
+	if chunk.Terms["retry"] != 3 || chunk.Terms["request"] != 1 {
+		t.Errorf("terms = %v, want the repeated words counted", chunk.Terms)
+	}
+	// retry x3, request, again, count (the stop words and the short words are not counted)
+	if chunk.Length != 6 {
+		t.Errorf("length = %d, want 6", chunk.Length)
+	}
+}
+
+func TestKeywordIndexScores(t *testing.T) {
+	index := newKeywordIndex(map[string]string{
+		"rare":     "configure the sweepTemperatures option",
+		"common":   "configure the model and configure the provider",
+		"repeated": "timeout timeout timeout timeout explained",
+		"once":     "timeout explained here",
+		"long":     "timeout explained here with many more unrelated words padding this chunk quite a lot",
+		"none":     "nothing relevant",
+	})
+
+	tests := []struct {
+		name     string
+		question string
+		higher   string
+		lower    string
+	}{
+		{"a rare term weighs more than a common one", "configure sweeptemperatures", "rare", "common"},
+		{"the term frequency raises the score", "timeout", "repeated", "once"},
+		{"the shorter chunk wins at equal frequency", "timeout", "once", "long"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			scores := index.Scores(test.question)
+			if scores[test.higher] <= scores[test.lower] {
+				t.Errorf("score of %s = %.4f, want more than %s = %.4f", test.higher, scores[test.higher], test.lower, scores[test.lower])
+			}
+			if _, found := scores["none"]; found {
+				t.Error("a chunk without any term of the question is scored")
+			}
+		})
+	}
+
+	// The term frequency saturates (k1): 4 occurrences score less than 4 times one occurrence
+	scores := index.Scores("timeout")
+	if scores["repeated"] >= 4*scores["once"] {
+		t.Errorf("score of 4 occurrences = %.4f, want less than 4 x %.4f", scores["repeated"], scores["once"])
+	}
+
+	if scores := index.Scores("unknown absent"); len(scores) != 0 {
+		t.Errorf("scores = %v, want none for unknown terms", scores)
+	}
+}
+
+func TestTopK(t *testing.T) {
+	similarities := func() []Similarity {
+		return []Similarity{{ID: "a", Score: 0.2}, {ID: "b", Score: 0.9}, {ID: "c", Score: 0.5}, {ID: "d", Score: 0.5}}
+	}
+	tests := []struct {
+		k    int
+		want []string
+	}{
+		{0, []string{"b", "c", "d", "a"}},
+		{-1, []string{"b", "c", "d", "a"}},
+		{2, []string{"b", "c"}},
+		{3, []string{"b", "c", "d"}},
+		{10, []string{"b", "c", "d", "a"}},
+	}
+	for _, test := range tests {
+		if ids := IDs(TopK(similarities(), test.k)); fmt.Sprint(ids) != fmt.Sprint(test.want) {
+			t.Errorf("TopK(%d) = %v, want %v (stable for equal scores)", test.k, ids, test.want)
+		}
+	}
+}
+
+// The hybrid search re-scores the vector results and adds the strong keyword matches missed by the vector search
+func TestHybridScores(t *testing.T) {
+	store, err := OpenStore(&config.Config{}, filepath.Join(t.TempDir(), "embeddings.json"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []budgierag.VectorRecord{
+		{Id: "vector", Prompt: "how to configure the retries", Embedding: []float64{1, 0}},
+		{Id: "keyword", Prompt: "the ListenForCancel helper stops the stream", Embedding: []float64{0, 1}},
+		{Id: "weak", Prompt: "the stream is printed", Embedding: []float64{0, 1}},
+	}
+	for _, record := range records {
+		if _, err := store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Persist(); err != nil {
+		t.Fatal(err)
+	}
+	agent := &agents.Agent{Store: store}
+
+	question := "listenforcancel stream"
+	vector := []Similarity{{ID: "vector", Content: records[0].Prompt, Score: 0.8}}
+	merged, err := hybridScores(question, []float64{1, 0}, agent, vector, 0.5, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scores := make(map[string]float64)
+	for _, similarity := range merged {
+		scores[similarity.ID] = similarity.Score
+	}
+
+	if scores["vector"] != 0.4 {
+		t.Errorf("score of the vector result without keyword = %.4f, want (1 - 0.5) x 0.8", scores["vector"])
+	}
+	// The best keyword match gets the full keyword weight, and its cosine similarity is 0
+	if scores["keyword"] != 0.5 {
+		t.Errorf("score of the keyword match = %.4f, want 0.5", scores["keyword"])
+	}
+	if _, found := scores["weak"]; found {
+		t.Error("a keyword match below half the best BM25 score was added")
+	}
+
+	filtered, err := hybridScores(question, []float64{1, 0}, agent, vector, 0.5, func(id string) bool { return id != "keyword" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := IDs(filtered); fmt.Sprint(ids) != "[vector]" {
+		t.Errorf("filtered results = %v, want the metadata filter applied to the keyword matches", ids)
+	}
+}
@@ -0,0 +1,289 @@
+package rag
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	budgierag "github.com/budgies-nest/budgie/rag"
+	"github.com/google/uuid"
+	bolt "go.etcd.io/bbolt"
+)
+
+// recordsBucket is the bbolt bucket holding the vector records, by id
+var recordsBucket = []byte("records")
+
+// boltLockTimeout is how long to wait for another budgie process to release the database
+const boltLockTimeout = 5 * time.Second
+
+// boltBatchSize is the number of saved records written together in a transaction (each commit is an fsync)
+const boltBatchSize = 1000
+
+// boltStore is a vector store persisted in a bbolt database: the searches read the records from the disk
+// instead of loading the whole store in memory, so it scales to large corpora, and the changes are written
+// in batches instead of rewriting the whole file. The database is opened on first use and closed
+// by Persist and Close, so its lock is only held while the store is used.
+type boltStore struct {
+	path     string
+	readOnly bool
+	db       *bolt.DB
+	// pending are the saved records not written yet, they are written by the next transaction
+	pending []budgierag.VectorRecord
+}
+
+// open opens the database on first use
+func (s *boltStore) open() (*bolt.DB, error) {
+	if s.db != nil {
+		return s.db, nil
+	}
+
+	db, err := bolt.Open(s.path, 0644, &bolt.Options{Timeout: boltLockTimeout, ReadOnly: s.readOnly})
+	if err != nil {
+		return nil, fmt.Errorf("error opening vector store %s: %w", s.path, err)
+	}
+	if !s.readOnly {
+		err = db.Update(func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucketIfNotExists(recordsBucket)
+			return err
+		})
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("error initializing vector store %s: %w", s.path, err)
+		}
+	}
+	s.db = db
+	return db, nil
+}
+
+// view runs fn with the records bucket (nil when the store is empty) in a read transaction,
+// once the pending records are written
+func (s *boltStore) view(fn func(bucket *bolt.Bucket) error) error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	return db.View(func(tx *bolt.Tx) error {
+		return fn(tx.Bucket(recordsBucket))
+	})
+}
+
+// update runs fn with the records bucket in a write transaction, after writing the pending records
+func (s *boltStore) update(fn func(bucket *bolt.Bucket) error) error {
+	if s.readOnly {
+		return fmt.Errorf("vector store %s is opened read-only", s.path)
+	}
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(recordsBucket)
+		for _, record := range s.pending {
+			if err := bucket.Put([]byte(record.Id), encodeRecord(record)); err != nil {
+				return err
+			}
+		}
+		return fn(bucket)
+	})
+	if err == nil {
+		s.pending = nil
+	}
+	return err
+}
+
+// flush writes the pending records
+func (s *boltStore) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	return s.update(func(*bolt.Bucket) error { return nil })
+}
+
+func (s *boltStore) ForEach(fn func(record budgierag.VectorRecord) error) error {
+	return s.view(func(bucket *bolt.Bucket) error {
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, value []byte) error {
+			record, err := decodeRecord(key, value)
+			if err != nil {
+				return err
+			}
+			return fn(record)
+		})
+	})
+}
+
+func (s *boltStore) GetAll() ([]budgierag.VectorRecord, error) {
+	var records []budgierag.VectorRecord
+	err := s.ForEach(func(record budgierag.VectorRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+func (s *boltStore) Get(id string) (budgierag.VectorRecord, bool, error) {
+	var record budgierag.VectorRecord
+	found := false
+	err := s.view(func(bucket *bolt.Bucket) error {
+		if bucket == nil {
+			return nil
+		}
+		value := bucket.Get([]byte(id))
+		if value == nil {
+			return nil
+		}
+		var err error
+		record, err = decodeRecord([]byte(id), value)
+		found = err == nil
+		return err
+	})
+	return record, found, err
+}
+
+func (s *boltStore) Save(record budgierag.VectorRecord) (budgierag.VectorRecord, error) {
+	if record.Id == "" {
+		record.Id = uuid.New().String()
+	}
+	if s.readOnly {
+		return record, fmt.Errorf("vector store %s is opened read-only", s.path)
+	}
+	s.pending = append(s.pending, record)
+	if len(s.pending) < boltBatchSize {
+		return record, nil
+	}
+	return record, s.flush()
+}
+
+func (s *boltStore) Delete(ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.update(func(bucket *bolt.Bucket) error {
+		for _, id := range ids {
+			if err := bucket.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *boltStore) Reset() error {
+	if s.readOnly {
+		return fmt.Errorf("vector store %s is opened read-only", s.path)
+	}
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	// The pending records are removed with the others
+	s.pending = nil
+	return db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(recordsBucket); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+			return err
+		}
+		_, err := tx.CreateBucket(recordsBucket)
+		return err
+	})
+}
+
+func (s *boltStore) SearchSimilarities(question budgierag.VectorRecord, limit float64) ([]budgierag.VectorRecord, error) {
+	var records []budgierag.VectorRecord
+	err := s.ForEach(func(record budgierag.VectorRecord) error {
+		record.CosineSimilarity = CosineSimilarity(question.Embedding, record.Embedding)
+		if record.CosineSimilarity >= limit {
+			records = append(records, record)
+		}
+		return nil
+	})
+	return records, err
+}
+
+func (s *boltStore) SearchTopNSimilarities(question budgierag.VectorRecord, limit float64, max int) ([]budgierag.VectorRecord, error) {
+	records, err := s.SearchSimilarities(question, limit)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].CosineSimilarity > records[j].CosineSimilarity
+	})
+	if len(records) > max {
+		records = records[:max]
+	}
+	return records, nil
+}
+
+func (s *boltStore) Persist() error {
+	if err := s.flush(); err != nil {
+		return err
+	}
+	if s.db == nil {
+		return nil
+	}
+	if !s.readOnly {
+		if err := s.db.Sync(); err != nil {
+			return err
+		}
+	}
+	return s.Close()
+}
+
+// Close writes the pending records and closes the database
+func (s *boltStore) Close() error {
+	flushErr := s.flush()
+	if s.db == nil {
+		return flushErr
+	}
+	err := s.db.Close()
+	s.db = nil
+	return errors.Join(flushErr, err)
+}
+
+// encodeRecord encodes the prompt and the embedding of a record: the prompt length (uvarint),
+// the prompt, then the embedding values (8 bytes each)
+func encodeRecord(record budgierag.VectorRecord) []byte {
+	data := binary.AppendUvarint(nil, uint64(len(record.Prompt)))
+	data = append(data, record.Prompt...)
+	for _, value := range record.Embedding {
+		data = binary.LittleEndian.AppendUint64(data, math.Float64bits(value))
+	}
+	return data
+}
+
+// decodeRecord decodes a record encoded by encodeRecord
+func decodeRecord(id, data []byte) (budgierag.VectorRecord, error) {
+	length, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < length || (uint64(len(data)-n)-length)%8 != 0 {
+		return budgierag.VectorRecord{}, fmt.Errorf("corrupted vector record %s", id)
+	}
+	prompt := string(data[n : n+int(length)])
+	values := data[n+int(length):]
+
+	embedding := make([]float64, len(values)/8)
+	for i := range embedding {
+		embedding[i] = math.Float64frombits(binary.LittleEndian.Uint64(values[i*8:]))
+	}
+	return budgierag.VectorRecord{Id: string(id), Prompt: prompt, Embedding: embedding}, nil
+}
+
+// CosineSimilarity returns the cosine similarity of two vectors (0 when one of them is null)
+func CosineSimilarity(v1, v2 []float64) float64 {
+	var product, norm1, norm2 float64
+	for i := range min(len(v1), len(v2)) {
+		product += v1[i] * v2[i]
+		norm1 += v1[i] * v1[i]
+		norm2 += v2[i] * v2[i]
+	}
+	if norm1 <= 0 || norm2 <= 0 {
+		return 0
+	}
+	return product / (math.Sqrt(norm1) * math.Sqrt(norm2))
+}
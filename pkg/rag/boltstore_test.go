@@ -0,0 +1,104 @@
+package rag
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	budgierag "github.com/budgies-nest/budgie/rag"
+)
+
+// The saved records are written in batches: they are visible before Persist, and persisted with it
+func TestBoltStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "embeddings.db")
+	store := &boltStore{path: path}
+
+	count := boltBatchSize*2 + 10
+	for i := range count {
+		record := budgierag.VectorRecord{Id: fmt.Sprintf("doc.md#chunk-%d", i), Prompt: fmt.Sprintf("chunk %d", i), Embedding: []float64{float64(i), 1, -0.5}}
+		if _, err := store.Save(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(store.pending) != 10 {
+		t.Errorf("pending = %d records, want the 10 records of the last batch", len(store.pending))
+	}
+
+	// A pending record is found before Persist
+	record, found, err := store.Get(fmt.Sprintf("doc.md#chunk-%d", count-1))
+	if err != nil || !found {
+		t.Fatalf("Get of a pending record = %v, %v", found, err)
+	}
+	if record.Prompt != fmt.Sprintf("chunk %d", count-1) {
+		t.Errorf("prompt = %q", record.Prompt)
+	}
+
+	if err := store.Delete("doc.md#chunk-0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Save(budgierag.VectorRecord{Id: "late.md#chunk-0", Prompt: "late", Embedding: []float64{0, 0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Persist(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := &boltStore{path: path, readOnly: true}
+	defer reopened.Close()
+	records, err := reopened.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != count {
+		t.Fatalf("%d records persisted, want %d", len(records), count)
+	}
+	if _, found, _ := reopened.Get("doc.md#chunk-0"); found {
+		t.Error("the deleted record was persisted")
+	}
+	record, found, err = reopened.Get("doc.md#chunk-42")
+	if err != nil || !found {
+		t.Fatalf("Get = %v, %v", found, err)
+	}
+	if record.Prompt != "chunk 42" || fmt.Sprint(record.Embedding) != fmt.Sprint([]float64{42, 1, -0.5}) {
+		t.Errorf("record = %q %v, want the saved prompt and embedding", record.Prompt, record.Embedding)
+	}
+
+	top, err := reopened.SearchTopNSimilarities(budgierag.VectorRecord{Embedding: []float64{0, 0, 1}}, 0.9, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(top) != 1 || top[0].Id != "late.md#chunk-0" {
+		t.Errorf("top similarity = %v, want late.md#chunk-0", top)
+	}
+}
+
+// A reset drops the records saved before it, written or pending
+func TestBoltStoreReset(t *testing.T) {
+	store := &boltStore{path: filepath.Join(t.TempDir(), "embeddings.db")}
+	for i := range boltBatchSize + 1 {
+		if _, err := store.Save(budgierag.VectorRecord{Id: fmt.Sprint(i), Prompt: "chunk", Embedding: []float64{1}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := store.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Persist(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := store.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if len(records) != 0 {
+		t.Errorf("%d records after the reset, want none", len(records))
+	}
+}
+
+func TestBoltStoreReadOnlySave(t *testing.T) {
+	store := &boltStore{path: filepath.Join(t.TempDir(), "embeddings.db"), readOnly: true}
+	if _, err := store.Save(budgierag.VectorRecord{Id: "1"}); err == nil {
+		t.Error("a read-only store accepted a record")
+	}
+}
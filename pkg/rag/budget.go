@@ -0,0 +1,39 @@
+package rag
+
+import (
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+)
+
+// minTruncatedTokens is the size of the smallest excerpt of a chunk kept by FitContext: when less of
+// the chunk fits in the budget, it is dropped instead of truncated
+const minTruncatedTokens = 100
+
+// FitContext keeps the chunks (best scores first) whose contents fit in about budget tokens (all of them
+// when budget <= 0). The first chunk that does not fit is truncated (and marked Truncated) when at least
+// minTruncatedTokens of it fit, the lower-scoring chunks are dropped. It returns the kept and dropped chunks.
+func FitContext(similarities []Similarity, budget int) ([]Similarity, []Similarity) {
+	if budget <= 0 {
+		return similarities, nil
+	}
+
+	used := 0
+	for i, similarity := range similarities {
+		size := tokens.Estimate(similarity.Content)
+		if used+size <= budget {
+			used += size
+			continue
+		}
+
+		kept := similarities[:i:i]
+		dropped := similarities[i:]
+		// The best chunk is always given, even when only an excerpt fits
+		if remaining := budget - used; remaining >= minTruncatedTokens || i == 0 {
+			similarity.Content = tokens.Truncate(similarity.Content, remaining) + "…"
+			similarity.Truncated = true
+			kept = append(kept, similarity)
+			dropped = similarities[i+1:]
+		}
+		return kept, dropped
+	}
+	return similarities, nil
+}
@@ -0,0 +1,63 @@
+package rag
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/budgies-nest/budgie-cli/pkg/tokens"
+)
+
+// chunkOf returns a chunk of about size tokens
+func chunkOf(id string, size int) Similarity {
+	return Similarity{ID: id, Content: strings.Repeat(" word", size)}
+}
+
+func TestFitContext(t *testing.T) {
+	similarities := []Similarity{chunkOf("a", 300), chunkOf("b", 300), chunkOf("c", 300)}
+
+	tests := []struct {
+		name      string
+		budget    int
+		kept      []string
+		dropped   []string
+		truncated string
+	}{
+		{"no budget", 0, []string{"a", "b", "c"}, nil, ""},
+		{"negative budget", -1, []string{"a", "b", "c"}, nil, ""},
+		{"everything fits", 900, []string{"a", "b", "c"}, nil, ""},
+		{"exact fit", 600, []string{"a", "b"}, []string{"c"}, ""},
+		{"the next chunk is truncated", 750, []string{"a", "b", "c"}, nil, "c"},
+		{"too little left to truncate", 650, []string{"a", "b"}, []string{"c"}, ""},
+		{"the best chunk is always given", 50, []string{"a"}, []string{"b", "c"}, "a"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			kept, dropped := FitContext(similarities, test.budget)
+			if fmt.Sprint(IDs(kept)) != fmt.Sprint(test.kept) || fmt.Sprint(IDs(dropped)) != fmt.Sprint(test.dropped) {
+				t.Fatalf("kept %v, dropped %v, want %v and %v", IDs(kept), IDs(dropped), test.kept, test.dropped)
+			}
+
+			used := 0
+			for _, similarity := range kept {
+				used += tokens.Estimate(strings.TrimSuffix(similarity.Content, "…"))
+				if truncated := similarity.ID == test.truncated; similarity.Truncated != truncated {
+					t.Errorf("%s truncated = %v, want %v", similarity.ID, similarity.Truncated, truncated)
+				}
+				if similarity.Truncated && !strings.HasSuffix(similarity.Content, "…") {
+					t.Errorf("the truncated chunk %s is not marked with an ellipsis", similarity.ID)
+				}
+			}
+			if test.budget > 0 && used > test.budget {
+				t.Errorf("%d tokens kept, over the budget of %d", used, test.budget)
+			}
+		})
+	}
+
+	// The chunks given are not modified
+	for _, similarity := range similarities {
+		if similarity.Truncated || tokens.Estimate(similarity.Content) != 300 {
+			t.Fatalf("FitContext modified the chunk %s", similarity.ID)
+		}
+	}
+}
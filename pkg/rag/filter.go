@@ -0,0 +1,74 @@
+package rag
+
+import (
+	"github.com/budgies-nest/budgie-cli/pkg/language"
+)
+
+// LanguageFilter keeps the chunks of the store written in the language of the question.
+// Chunks without a detected language are kept. It returns nil (no filtering) when the
+// language of the question cannot be detected or when the store has no language metadata.
+func LanguageFilter(storePath, question string) Filter {
+	questionLanguage := language.Detect(question)
+	if questionLanguage == "" {
+		return nil
+	}
+
+	manifest, err := LoadManifest(ManifestPath(storePath))
+	if err != nil {
+		return nil
+	}
+	metadata := manifest.ChunkMetadata()
+	if len(metadata) == 0 {
+		return nil
+	}
+
+	return func(id string) bool {
+		chunkLanguage := metadata[id].Language
+		return chunkLanguage == "" || chunkLanguage == questionLanguage
+	}
+}
+
+// MetadataFilter keeps the chunks of the store matching every condition (chunks without metadata are
+// left out). It returns nil (no filtering) when there is no condition.
+func MetadataFilter(storePath string, conditions []MetadataCondition) (Filter, error) {
+	if len(conditions) == 0 {
+		return nil, nil
+	}
+
+	manifest, err := LoadManifest(ManifestPath(storePath))
+	if err != nil {
+		return nil, err
+	}
+	metadata := manifest.ChunkMetadata()
+
+	return func(id string) bool {
+		chunk := metadata[id]
+		for _, condition := range conditions {
+			if !condition.Match(chunk) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// AllFilters combines filters: a chunk is kept when every filter keeps it (nil filters are ignored)
+func AllFilters(filters ...Filter) Filter {
+	var active []Filter
+	for _, filter := range filters {
+		if filter != nil {
+			active = append(active, filter)
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	return func(id string) bool {
+		for _, filter := range active {
+			if !filter(id) {
+				return false
+			}
+		}
+		return true
+	}
+}
@@ -0,0 +1,72 @@
+package rag
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/budgies-nest/budgie/agents"
+	budgierag "github.com/budgies-nest/budgie/rag"
+)
+
+// termRegex matches the words of a text
+var termRegex = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// stopWords are frequent words ignored by the keyword search
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "are": true, "how": true, "what": true, "why": true,
+	"can": true, "does": true, "with": true, "this": true, "that": true, "from": true, "you": true,
+	"your": true, "which": true, "when": true, "where": true, "who": true, "into": true, "about": true,
+}
+
+// Terms returns the distinct significant lowercase words of a text
+func Terms(text string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, word := range termRegex.FindAllString(strings.ToLower(text), -1) {
+		if len([]rune(word)) < 3 || stopWords[word] || seen[word] {
+			continue
+		}
+		seen[word] = true
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// KeywordSearch scores the chunks of the agent's vector store by the share of the question terms they contain.
+// It does not call the embedding model, so it works when the model backend is unreachable.
+// The results are sorted by decreasing score and limited to k (all when k <= 0).
+func KeywordSearch(question string, agent *agents.Agent, k int) []Similarity {
+	if agent == nil {
+		return nil
+	}
+	store, ok := AgentStore(agent)
+	if !ok {
+		return nil
+	}
+
+	terms := Terms(question)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	var similarities []Similarity
+	store.ForEach(func(record budgierag.VectorRecord) error {
+		content := strings.ToLower(record.Prompt)
+		matched := 0
+		for _, term := range terms {
+			if strings.Contains(content, term) {
+				matched++
+			}
+		}
+		if matched > 0 {
+			similarities = append(similarities, Similarity{
+				ID:      record.Id,
+				Content: record.Prompt,
+				Score:   float64(matched) / float64(len(terms)),
+			})
+		}
+		return nil
+	})
+
+	return TopK(similarities, k)
+}
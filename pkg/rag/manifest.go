@@ -0,0 +1,181 @@
+package rag
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie/agents"
+	budgierag "github.com/budgies-nest/budgie/rag"
+)
+
+// ManifestVersion is the version of the chunk ids of the manifests written by generate-embeddings: the
+// incremental runs regenerate the stores of older manifests (version 2 ids are relative to the docs directory)
+const ManifestVersion = 2
+
+// Manifest records, for each embedded file, its content hash and the ids of its chunks, along with the
+// embedding model and the dimension of its embeddings.
+// It is stored alongside the embeddings file and enables incremental generation.
+type Manifest struct {
+	Version        int                  `json:"version,omitempty"`
+	EmbeddingModel string               `json:"embedding-model"`
+	Dimension      int                  `json:"dimension,omitempty"`
+	VectorStore    string               `json:"vector-store,omitempty"`
+	Docs           string               `json:"docs,omitempty"`
+	Files          map[string]FileEntry `json:"files"`
+}
+
+// FileEntry describes an embedded file
+type FileEntry struct {
+	Hash     string                   `json:"hash"`
+	Chunking string                   `json:"chunking"`
+	Chunks   []string                 `json:"chunks"`
+	Metadata map[string]ChunkMetadata `json:"metadata,omitempty"`
+}
+
+// ChunkMetadata describes a chunk, it is used to filter the retrieval
+type ChunkMetadata struct {
+	Language string   `json:"language,omitempty"`
+	Category string   `json:"category,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+	// Navigation is the position of the page in the docs site navigation (e.g. ["User Guide", "Installation"])
+	Navigation []string `json:"navigation,omitempty"`
+	// URL is the address of the web page the chunk comes from (crawled or fetched pages), cited as its source
+	URL string `json:"url,omitempty"`
+}
+
+// Empty reports whether nothing is known about the chunk
+func (c ChunkMetadata) Empty() bool {
+	return c.Language == "" && c.Category == "" && len(c.Keywords) == 0 && len(c.Navigation) == 0 && c.URL == ""
+}
+
+// ChunkMetadata returns the metadata of all the chunks of the manifest, by chunk id
+func (m *Manifest) ChunkMetadata() map[string]ChunkMetadata {
+	metadata := make(map[string]ChunkMetadata)
+	for _, entry := range m.Files {
+		for id, chunk := range entry.Metadata {
+			metadata[id] = chunk
+		}
+	}
+	return metadata
+}
+
+// ManifestPath returns the manifest path of an embeddings file (embeddings.json -> embeddings.hashes.json)
+func ManifestPath(storePath string) string {
+	return strings.TrimSuffix(storePath, ".json") + ".hashes.json"
+}
+
+// LoadManifest reads a manifest file, returning an empty manifest when it does not exist
+func LoadManifest(path string) (*Manifest, error) {
+	manifest := &Manifest{Files: make(map[string]FileEntry)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]FileEntry)
+	}
+	return manifest, nil
+}
+
+// Save writes the manifest file
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// HashContent returns the SHA-256 hex digest of a content
+func HashContent(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// DeleteRecords removes the records with the given ids from the agent's vector store
+func DeleteRecords(agent *agents.Agent, ids []string) {
+	if store, ok := AgentStore(agent); ok {
+		store.Delete(ids...)
+	}
+}
+
+// StoreEmbeddingModel returns the embedding model recorded in the manifest of an embeddings file
+// ("" when the store has no manifest)
+func StoreEmbeddingModel(storePath string) (string, error) {
+	manifest, err := LoadManifest(ManifestPath(storePath))
+	if err != nil {
+		return "", err
+	}
+	return manifest.EmbeddingModel, nil
+}
+
+// ModelMismatchError is returned when the embeddings of a store were generated with another embedding model
+// than the configured one: their similarity scores would be meaningless
+type ModelMismatchError struct {
+	StorePath       string
+	StoreModel      string
+	ConfiguredModel string
+}
+
+func (e *ModelMismatchError) Error() string {
+	return fmt.Sprintf("the embeddings of %s were generated with %s but the configured embedding model is %s (regenerate them with budgie generate-embeddings, or budgie index for the code collection)", e.StorePath, e.StoreModel, e.ConfiguredModel)
+}
+
+// DimensionMismatchError is returned when the embeddings of a store and the embedding of the question
+// have different dimensions
+type DimensionMismatchError struct {
+	StoreDimension    int
+	QuestionDimension int
+}
+
+func (e *DimensionMismatchError) Error() string {
+	return fmt.Sprintf("the stored embeddings have %d dimensions but the embedding model creates embeddings of %d dimensions (regenerate them with budgie generate-embeddings, or budgie index for the code collection)", e.StoreDimension, e.QuestionDimension)
+}
+
+// errStopForEach stops the iteration over the records of a store
+var errStopForEach = errors.New("stop")
+
+// StoreDimension returns the dimension of the embeddings of a store, read from its first record (0 when empty)
+func StoreDimension(store Store) (int, error) {
+	dimension := 0
+	err := store.ForEach(func(record budgierag.VectorRecord) error {
+		dimension = len(record.Embedding)
+		return errStopForEach
+	})
+	if err != nil && !errors.Is(err, errStopForEach) {
+		return 0, err
+	}
+	return dimension, nil
+}
+
+// RecordStoreModel records the embedding model, the vector store backend and the dimension of the
+// embeddings of the agent's store in the manifest of an embeddings file
+func RecordStoreModel(config *config.Config, storePath string, agent *agents.Agent) error {
+	path := ManifestPath(storePath)
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		return err
+	}
+	manifest.EmbeddingModel = config.EmbeddingModel
+	manifest.VectorStore = StoreBackend(config)
+	if store, ok := AgentStore(agent); ok {
+		if manifest.Dimension, err = StoreDimension(store); err != nil {
+			return err
+		}
+	}
+	return manifest.Save(path)
+}
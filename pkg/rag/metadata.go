@@ -0,0 +1,128 @@
+package rag
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DocMetadata is the metadata declared by a document in a METADATA block, e.g. at the top of a source file:
+//
+//	/*
+//	METADATA:
+//	Keywords: goroutines, concurrency, go keyword
+//	Category: concurrency
+//	*/
+//
+// It applies to every chunk of the document. The pages fetched from the web declare their URL as Source.
+type DocMetadata struct {
+	Category string
+	Keywords []string
+	Source   string
+}
+
+// metadataMarker starts a METADATA block
+const metadataMarker = "METADATA:"
+
+// ParseDocMetadata reads the first METADATA block of a document: the "Key: value" lines following the
+// marker, up to the first line which is not a field (end of comment, blank line...). Unknown keys are ignored.
+func ParseDocMetadata(content string) DocMetadata {
+	var metadata DocMetadata
+	lines := strings.Split(content, "\n")
+	start := slices.IndexFunc(lines, func(line string) bool {
+		return strings.TrimSpace(line) == metadataMarker
+	})
+	if start < 0 {
+		return metadata
+	}
+
+	for _, line := range lines[start+1:] {
+		key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			break
+		}
+		switch strings.ToLower(key) {
+		case "category":
+			metadata.Category = strings.TrimSpace(value)
+		case "keywords":
+			metadata.Keywords = splitValues(value)
+		case "source":
+			metadata.Source = strings.TrimSpace(value)
+		}
+	}
+	return metadata
+}
+
+// Empty reports whether the document declared no metadata
+func (m DocMetadata) Empty() bool {
+	return m.Category == "" && len(m.Keywords) == 0 && m.Source == ""
+}
+
+// MetadataCondition is a retrieval filter on the chunk metadata: the chunk must have one of the values
+type MetadataCondition struct {
+	Key    string
+	Values []string
+}
+
+// Keys of the metadata filters
+const (
+	FilterCategory = "category"
+	FilterKeyword  = "keyword"
+	FilterSection  = "section"
+)
+
+// ParseMetadataFilters parses "key=value[,value...]" filters (e.g. "category=concurrency", "keyword=goroutines,channels").
+// The keys are category and keyword (or keywords) for the METADATA blocks, and section for the docs site
+// navigation (any level of the navigation path of the page). The values are matched case-insensitively.
+func ParseMetadataFilters(filters []string) ([]MetadataCondition, error) {
+	var conditions []MetadataCondition
+	for _, filter := range filters {
+		key, value, found := strings.Cut(filter, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		values := splitValues(value)
+		if !found || len(values) == 0 {
+			return nil, fmt.Errorf("invalid filter %q: expected key=value (e.g. category=concurrency)", filter)
+		}
+		switch key {
+		case FilterCategory, FilterSection:
+		case FilterKeyword, "keywords":
+			key = FilterKeyword
+		default:
+			return nil, fmt.Errorf("invalid filter %q: unknown key %q (use %s, %s or %s)", filter, key, FilterCategory, FilterKeyword, FilterSection)
+		}
+		conditions = append(conditions, MetadataCondition{Key: key, Values: values})
+	}
+	return conditions, nil
+}
+
+// Match reports whether a chunk satisfies the condition
+func (c MetadataCondition) Match(metadata ChunkMetadata) bool {
+	var chunkValues []string
+	switch c.Key {
+	case FilterCategory:
+		chunkValues = []string{metadata.Category}
+	case FilterKeyword:
+		chunkValues = metadata.Keywords
+	case FilterSection:
+		chunkValues = metadata.Navigation
+	}
+	for _, value := range c.Values {
+		for _, chunkValue := range chunkValues {
+			if strings.EqualFold(value, chunkValue) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// splitValues splits a comma-separated list, trimming the values and dropping the empty ones
+func splitValues(list string) []string {
+	var values []string
+	for _, value := range strings.Split(list, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,97 @@
+package rag
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// remoteTimeout is the timeout of the download of each file of a remote store
+const remoteTimeout = 2 * time.Minute
+
+// FetchStore downloads a vector store published over http(s) (json backend) to a cache directory: the
+// embeddings (the URL of an embeddings file, or of its directory), along with their manifest and keyword
+// index when they are published. The cached files are only downloaded again when they changed
+// (If-Modified-Since). It returns the path of the cached embeddings.
+func FetchStore(address, cacheDir string) (string, error) {
+	embeddingsURL, err := url.Parse(address)
+	if err != nil || (embeddingsURL.Scheme != "http" && embeddingsURL.Scheme != "https") || embeddingsURL.Host == "" {
+		return "", fmt.Errorf("unsupported store URL %s (http or https only)", address)
+	}
+	if !strings.HasSuffix(embeddingsURL.Path, ".json") {
+		embeddingsURL.Path = strings.TrimSuffix(embeddingsURL.Path, "/") + "/embeddings.json"
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return "", fmt.Errorf("error creating the store cache: %w", err)
+	}
+	storePath := filepath.Join(cacheDir, "embeddings.json")
+	if err := fetchFile(embeddingsURL.String(), storePath, true); err != nil {
+		return "", err
+	}
+	for _, sibling := range []func(string) string{ManifestPath, KeywordIndexPath} {
+		siblingURL := *embeddingsURL
+		siblingURL.Path = sibling(embeddingsURL.Path)
+		if err := fetchFile(siblingURL.String(), sibling(storePath), false); err != nil {
+			return "", err
+		}
+	}
+	return storePath, nil
+}
+
+// fetchFile downloads a file unless the local copy is up to date. An optional file missing from the server
+// (404) is removed from the cache.
+func fetchFile(address, path string, required bool) error {
+	request, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return fmt.Errorf("error fetching %s: %w", address, err)
+	}
+	if info, err := os.Stat(path); err == nil {
+		request.Header.Set("If-Modified-Since", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+
+	client := &http.Client{Timeout: remoteTimeout}
+	response, err := client.Do(request)
+	if err != nil {
+		return fmt.Errorf("error fetching %s: %w", address, err)
+	}
+	defer response.Body.Close()
+	switch {
+	case response.StatusCode == http.StatusNotModified:
+		return nil
+	case response.StatusCode == http.StatusNotFound && !required:
+		os.Remove(path)
+		return nil
+	case response.StatusCode >= 400:
+		return fmt.Errorf("error fetching %s: %s", address, response.Status)
+	}
+
+	// Written to a temporary file first: an interrupted download does not replace the cached copy
+	temp := path + ".download"
+	file, err := os.Create(temp)
+	if err != nil {
+		return fmt.Errorf("error caching %s: %w", address, err)
+	}
+	_, err = io.Copy(file, response.Body)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("error fetching %s: %w", address, err)
+	}
+	if err := os.Rename(temp, path); err != nil {
+		os.Remove(temp)
+		return fmt.Errorf("error caching %s: %w", address, err)
+	}
+	// The modification time of the server is the one sent back with If-Modified-Since
+	if modified, err := http.ParseTime(response.Header.Get("Last-Modified")); err == nil {
+		os.Chtimes(path, modified, modified)
+	}
+	return nil
+}
@@ -3,23 +3,37 @@ package rag
 import (
 	"context"
 	"fmt"
-	"os"
+	"io"
+	"sort"
 	"strings"
 
 	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
+	"github.com/budgies-nest/budgie-cli/pkg/tracing"
 	"github.com/budgies-nest/budgie/agents"
+	budgierag "github.com/budgies-nest/budgie/rag"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/openai/openai-go"
+	"go.opentelemetry.io/otel/attribute"
 )
 
-// CreateSearchAgent creates and configures a search agent for RAG functionality
-func CreateSearchAgent(config *config.Config, embeddingsPath string) (*agents.Agent, error) {
+// CreateSearchAgent creates and configures a search agent for RAG functionality.
+// It fails with a ModelMismatchError when the embeddings were generated with another embedding model,
+// unless allowModelMismatch is true (the user chose to search them anyway).
+func CreateSearchAgent(config *config.Config, embeddingsPath string, allowModelMismatch bool) (*agents.Agent, error) {
 	if embeddingsPath == "" {
 		embeddingsPath = ".budgie/embeddings.json"
 	}
 
-	// Check if embeddings file exists
-	if _, err := os.Stat(embeddingsPath); os.IsNotExist(err) {
+	// A shared store may have been generated with another backend than the configured one
+	if manifest, err := LoadManifest(ManifestPath(embeddingsPath)); err == nil && manifest.VectorStore != "" && manifest.VectorStore != StoreBackend(config) {
+		storeConfig := *config
+		storeConfig.VectorStore = manifest.VectorStore
+		config = &storeConfig
+	}
+
+	// Check if the vector store was generated
+	if !StoreExists(config, embeddingsPath) {
 		return nil, nil // No embeddings file, return nil
 	}
 
@@ -27,85 +41,252 @@ func CreateSearchAgent(config *config.Config, embeddingsPath string) (*agents.Ag
 		return nil, fmt.Errorf("embedding-model not specified in config file")
 	}
 
+	if !allowModelMismatch {
+		storeModel, err := StoreEmbeddingModel(embeddingsPath)
+		if err != nil {
+			return nil, fmt.Errorf("error loading embeddings manifest: %w", err)
+		}
+		if storeModel != "" && storeModel != config.EmbeddingModel {
+			return nil, &ModelMismatchError{StorePath: embeddingsPath, StoreModel: storeModel, ConfiguredModel: config.EmbeddingModel}
+		}
+	}
+
+	clientOption, err := provider.ClientOption(config)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create budgie-search agent for similarity search
 	searchAgent, err := agents.NewAgent("budgie-search",
-		agents.WithDMR(config.BaseURL),
+		clientOption,
 		agents.WithEmbeddingParams(
 			openai.EmbeddingNewParams{
 				Model: openai.EmbeddingModel(config.EmbeddingModel),
 			},
 		),
-		agents.WithMemoryVectorStore(embeddingsPath),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error creating search agent: %w", err)
 	}
 
-	// Load existing embeddings
-	err = searchAgent.LoadMemoryVectorStore()
+	// Load existing embeddings (read-only, so the other budgie processes can read them too)
+	store, err := OpenStore(config, embeddingsPath, true)
 	if err != nil {
 		return nil, fmt.Errorf("error loading vector store: %w", err)
 	}
+	searchAgent.Store = store
 
 	return searchAgent, nil
 }
 
-// SearchSimilarities searches for similar content using the search agent
-func SearchSimilarities(question string, searchAgent *agents.Agent, config *config.Config) ([]string, error) {
-	if searchAgent == nil {
+// Similarity is a chunk found by the similarity search along with its cosine similarity score
+type Similarity struct {
+	ID      string  `json:"id"`
+	Content string  `json:"content"`
+	Score   float64 `json:"score"`
+	// Source is the file the chunk comes from
+	Source string `json:"source,omitempty"`
+	// Truncated is set when the content was shortened to fit the context budget (see FitContext)
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Filter tells whether a chunk (by id) can be retrieved
+type Filter func(id string) bool
+
+// SearchSimilarities searches for similar content using the search agent.
+// With a keyword-weight, the search is hybrid (see hybridScores).
+// The chunks rejected by the filter (if any) are left out, then the results are sorted
+// by decreasing similarity score and limited to the top-k setting (if any).
+func SearchSimilarities(question string, searchAgent *agents.Agent, config *config.Config, filter Filter) ([]Similarity, error) {
+	if searchAgent == nil || searchAgent.Store == nil {
 		return nil, nil // No search agent available
 	}
 
-	// Search for similarities
-	similarities, err := searchAgent.RAGMemorySearchSimilaritiesWithText(
-		context.Background(),
-		question,
+	embedding, err := Embed(context.Background(), searchAgent, question)
+	if err != nil {
+		return nil, fmt.Errorf("error searching similarities: %w", err)
+	}
+
+	_, span := tracing.Start(context.Background(), "similarity search",
+		attribute.Float64("budgie.rag.cosine_limit", config.CosineLimit),
+		attribute.Int("budgie.rag.top_k", config.TopK),
+		attribute.Float64("budgie.rag.keyword_weight", config.KeywordWeight),
+	)
+	similarities, err := searchStore(question, embedding.Embedding, searchAgent, config, filter)
+	if err == nil {
+		span.SetAttributes(attribute.Int("budgie.rag.results", len(similarities)))
+	}
+	tracing.End(span, err)
+	return similarities, err
+}
+
+// searchStore searches the vector store (and the keywords with a keyword-weight) for the chunks similar to
+// the embedding of the question
+func searchStore(question string, embedding []float64, searchAgent *agents.Agent, config *config.Config, filter Filter) ([]Similarity, error) {
+
+	// Embeddings of different dimensions would be compared on their common part only
+	if store, ok := AgentStore(searchAgent); ok {
+		dimension, err := StoreDimension(store)
+		if err != nil {
+			return nil, fmt.Errorf("error searching similarities: %w", err)
+		}
+		if dimension > 0 && dimension != len(embedding) {
+			return nil, &DimensionMismatchError{StoreDimension: dimension, QuestionDimension: len(embedding)}
+		}
+	}
+
+	records, err := searchAgent.Store.SearchSimilarities(
+		budgierag.VectorRecord{Embedding: embedding},
 		config.CosineLimit,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error searching similarities: %w", err)
 	}
 
-	return similarities, nil
+	similarities := make([]Similarity, 0, len(records))
+	for _, record := range records {
+		if filter != nil && !filter(record.Id) {
+			continue
+		}
+		similarities = append(similarities, Similarity{
+			ID:      record.Id,
+			Content: record.Prompt,
+			Score:   record.CosineSimilarity,
+		})
+	}
+
+	if config.KeywordWeight > 0 {
+		similarities, err = hybridScores(question, embedding, searchAgent, similarities, config.KeywordWeight, filter)
+		if err != nil {
+			return nil, fmt.Errorf("error searching keywords: %w", err)
+		}
+	}
+
+	return TopK(similarities, config.TopK), nil
 }
 
-// DisplaySimilarities displays the found similarities in a formatted way
-func DisplaySimilarities(similarities []string) {
+// hybridScores merges the vector search results with the BM25 keyword search: the score of a chunk is
+// (1 - weight) * cosine similarity + weight * BM25 score (relative to the best BM25 score).
+// Besides the chunks above the cosine limit, the strong keyword matches (BM25 score of at least
+// half the best one) are retrieved, so exact identifiers are found even when their similarity is low.
+func hybridScores(question string, questionEmbedding []float64, searchAgent *agents.Agent, similarities []Similarity, weight float64, filter Filter) ([]Similarity, error) {
+	index, err := KeywordIndexOf(searchAgent)
+	if err != nil || index == nil {
+		return similarities, err
+	}
+	keywordScores := index.Scores(question)
+	best := 0.0
+	for _, score := range keywordScores {
+		best = max(best, score)
+	}
+	if best == 0 {
+		return similarities, nil
+	}
+
+	merged := make([]Similarity, 0, len(similarities))
+	found := make(map[string]bool)
+	for _, similarity := range similarities {
+		found[similarity.ID] = true
+		similarity.Score = (1-weight)*similarity.Score + weight*keywordScores[similarity.ID]/best
+		merged = append(merged, similarity)
+	}
+
+	store, _ := AgentStore(searchAgent)
+	for id, score := range keywordScores {
+		if found[id] || score < best/2 || (filter != nil && !filter(id)) {
+			continue
+		}
+		record, ok, err := store.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		merged = append(merged, Similarity{
+			ID:      id,
+			Content: record.Prompt,
+			Score:   (1-weight)*CosineSimilarity(questionEmbedding, record.Embedding) + weight*score/best,
+		})
+	}
+	return merged, nil
+}
+
+// TopK sorts the similarities by decreasing score and keeps the best k (all when k <= 0)
+func TopK(similarities []Similarity, k int) []Similarity {
+	sort.SliceStable(similarities, func(i, j int) bool {
+		return similarities[i].Score > similarities[j].Score
+	})
+	if k > 0 && len(similarities) > k {
+		return similarities[:k]
+	}
+	return similarities
+}
+
+// Contents returns the text of the similarities
+func Contents(similarities []Similarity) []string {
+	contents := make([]string, 0, len(similarities))
+	for _, similarity := range similarities {
+		contents = append(contents, similarity.Content)
+	}
+	return contents
+}
+
+// IDs returns the chunk ids of the similarities
+func IDs(similarities []Similarity) []string {
+	ids := make([]string, 0, len(similarities))
+	for _, similarity := range similarities {
+		ids = append(ids, similarity.ID)
+	}
+	return ids
+}
+
+// DisplaySimilarities displays the found similarities in a formatted way on w
+func DisplaySimilarities(w io.Writer, similarities []Similarity) {
 	if len(similarities) == 0 {
-		fmt.Println("📚 No relevant documentation found")
-		fmt.Println()
+		fmt.Fprintln(w, "📚 No relevant documentation found")
+		fmt.Fprintln(w)
 		return
 	}
 
 	greenStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true)
 	headerStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("2")).Bold(true).Background(lipgloss.Color("0"))
 	contentStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+	scoreStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("3"))
 
-	fmt.Println(headerStyle.Render(fmt.Sprintf("📚 Found %d relevant documentation chunks:", len(similarities))))
-	fmt.Println()
+	fmt.Fprintln(w, headerStyle.Render(fmt.Sprintf("📚 Found %d relevant documentation chunks:", len(similarities))))
+	fmt.Fprintln(w)
 
 	for i, similarity := range similarities {
-		lines := strings.Split(strings.TrimSpace(similarity), "\n")
+		lines := strings.Split(strings.TrimSpace(similarity.Content), "\n")
 
-		fmt.Printf("%s %d. ", greenStyle.Render("  "), i+1)
+		fmt.Fprintf(w, "%s %d. %s ", greenStyle.Render("  "), i+1, scoreStyle.Render(fmt.Sprintf("[%.1f%%]", similarity.Score*100)))
 
+		first := true
 		for _, line := range lines {
 			line = strings.TrimSpace(line)
 			if line == "" {
 				continue
 			}
 
-			if strings.HasPrefix(line, "TITLE:") {
-				fmt.Println(greenStyle.Render(line))
-			} else if strings.HasPrefix(line, "HIERARCHY:") {
-				fmt.Printf("     %s\n", contentStyle.Render(line))
-			} else if strings.HasPrefix(line, "CONTENT:") {
-				fmt.Printf("     %s\n", contentStyle.Render(line))
+			if first {
+				// The first line goes next to the chunk number and score
+				if strings.HasPrefix(line, "TITLE:") {
+					fmt.Fprintln(w, greenStyle.Render(line))
+				} else {
+					fmt.Fprintln(w, contentStyle.Render(line))
+				}
+				first = false
+			} else if strings.HasPrefix(line, "TITLE:") {
+				fmt.Fprintln(w, greenStyle.Render(line))
 			} else {
-				// Content continuation
-				fmt.Printf("     %s\n", contentStyle.Render(line))
+				// HIERARCHY, CONTENT and content continuation
+				fmt.Fprintf(w, "     %s\n", contentStyle.Render(line))
 			}
 		}
-		fmt.Println()
+		if first {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w)
 	}
-}
\ No newline at end of file
+}
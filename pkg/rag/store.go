@@ -0,0 +1,101 @@
+package rag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie-cli/pkg/provider"
+	"github.com/budgies-nest/budgie-cli/pkg/tracing"
+	"github.com/budgies-nest/budgie/agents"
+	budgierag "github.com/budgies-nest/budgie/rag"
+	"github.com/openai/openai-go"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CreateEmbeddingAgent creates an agent able to create embeddings and save them to the given store file.
+// The existing records are loaded when the file exists, otherwise the store starts empty.
+func CreateEmbeddingAgent(config *config.Config, storePath string) (*agents.Agent, error) {
+	agent, err := NewEmbedder(config)
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := OpenStore(config, storePath, false)
+	if err != nil {
+		return nil, fmt.Errorf("error loading vector store: %w", err)
+	}
+	agent.Store = store
+
+	return agent, nil
+}
+
+// NewEmbedder creates an agent only able to create embeddings (without vector store).
+// An agent must not create embeddings concurrently: use one agent per goroutine.
+func NewEmbedder(config *config.Config) (*agents.Agent, error) {
+	if config.EmbeddingModel == "" {
+		return nil, fmt.Errorf("embedding-model not specified in config file")
+	}
+
+	clientOption, err := provider.ClientOption(config)
+	if err != nil {
+		return nil, err
+	}
+
+	agent, err := agents.NewAgent("budgie-search",
+		clientOption,
+		agents.WithEmbeddingParams(
+			openai.EmbeddingNewParams{
+				Model: openai.EmbeddingModel(config.EmbeddingModel),
+			},
+		),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error creating agent: %w", err)
+	}
+	return agent, nil
+}
+
+// DeleteRecordsWithPrefix removes the records whose id starts with prefix from the agent's vector store.
+// It returns the number of deleted records.
+func DeleteRecordsWithPrefix(agent *agents.Agent, prefix string) int {
+	store, ok := AgentStore(agent)
+	if !ok {
+		return 0
+	}
+
+	var ids []string
+	store.ForEach(func(record budgierag.VectorRecord) error {
+		if strings.HasPrefix(record.Id, prefix) {
+			ids = append(ids, record.Id)
+		}
+		return nil
+	})
+	if err := store.Delete(ids...); err != nil {
+		return 0
+	}
+	return len(ids)
+}
+
+// Embed creates the embedding of a text with the agent, in an "embedding" tracing span
+func Embed(ctx context.Context, agent *agents.Agent, text string) (openai.Embedding, error) {
+	ctx, span := tracing.Start(ctx, "embedding",
+		attribute.String("gen_ai.operation.name", "embeddings"),
+		attribute.String("gen_ai.request.model", agent.EmbeddingParams.Model),
+		attribute.Int("budgie.text.length", len(text)),
+	)
+	embedding, err := agent.CreateEmbeddingFromText(ctx, text)
+	tracing.End(span, err)
+	return embedding, err
+}
+
+// RecordPrompt returns the text of a record of the agent's vector store
+func RecordPrompt(agent *agents.Agent, id string) (string, bool) {
+	store, ok := AgentStore(agent)
+	if !ok {
+		return "", false
+	}
+	record, found, err := store.Get(id)
+	return record.Prompt, found && err == nil
+}
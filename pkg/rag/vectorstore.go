@@ -0,0 +1,281 @@
+package rag
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+	"github.com/budgies-nest/budgie/agents"
+	budgierag "github.com/budgies-nest/budgie/rag"
+)
+
+// Vector store backends (vector-store config option)
+const (
+	JSONStore = "json"
+	BoltStore = "bbolt"
+)
+
+// Store is a vector store backend: the budgie vector store along with the operations
+// used to maintain the embeddings incrementally
+type Store interface {
+	budgierag.VectorStore
+
+	// ForEach calls fn for every record, stopping at the first error
+	ForEach(fn func(record budgierag.VectorRecord) error) error
+	// Get returns the record with the given id
+	Get(id string) (budgierag.VectorRecord, bool, error)
+	// Delete removes the records with the given ids
+	Delete(ids ...string) error
+	// Reset removes all the records
+	Reset() error
+	// Persist writes the changes to the disk
+	Persist() error
+	// Close releases the store
+	Close() error
+}
+
+// StoreBackend returns the configured vector store backend (json by default)
+func StoreBackend(config *config.Config) string {
+	if config.VectorStore == "" {
+		return JSONStore
+	}
+	return config.VectorStore
+}
+
+// StoreFile returns the file of the configured backend for an embeddings path:
+// the path itself for json, the path with a .db extension for bbolt (embeddings.json -> embeddings.db)
+func StoreFile(config *config.Config, storePath string) string {
+	if StoreBackend(config) == BoltStore {
+		return strings.TrimSuffix(storePath, ".json") + ".db"
+	}
+	return storePath
+}
+
+// StoreExists reports whether the vector store of an embeddings path was generated
+func StoreExists(config *config.Config, storePath string) bool {
+	_, err := os.Stat(StoreFile(config, storePath))
+	return err == nil
+}
+
+// OpenStore opens the configured vector store of an embeddings path. A read-only store
+// does not lock out the other budgie processes reading it.
+// The store maintains the keyword index of its records.
+func OpenStore(config *config.Config, storePath string, readOnly bool) (Store, error) {
+	var store Store
+	switch StoreBackend(config) {
+	case JSONStore:
+		jsonStore, err := loadJSONStore(storePath)
+		if err != nil {
+			return nil, err
+		}
+		store = jsonStore
+	case BoltStore:
+		store = &boltStore{path: StoreFile(config, storePath), readOnly: readOnly}
+	default:
+		return nil, fmt.Errorf("unknown vector-store %q (supported: %s, %s)", config.VectorStore, JSONStore, BoltStore)
+	}
+	return &indexedStore{Store: store, indexPath: KeywordIndexPath(storePath)}, nil
+}
+
+// AgentStore returns the vector store of an agent created by CreateEmbeddingAgent or CreateSearchAgent
+func AgentStore(agent *agents.Agent) (Store, bool) {
+	if agent == nil {
+		return nil, false
+	}
+	store, ok := agent.Store.(Store)
+	return store, ok
+}
+
+// PersistStore writes the changes of the agent's vector store to the disk
+func PersistStore(agent *agents.Agent) error {
+	store, ok := AgentStore(agent)
+	if !ok {
+		return nil
+	}
+	return store.Persist()
+}
+
+// ResetStore removes all the records of the agent's vector store
+func ResetStore(agent *agents.Agent) error {
+	store, ok := AgentStore(agent)
+	if !ok {
+		return nil
+	}
+	return store.Reset()
+}
+
+// CloseStore releases the agent's vector store (the bbolt database lock)
+func CloseStore(agent *agents.Agent) {
+	if store, ok := AgentStore(agent); ok {
+		store.Close()
+	}
+}
+
+// indexedStore is a vector store along with its keyword index. The records saved and deleted are tracked:
+// Persist only updates their chunks in the saved index, the index is rebuilt after a reset or when the store
+// has none yet.
+type indexedStore struct {
+	Store
+	indexPath string
+	// changes are the chunks of the records saved since the last Persist, nil for the deleted records
+	changes map[string]*IndexedChunk
+	reset   bool
+}
+
+func (s *indexedStore) Save(record budgierag.VectorRecord) (budgierag.VectorRecord, error) {
+	saved, err := s.Store.Save(record)
+	if err == nil {
+		chunk := indexChunk(saved.Prompt)
+		s.track(saved.Id, &chunk)
+	}
+	return saved, err
+}
+
+func (s *indexedStore) Delete(ids ...string) error {
+	if err := s.Store.Delete(ids...); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		s.track(id, nil)
+	}
+	return nil
+}
+
+func (s *indexedStore) Reset() error {
+	if err := s.Store.Reset(); err != nil {
+		return err
+	}
+	s.changes, s.reset = nil, true
+	return nil
+}
+
+// track records the change of a chunk for the next Persist
+func (s *indexedStore) track(id string, chunk *IndexedChunk) {
+	if s.changes == nil {
+		s.changes = make(map[string]*IndexedChunk)
+	}
+	s.changes[id] = chunk
+}
+
+func (s *indexedStore) Persist() error {
+	var index *KeywordIndex
+	var err error
+	if !s.reset {
+		if len(s.changes) == 0 {
+			if _, err := os.Stat(s.indexPath); err == nil {
+				return s.Store.Persist()
+			}
+		}
+		if index, err = LoadKeywordIndex(s.indexPath); err != nil {
+			return err
+		}
+	}
+	if index == nil {
+		// The bbolt store is read before Persist closes it
+		if index, err = BuildKeywordIndex(s.Store); err != nil {
+			return err
+		}
+	} else {
+		for id, chunk := range s.changes {
+			if chunk == nil {
+				delete(index.Chunks, id)
+			} else {
+				index.Chunks[id] = *chunk
+			}
+		}
+	}
+
+	if err := s.Store.Persist(); err != nil {
+		return err
+	}
+	if err := index.Save(s.indexPath); err != nil {
+		return err
+	}
+	s.changes, s.reset = nil, false
+	return nil
+}
+
+// KeywordIndexOf returns the keyword index of the agent's vector store. Stores persisted before the
+// keyword index existed are indexed in memory.
+func KeywordIndexOf(agent *agents.Agent) (*KeywordIndex, error) {
+	store, ok := AgentStore(agent)
+	if !ok {
+		return nil, nil
+	}
+	if indexed, ok := store.(*indexedStore); ok {
+		index, err := LoadKeywordIndex(indexed.indexPath)
+		if err != nil || index != nil {
+			return index, err
+		}
+	}
+	return BuildKeywordIndex(store)
+}
+
+// jsonStore is the default vector store: a JSON file loaded in memory and rewritten by Persist
+type jsonStore struct {
+	*budgierag.MemoryVectorStore
+	path string
+}
+
+// loadJSONStore loads the records of a JSON store file, the store starts empty when the file does not exist
+func loadJSONStore(path string) (*jsonStore, error) {
+	store := &jsonStore{
+		MemoryVectorStore: &budgierag.MemoryVectorStore{Records: make(map[string]budgierag.VectorRecord)},
+		path:              path,
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, store.MemoryVectorStore); err != nil {
+		return nil, err
+	}
+	if store.Records == nil {
+		store.Records = make(map[string]budgierag.VectorRecord)
+	}
+	return store, nil
+}
+
+func (s *jsonStore) ForEach(fn func(record budgierag.VectorRecord) error) error {
+	for _, record := range s.Records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *jsonStore) Get(id string) (budgierag.VectorRecord, bool, error) {
+	record, ok := s.Records[id]
+	return record, ok, nil
+}
+
+func (s *jsonStore) Delete(ids ...string) error {
+	for _, id := range ids {
+		delete(s.Records, id)
+	}
+	return nil
+}
+
+func (s *jsonStore) Reset() error {
+	s.Records = make(map[string]budgierag.VectorRecord)
+	return nil
+}
+
+func (s *jsonStore) Persist() error {
+	data, err := json.MarshalIndent(s.MemoryVectorStore, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+func (s *jsonStore) Close() error {
+	return nil
+}
@@ -0,0 +1,129 @@
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+)
+
+// builtinRules are the regular expressions of the built-in rules (redact.builtins). When a regular expression
+// has a group, only the group is masked (e.g. the value of a password assignment, not its name).
+var builtinRules = map[string][]*regexp.Regexp{
+	config.RedactPrivateKeys: {
+		regexp.MustCompile(`(?s)-----BEGIN[A-Z ]*PRIVATE KEY-----.*?-----END[A-Z ]*PRIVATE KEY-----`),
+	},
+	config.RedactAPIKeys: {
+		// OpenAI, Anthropic
+		regexp.MustCompile(`\bsk-[A-Za-z0-9_-]{20,}`),
+		// AWS access key IDs
+		regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+		// GitHub, GitLab
+		regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,}|glpat-[A-Za-z0-9_-]{20,})`),
+		// Slack
+		regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+		// Google
+		regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`),
+		// Stripe
+		regexp.MustCompile(`\b[rs]k_(?:live|test)_[0-9A-Za-z]{20,}`),
+		// Hugging Face
+		regexp.MustCompile(`\bhf_[A-Za-z0-9]{30,}`),
+		// JSON Web Tokens
+		regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	},
+	config.RedactSecrets: {
+		// password=..., "api_key": "...", DB_PASSWORD: ..., client-secret ...
+		regexp.MustCompile(`(?i)[a-z0-9_.-]*(?:password|passwd|secret|token|api[_-]?key|access[_-]?key)[a-z0-9_.-]*["']?\s*[:=]\s*["']?([^\s"',;()\[\]]{6,})`),
+		regexp.MustCompile(`(?i)\bBearer\s+([A-Za-z0-9._~+/=-]{16,})`),
+	},
+	config.RedactEmails: {
+		regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+	},
+}
+
+// builtinOrder is the order the built-in rules are applied in: the private keys first, as they contain
+// the other secrets
+var builtinOrder = []string{config.RedactPrivateKeys, config.RedactAPIKeys, config.RedactSecrets, config.RedactEmails}
+
+// Redaction is a value masked in a text
+type Redaction struct {
+	Rule  string
+	Value string
+}
+
+// rule is a compiled redaction rule
+type rule struct {
+	name        string
+	regex       *regexp.Regexp
+	replacement string
+}
+
+// Redactor masks the matches of the redaction rules of the config
+type Redactor struct {
+	rules []rule
+}
+
+// New compiles the redaction rules of the config: its built-in rules, then its patterns
+func New(redactConfig config.RedactConfig) (*Redactor, error) {
+	redactor := &Redactor{}
+	for _, name := range builtinOrder {
+		if !slices.Contains(redactConfig.Builtins, name) {
+			continue
+		}
+		for _, regex := range builtinRules[name] {
+			redactor.rules = append(redactor.rules, rule{name: name, regex: regex, replacement: replacement(name, "")})
+		}
+	}
+	for _, pattern := range redactConfig.Patterns {
+		regex, err := regexp.Compile(pattern.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex of the redact pattern %s: %w", pattern.Name, err)
+		}
+		redactor.rules = append(redactor.rules, rule{name: pattern.Name, regex: regex, replacement: replacement(pattern.Name, pattern.Replacement)})
+	}
+	return redactor, nil
+}
+
+// Empty reports whether the redactor has no rule
+func (r *Redactor) Empty() bool {
+	return len(r.rules) == 0
+}
+
+// Redact masks the matches of the rules in a text, and returns the masked values
+func (r *Redactor) Redact(text string) (string, []Redaction) {
+	var redactions []Redaction
+	for _, rule := range r.rules {
+		matches := rule.regex.FindAllStringSubmatchIndex(text, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		var redacted strings.Builder
+		last := 0
+		for _, match := range matches {
+			start, end := match[0], match[1]
+			if len(match) > 2 {
+				start, end = match[2], match[3]
+			}
+			if start < 0 || start == end {
+				continue
+			}
+			redacted.WriteString(text[last:start])
+			redacted.WriteString(rule.replacement)
+			last = end
+			redactions = append(redactions, Redaction{Rule: rule.name, Value: text[start:end]})
+		}
+		redacted.WriteString(text[last:])
+		text = redacted.String()
+	}
+	return text, redactions
+}
+
+// replacement returns the text replacing the matches of a rule
+func replacement(name, custom string) string {
+	if custom != "" {
+		return custom
+	}
+	return "[REDACTED:" + name + "]"
+}
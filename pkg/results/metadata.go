@@ -0,0 +1,96 @@
+package results
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Metadata describes the conditions which produced a result. It is written as the YAML front-matter
+// of the result file: the hashes identify the system prompt, the effective config and the embeddings
+// store, and the snapshots keep a copy of the system prompt and config to reproduce the answer.
+type Metadata struct {
+	Date                 time.Time
+	Provider             string
+	Model                string
+	Profile              string
+	Temperature          float64
+	Seed                 *int64
+	EmbeddingModel       string
+	SystemPrompt         string
+	SystemPromptSnapshot string
+	Config               string
+	ConfigSnapshot       string
+	Embeddings           string
+	Attachment           string
+	// Question is the question answered, and Sources the ids of the RAG chunks given to the model
+	Question string
+	Sources  []string
+	// Partial marks the result file of an answer still streaming, or interrupted
+	Partial bool
+}
+
+// Hash returns the "sha256:<hex>" digest of a content
+func Hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// SaveSnapshot writes a content to <dir>/<name>-<short hash><ext> unless it already exists
+// (snapshots are content-addressed, identical contents share the same file) and returns its path
+func SaveSnapshot(dir, name, ext string, content []byte) (string, error) {
+	sum := sha256.Sum256(content)
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", name, hex.EncodeToString(sum[:6]), ext))
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// FrontMatter renders the metadata as a YAML front-matter block (the empty fields are omitted)
+func (m Metadata) FrontMatter() string {
+	var builder strings.Builder
+	builder.WriteString("---\n")
+	builder.WriteString("date: " + m.Date.Format(time.RFC3339) + "\n")
+	field := func(key, value string) {
+		if value != "" {
+			builder.WriteString(fmt.Sprintf("%s: %q\n", key, value))
+		}
+	}
+	field("question", m.Question)
+	field("provider", m.Provider)
+	field("model", m.Model)
+	field("profile", m.Profile)
+	builder.WriteString(fmt.Sprintf("temperature: %g\n", m.Temperature))
+	if m.Seed != nil {
+		builder.WriteString(fmt.Sprintf("seed: %d\n", *m.Seed))
+	}
+	field("embedding-model", m.EmbeddingModel)
+	field("system-prompt", m.SystemPrompt)
+	field("system-prompt-snapshot", filepath.ToSlash(m.SystemPromptSnapshot))
+	field("config", m.Config)
+	field("config-snapshot", filepath.ToSlash(m.ConfigSnapshot))
+	field("embeddings", m.Embeddings)
+	field("attachment", m.Attachment)
+	if len(m.Sources) > 0 {
+		builder.WriteString("sources:\n")
+		for _, source := range m.Sources {
+			builder.WriteString(fmt.Sprintf("  - %q\n", source))
+		}
+	}
+	if m.Partial {
+		builder.WriteString("partial: true\n")
+	}
+	builder.WriteString("---\n\n")
+	return builder.String()
+}
@@ -0,0 +1,104 @@
+package results
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/budgies-nest/budgie-cli/pkg/prompts"
+)
+
+// DefaultNameTemplate is the name of the result files when no output-name is set
+const DefaultNameTemplate = "result-{{timestamp}}.md"
+
+// maxSlugLength is the maximum length of the {{slug}} of a question
+const maxSlugLength = 60
+
+// NameVariables are the variables of the output-name templates
+var NameVariables = []string{"date", "time", "timestamp", "slug", "model", "profile", "session"}
+
+// CheckNameTemplate validates an output-name template: a file name using only the known variables
+func CheckNameTemplate(template string) error {
+	if strings.ContainsAny(template, `/\`) {
+		return fmt.Errorf("invalid output name %q: it must be a file name (use results-layout to organize the result files in folders)", template)
+	}
+	for _, variable := range prompts.Variables(template) {
+		if !slices.Contains(NameVariables, variable) {
+			return fmt.Errorf("unknown variable {{%s}} in output name %q (supported: %s)", variable, template, strings.Join(NameVariables, ", "))
+		}
+	}
+	return nil
+}
+
+// FileName fills an output-name template (DefaultNameTemplate when empty) with the date of the answer, the
+// slug of the question and the values of the other variables. The .md extension is added when missing.
+func FileName(template string, now time.Time, question string, values map[string]string) string {
+	if template == "" {
+		template = DefaultNameTemplate
+	}
+	filled := map[string]string{
+		"date":      now.Format("2006-01-02"),
+		"time":      now.Format("15-04-05"),
+		"timestamp": now.Format("2006-01-02-15-04-05"),
+		"slug":      Slug(question),
+	}
+	for key, value := range values {
+		filled[key] = safeName(value)
+	}
+	name := prompts.Fill(template, filled)
+	if !strings.HasSuffix(name, ".md") {
+		name += ".md"
+	}
+	return name
+}
+
+// Slug turns a question into a short file name: its words in lowercase joined by dashes ("answer" when empty)
+func Slug(question string) string {
+	words := strings.FieldsFunc(strings.ToLower(question), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	slug := ""
+	for _, word := range words {
+		if len(slug)+len(word)+1 > maxSlugLength {
+			break
+		}
+		if slug != "" {
+			slug += "-"
+		}
+		slug += word
+	}
+	if slug == "" {
+		return "answer"
+	}
+	return slug
+}
+
+// UniquePath returns the path itself when no file exists there, otherwise the first free
+// <name>-2<ext>, <name>-3<ext>... next to it
+func UniquePath(path string) string {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return path
+	}
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d%s", base, i, ext)
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			return candidate
+		}
+	}
+}
+
+// safeName replaces the characters of a value which are not allowed in file names
+func safeName(value string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>| `, r) {
+			return '-'
+		}
+		return r
+	}, value)
+}
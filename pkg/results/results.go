@@ -0,0 +1,136 @@
+package results
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Result file layouts
+const (
+	Flat    = "flat"
+	Date    = "date"
+	Session = "session"
+)
+
+// sessionsDir is the folder (inside the output directory) holding the per-session result folders
+const sessionsDir = "sessions"
+
+// Dir returns the directory where a result file is written, according to the layout:
+// "flat" (default) writes to <output>, "date" to <output>/YYYY/MM/DD
+// and "session" to <output>/sessions/<session> (<output>/YYYY/MM/DD without a session)
+func Dir(output, layout, session string, now time.Time) (string, error) {
+	switch layout {
+	case Flat, "":
+		return output, nil
+	case Date:
+		return filepath.Join(output, now.Format("2006"), now.Format("01"), now.Format("02")), nil
+	case Session:
+		if session == "" {
+			return Dir(output, Date, "", now)
+		}
+		return filepath.Join(output, sessionsDir, session), nil
+	default:
+		return "", fmt.Errorf("unknown results layout %q (supported: flat, date, session)", layout)
+	}
+}
+
+// ParseAge parses an age such as "30d", "2w" or any Go duration ("12h", "90m")
+func ParseAge(age string) (time.Duration, error) {
+	age = strings.TrimSpace(age)
+	units := map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+	}
+	for suffix, unit := range units {
+		if value, ok := strings.CutSuffix(age, suffix); ok {
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", age)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+
+	duration, err := time.ParseDuration(age)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q (use e.g. 30d, 2w or 12h)", age)
+	}
+	return duration, nil
+}
+
+// GC removes the result files under root last modified before the cutoff, then the folders left empty by
+// the removal. Only the given files (the result files generated by budgie) are removed: the other files of
+// root (notes, docs, result-*.md files of the user) are left alone. With dryRun, nothing is removed.
+// It returns the removed (or removable) files.
+func GC(root string, files []string, cutoff time.Time, dryRun bool) ([]string, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	dirs := make(map[string]bool)
+	for _, path := range files {
+		rel, err := filepath.Rel(absRoot, path)
+		if err != nil || !filepath.IsLocal(rel) {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		if !dryRun {
+			if err := os.Remove(path); err != nil {
+				return removed, err
+			}
+		}
+		removed = append(removed, path)
+		for dir := filepath.Dir(path); dir != absRoot; dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	// Remove the folders left empty, deepest first (only folders of the dated/session layouts)
+	if !dryRun {
+		sorted := slices.SortedFunc(maps.Keys(dirs), func(a, b string) int { return len(b) - len(a) })
+		for _, dir := range sorted {
+			if !layoutDir(absRoot, dir) {
+				continue
+			}
+			entries, err := os.ReadDir(dir)
+			if err == nil && len(entries) == 0 {
+				os.Remove(dir)
+			}
+		}
+	}
+
+	return removed, nil
+}
+
+// layoutDir reports whether dir is a folder created by the date or session layouts
+func layoutDir(root, dir string) bool {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return false
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	if parts[0] == sessionsDir {
+		return len(parts) <= 2
+	}
+	if len(parts) > 3 {
+		return false
+	}
+	for _, part := range parts {
+		if _, err := strconv.Atoi(part); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,163 @@
+package results
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDir(t *testing.T) {
+	now := time.Date(2026, 3, 7, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		layout  string
+		session string
+		want    string
+	}{
+		{"", "", "out"},
+		{Flat, "work", "out"},
+		{Date, "work", filepath.Join("out", "2026", "03", "07")},
+		{Session, "work", filepath.Join("out", "sessions", "work")},
+		{Session, "", filepath.Join("out", "2026", "03", "07")},
+	}
+	for _, test := range tests {
+		dir, err := Dir("out", test.layout, test.session, now)
+		if err != nil || dir != test.want {
+			t.Errorf("Dir(%q, %q) = %q, %v, want %q", test.layout, test.session, dir, err, test.want)
+		}
+	}
+	if _, err := Dir("out", "monthly", "", now); err == nil {
+		t.Error("an unknown layout was accepted")
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		invalid bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{" 2w ", 14 * 24 * time.Hour, false},
+		{"0d", 0, false},
+		{"12h", 12 * time.Hour, false},
+		{"90m", 90 * time.Minute, false},
+		{"-3d", 0, true},
+		{"d", 0, true},
+		{"1.5d", 0, true},
+		{"soon", 0, true},
+		{"", 0, true},
+	}
+	for _, test := range tests {
+		age, err := ParseAge(test.age)
+		if test.invalid {
+			if err == nil {
+				t.Errorf("ParseAge(%q) = %v, want an error", test.age, age)
+			}
+			continue
+		}
+		if err != nil || age != test.want {
+			t.Errorf("ParseAge(%q) = %v, %v, want %v", test.age, age, err, test.want)
+		}
+	}
+}
+
+func TestLayoutDir(t *testing.T) {
+	root := filepath.FromSlash("/project/results")
+	tests := []struct {
+		dir  string
+		want bool
+	}{
+		{"2026", true},
+		{"2026/03", true},
+		{"2026/03/07", true},
+		{"2026/03/07/extra", false},
+		{"sessions", true},
+		{"sessions/work", true},
+		{"sessions/work/notes", false},
+		{"notes", false},
+		{"2026/drafts", false},
+	}
+	for _, test := range tests {
+		dir := filepath.Join(root, filepath.FromSlash(test.dir))
+		if got := layoutDir(root, dir); got != test.want {
+			t.Errorf("layoutDir(%q) = %v, want %v", test.dir, got, test.want)
+		}
+	}
+}
+
+// writeFile creates a file with the given modification time
+func writeFile(t *testing.T, path string, modified time.Time) string {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("answer"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// Only the recorded result files older than the cutoff are removed, then the layout folders left empty
+func TestGC(t *testing.T) {
+	for _, dryRun := range []bool{false, true} {
+		name := "remove"
+		if dryRun {
+			name = "dry run"
+		}
+		t.Run(name, func(t *testing.T) {
+			base := t.TempDir()
+			root := filepath.Join(base, "results")
+			old := time.Now().Add(-60 * 24 * time.Hour)
+			recent := time.Now().Add(-time.Hour)
+
+			dated := writeFile(t, filepath.Join(root, "2026", "01", "05", "result-1.md"), old)
+			flat := writeFile(t, filepath.Join(root, "result-2.md"), old)
+			fresh := writeFile(t, filepath.Join(root, "result-3.md"), recent)
+			userFile := writeFile(t, filepath.Join(root, "result-notes.md"), old)
+			notesDir := writeFile(t, filepath.Join(root, "notes", "result-4.md"), old)
+			outside := writeFile(t, filepath.Join(base, "elsewhere", "result-5.md"), old)
+
+			recorded := []string{dated, flat, fresh, notesDir, outside, filepath.Join(root, "gone.md")}
+			removed, err := GC(root, recorded, time.Now().Add(-30*24*time.Hour), dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			slices.Sort(removed)
+			want := []string{notesDir, dated, flat}
+			slices.Sort(want)
+			if !slices.Equal(removed, want) {
+				t.Fatalf("removed %v, want %v", removed, want)
+			}
+
+			for _, path := range want {
+				if exists(path) == !dryRun {
+					t.Errorf("%s exists = %v after the gc (dry run: %v)", path, exists(path), dryRun)
+				}
+			}
+			for _, path := range []string{fresh, userFile, outside} {
+				if !exists(path) {
+					t.Errorf("%s was removed", path)
+				}
+			}
+
+			// The empty dated folders are removed, the folders of the user are kept
+			if exists(filepath.Join(root, "2026")) == !dryRun {
+				t.Errorf("the empty dated folders exist = %v (dry run: %v)", exists(filepath.Join(root, "2026")), dryRun)
+			}
+			if !exists(filepath.Join(root, "notes")) {
+				t.Error("the notes folder of the user was removed")
+			}
+		})
+	}
+}
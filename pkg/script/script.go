@@ -0,0 +1,101 @@
+package script
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Script is a conversation file: the ordered user turns of a scripted multi-turn run
+type Script struct {
+	// Title heads the transcript (the name of the file without extension when empty)
+	Title string `yaml:"title"`
+	// System replaces the system instructions files (or directories) of the run
+	System Files `yaml:"system"`
+	// RAG is the default RAG mode of the turns
+	RAG bool `yaml:"rag"`
+	// Transcript is the path of the transcript (a result file path when empty)
+	Transcript string `yaml:"transcript"`
+	Turns      []Turn `yaml:"turns"`
+}
+
+// Turn is a user turn of a script
+type Turn struct {
+	Question string `yaml:"question"`
+	// Use lists the files added to the conversation as system messages before the question
+	Use Files `yaml:"use"`
+	// RAG overrides the RAG mode of the script for this turn
+	RAG *bool `yaml:"rag"`
+}
+
+// Files is a list of file paths, written as a YAML list or as a single path
+type Files []string
+
+// UnmarshalYAML accepts a single path as well as a list
+func (f *Files) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*f = Files{node.Value}
+		return nil
+	}
+	var paths []string
+	if err := node.Decode(&paths); err != nil {
+		return err
+	}
+	*f = paths
+	return nil
+}
+
+// Load reads a conversation file. The relative paths it holds (system and use files, transcript) are
+// resolved against its directory, so a script gives the same run wherever budgie is started.
+func Load(path string) (*Script, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var script Script
+	if err := yaml.Unmarshal(data, &script); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	if len(script.Turns) == 0 {
+		return nil, fmt.Errorf("%s has no turns", path)
+	}
+	for i, turn := range script.Turns {
+		if strings.TrimSpace(turn.Question) == "" {
+			return nil, fmt.Errorf("turn %d of %s has no question", i+1, path)
+		}
+	}
+
+	if script.Title == "" {
+		script.Title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	dir := filepath.Dir(path)
+	for i, file := range script.System {
+		script.System[i] = resolve(dir, file)
+	}
+	script.Transcript = resolve(dir, script.Transcript)
+	for i := range script.Turns {
+		for j, file := range script.Turns[i].Use {
+			script.Turns[i].Use[j] = resolve(dir, file)
+		}
+	}
+	return &script, nil
+}
+
+// UsesRAG reports whether a turn runs the RAG search
+func (s *Script) UsesRAG(turn Turn) bool {
+	if turn.RAG != nil {
+		return *turn.RAG
+	}
+	return s.RAG
+}
+
+// resolve returns a path relative to dir (empty and absolute paths are kept)
+func resolve(dir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/openai/openai-go"
+)
+
+// Session represents a saved interactive conversation
+type Session struct {
+	Name     string                                   `json:"name"`
+	SavedAt  time.Time                                `json:"saved-at"`
+	Messages []openai.ChatCompletionMessageParamUnion `json:"messages"`
+}
+
+// Path returns the path of the session file for the given name
+func Path(dir, name string) string {
+	return filepath.Join(dir, name+".json")
+}
+
+// validateName rejects session names that would escape the sessions directory
+func validateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("session name is required")
+	}
+	if strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return fmt.Errorf("invalid session name: %s", name)
+	}
+	return nil
+}
+
+// Save writes the conversation history to <dir>/<name>.json
+func Save(dir, name string, messages []openai.ChatCompletionMessageParamUnion) (string, error) {
+	if err := validateName(name); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating sessions directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(Session{
+		Name:     name,
+		SavedAt:  time.Now(),
+		Messages: messages,
+	}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding session: %w", err)
+	}
+
+	path := Path(dir, name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("error writing session file: %w", err)
+	}
+	return path, nil
+}
+
+// Load reads the conversation history saved in <dir>/<name>.json
+func Load(dir, name string) (*Session, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(Path(dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("error decoding session %s: %w", name, err)
+	}
+	return &s, nil
+}
+
+// Exists reports whether a session with the given name has been saved
+func Exists(dir, name string) bool {
+	_, err := os.Stat(Path(dir, name))
+	return err == nil
+}
@@ -0,0 +1,80 @@
+package sitedocs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The docusaurus config is JavaScript: the docs directory is read from the "path" option of the docs
+// plugin (or of the docs options of the classic preset), "docs" by default
+var (
+	docusaurusDocsRegex     = regexp.MustCompile(`(?s)\bdocs\s*:\s*(\{.*?\}|false)`)
+	docusaurusPathRegex     = regexp.MustCompile(`\bpath\s*:\s*['"\x60]([^'"\x60]+)['"\x60]`)
+	docusaurusPluginRegex   = regexp.MustCompile(`(?s)['"]@docusaurus/plugin-content-docs['"]\s*,\s*(\{.*?\})`)
+	docusaurusCategoryFiles = []string{"_category_.json", "_category_.yml", "_category_.yaml"}
+)
+
+// loadDocusaurus reads docusaurus.config.js: the navigation follows the directory structure, the
+// sections are titled with the label of their _category_ file and the pages with their sidebar label
+func loadDocusaurus(siteConfig string) (*Site, error) {
+	data, err := os.ReadFile(siteConfig)
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+
+	docsDir := "docs"
+	options := ""
+	if match := docusaurusPluginRegex.FindStringSubmatch(content); match != nil {
+		options = match[1]
+	} else if match := docusaurusDocsRegex.FindStringSubmatch(content); match != nil {
+		if match[1] == "false" {
+			return nil, fmt.Errorf("the docs plugin is disabled in %s", siteConfig)
+		}
+		options = match[1]
+	}
+	if match := docusaurusPathRegex.FindStringSubmatch(options); match != nil {
+		docsDir = match[1]
+	}
+
+	site := &Site{Generator: Docusaurus, DocsDir: filepath.Join(filepath.Dir(siteConfig), docsDir)}
+	if _, err := os.Stat(site.DocsDir); err != nil {
+		return nil, fmt.Errorf("docs directory of %s not found: %w", siteConfig, err)
+	}
+
+	site.Pages, err = walkPages(Docusaurus, site.DocsDir, docusaurusCategory, func(path string) string {
+		return pageTitle(path, "sidebar_label", "title")
+	})
+	if err != nil {
+		return nil, err
+	}
+	return site, nil
+}
+
+// docusaurusCategory returns the label of a directory, read from its _category_.json (or .yml) file
+func docusaurusCategory(dir string) string {
+	for _, name := range docusaurusCategoryFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		var category struct {
+			Label string `json:"label" yaml:"label"`
+		}
+		if strings.HasSuffix(name, ".json") {
+			err = json.Unmarshal(data, &category)
+		} else {
+			err = yaml.Unmarshal(data, &category)
+		}
+		if err == nil && category.Label != "" {
+			return category.Label
+		}
+	}
+	return titleFromName(filepath.Base(dir))
+}
@@ -0,0 +1,102 @@
+package sitedocs
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// mkdocsConfig is the part of mkdocs.yml describing the documentation source
+type mkdocsConfig struct {
+	DocsDir string    `yaml:"docs_dir"`
+	Nav     yaml.Node `yaml:"nav"`
+}
+
+// loadMkDocs reads mkdocs.yml: the pages of the nav tree keep their section titles, the pages
+// left out of the nav (or all of them without nav) follow the directory structure
+func loadMkDocs(siteConfig string) (*Site, error) {
+	data, err := os.ReadFile(siteConfig)
+	if err != nil {
+		return nil, err
+	}
+	var siteCfg mkdocsConfig
+	if err := yaml.Unmarshal(data, &siteCfg); err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", siteConfig, err)
+	}
+	if siteCfg.DocsDir == "" {
+		siteCfg.DocsDir = "docs"
+	}
+
+	site := &Site{Generator: MkDocs, DocsDir: filepath.Join(filepath.Dir(siteConfig), siteCfg.DocsDir)}
+	if _, err := os.Stat(site.DocsDir); err != nil {
+		return nil, fmt.Errorf("docs directory of %s not found: %w", siteConfig, err)
+	}
+
+	inNav := make(map[string]bool)
+	if siteCfg.Nav.Kind == yaml.SequenceNode {
+		for _, page := range mkdocsNav(site.DocsDir, &siteCfg.Nav, nil) {
+			if !inNav[page.Path] {
+				inNav[page.Path] = true
+				site.Pages = append(site.Pages, page)
+			}
+		}
+	}
+
+	pages, err := walkPages(MkDocs, site.DocsDir, func(dir string) string {
+		return titleFromName(filepath.Base(dir))
+	}, func(path string) string {
+		return pageTitle(path, "title")
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		if !inNav[page.Path] {
+			site.Pages = append(site.Pages, page)
+		}
+	}
+	return site, nil
+}
+
+// mkdocsNav walks a nav sequence: an item is a page path ("index.md"), a titled page
+// ("Home: index.md"), a section ("Guide: [...]") or an external link (ignored)
+func mkdocsNav(docsDir string, node *yaml.Node, parents []string) []Page {
+	var pages []Page
+	for _, item := range node.Content {
+		switch item.Kind {
+		case yaml.ScalarNode:
+			if page, ok := mkdocsPage(docsDir, "", item.Value, parents); ok {
+				pages = append(pages, page)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(item.Content); i += 2 {
+				title, value := item.Content[i].Value, item.Content[i+1]
+				switch value.Kind {
+				case yaml.ScalarNode:
+					if page, ok := mkdocsPage(docsDir, title, value.Value, parents); ok {
+						pages = append(pages, page)
+					}
+				case yaml.SequenceNode:
+					pages = append(pages, mkdocsNav(docsDir, value, append(append([]string{}, parents...), title))...)
+				}
+			}
+		}
+	}
+	return pages
+}
+
+// mkdocsPage returns the page of a nav entry, titled with its own title when the entry has none
+func mkdocsPage(docsDir, title, pagePath string, parents []string) (Page, bool) {
+	if strings.Contains(pagePath, "://") || !isPage(MkDocs, pagePath) {
+		return Page{}, false
+	}
+	pagePath = path.Clean(strings.TrimPrefix(filepath.ToSlash(pagePath), "/"))
+	if title == "" {
+		title = pageTitle(filepath.Join(docsDir, filepath.FromSlash(pagePath)), "title")
+	}
+	return Page{Path: pagePath, Navigation: append(append([]string{}, parents...), title)}, true
+}
@@ -0,0 +1,227 @@
+package sitedocs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/budgies-nest/budgie-cli/pkg/chunking"
+	"github.com/budgies-nest/budgie-cli/pkg/config"
+)
+
+// Documentation site generators
+const (
+	MkDocs     = "mkdocs"
+	Docusaurus = "docusaurus"
+)
+
+// Site is the documentation of a docs site: its source directory and its pages,
+// each with its position in the site navigation
+type Site struct {
+	Generator string
+	// DocsDir is the docs source directory (relative to the site config directory, joined with it)
+	DocsDir string
+	Pages   []Page
+}
+
+// Page is a page of the site: its path relative to the docs directory and its navigation
+// path, from the top-level section to the page title (e.g. ["User Guide", "Installation"])
+type Page struct {
+	Path       string
+	Navigation []string
+}
+
+// Load reads a site config (mkdocs.yml or docusaurus.config.js) and discovers its pages
+func Load(siteConfig string) (*Site, error) {
+	name := strings.ToLower(filepath.Base(siteConfig))
+	switch {
+	case name == "mkdocs.yml" || name == "mkdocs.yaml":
+		return loadMkDocs(siteConfig)
+	case strings.HasPrefix(name, "docusaurus.config."):
+		return loadDocusaurus(siteConfig)
+	default:
+		return nil, fmt.Errorf("unsupported site config %s (supported: mkdocs.yml, docusaurus.config.js)", siteConfig)
+	}
+}
+
+// ChunkingRules returns the chunking rules suited to the markdown flavor of the generator:
+// both split the pages by heading hierarchy, Docusaurus pages can also be MDX files
+func (s *Site) ChunkingRules() map[string]config.ChunkingRule {
+	rules := map[string]config.ChunkingRule{".md": {Strategy: chunking.Hierarchy}}
+	if s.Generator == Docusaurus {
+		rules[".mdx"] = config.ChunkingRule{Strategy: chunking.Hierarchy}
+	}
+	return rules
+}
+
+// Exclude returns the patterns of the docs files which are not pages
+// (Docusaurus ignores the files and directories starting with an underscore, e.g. partials)
+func (s *Site) Exclude() []string {
+	if s.Generator == Docusaurus {
+		return []string{"_*"}
+	}
+	return nil
+}
+
+// Navigation maps the pages of the docs directory (path relative to it, slash-separated) to their navigation path.
+// It is written by `budgie ingest site-config` and read by generate-embeddings, which records the navigation
+// path of each chunk in the embeddings manifest.
+type Navigation struct {
+	Generator string              `json:"generator"`
+	Docs      string              `json:"docs"`
+	Pages     map[string][]string `json:"pages"`
+}
+
+// NavigationPath returns the path of the navigation file, stored next to the config file
+func NavigationPath(configFile string) string {
+	return filepath.Join(filepath.Dir(configFile), "navigation.json")
+}
+
+// NewNavigation returns the navigation of the site pages
+func (s *Site) NewNavigation() *Navigation {
+	navigation := &Navigation{Generator: s.Generator, Docs: filepath.ToSlash(s.DocsDir), Pages: make(map[string][]string)}
+	for _, page := range s.Pages {
+		navigation.Pages[page.Path] = page.Navigation
+	}
+	return navigation
+}
+
+// LoadNavigation reads a navigation file, it returns nil when the file does not exist
+func LoadNavigation(path string) (*Navigation, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var navigation Navigation
+	if err := json.Unmarshal(data, &navigation); err != nil {
+		return nil, err
+	}
+	return &navigation, nil
+}
+
+// Save writes the navigation file
+func (n *Navigation) Save(path string) error {
+	data, err := json.MarshalIndent(n, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// For returns the navigation path of a docs file (nil when the navigation does not describe the
+// docs directory or the file is not in the navigation)
+func (n *Navigation) For(docsPath, filePath string) []string {
+	if n == nil || filepath.Clean(filepath.FromSlash(n.Docs)) != filepath.Clean(docsPath) {
+		return nil
+	}
+	relPath, err := filepath.Rel(docsPath, filePath)
+	if err != nil {
+		return nil
+	}
+	return n.Pages[filepath.ToSlash(relPath)]
+}
+
+// headingRegex matches the first level-1 heading of a page
+var headingRegex = regexp.MustCompile(`(?m)^#\s+(.+?)\s*#*\s*$`)
+
+// pageTitle returns the title of a page: the title (or label) of its front-matter, its first
+// level-1 heading, or its file name
+func pageTitle(path string, frontMatterKeys ...string) string {
+	content, err := os.ReadFile(path)
+	if err == nil {
+		frontMatter, body := splitFrontMatter(string(content))
+		for _, key := range frontMatterKeys {
+			if value := frontMatter[key]; value != "" {
+				return value
+			}
+		}
+		if match := headingRegex.FindStringSubmatch(body); match != nil {
+			return match[1]
+		}
+	}
+	return titleFromName(filepath.Base(path))
+}
+
+// splitFrontMatter returns the simple "key: value" fields of the YAML front-matter of a page and the rest of the page
+func splitFrontMatter(content string) (map[string]string, string) {
+	fields := make(map[string]string)
+	if !strings.HasPrefix(content, "---\n") && !strings.HasPrefix(content, "---\r\n") {
+		return fields, content
+	}
+	lines := strings.SplitAfter(content, "\n")
+	for i, line := range lines[1:] {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "---" {
+			return fields, strings.Join(lines[i+2:], "")
+		}
+		if key, value, found := strings.Cut(trimmed, ":"); found {
+			fields[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return fields, content
+}
+
+// titleFromName turns a file or directory name into a title ("getting-started.md" -> "Getting started")
+func titleFromName(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	// Drop the ordering prefixes ("01-intro" -> "intro")
+	if trimmed := strings.TrimLeft(strings.TrimLeft(name, "0123456789"), "-_. "); trimmed != "" {
+		name = trimmed
+	}
+	name = strings.NewReplacer("-", " ", "_", " ").Replace(name)
+	return strings.ToUpper(name[:1]) + name[1:]
+}
+
+// isPage reports whether a file is a page of the generator
+func isPage(generator, path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md":
+		return true
+	case ".mdx":
+		return generator == Docusaurus
+	}
+	return false
+}
+
+// walkPages returns the pages of a docs directory, their navigation following the directory
+// structure: dirTitle names the directories and title the pages
+func walkPages(generator, docsDir string, dirTitle func(dir string) string, title func(path string) string) ([]Page, error) {
+	var pages []Page
+	err := filepath.WalkDir(docsDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != docsDir && strings.HasPrefix(entry.Name(), "_") && generator == Docusaurus {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() || !isPage(generator, path) {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(docsDir, path)
+		if err != nil {
+			return err
+		}
+		var navigation []string
+		segments := strings.Split(filepath.ToSlash(relPath), "/")
+		for i := range segments[:len(segments)-1] {
+			navigation = append(navigation, dirTitle(filepath.Join(docsDir, filepath.Join(segments[:i+1]...))))
+		}
+		navigation = append(navigation, title(path))
+		pages = append(pages, Page{Path: filepath.ToSlash(relPath), Navigation: navigation})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error reading the docs directory %s: %w", docsDir, err)
+	}
+	return pages, nil
+}
@@ -0,0 +1,40 @@
+package sniff
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// sniffLength is the number of leading bytes examined to detect binary content
+const sniffLength = 8000
+
+// ErrBinary is returned by ReadTextFile for binary files (images, archives, executables...)
+var ErrBinary = errors.New("binary file")
+
+// Binary reports whether a content is binary rather than text, and returns its detected content type.
+// A content is binary when its leading bytes contain a NUL byte or are not sniffed as text.
+func Binary(data []byte) (bool, string) {
+	head := data[:min(len(data), sniffLength)]
+	contentType := http.DetectContentType(head)
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true, contentType
+	}
+	return !strings.HasPrefix(contentType, "text/"), contentType
+}
+
+// ReadTextFile reads a text file. For binary files, it returns an error wrapping ErrBinary
+// mentioning the detected content type.
+func ReadTextFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if binary, contentType := Binary(data); binary {
+		return "", fmt.Errorf("%w (%s)", ErrBinary, contentType)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,111 @@
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// schemaResource is the address of the compiled schema (it has no URL of its own)
+const schemaResource = "budgie://schema.json"
+
+// invalidName matches the characters not allowed in the schema name sent to the provider
+var invalidName = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// fence matches a Markdown code block around the JSON of an answer
+var fence = regexp.MustCompile("(?s)^```[a-zA-Z]*\\s*\\n(.*?)\\n?```$")
+
+// Schema is a JSON schema the answers must match
+type Schema struct {
+	// Name identifies the schema in the request (the title of the schema or the name of its file)
+	Name string
+	// Document is the parsed schema, as sent to the provider
+	Document map[string]any
+	text     string
+	compiled *jsonschema.Schema
+}
+
+// Load reads a JSON schema: the path of a schema file, or the schema itself when the value starts with {
+func Load(value string) (*Schema, error) {
+	data := []byte(value)
+	name := "answer"
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") {
+		var err error
+		if data, err = os.ReadFile(value); err != nil {
+			return nil, fmt.Errorf("error reading JSON schema: %w", err)
+		}
+		name = strings.TrimSuffix(filepath.Base(value), filepath.Ext(value))
+		name = strings.TrimSuffix(name, ".schema")
+	}
+
+	var document map[string]any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	if title, ok := document["title"].(string); ok && title != "" {
+		name = title
+	}
+	if name = strings.Trim(invalidName.ReplaceAllString(name, "_"), "_"); name == "" {
+		name = "answer"
+	}
+
+	resource, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource(schemaResource, resource); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	compiled, err := compiler.Compile(schemaResource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	text, err := json.MarshalIndent(document, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &Schema{Name: name, Document: document, text: string(text), compiled: compiled}, nil
+}
+
+// Instructions asks the model to answer with JSON matching the schema, for the providers without
+// constrained decoding
+func (s *Schema) Instructions() string {
+	return "Answer only with a JSON value matching the following JSON schema, without any explanation nor code fence:\n\n" + s.text
+}
+
+// Validate checks that an answer is JSON matching the schema (a Markdown code block around it is ignored)
+// and returns the indented JSON
+func (s *Schema) Validate(answer string) (string, error) {
+	answer = strings.TrimSpace(answer)
+	if match := fence.FindStringSubmatch(answer); match != nil {
+		answer = strings.TrimSpace(match[1])
+	}
+	value, err := jsonschema.UnmarshalJSON(strings.NewReader(answer))
+	if err != nil {
+		return "", fmt.Errorf("the answer is not JSON: %w", err)
+	}
+	if err := s.compiled.Validate(value); err != nil {
+		// The first line only names the schema resource, the next ones are the errors
+		lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+		if len(lines) > 1 {
+			lines = lines[1:]
+		}
+		for i, line := range lines {
+			lines[i] = strings.TrimPrefix(strings.TrimSpace(line), "- ")
+		}
+		return "", errors.New(strings.Join(lines, "; "))
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(answer), "", "  "); err != nil {
+		return "", fmt.Errorf("the answer is not JSON: %w", err)
+	}
+	return indented.String(), nil
+}
@@ -0,0 +1,116 @@
+package structured
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const personSchema = `{
+  "type": "object",
+  "properties": {
+    "name": {"type": "string"},
+    "age": {"type": "integer", "minimum": 0}
+  },
+  "required": ["name", "age"],
+  "additionalProperties": false
+}`
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "person.schema.json")
+	if err := os.WriteFile(path, []byte(personSchema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	titled := filepath.Join(dir, "titled.json")
+	if err := os.WriteFile(titled, []byte(`{"title": "Release notes!", "type": "string"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    string
+		invalid bool
+	}{
+		{"inline schema", personSchema, "answer", false},
+		{"inline schema with spaces", "  " + personSchema, "answer", false},
+		{"schema file", path, "person", false},
+		{"title of the schema", titled, "Release_notes", false},
+		{"title without valid characters", `{"title": "???"}`, "answer", false},
+		{"missing file", filepath.Join(dir, "missing.json"), "", true},
+		{"invalid JSON", `{"type": `, "", true},
+		{"invalid schema", `{"type": "text"}`, "", true},
+		{"invalid minimum", `{"type": "integer", "minimum": "zero"}`, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := Load(test.value)
+			if test.invalid {
+				if err == nil {
+					t.Errorf("Load(%q) was accepted", test.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if schema.Name != test.want {
+				t.Errorf("Name = %q, want %q", schema.Name, test.want)
+			}
+			if !strings.Contains(schema.Instructions(), "JSON schema") || schema.Document == nil {
+				t.Errorf("the schema %q was not kept", test.value)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	schema, err := Load(personSchema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		answer string
+		want   string
+		error  string
+	}{
+		{"valid", `{"name":"Ada","age":36}`, "{\n  \"name\": \"Ada\",\n  \"age\": 36\n}", ""},
+		{"surrounding spaces", "\n  {\"name\":\"Ada\",\"age\":36}  \n", "{\n  \"name\": \"Ada\",\n  \"age\": 36\n}", ""},
+		{"json code fence", "```json\n{\"name\":\"Ada\",\"age\":36}\n```", "{\n  \"name\": \"Ada\",\n  \"age\": 36\n}", ""},
+		{"bare code fence", "```\n{\"name\":\"Ada\",\"age\":36}```", "{\n  \"name\": \"Ada\",\n  \"age\": 36\n}", ""},
+		{"not JSON", "Ada is 36 years old", "", "not JSON"},
+		{"text around the fence", "Here it is:\n```json\n{\"name\":\"Ada\",\"age\":36}\n```", "", "not JSON"},
+		{"missing field", `{"name":"Ada"}`, "", "age"},
+		{"wrong type", `{"name":"Ada","age":"36"}`, "", "integer"},
+		{"below the minimum", `{"name":"Ada","age":-1}`, "", "minimum"},
+		{"additional property", `{"name":"Ada","age":36,"email":"ada@example.com"}`, "", "email"},
+		{"not an object", `["Ada", 36]`, "", "object"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			validated, err := schema.Validate(test.answer)
+			if test.error != "" {
+				if err == nil {
+					t.Fatalf("Validate(%q) = %q, want an error", test.answer, validated)
+				}
+				if !strings.Contains(err.Error(), test.error) {
+					t.Errorf("error %q does not mention %q", err, test.error)
+				}
+				if strings.Contains(err.Error(), schemaResource) {
+					t.Errorf("error %q names the schema resource", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if validated != test.want {
+				t.Errorf("Validate(%q) = %q, want %q", test.answer, validated, test.want)
+			}
+		})
+	}
+}
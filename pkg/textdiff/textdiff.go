@@ -0,0 +1,140 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// contextLines is the number of unchanged lines shown around the changes
+const contextLines = 3
+
+// maxCells bounds the size of the comparison table: beyond it, the whole old text is shown as replaced
+const maxCells = 4_000_000
+
+// operation is a line of the diff: kept (' '), removed ('-') or added ('+'), with the position of the line
+// in the old and new texts (0-based index of the next line of each text)
+type operation struct {
+	kind     byte
+	line     string
+	old, new int
+}
+
+// Unified returns the unified diff between two texts (with the --- and +++ headers of oldName and newName),
+// or "" when they are identical
+func Unified(oldName, newName, oldText, newText string) string {
+	if oldText == newText {
+		return ""
+	}
+	operations := compare(lines(oldText), lines(newText))
+
+	var diff strings.Builder
+	fmt.Fprintf(&diff, "--- %s\n+++ %s\n", oldName, newName)
+	for _, hunk := range hunks(operations) {
+		writeHunk(&diff, hunk)
+	}
+	return diff.String()
+}
+
+// lines splits a text in lines, without their newline
+func lines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// compare returns the operations turning a into b, keeping their longest common subsequence of lines
+func compare(a, b []string) []operation {
+	var operations []operation
+	if len(a)*len(b) > maxCells {
+		for i, line := range a {
+			operations = append(operations, operation{'-', line, i, 0})
+		}
+		for j, line := range b {
+			operations = append(operations, operation{'+', line, len(a), j})
+		}
+		return operations
+	}
+
+	// common[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	common := make([][]int32, len(a)+1)
+	for i := range common {
+		common[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			operations = append(operations, operation{' ', a[i], i, j})
+			i, j = i+1, j+1
+		case i < len(a) && (j == len(b) || common[i+1][j] >= common[i][j+1]):
+			// The removed lines come before the added ones
+			operations = append(operations, operation{'-', a[i], i, j})
+			i++
+		default:
+			operations = append(operations, operation{'+', b[j], i, j})
+			j++
+		}
+	}
+	return operations
+}
+
+// hunks groups the changed operations with their context lines, merging the groups which overlap
+func hunks(operations []operation) [][]operation {
+	var groups [][]operation
+	start, end := -1, -1
+	for i, op := range operations {
+		if op.kind == ' ' {
+			continue
+		}
+		if start >= 0 && i-contextLines <= end {
+			end = min(len(operations), i+contextLines+1)
+			continue
+		}
+		if start >= 0 {
+			groups = append(groups, operations[start:end])
+		}
+		start, end = max(0, i-contextLines), min(len(operations), i+contextLines+1)
+	}
+	if start >= 0 {
+		groups = append(groups, operations[start:end])
+	}
+	return groups
+}
+
+// writeHunk writes a hunk with its @@ -start,count +start,count @@ header
+func writeHunk(diff *strings.Builder, hunk []operation) {
+	oldCount, newCount := 0, 0
+	for _, op := range hunk {
+		if op.kind != '+' {
+			oldCount++
+		}
+		if op.kind != '-' {
+			newCount++
+		}
+	}
+	// The start of an empty range is the line before it
+	oldStart, newStart := hunk[0].old, hunk[0].new
+	if oldCount > 0 {
+		oldStart++
+	}
+	if newCount > 0 {
+		newStart++
+	}
+
+	fmt.Fprintf(diff, "@@ -%d,%d +%d,%d @@\n", oldStart, oldCount, newStart, newCount)
+	for _, op := range hunk {
+		diff.WriteByte(op.kind)
+		diff.WriteString(op.line + "\n")
+	}
+}
@@ -0,0 +1,123 @@
+package tokens
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/budgies-nest/budgie/helpers"
+	"github.com/openai/openai-go"
+)
+
+// pieces splits a text like the pre-tokenizer of the tiktoken encodings (cl100k_base): contractions,
+// words with their leading space or punctuation, numbers of up to 3 digits, punctuation runs and spaces.
+// The BPE merges of a piece are then estimated by pieceTokens.
+var pieces = regexp.MustCompile(`(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\pL\pN]?\pL+|\pN{1,3}| ?[^\s\pL\pN]+[\r\n]*|\s*[\r\n]+|\s+`)
+
+// commonWordLength is the length (with the leading space) up to which a word is usually a single token,
+// each letter of a longer (or rarer) word adds about tokensPerLetter token
+const (
+	commonWordLength = 6
+	tokensPerLetter  = 0.2
+)
+
+// Estimate returns an approximate number of tokens of a text, counted like the BPE tokenizers of the
+// models (tiktoken): no tokenizer is available for every model, the estimate is usually within 10-15%
+// for English text and code
+func Estimate(text string) int {
+	total := 0.0
+	for _, piece := range pieces.FindAllString(text, -1) {
+		total += pieceTokens(piece)
+	}
+	return int(math.Ceil(total))
+}
+
+// pieceTokens estimates the number of tokens of a pre-tokenized piece
+func pieceTokens(piece string) float64 {
+	runes := utf8.RuneCountInString(piece)
+	first, _ := utf8.DecodeRuneInString(piece)
+	last, _ := utf8.DecodeLastRuneInString(piece)
+	switch {
+	case unicode.IsLetter(last) && !isASCII(piece):
+		// The scripts without spaces (CJK...) and the accented words take about a token per letter or two
+		return max(1, float64(runes)*2/3)
+	case unicode.IsLetter(last):
+		return 1 + float64(max(0, runes-commonWordLength))*tokensPerLetter
+	case unicode.IsSpace(first) && unicode.IsSpace(last), unicode.IsDigit(first):
+		return 1
+	default:
+		// Runs of punctuation and symbols merge in pairs
+		return max(1, float64(runes-1)/2)
+	}
+}
+
+// isASCII reports whether a text only holds ASCII characters
+func isASCII(text string) bool {
+	for i := 0; i < len(text); i++ {
+		if text[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// EstimateMessages returns the approximate number of tokens of the messages contents
+func EstimateMessages(messages []openai.ChatCompletionMessageParamUnion) int {
+	total := 0
+	for _, message := range messages {
+		fields, err := helpers.MessageToMap(message)
+		if err != nil {
+			continue
+		}
+		total += Estimate(fields["content"])
+	}
+	return total
+}
+
+// Truncate returns the beginning of a text that fits in about limit tokens (cut between two pieces)
+func Truncate(text string, limit int) string {
+	total := 0.0
+	for _, bounds := range pieces.FindAllStringIndex(text, -1) {
+		total += pieceTokens(text[bounds[0]:bounds[1]])
+		if total > float64(limit) {
+			return text[:bounds[0]]
+		}
+	}
+	return text
+}
+
+// Split cuts a text into parts of about limit tokens at most, between two lines when possible (a line
+// longer than the limit is cut between two pieces)
+func Split(text string, limit int) []string {
+	var parts []string
+	var current strings.Builder
+	size := 0
+	flush := func() {
+		if strings.TrimSpace(current.String()) != "" {
+			parts = append(parts, current.String())
+		}
+		current.Reset()
+		size = 0
+	}
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineTokens := Estimate(line)
+		if size+lineTokens > limit {
+			flush()
+		}
+		for lineTokens > limit {
+			head := Truncate(line, limit)
+			if head == "" {
+				break
+			}
+			parts = append(parts, head)
+			line = line[len(head):]
+			lineTokens = Estimate(line)
+		}
+		current.WriteString(line)
+		size += lineTokens
+	}
+	flush()
+	return parts
+}
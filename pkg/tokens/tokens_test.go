@@ -0,0 +1,76 @@
+package tokens
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},
+		{" hello world", 2},
+		{"Hello, world!", 4},
+		{"2026", 2},
+		{"internationalization", 4},
+		{"if err != nil {", 5},
+		{"日本語", 2},
+	}
+	for _, test := range tests {
+		if got := Estimate(test.text); got != test.want {
+			t.Errorf("Estimate(%q) = %d, want %d", test.text, got, test.want)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	text := strings.Repeat(" word", 10)
+	tests := []struct {
+		limit int
+		want  string
+	}{
+		{0, ""},
+		{3, " word word word"},
+		{10, text},
+		{20, text},
+	}
+	for _, test := range tests {
+		got := Truncate(text, test.limit)
+		if got != test.want {
+			t.Errorf("Truncate(%d) = %q, want %q", test.limit, got, test.want)
+		}
+		if Estimate(got) > test.limit {
+			t.Errorf("Truncate(%d) kept %d tokens", test.limit, Estimate(got))
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{"fits", "one two\nthree\n", 10, []string{"one two\nthree\n"}},
+		{"cut between lines", "one two\nthree four\nfive\n", 4, []string{"one two\n", "three four\n", "five\n"}},
+		{"long line cut between pieces", strings.Repeat(" word", 5), 2, []string{" word word", " word word", " word"}},
+		{"blank parts skipped", "\n\n\n", 1, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parts := Split(test.text, test.limit)
+			if strings.Join(parts, "|") != strings.Join(test.want, "|") || len(parts) != len(test.want) {
+				t.Fatalf("Split = %q, want %q", parts, test.want)
+			}
+			for _, part := range parts {
+				if Estimate(part) > test.limit {
+					t.Errorf("part %q is %d tokens, over the limit of %d", part, Estimate(part), test.limit)
+				}
+			}
+		})
+	}
+}
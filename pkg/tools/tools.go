@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/shared"
+)
+
+// Kinds of tools
+const (
+	// KindFile reads a file of the project
+	KindFile = "file"
+	// KindFetch fetches the content of a URL
+	KindFetch = "fetch"
+	// KindShell runs a shell command: a fixed command line (with {{parameter}} placeholders) or any command
+	KindShell = "shell"
+)
+
+// Tool is a tool the model can call during `budgie ask --allow-tools`, defined in .budgie/tools.json:
+//
+//	{
+//	  "tools": [
+//	    {"name": "read_file", "kind": "file", "description": "Read a file of the project", "trusted": true},
+//	    {"name": "fetch_url", "kind": "fetch", "description": "Fetch a web page"},
+//	    {"name": "run_tests", "kind": "shell", "description": "Run the tests of a package", "command": "go test {{package}}",
+//	     "parameters": {"type": "object", "properties": {"package": {"type": "string"}}, "required": ["package"]}}
+//	  ]
+//	}
+//
+// Each call is approved interactively unless the tool is trusted.
+type Tool struct {
+	Name        string         `json:"name"`
+	Kind        string         `json:"kind"`
+	Description string         `json:"description,omitempty"`
+	Command     string         `json:"command,omitempty"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+	Trusted     bool           `json:"trusted,omitempty"`
+}
+
+// File is the content of the tools file
+type File struct {
+	Tools []Tool `json:"tools"`
+}
+
+// nameRegex matches the valid tool names (function names of the chat completion API)
+var nameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// placeholderRegex matches the {{parameter}} placeholders of a shell command line
+var placeholderRegex = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_-]+)\s*\}\}`)
+
+// Path returns the path of the tools file, stored next to the config file
+func Path(configFile string) string {
+	return filepath.Join(filepath.Dir(configFile), "tools.json")
+}
+
+// Load reads and validates a tools file. It returns no tools when the file does not exist.
+func Load(path string) ([]Tool, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var file File
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid tools file %s: %w", path, err)
+	}
+
+	names := make(map[string]bool)
+	for i, tool := range file.Tools {
+		if !nameRegex.MatchString(tool.Name) {
+			return nil, fmt.Errorf("invalid tools file %s: tool %d: invalid name %q (letters, digits, _ and -)", path, i+1, tool.Name)
+		}
+		if names[tool.Name] {
+			return nil, fmt.Errorf("invalid tools file %s: duplicate tool %s", path, tool.Name)
+		}
+		names[tool.Name] = true
+		switch tool.Kind {
+		case KindFile, KindFetch, KindShell:
+		default:
+			return nil, fmt.Errorf("invalid tools file %s: tool %s: unknown kind %q (use %s, %s or %s)", path, tool.Name, tool.Kind, KindFile, KindFetch, KindShell)
+		}
+	}
+	return file.Tools, nil
+}
+
+// Params returns the definitions of the tools for the chat completion API
+func Params(tools []Tool) []openai.ChatCompletionToolParam {
+	params := make([]openai.ChatCompletionToolParam, 0, len(tools))
+	for _, tool := range tools {
+		params = append(params, openai.ChatCompletionToolParam{
+			Function: shared.FunctionDefinitionParam{
+				Name:        tool.Name,
+				Description: openai.String(tool.description()),
+				Parameters:  tool.parameters(),
+			},
+		})
+	}
+	return params
+}
+
+// Find returns the tool with the given name
+func Find(tools []Tool, name string) (Tool, bool) {
+	for _, tool := range tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return Tool{}, false
+}
+
+// description returns the description of the tool, a default one for its kind when not set
+func (t Tool) description() string {
+	if t.Description != "" {
+		return t.Description
+	}
+	switch t.Kind {
+	case KindFile:
+		return "Read a file of the project"
+	case KindFetch:
+		return "Fetch the content of a URL"
+	}
+	if t.Command != "" {
+		return "Run the shell command: " + t.Command
+	}
+	return "Run a shell command"
+}
+
+// parameters returns the JSON schema of the tool parameters: the declared one, or the one of its kind
+func (t Tool) parameters() shared.FunctionParameters {
+	if t.Parameters != nil {
+		return t.Parameters
+	}
+	property := func(name, description string) shared.FunctionParameters {
+		return shared.FunctionParameters{
+			"type": "object",
+			"properties": map[string]any{
+				name: map[string]any{"type": "string", "description": description},
+			},
+			"required": []string{name},
+		}
+	}
+	switch t.Kind {
+	case KindFile:
+		return property("path", "Path of the file, relative to the current directory")
+	case KindFetch:
+		return property("url", "URL to fetch (http or https)")
+	}
+	if t.Command != "" {
+		// A fixed command line takes one string parameter per placeholder
+		properties := make(map[string]any)
+		var required []string
+		for _, match := range placeholderRegex.FindAllStringSubmatch(t.Command, -1) {
+			if _, found := properties[match[1]]; !found {
+				properties[match[1]] = map[string]any{"type": "string"}
+				required = append(required, match[1])
+			}
+		}
+		return shared.FunctionParameters{"type": "object", "properties": properties, "required": required}
+	}
+	return property("command", "Shell command line to run")
+}
+
+// CommandLine returns the shell command line of a call of a shell tool: the fixed command line with its
+// placeholders replaced by the shell-quoted arguments, or the command argument
+func (t Tool) CommandLine(args map[string]any) (string, error) {
+	if t.Command == "" {
+		return StringArg(args, "command")
+	}
+	var missing []string
+	commandLine := placeholderRegex.ReplaceAllStringFunc(t.Command, func(placeholder string) string {
+		name := placeholderRegex.FindStringSubmatch(placeholder)[1]
+		value, found := args[name]
+		if !found {
+			missing = append(missing, name)
+			return ""
+		}
+		return shellQuote(fmt.Sprint(value))
+	})
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return "", fmt.Errorf("missing argument(s): %s", strings.Join(missing, ", "))
+	}
+	return commandLine, nil
+}
+
+// StringArg returns a required string argument of a call
+func StringArg(args map[string]any, name string) (string, error) {
+	value, ok := args[name].(string)
+	if !ok || strings.TrimSpace(value) == "" {
+		return "", fmt.Errorf("missing argument: %s", name)
+	}
+	return value, nil
+}
+
+// shellQuote quotes a value for a POSIX shell command line
+func shellQuote(value string) string {
+	if value != "" && !strings.ContainsFunc(value, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,@%+", r))
+	}) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
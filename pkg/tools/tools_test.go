@@ -0,0 +1,103 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"./pkg/rag", "./pkg/rag"},
+		{"key=value,user@host:80", "key=value,user@host:80"},
+		{"", "''"},
+		{"two words", "'two words'"},
+		{"$(rm -rf ~)", "'$(rm -rf ~)'"},
+		{"a;b|c&d", "'a;b|c&d'"},
+		{"`id`", "'`id`'"},
+		{"it's", `'it'\''s'`},
+		{"line\nbreak", "'line\nbreak'"},
+		{"*.go", "'*.go'"},
+	}
+	for _, test := range tests {
+		if got := shellQuote(test.value); got != test.want {
+			t.Errorf("shellQuote(%q) = %s, want %s", test.value, got, test.want)
+		}
+	}
+}
+
+func TestCommandLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		args    map[string]any
+		want    string
+		error   string
+	}{
+		{"placeholder", "go test {{package}}", map[string]any{"package": "./pkg/rag"}, "go test ./pkg/rag", ""},
+		{"spaces in the placeholder", "go test {{ package }} -run {{test}}", map[string]any{"package": "./...", "test": "TestTopK"}, "go test ./... -run TestTopK", ""},
+		{"repeated placeholder", "echo {{name}} {{name}}", map[string]any{"name": "budgie"}, "echo budgie budgie", ""},
+		{"quoted argument", "grep -rn {{pattern}} .", map[string]any{"pattern": "x; rm -rf ~"}, "grep -rn 'x; rm -rf ~' .", ""},
+		{"number argument", "head -n {{lines}} README.md", map[string]any{"lines": 20.0}, "head -n 20 README.md", ""},
+		{"missing arguments", "cp {{source}} {{target}}", map[string]any{}, "", "missing argument(s): source, target"},
+		{"any command", "", map[string]any{"command": "ls -la"}, "ls -la", ""},
+		{"missing command", "", map[string]any{"command": "  "}, "", "missing argument: command"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tool := Tool{Name: "run", Kind: KindShell, Command: test.command}
+			commandLine, err := tool.CommandLine(test.args)
+			if test.error != "" {
+				if err == nil || err.Error() != test.error {
+					t.Errorf("CommandLine = %q, %v, want the error %q", commandLine, err, test.error)
+				}
+				return
+			}
+			if err != nil || commandLine != test.want {
+				t.Errorf("CommandLine = %q, %v, want %q", commandLine, err, test.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		tools   int
+		error   string
+	}{
+		{"valid", `{"tools": [{"name": "read_file", "kind": "file"}, {"name": "run-tests", "kind": "shell", "command": "go test {{package}}"}]}`, 2, ""},
+		{"invalid name", `{"tools": [{"name": "read file", "kind": "file"}]}`, 0, "invalid name"},
+		{"duplicate name", `{"tools": [{"name": "fetch", "kind": "fetch"}, {"name": "fetch", "kind": "fetch"}]}`, 0, "duplicate tool fetch"},
+		{"unknown kind", `{"tools": [{"name": "mail", "kind": "smtp"}]}`, 0, "unknown kind"},
+		{"invalid JSON", `{"tools": [`, 0, "invalid tools file"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.ReplaceAll(test.name, " ", "-")+".json")
+			if err := os.WriteFile(path, []byte(test.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tools, err := Load(path)
+			if test.error != "" {
+				if err == nil || !strings.Contains(err.Error(), test.error) {
+					t.Errorf("Load = %v, want an error about %q", err, test.error)
+				}
+				return
+			}
+			if err != nil || len(tools) != test.tools {
+				t.Errorf("Load = %d tools, %v, want %d tools", len(tools), err, test.tools)
+			}
+		})
+	}
+
+	if tools, err := Load(filepath.Join(dir, "missing.json")); err != nil || tools != nil {
+		t.Errorf("Load of a missing file = %v, %v, want no tools", tools, err)
+	}
+}
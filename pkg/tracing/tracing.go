@@ -0,0 +1,102 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracesPath is the path of the OTLP/HTTP traces endpoint, added to the endpoints given without a path
+const tracesPath = "/v1/traces"
+
+// shutdownTimeout bounds the time spent exporting the last spans when the command ends
+const shutdownTimeout = 5 * time.Second
+
+var (
+	// provider exports the spans, nil when tracing is disabled (the spans are then no-ops)
+	provider *sdktrace.TracerProvider
+	// command is the span of the running command, parent of the spans started without a parent
+	command trace.Span
+)
+
+// Setup exports the spans to the OTLP/HTTP collector at endpoint (e.g. http://localhost:4318).
+// The standard OTEL_EXPORTER_OTLP_* environment variables (e.g. OTEL_EXPORTER_OTLP_HEADERS) configure the exporter.
+func Setup(endpoint, version string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("invalid tracing endpoint %q (expected e.g. http://localhost:4318)", endpoint)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = tracesPath
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), otlptracehttp.WithEndpointURL(u.String()))
+	if err != nil {
+		return err
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(
+			attribute.String("service.name", "budgie"),
+			attribute.String("service.version", version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+
+	// An unreachable collector must not fail the command
+	otel.SetErrorHandler(otel.ErrorHandlerFunc(func(err error) {
+		fmt.Fprintf(os.Stderr, "Warning: tracing: %v\n", err)
+	}))
+	return nil
+}
+
+// StartCommand starts the span of the running command (e.g. "budgie ask"), parent of the other spans
+func StartCommand(name string) {
+	if provider == nil {
+		return
+	}
+	_, command = provider.Tracer("budgie").Start(context.Background(), name)
+}
+
+// Shutdown ends the span of the command (recording err, if any) and exports the remaining spans
+func Shutdown(err error) {
+	if provider == nil {
+		return
+	}
+	if command != nil {
+		End(command, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: tracing: %v\n", err)
+	}
+}
+
+// Start starts a span, child of the span of ctx or, when ctx has none, of the span of the command
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	if command != nil && !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = trace.ContextWithSpan(ctx, command)
+	}
+	return otel.Tracer("budgie").Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End ends a span, marking it as failed when err is not nil
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -1,39 +1,94 @@
 package utils
 
 import (
-	"context"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/eiannone/keyboard"
 )
 
-// SetupEscListener sets up a goroutine to listen for ESC key presses to cancel streaming
-func SetupEscListener(ctx context.Context, cancel context.CancelFunc) {
+// CancelListener stops a streaming completion when the user presses ESC or Ctrl+C, or when the process
+// receives SIGINT or SIGTERM (e.g. without a terminal, or from another process). A nil listener does nothing.
+type CancelListener struct {
+	stop     chan struct{}
+	done     chan struct{}
+	once     sync.Once
+	keyboard bool
+}
+
+// ListenForCancel calls cancel on the first ESC, Ctrl+C, SIGINT or SIGTERM. With interactive set (stdin and
+// stdout are terminals), the keyboard is listened to: the terminal is in raw mode (Ctrl+C is read as a key)
+// until Stop restores it and releases the signals as soon as the streaming ends, so the keyboard and Ctrl+C
+// work as usual between the answers. Otherwise (CI, pipes), or when the keyboard cannot be opened, only the
+// signals cancel the stream. The notice of the cancellation is written to notices (not to the answer).
+func ListenForCancel(cancel func(), interactive bool, notices io.Writer) *CancelListener {
+	l := &CancelListener{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	// A nil channel never receives: without the keyboard, the loop only waits for the signals
+	var keys <-chan keyboard.KeyEvent
+	if interactive {
+		if opened, err := keyboard.GetKeys(10); err == nil {
+			keys, l.keyboard = opened, true
+		}
+	}
+
 	go func() {
-		err := keyboard.Open()
-		if err != nil {
-			return
+		defer close(l.done)
+		defer signal.Stop(signals)
+		if l.keyboard {
+			defer keyboard.Close()
 		}
-		defer keyboard.Close()
 
 		for {
 			select {
-			case <-ctx.Done():
+			case <-l.stop:
+				return
+			case <-signals:
+				fmt.Fprint(notices, "\n🛑 Stream stopped (interrupted)\n")
+				cancel()
 				return
-			default:
-				char, key, err := keyboard.GetKey()
-				if err != nil {
+			case event, ok := <-keys:
+				if !ok {
+					keys = nil
 					continue
 				}
-
-				// Check for ESC key
-				if key == keyboard.KeyEsc || char == 27 {
-					fmt.Print("\n🛑 Stream stopped by user (ESC pressed)\n")
-					cancel()
-					return
+				switch {
+				case event.Key == keyboard.KeyEsc:
+					fmt.Fprint(notices, "\n🛑 Stream stopped by user (ESC pressed)\n")
+				case event.Key == keyboard.KeyCtrlC:
+					fmt.Fprint(notices, "\n🛑 Stream stopped by user (Ctrl+C pressed)\n")
+				default:
+					continue
 				}
+				cancel()
+				return
 			}
 		}
 	}()
+	return l
 }
 
+// Keyboard reports whether ESC and Ctrl+C are read from the keyboard (otherwise only the signals stop the stream)
+func (l *CancelListener) Keyboard() bool {
+	return l != nil && l.keyboard
+}
+
+// Stop stops listening and waits until the terminal is restored, so another program (e.g. the pager or
+// the next prompt) can read the keyboard. It can be called several times.
+func (l *CancelListener) Stop() {
+	if l == nil {
+		return
+	}
+	l.once.Do(func() { close(l.stop) })
+	<-l.done
+}
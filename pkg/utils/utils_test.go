@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// Without a terminal (CI, pipes), SIGINT stops the stream and the notice is written to the notices writer
+func TestListenForCancelPipedSignal(t *testing.T) {
+	cancelled := make(chan struct{})
+	var notices bytes.Buffer
+	listener := ListenForCancel(func() { close(cancelled) }, false, &notices)
+	if listener.Keyboard() {
+		t.Fatal("the keyboard is read without a terminal")
+	}
+
+	if err := syscall.Kill(os.Getpid(), syscall.SIGINT); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SIGINT did not cancel the stream")
+	}
+	listener.Stop()
+
+	if !strings.Contains(notices.String(), "Stream stopped (interrupted)") {
+		t.Errorf("notice = %q, want the interruption notice", notices.String())
+	}
+}
+
+// Stopping the listener releases the signals without cancelling the stream
+func TestListenForCancelStop(t *testing.T) {
+	cancelled := false
+	var notices bytes.Buffer
+	listener := ListenForCancel(func() { cancelled = true }, false, &notices)
+	listener.Stop()
+	listener.Stop()
+
+	if cancelled {
+		t.Error("Stop cancelled the stream")
+	}
+	if notices.Len() > 0 {
+		t.Errorf("notice = %q, want none", notices.String())
+	}
+}
+
+func TestNilCancelListener(t *testing.T) {
+	var listener *CancelListener
+	listener.Stop()
+	if listener.Keyboard() {
+		t.Error("a nil listener reads the keyboard")
+	}
+}
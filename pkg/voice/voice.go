@@ -0,0 +1,133 @@
+package voice
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// fileArgument is replaced by the path of the recorded file in the arguments of a recording command
+const fileArgument = "{file}"
+
+// stopTimeout is the delay given to a recorder to finish its file after the interrupt signal
+const stopTimeout = 5 * time.Second
+
+// Recorder is a command recording the microphone to a WAV file
+type Recorder struct {
+	Name string
+	Args []string
+}
+
+// recorders are the recording commands looked for on the PATH, in order of preference (16 kHz mono, the
+// sample rate of the Whisper models)
+func recorders() []Recorder {
+	ffmpeg := Recorder{Name: "ffmpeg", Args: []string{"-loglevel", "error", "-f", "pulse", "-i", "default", "-ac", "1", "-ar", "16000", "-y", fileArgument}}
+	if runtime.GOOS == "darwin" {
+		ffmpeg.Args = []string{"-loglevel", "error", "-f", "avfoundation", "-i", ":0", "-ac", "1", "-ar", "16000", "-y", fileArgument}
+	}
+	return []Recorder{
+		{Name: "rec", Args: []string{"-q", "-c", "1", "-r", "16000", fileArgument}},
+		{Name: "arecord", Args: []string{"-q", "-f", "S16_LE", "-c", "1", "-r", "16000", fileArgument}},
+		ffmpeg,
+	}
+}
+
+// FindRecorder returns the configured recording command (its words, {file} standing for the output file),
+// or the first recorder found on the PATH: rec (SoX), arecord (ALSA) or ffmpeg
+func FindRecorder(command string) (Recorder, error) {
+	if fields := strings.Fields(command); len(fields) > 0 {
+		if !strings.Contains(command, fileArgument) {
+			return Recorder{}, fmt.Errorf("the recording command %q has no %s argument", command, fileArgument)
+		}
+		return Recorder{Name: fields[0], Args: fields[1:]}, nil
+	}
+	var names []string
+	for _, recorder := range recorders() {
+		if _, err := exec.LookPath(recorder.Name); err == nil {
+			return recorder, nil
+		}
+		names = append(names, recorder.Name)
+	}
+	return Recorder{}, fmt.Errorf("no recorder found (install one of %s, or set voice.record-command)", strings.Join(names, ", "))
+}
+
+// Recording is a running recorder
+type Recording struct {
+	command *exec.Cmd
+	path    string
+	stderr  bytes.Buffer
+	done    chan struct{}
+	err     error
+}
+
+// Start starts recording the microphone to a file
+func (r Recorder) Start(path string) (*Recording, error) {
+	args := make([]string, len(r.Args))
+	for i, arg := range r.Args {
+		args[i] = strings.ReplaceAll(arg, fileArgument, path)
+	}
+	recording := &Recording{path: path, done: make(chan struct{})}
+	recording.command = exec.Command(r.Name, args...)
+	recording.command.Stderr = &recording.stderr
+	if err := recording.command.Start(); err != nil {
+		return nil, fmt.Errorf("error starting %s: %w", r.Name, err)
+	}
+	go func() {
+		recording.err = recording.command.Wait()
+		close(recording.done)
+	}()
+	return recording, nil
+}
+
+// Done is closed when the recorder exits (after Stop, or by itself on a failure)
+func (r *Recording) Done() <-chan struct{} {
+	return r.done
+}
+
+// Stop interrupts the recorder so it finishes the file, and waits for it. The recording fails when the
+// recorder exited by itself or wrote no audio.
+func (r *Recording) Stop() error {
+	select {
+	case <-r.done:
+		return r.failure()
+	default:
+	}
+	// The recorders finish their file on SIGINT (not supported on Windows: the recorder is killed)
+	if err := r.command.Process.Signal(os.Interrupt); err != nil {
+		r.command.Process.Kill()
+	}
+	select {
+	case <-r.done:
+	case <-time.After(stopTimeout):
+		r.command.Process.Kill()
+		<-r.done
+	}
+	// A WAV file is more than its 44-byte header
+	if info, err := os.Stat(r.path); err != nil || info.Size() <= 44 {
+		return r.failure()
+	}
+	return nil
+}
+
+// Cancel stops the recorder without waiting for its file
+func (r *Recording) Cancel() {
+	r.command.Process.Kill()
+	<-r.done
+}
+
+// failure describes why the recorder did not record anything
+func (r *Recording) failure() error {
+	message := strings.TrimSpace(r.stderr.String())
+	if message == "" && r.err != nil {
+		message = r.err.Error()
+	}
+	if message == "" {
+		return errors.New("nothing was recorded")
+	}
+	return fmt.Errorf("%s failed: %s", r.command.Path, message)
+}
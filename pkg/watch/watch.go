@@ -0,0 +1,89 @@
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Run watches root and its subdirectories and calls onChange with the paths modified
+// since the last call, once no new event has been received for the debounce duration.
+// Directories for which skipDir returns true are not watched.
+// Run blocks until the context is cancelled.
+func Run(ctx context.Context, root string, debounce time.Duration, skipDir func(path string) bool, onChange func(paths []string)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	addTree := func(dir string) error {
+		return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if !d.IsDir() {
+				return nil
+			}
+			if path != root && skipDir != nil && skipDir(path) {
+				return filepath.SkipDir
+			}
+			return watcher.Add(path)
+		})
+	}
+
+	if err := addTree(root); err != nil {
+		return err
+	}
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(debounce)
+	timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+
+			// Watch newly created directories too
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if skipDir == nil || !skipDir(event.Name) {
+						addTree(event.Name)
+					}
+					continue
+				}
+			}
+
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+
+			pending[event.Name] = true
+			timer.Reset(debounce)
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			return err
+
+		case <-timer.C:
+			paths := make([]string, 0, len(pending))
+			for path := range pending {
+				paths = append(paths, path)
+			}
+			pending = make(map[string]bool)
+			onChange(paths)
+		}
+	}
+}
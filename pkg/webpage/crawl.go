@@ -0,0 +1,73 @@
+package webpage
+
+import (
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// CrawlOptions are the limits of a crawl
+type CrawlOptions struct {
+	// Depth is the number of links followed from the start page (0 for the start page alone)
+	Depth int
+	// MaxPages is the maximum number of pages fetched
+	MaxPages int
+	Timeout  time.Duration
+	MaxBytes int64
+}
+
+// assetExtensions are the extensions of the links which are not pages (not fetched)
+var assetExtensions = []string{
+	".png", ".jpg", ".jpeg", ".gif", ".svg", ".webp", ".ico", ".css", ".js", ".mjs", ".map", ".pdf", ".zip",
+	".gz", ".tgz", ".tar", ".xml", ".woff", ".woff2", ".ttf", ".eot", ".mp4", ".webm", ".mp3",
+}
+
+// Crawl fetches the start page and the pages it links to on the same host, breadth first, up to the depth
+// and the maximum number of pages. visit is called with each fetched page, or with the error of a page
+// which could not be fetched (the crawl goes on).
+func Crawl(start string, options CrawlOptions, visit func(address string, page *Page, err error)) error {
+	startURL, err := url.Parse(start)
+	if err != nil {
+		return err
+	}
+	host := startURL.Host
+
+	type pending struct {
+		address string
+		depth   int
+	}
+	queue := []pending{{address: start}}
+	seen := []string{strings.TrimSuffix(start, "/")}
+	fetched := 0
+	for len(queue) > 0 && fetched < options.MaxPages {
+		current := queue[0]
+		queue = queue[1:]
+
+		page, err := Fetch(current.address, options.Timeout, options.MaxBytes)
+		fetched++
+		visit(current.address, page, err)
+		if err != nil || current.depth >= options.Depth {
+			continue
+		}
+		// A page redirected to another host is kept, but its links are not followed
+		if finalURL, err := url.Parse(page.URL); err != nil || finalURL.Host != host {
+			continue
+		}
+		for _, link := range page.Links {
+			linkURL, err := url.Parse(link)
+			if err != nil || linkURL.Host != host || slices.Contains(assetExtensions, strings.ToLower(path.Ext(linkURL.Path))) {
+				continue
+			}
+			// The same page with and without trailing slash is fetched once
+			key := strings.TrimSuffix(link, "/")
+			if slices.Contains(seen, key) {
+				continue
+			}
+			seen = append(seen, key)
+			queue = append(queue, pending{address: link, depth: current.depth + 1})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,494 @@
+package webpage
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/budgies-nest/budgie-cli/pkg/sniff"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Page is a fetched web page converted to Markdown
+type Page struct {
+	// URL is the address of the page, after the redirects
+	URL      string
+	Title    string
+	Markdown string
+	// Links are the addresses (http or https, without fragment) of the links of an HTML page, in order
+	Links []string
+}
+
+// Fetch downloads a page (http or https) and converts it to readable Markdown: the HTML pages lose their
+// boilerplate (navigation, headers, footers, scripts...), the text pages (plain text, Markdown, JSON) are
+// kept as is. At most maxBytes of the page are read.
+func Fetch(address string, timeout time.Duration, maxBytes int64) (*Page, error) {
+	parsed, err := url.Parse(address)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("unsupported URL %s (http or https only)", address)
+	}
+
+	client := &http.Client{Timeout: timeout}
+	response, err := client.Get(address)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", address, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 400 {
+		return nil, fmt.Errorf("error fetching %s: %s", address, response.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(response.Body, maxBytes))
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", address, err)
+	}
+
+	page := &Page{URL: response.Request.URL.String()}
+	mediaType, _, _ := mime.ParseMediaType(response.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		page.Title, page.Markdown, err = Convert(string(body), response.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %w", address, err)
+		}
+		page.Links = Links(string(body), response.Request.URL)
+	case strings.HasPrefix(mediaType, "text/") || mediaType == "application/json" || mediaType == "":
+		if binary, contentType := sniff.Binary(body); binary {
+			return nil, fmt.Errorf("%s is not a text page (%s)", address, contentType)
+		}
+		page.Markdown = strings.TrimSpace(string(body))
+	default:
+		return nil, fmt.Errorf("%s is not a text page (%s)", address, mediaType)
+	}
+	if page.Title == "" {
+		page.Title = page.URL
+	}
+	if page.Markdown == "" {
+		return nil, fmt.Errorf("no readable text in %s", address)
+	}
+	return page, nil
+}
+
+// skippedElements are the boilerplate and non-content elements
+var skippedElements = []atom.Atom{
+	atom.Head, atom.Script, atom.Style, atom.Noscript, atom.Nav, atom.Header, atom.Footer, atom.Aside,
+	atom.Form, atom.Button, atom.Select, atom.Input, atom.Textarea, atom.Svg, atom.Iframe, atom.Template,
+	atom.Canvas, atom.Dialog,
+}
+
+// boilerplateNames are the class or id words of the boilerplate containers (e.g. class="md-sidebar")
+var boilerplateNames = []string{"sidebar", "breadcrumb", "breadcrumbs", "navbar", "menu", "cookie", "cookies", "toc", "skip"}
+
+// blockElements are the elements rendered as Markdown blocks
+var blockElements = []atom.Atom{
+	atom.P, atom.Div, atom.Section, atom.Article, atom.Main, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5,
+	atom.H6, atom.Ul, atom.Ol, atom.Li, atom.Pre, atom.Blockquote, atom.Table, atom.Hr, atom.Dl, atom.Dt,
+	atom.Dd, atom.Figure, atom.Figcaption, atom.Details, atom.Summary, atom.Body, atom.Html,
+}
+
+// wordSeparators split the class and id attributes into words
+var wordSeparators = regexp.MustCompile(`[\s_-]+`)
+
+// Convert converts an HTML document to Markdown, keeping its main content (the <main> or <article>
+// element when there is one). base resolves the relative links. It returns the title and the Markdown.
+func Convert(document string, base *url.URL) (string, string, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", "", err
+	}
+	title := ""
+	if node := find(root, func(n *html.Node) bool { return n.DataAtom == atom.Title }); node != nil {
+		title = collapse(textContent(node))
+	}
+
+	content := find(root, func(n *html.Node) bool { return n.DataAtom == atom.Main || attribute(n, "role") == "main" })
+	if content == nil {
+		content = find(root, func(n *html.Node) bool { return n.DataAtom == atom.Article })
+	}
+	if content == nil {
+		content = root
+	}
+
+	c := converter{base: base}
+	markdown := strings.Join(c.blocks(content), "\n\n")
+	if title == "" {
+		if node := find(content, func(n *html.Node) bool { return n.DataAtom == atom.H1 }); node != nil {
+			title = collapse(textContent(node))
+		}
+	}
+	return title, strings.TrimSpace(markdown), nil
+}
+
+// Links returns the addresses of the links of an HTML document (navigation included), resolved against
+// base, without their fragment and duplicates. Only the http and https links are kept.
+func Links(document string, base *url.URL) []string {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return nil
+	}
+	var links []string
+	walk(root, func(n *html.Node) bool {
+		if n.Type != html.ElementNode || n.DataAtom != atom.A {
+			return true
+		}
+		reference, err := url.Parse(strings.TrimSpace(attribute(n, "href")))
+		if err != nil {
+			return true
+		}
+		link := base.ResolveReference(reference)
+		link.Fragment, link.RawFragment = "", ""
+		if (link.Scheme == "http" || link.Scheme == "https") && !slices.Contains(links, link.String()) {
+			links = append(links, link.String())
+		}
+		return true
+	})
+	return links
+}
+
+// converter renders HTML nodes as Markdown
+type converter struct {
+	base *url.URL
+}
+
+// blocks renders the children of a node as Markdown blocks: the runs of inline content are paragraphs
+func (c *converter) blocks(n *html.Node) []string {
+	var blocks []string
+	var paragraph strings.Builder
+	flush := func() {
+		if text := paragraphText(paragraph.String()); text != "" {
+			blocks = append(blocks, text)
+		}
+		paragraph.Reset()
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if skipped(child) {
+			continue
+		}
+		if child.Type == html.ElementNode && slices.Contains(blockElements, child.DataAtom) {
+			flush()
+			if block := c.block(child); block != "" {
+				blocks = append(blocks, block)
+			}
+			continue
+		}
+		paragraph.WriteString(c.inline(child))
+	}
+	flush()
+	return blocks
+}
+
+// block renders a block element
+func (c *converter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		text := paragraphText(c.inlineChildren(n))
+		if text == "" {
+			return ""
+		}
+		level, _ := strconv.Atoi(n.Data[1:])
+		return strings.Repeat("#", level) + " " + strings.ReplaceAll(text, "\n", " ")
+	case atom.Pre:
+		return c.codeBlock(n)
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Blockquote:
+		return prefixLines(strings.Join(c.blocks(n), "\n\n"), "> ", "> ")
+	case atom.Table:
+		return c.table(n)
+	case atom.Hr:
+		return "---"
+	case atom.Dt:
+		if text := paragraphText(c.inlineChildren(n)); text != "" {
+			return "**" + text + "**"
+		}
+		return ""
+	default:
+		return strings.Join(c.blocks(n), "\n\n")
+	}
+}
+
+// codeBlock renders a <pre> element as a fenced code block, with the language of its class
+// (e.g. class="language-go") when there is one
+func (c *converter) codeBlock(n *html.Node) string {
+	language := ""
+	for _, node := range []*html.Node{n, find(n, func(n *html.Node) bool { return n.DataAtom == atom.Code })} {
+		if node == nil {
+			continue
+		}
+		for _, class := range strings.Fields(attribute(node, "class")) {
+			if name, found := strings.CutPrefix(class, "language-"); found {
+				language = name
+			} else if name, found := strings.CutPrefix(class, "lang-"); found {
+				language = name
+			}
+		}
+	}
+	code := strings.Trim(textContent(n), "\n")
+	if strings.TrimSpace(code) == "" {
+		return ""
+	}
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// list renders a <ul> or <ol> element, its nested lists indented
+func (c *converter) list(n *html.Node) string {
+	var items []string
+	number := 1
+	if start, err := strconv.Atoi(attribute(n, "start")); err == nil {
+		number = start
+	}
+	for item := n.FirstChild; item != nil; item = item.NextSibling {
+		if item.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", number)
+			number++
+		}
+		content := strings.Join(c.blocks(item), "\n")
+		if content == "" {
+			continue
+		}
+		items = append(items, prefixLines(content, marker, strings.Repeat(" ", len(marker))))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table renders a table as a Markdown table, its first row as the header
+func (c *converter) table(n *html.Node) string {
+	var rows [][]string
+	walk(n, func(node *html.Node) bool {
+		if node.DataAtom != atom.Tr {
+			return true
+		}
+		var cells []string
+		for cell := node.FirstChild; cell != nil; cell = cell.NextSibling {
+			if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+				text := strings.ReplaceAll(paragraphText(c.inlineChildren(cell)), "\n", " ")
+				cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+		return false
+	})
+	if len(rows) == 0 {
+		return ""
+	}
+
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	var lines []string
+	for i, row := range rows {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", columns))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inlineChildren renders the children of a node as inline Markdown
+func (c *converter) inlineChildren(n *html.Node) string {
+	var builder strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		builder.WriteString(c.inline(child))
+	}
+	return builder.String()
+}
+
+// inline renders a node as inline Markdown: text, links, code, emphasis
+func (c *converter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return collapseSpaces(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if skipped(n) {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "\n"
+	case atom.Code, atom.Kbd, atom.Samp:
+		if text := collapse(textContent(n)); text != "" {
+			return "`" + text + "`"
+		}
+		return ""
+	case atom.Strong, atom.B:
+		return emphasis(c.inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return emphasis(c.inlineChildren(n), "*")
+	case atom.Img:
+		if alt := collapse(attribute(n, "alt")); alt != "" {
+			return "![" + alt + "](" + c.resolve(attribute(n, "src")) + ")"
+		}
+		return ""
+	case atom.A:
+		text := c.inlineChildren(n)
+		href := attribute(n, "href")
+		if strings.TrimSpace(text) == "" || href == "" || strings.HasPrefix(href, "#") || strings.HasPrefix(href, "javascript:") {
+			return text
+		}
+		// Keep the spaces around the link text outside of the brackets
+		leading := text[:len(text)-len(strings.TrimLeft(text, " "))]
+		trailing := text[len(strings.TrimRight(text, " ")):]
+		return leading + "[" + strings.TrimSpace(text) + "](" + c.resolve(href) + ")" + trailing
+	}
+	return c.inlineChildren(n)
+}
+
+// resolve returns the absolute URL of a link
+func (c *converter) resolve(href string) string {
+	if c.base == nil {
+		return href
+	}
+	reference, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return href
+	}
+	return c.base.ResolveReference(reference).String()
+}
+
+// skipped reports whether an element is boilerplate, hidden or not content
+func skipped(n *html.Node) bool {
+	if n.Type == html.CommentNode {
+		return true
+	}
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if slices.Contains(skippedElements, n.DataAtom) || attribute(n, "role") == "navigation" ||
+		attribute(n, "aria-hidden") == "true" || hasAttribute(n, "hidden") {
+		return true
+	}
+	for _, word := range wordSeparators.Split(strings.ToLower(attribute(n, "class")+" "+attribute(n, "id")), -1) {
+		if slices.Contains(boilerplateNames, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// emphasis wraps a text in a Markdown emphasis marker, keeping its spaces outside
+func emphasis(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:len(text)-len(strings.TrimLeft(text, " \n"))]
+	trailing := text[len(strings.TrimRight(text, " \n")):]
+	return leading + marker + trimmed + marker + trailing
+}
+
+// paragraphText cleans an inline run: its lines (split by <br>) trimmed, the double spaces collapsed
+func paragraphText(text string) string {
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(collapseSpaces(line)); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// prefixLines prefixes the first line of a text with first, and the other ones with rest
+func prefixLines(text, first, rest string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		switch {
+		case i == 0:
+			lines[i] = first + line
+		case line == "":
+			lines[i] = strings.TrimRight(rest, " ")
+		default:
+			lines[i] = rest + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// spaces matches the runs of whitespace
+var spaces = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// collapseSpaces replaces the runs of whitespace by a single space
+func collapseSpaces(text string) string {
+	return spaces.ReplaceAllString(text, " ")
+}
+
+// collapse collapses the whitespace of a text and trims it
+func collapse(text string) string {
+	return strings.TrimSpace(collapseSpaces(text))
+}
+
+// textContent returns the text of a node and its descendants
+func textContent(n *html.Node) string {
+	var builder strings.Builder
+	walk(n, func(node *html.Node) bool {
+		if node.Type == html.TextNode {
+			builder.WriteString(node.Data)
+		}
+		return true
+	})
+	return builder.String()
+}
+
+// find returns the first descendant of a node (or the node itself) matching the predicate
+func find(n *html.Node, match func(n *html.Node) bool) *html.Node {
+	var found *html.Node
+	walk(n, func(node *html.Node) bool {
+		if found == nil && node.Type == html.ElementNode && match(node) {
+			found = node
+		}
+		return found == nil
+	})
+	return found
+}
+
+// walk visits a node and its descendants in document order, the children of a node being visited
+// when visit returns true
+func walk(n *html.Node, visit func(n *html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		walk(child, visit)
+	}
+}
+
+// attribute returns the value of an attribute of an element
+func attribute(n *html.Node, name string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == name {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// hasAttribute reports whether an element has an attribute
+func hasAttribute(n *html.Node, name string) bool {
+	return slices.ContainsFunc(n.Attr, func(attr html.Attribute) bool { return attr.Key == name })
+}
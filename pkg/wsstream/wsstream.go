@@ -0,0 +1,217 @@
+package wsstream
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event represents a message mirrored to websocket clients
+type Event struct {
+	Type    string `json:"type"`
+	Status  string `json:"status,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+// Options restrict the clients of the server
+type Options struct {
+	// AllowedOrigins are the origins (e.g. "https://dashboard.example.com") of the web pages allowed to
+	// connect, besides the pages served from localhost and the clients sending no Origin (non-browser clients)
+	AllowedOrigins []string
+	// AnyOrigin allows the web pages of any origin to connect
+	AnyOrigin bool
+}
+
+// writeTimeout bounds the write of an event to a client: the clients not reading are disconnected
+const writeTimeout = 5 * time.Second
+
+// clientBuffer is the number of events queued for a client before it is disconnected as too slow
+const clientBuffer = 1024
+
+// Server mirrors streamed tokens and status events to connected websocket clients
+type Server struct {
+	mu       sync.Mutex
+	clients  map[*websocket.Conn]*client
+	closed   bool
+	writers  sync.WaitGroup
+	upgrader websocket.Upgrader
+	server   *http.Server
+	addr     string
+}
+
+// client is a connected client and the queue of the events its writer sends, so a slow client does not
+// block the answer nor the other clients
+type client struct {
+	conn *websocket.Conn
+	send chan []byte
+}
+
+// Start starts a websocket server listening on the given address. A port-only address (e.g. ":9000")
+// listens on the loopback interface: the answers may hold private RAG content, the other hosts of the
+// network must be allowed explicitly (e.g. "0.0.0.0:9000").
+func Start(addr string, options Options) (*Server, error) {
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	s := &Server{
+		clients: make(map[*websocket.Conn]*client),
+		upgrader: websocket.Upgrader{
+			// Any web page open in the browser can connect to a local port: only the trusted origins can
+			CheckOrigin: func(r *http.Request) bool { return allowedOrigin(r.Header.Get("Origin"), options) },
+		},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.handle)
+	s.server = &http.Server{Addr: addr, Handler: mux}
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s.addr = listener.Addr().String()
+	go s.server.Serve(listener)
+
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// allowedOrigin reports whether a client with this Origin header may connect: the clients without
+// Origin (scripts, editors), the pages served from localhost and the allowed origins
+func allowedOrigin(origin string, options Options) bool {
+	if origin == "" || options.AnyOrigin {
+		return true
+	}
+	if slices.ContainsFunc(options.AllowedOrigins, func(allowed string) bool {
+		return strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin)
+	}) {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	switch parsed.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	c := &client{conn: conn, send: make(chan []byte, clientBuffer)}
+	s.clients[conn] = c
+	s.writers.Add(1)
+	s.mu.Unlock()
+	go s.write(c)
+
+	// Drain incoming messages so close frames are processed
+	go func() {
+		defer s.remove(conn)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// write sends the queued events to a client until its queue is closed, then closes the connection
+func (s *Server) write(c *client) {
+	defer s.writers.Done()
+	defer c.conn.Close()
+	for data := range c.send {
+		c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+			s.remove(c.conn)
+			return
+		}
+	}
+}
+
+func (s *Server) remove(conn *websocket.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(conn)
+}
+
+// removeLocked forgets a client and closes its queue (its writer sends the events already queued and closes
+// the connection). s.mu must be held.
+func (s *Server) removeLocked(conn *websocket.Conn) {
+	if c, ok := s.clients[conn]; ok {
+		delete(s.clients, conn)
+		close(c.send)
+	}
+}
+
+func (s *Server) broadcast(event Event) {
+	if s == nil {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn, c := range s.clients {
+		select {
+		case c.send <- data:
+		default:
+			// The client does not keep up: it is disconnected rather than missing events
+			s.removeLocked(conn)
+			conn.Close()
+		}
+	}
+}
+
+// Token mirrors a streamed token to all clients
+func (s *Server) Token(content string) {
+	s.broadcast(Event{Type: "token", Content: content})
+}
+
+// Status mirrors a status event (e.g. "searching", "streaming", "done") to all clients
+func (s *Server) Status(status, content string) {
+	s.broadcast(Event{Type: "status", Status: status, Content: content})
+}
+
+// Close sends the queued events, disconnects all clients and stops the server
+func (s *Server) Close() error {
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.clients {
+		s.removeLocked(conn)
+	}
+	s.mu.Unlock()
+	s.writers.Wait()
+
+	return s.server.Close()
+}
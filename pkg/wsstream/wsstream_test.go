@@ -0,0 +1,140 @@
+package wsstream
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAllowedOrigin(t *testing.T) {
+	options := Options{AllowedOrigins: []string{"https://dashboard.example.com/"}}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://localhost:3000", true},
+		{"http://127.0.0.1:8080", true},
+		{"http://[::1]:8080", true},
+		{"https://dashboard.example.com", true},
+		{"https://DASHBOARD.example.com", true},
+		{"https://evil.example.com", false},
+		{"http://localhost.evil.com", false},
+		{"null", false},
+	}
+	for _, test := range tests {
+		if got := allowedOrigin(test.origin, options); got != test.want {
+			t.Errorf("allowedOrigin(%q) = %v, want %v", test.origin, got, test.want)
+		}
+	}
+	if !allowedOrigin("https://evil.example.com", Options{AnyOrigin: true}) {
+		t.Error("an origin was refused with AnyOrigin")
+	}
+}
+
+// dial connects a client and waits for the server to register it
+func dial(t *testing.T, s *Server) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+s.Addr()+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		s.mu.Lock()
+		registered := len(s.clients)
+		s.mu.Unlock()
+		if registered > 0 {
+			return conn
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the client was not registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// The events are received in order, the ones queued before Close included
+func TestBroadcast(t *testing.T) {
+	s, err := Start(":0", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(s.Addr(), "127.0.0.1:") {
+		t.Errorf("Addr = %s, want the loopback interface", s.Addr())
+	}
+	conn := dial(t, s)
+
+	s.Status("streaming", "")
+	for _, token := range []string{"Hello", ", ", "world"} {
+		s.Token(token)
+	}
+	s.Status("done", "")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var received []string
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var event Event
+		if err := conn.ReadJSON(&event); err != nil {
+			break
+		}
+		received = append(received, event.Type+":"+event.Status+event.Content)
+	}
+	want := "status:streaming|token:Hello|token:, |token:world|status:done"
+	if strings.Join(received, "|") != want {
+		t.Errorf("received %q, want %q", strings.Join(received, "|"), want)
+	}
+
+	// A nil server (no --ws) ignores the events
+	var none *Server
+	none.Token("ignored")
+	if err := none.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+// A client whose queue is full is disconnected, the broadcast does not wait for it
+func TestBroadcastSlowClient(t *testing.T) {
+	upgraded := make(chan *websocket.Conn, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err == nil {
+			upgraded <- conn
+		}
+	}))
+	defer server.Close()
+	clientConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clientConn.Close()
+	conn := <-upgraded
+
+	// No writer sends the queue of the client: it is full after one event
+	s := &Server{clients: map[*websocket.Conn]*client{conn: {conn: conn, send: make(chan []byte, 1)}}}
+	done := make(chan struct{})
+	go func() {
+		s.Token("queued")
+		s.Token("dropped")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the broadcast waited for the slow client")
+	}
+	if len(s.clients) != 0 {
+		t.Error("the slow client was not disconnected")
+	}
+	clientConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, _, err := clientConn.ReadMessage(); err == nil {
+		t.Error("the connection of the slow client is still open")
+	}
+}
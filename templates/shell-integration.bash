@@ -0,0 +1,44 @@
+# budgie shell integration for bash
+# Install it by adding this line to your ~/.bashrc:
+#   eval "$(budgie shell-integration bash)"
+#
+# Press Ctrl+X Ctrl+A (or the key sequence in $BUDGIE_WIDGET_KEY) to ask budgie about
+# the current command line or, when the command line is empty, about the last command
+# and its exit status. Inside tmux, the last lines of the pane (the command output) are
+# sent as context.
+
+_budgie_last_status=0
+
+_budgie_prompt_command() {
+  _budgie_last_status=$?
+}
+
+PROMPT_COMMAND="_budgie_prompt_command${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+
+_budgie_quick_ask() {
+  local question context=""
+
+  if [[ -n $READLINE_LINE ]]; then
+    question="Explain this shell command and point out any mistake: $READLINE_LINE"
+  else
+    local last_command
+    last_command=$(fc -ln -1 | sed 's/^[[:space:]]*//')
+    if (( _budgie_last_status != 0 )); then
+      question="Why did this shell command fail with exit status $_budgie_last_status, and how do I fix it? $last_command"
+    else
+      question="Explain this shell command and its output: $last_command"
+    fi
+    if [[ -n $TMUX ]]; then
+      context=$(tmux capture-pane -p -S -"${BUDGIE_WIDGET_LINES:-100}")
+    fi
+  fi
+
+  printf '\e[90m🐦 budgie is thinking...\e[0m\n'
+  if [[ -n $context ]]; then
+    printf '%s\n' "$context" | budgie ask -q "$question" --format plain --quiet -g=false 2>/dev/null
+  else
+    budgie ask -q "$question" --format plain --quiet -g=false 2>/dev/null </dev/null
+  fi
+}
+
+bind -x "\"${BUDGIE_WIDGET_KEY:-\\C-x\\C-a}\": _budgie_quick_ask"